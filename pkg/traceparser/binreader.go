@@ -198,3 +198,17 @@ func (tr versionFilterReader) Bool(defaultForOlderVersions bool) bool {
 	}
 	return tr.traceReader.Bool()
 }
+
+func (tr versionFilterReader) String(defaultForOlderVersions string) string {
+	if tr.filtered {
+		return defaultForOlderVersions
+	}
+	return tr.traceReader.String()
+}
+
+func (tr versionFilterReader) UVarint(defaultForOlderVersions uint64) uint64 {
+	if tr.filtered {
+		return defaultForOlderVersions
+	}
+	return tr.traceReader.UVarint()
+}
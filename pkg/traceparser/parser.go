@@ -470,9 +470,11 @@ func (tp *traceParser) dbTransactionEnd() *tracepb2.DBTransactionEnd {
 
 func (tp *traceParser) pubsubPublishStart() *tracepb2.PubsubPublishStart {
 	return &tracepb2.PubsubPublishStart{
-		Topic:   tp.String(),
-		Message: tp.ByteString(),
-		Stack:   tp.stack(),
+		Topic:       tp.String(),
+		Message:     tp.ByteString(),
+		Stack:       tp.stack(),
+		OrderingKey: ptrOrNil(tp.FromVer(16).String("")),
+		PayloadSize: tp.FromVer(16).UVarint(0),
 	}
 }
 
@@ -443,6 +443,8 @@ func TestParse(t *testing.T) {
 					Topic:       "topic",
 					Message:     []byte("message"),
 					Stack:       stack.Stack{},
+					OrderingKey: "customer-1",
+					PayloadSize: 7,
 				})
 			},
 			Want: &tracepb2.TraceEvent{
@@ -453,9 +455,11 @@ func TestParse(t *testing.T) {
 					DefLoc: &udefLoc,
 					Data: &tracepb2.SpanEvent_PubsubPublishStart{
 						PubsubPublishStart: &tracepb2.PubsubPublishStart{
-							Topic:   "topic",
-							Message: []byte("message"),
-							Stack:   nil,
+							Topic:       "topic",
+							Message:     []byte("message"),
+							Stack:       nil,
+							OrderingKey: ptr("customer-1"),
+							PayloadSize: 7,
 						},
 					},
 				}},
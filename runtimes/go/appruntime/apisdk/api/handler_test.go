@@ -420,7 +420,7 @@ func testServer(t *testing.T, klock clock.Clock, mockTraces bool) (*api.Server,
 	json := jsoniter.ConfigCompatibleWithStandardLibrary
 	encoreMgr := encore.NewManager(static, runtime, rt)
 	tsMgr := testsupport.NewManager(static, rt, logger)
-	pubsubMgr := pubsub.NewManager(static, runtime, rt, tsMgr, logger, json)
+	pubsubMgr := pubsub.NewManager(static, runtime, rt, tsMgr, logger, json, nil)
 	healthMgr := health.NewCheckRegistry()
 	testingMgr := testsupport.NewManager(static, rt, logger)
 	server := api.NewServer(static, runtime, rt, nil, encoreMgr, pubsubMgr, logger, metricsRegistry, healthMgr, testingMgr, json, klock)
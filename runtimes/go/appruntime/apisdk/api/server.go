@@ -124,6 +124,8 @@ type Server struct {
 	httpsrv         *http.Server
 	httpCtx         context.Context
 	httpCtxCancel   context.CancelFunc
+	bgCtx           context.Context    // for background tasks of the server; cancelled when it begins shutting down
+	bgCtxCancel     context.CancelFunc // cancels bgCtx
 	runningHandlers sync.WaitGroup
 
 	callCtr uint64
@@ -232,6 +234,7 @@ func NewServer(static *config.Static, runtime *config.Runtime, rt *reqtrack.Requ
 
 	// Now we have the handler chain setup, create the HTTP server object
 	s.httpCtx, s.httpCtxCancel = context.WithCancel(context.Background())
+	s.bgCtx, s.bgCtxCancel = context.WithCancel(context.Background())
 	s.httpsrv = &http.Server{
 		Handler: h2c.NewHandler(activeHandlersWrapper, &http2.Server{}),
 		BaseContext: func(_ net.Listener) context.Context {
@@ -242,6 +245,12 @@ func NewServer(static *config.Static, runtime *config.Runtime, rt *reqtrack.Requ
 
 	s.registerEncoreRoutes()
 
+	if pubsubMgr != nil {
+		// Let subscriptions validate the auth data of messages published on behalf of users.
+		// The type is registered after the server is created, so look it up lazily.
+		pubsubMgr.SetAuthDataType(func() reflect.Type { return RegisteredAuthDataType })
+	}
+
 	return s
 }
 
@@ -371,11 +380,21 @@ func (s *Server) Serve(ln net.Listener) error {
 	if s.runtime.EnvCloud != "local" || s.IsGateway() {
 		s.rootLogger.Info().Msg("listening for incoming HTTP requests")
 	}
+
+	// Report any differences between the declared pubsub topology
+	// and what exists at the cloud provider, without changing anything.
+	if s.pubsubMgr != nil && s.runtime.EnvCloud != "local" {
+		go s.pubsubMgr.LogTopologyDrift(s.bgCtx)
+	}
+
 	return s.httpsrv.Serve(ln)
 }
 
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(p *shutdown.Process) error {
+	// Stop any background tasks right away.
+	s.bgCtxCancel()
+
 	// Once it's time to force-close tasks, cancel the base context.
 	go func() {
 		<-p.ForceCloseTasks.Done()
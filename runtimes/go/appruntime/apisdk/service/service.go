@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -21,6 +22,11 @@ import (
 	eshutdown "encore.dev/shutdown"
 )
 
+// ErrInitFailed is reported when a service struct could not be initialized
+// because its initService function returned an error.
+// Initialization is attempted again the next time the service is used.
+var ErrInitFailed = errors.New("initialization failed")
+
 // Initializer is a service initializer.
 type Initializer interface {
 	// ServiceName reports the name of the service.
@@ -82,7 +88,7 @@ func doSetupService[T any](mgr *Manager, decl *Decl[T], holder *InstanceHolder[T
 	instance, err := setupFn()
 	if err != nil {
 		mgr.rt.Logger().Error().Err(err).Str("service", decl.Service).Msg("service initialization failed")
-		return errs.B().Code(errs.Internal).Msgf("service %s: initialization failed", decl.Service).Err()
+		return errs.B().Code(errs.Internal).Cause(ErrInitFailed).Msgf("service %s: initialization failed", decl.Service).Err()
 	}
 	holder.instance = instance
 
@@ -260,8 +260,11 @@ type NSQProvider struct {
 
 type EncoreCloudPubsubProvider struct{}
 
-// GCPPubsubProvider currently has no specific configuration.
 type GCPPubsubProvider struct {
+	// CheckTopology, if set, makes the application compare the topics and
+	// subscriptions it declares against what exists in GCP when it starts,
+	// logging any differences. It never changes anything in GCP.
+	CheckTopology bool `json:"check_topology,omitempty"`
 }
 
 // AWSPubsubProvider currently has no specific configuration.
@@ -133,6 +133,12 @@ type PubSubMsgData struct {
 	DecodedPayload any
 	// Payload is the JSON-encoded payload.
 	Payload []byte
+
+	// UserID and AuthData are the authentication information
+	// of the user the message was published on behalf of,
+	// if the subscription propagates auth.
+	UserID   UID
+	AuthData any
 }
 
 type TestData struct {
@@ -172,9 +178,21 @@ type TestConfig struct {
 	ServiceMocks     map[string]ServiceMock
 	APIMocks         map[string]map[string]ApiMock
 	IsolatedServices *bool                // Whether to isolate services for this test
+	InMemoryPubsub   *bool                // Whether published messages are delivered to subscriptions
+	PubsubRedelivery *PubsubRedelivery    // How in-memory pubsub simulates redelivery, if at all
+	PubsubLease      *time.Duration       // The lease in-memory pubsub gives delivered messages, if any
 	EndCallbacks     []func(t *testing.T) // Callbacks to run when the test ends
 }
 
+// PubsubRedelivery configures how in-memory pubsub simulates a broker
+// redelivering messages, to surface subscriptions which aren't idempotent
+// or which depend on the order messages are delivered in.
+type PubsubRedelivery struct {
+	Seed           int64   // The seed for the random source, making deliveries reproducible
+	DuplicateRate  float64 // The probability of a message being delivered again
+	PrefetchWindow int     // How many messages may be delivered out of order
+}
+
 type ServiceMock struct {
 	Service       any
 	RunMiddleware bool
@@ -522,9 +522,11 @@ func (l *Log) DBTransactionEnd(p DBTransactionEndParams) {
 
 type PubsubPublishStartParams struct {
 	EventParams
-	Topic   string
-	Message []byte
-	Stack   stack.Stack
+	Topic       string
+	Message     []byte
+	Stack       stack.Stack
+	OrderingKey string // the message's ordering key, if any
+	PayloadSize int    // the size of the published payload in bytes
 }
 
 func (l *Log) PubsubPublishStart(p PubsubPublishStartParams) EventID {
@@ -536,6 +538,8 @@ func (l *Log) PubsubPublishStart(p PubsubPublishStartParams) EventID {
 	tb.String(p.Topic)
 	tb.ByteString(p.Message)
 	tb.Stack(p.Stack)
+	tb.String(p.OrderingKey)
+	tb.UVarint(uint64(p.PayloadSize))
 
 	return l.Add(Event{
 		Type:    PubsubPublishStart,
@@ -192,5 +192,18 @@ func (t *RequestTracker) clearReq() {
 	e.req = nil
 }
 
+// restoreReq restores req as the request data of the running g
+// without incrementing the ref count, undoing a clearReq.
+// The g must be part of an operation and not processing a request.
+func (t *RequestTracker) restoreReq(req *encoreReq) {
+	e := t.impl.get()
+	if e == nil {
+		panic("encore.restoreReq: goroutine not in an operation")
+	} else if e.req != nil {
+		panic("encore.restoreReq: request already running")
+	}
+	e.req = req
+}
+
 //go:linkname nanotime runtime.nanotime
 func nanotime() int64
@@ -40,6 +40,25 @@ func (t *RequestTracker) FinishOperation() {
 	t.finishOp()
 }
 
+// InOperation reports whether the current goroutine is already part of an operation,
+// in which case BeginOperation must not be called.
+func (t *RequestTracker) InOperation() bool {
+	return t.impl.get() != nil
+}
+
+// SaveRequest saves the current goroutine's request, if any, so that it can be
+// restored by calling restore once a request begun within it has finished.
+// BeginRequest replaces the current request, so without restoring it
+// the goroutine would no longer be processing a request afterwards.
+func (t *RequestTracker) SaveRequest() (restore func()) {
+	e := t.impl.get()
+	if e == nil || e.req == nil {
+		return func() {}
+	}
+	req := e.req
+	return func() { t.restoreReq(req) }
+}
+
 func (t *RequestTracker) BeginRequest(req *model.Request) {
 	if prev, _, _, _ := t.currentReq(); prev != nil {
 		copyReqInfoFromParent(req, prev)
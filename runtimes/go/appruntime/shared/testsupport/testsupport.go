@@ -280,6 +280,65 @@ func (mgr *Manager) GetIsolatedServices() bool {
 	return *result
 }
 
+// SetInMemoryPubsub sets whether published messages should be delivered to subscriptions for the current test
+func (mgr *Manager) SetInMemoryPubsub(enabled bool) {
+	cfg := mgr.currentConfig()
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	cfg.InMemoryPubsub = &enabled
+}
+
+// GetInMemoryPubsub returns whether published messages are delivered to subscriptions for the current test
+func (mgr *Manager) GetInMemoryPubsub() bool {
+	result, _ := walkConfig(mgr.currentConfig(), func(cfg *TestConfig) (value *bool, found bool) {
+		value, found = cfg.InMemoryPubsub, cfg.InMemoryPubsub != nil
+		return
+	})
+
+	if result == nil {
+		return false
+	}
+	return *result
+}
+
+// SetPubsubRedelivery sets how in-memory pubsub simulates redelivering messages for the current test
+func (mgr *Manager) SetPubsubRedelivery(redelivery model.PubsubRedelivery) {
+	cfg := mgr.currentConfig()
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	cfg.PubsubRedelivery = &redelivery
+}
+
+// GetPubsubRedelivery returns how in-memory pubsub simulates redelivering messages for the current test,
+// or nil if messages are delivered exactly once and in order
+func (mgr *Manager) GetPubsubRedelivery() *model.PubsubRedelivery {
+	result, _ := walkConfig(mgr.currentConfig(), func(cfg *TestConfig) (value *model.PubsubRedelivery, found bool) {
+		value, found = cfg.PubsubRedelivery, cfg.PubsubRedelivery != nil
+		return
+	})
+	return result
+}
+
+// SetPubsubLease sets the lease in-memory pubsub gives messages delivered in the current test
+func (mgr *Manager) SetPubsubLease(lease time.Duration) {
+	cfg := mgr.currentConfig()
+	cfg.Mu.Lock()
+	defer cfg.Mu.Unlock()
+	cfg.PubsubLease = &lease
+}
+
+// GetPubsubLease returns the lease in-memory pubsub gives messages delivered in the current test,
+// or zero if messages are delivered without a lease
+func (mgr *Manager) GetPubsubLease() time.Duration {
+	result, _ := walkConfig(mgr.currentConfig(), func(cfg *TestConfig) (value time.Duration, found bool) {
+		if cfg.PubsubLease != nil {
+			value, found = *cfg.PubsubLease, true
+		}
+		return
+	})
+	return result
+}
+
 // SetServiceMock allows us to set a mock for a service for the current test
 func (mgr *Manager) SetServiceMock(service string, mock any, runMiddleware bool) {
 	service = strings.TrimSpace(strings.ToLower(service))
@@ -29,6 +29,11 @@ func (mgr *Manager) UserID() (UID, bool) {
 		if curr.Req.RPCData != nil {
 			uid := curr.Req.RPCData.UserID
 			return uid, uid != ""
+		} else if curr.Req.MsgData != nil {
+			// Messages are only processed on behalf of the user who published them,
+			// even under test, so the auth information of the test isn't reported
+			uid := curr.Req.MsgData.UserID
+			return uid, uid != ""
 		} else if curr.Req.Test != nil {
 			uid := curr.Req.Test.UserID
 			return uid, uid != ""
@@ -41,6 +46,8 @@ func (mgr *Manager) Data() interface{} {
 	if curr := mgr.rt.Current(); curr.Req != nil {
 		if curr.Req.RPCData != nil {
 			return curr.Req.RPCData.AuthData
+		} else if curr.Req.MsgData != nil {
+			return curr.Req.MsgData.AuthData
 		} else if curr.Req.Test != nil {
 			return curr.Req.Test.AuthData
 		}
@@ -15,6 +15,9 @@ func (mgr *Manager) OverrideAuthInfo(uid auth.UID, authData any) {
 		if rpcData := curr.Req.RPCData; rpcData != nil {
 			rpcData.UserID = uid
 			rpcData.AuthData = authData
+		} else if msgData := curr.Req.MsgData; msgData != nil {
+			msgData.UserID = uid
+			msgData.AuthData = authData
 		} else if testData := curr.Req.Test; testData != nil {
 			testData.UserID = uid
 			testData.AuthData = authData
@@ -4,8 +4,13 @@ package et
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"time"
 
+	"encore.dev/appruntime/exported/model"
 	"encore.dev/beta/auth"
+	"encore.dev/pubsub"
 	"encore.dev/storage/sqldb"
 )
 
@@ -24,6 +29,15 @@ import (
 // API calls made with these options will not be made and will immediately return
 // a client-side error.
 //
+// Messages published from the request to a topic with PropagateAuth set record
+// the overridden auth information, so with in-memory pubsub enabled a test can
+// check a subscription processes them on behalf of the same user:
+//
+//	et.EnableInMemoryPubsub()
+//	et.OverrideAuthInfo("some-user", nil)
+//	_, err := MyTopic.Publish(ctx, &MyEvent{}) // the handler of a subscription with
+//	                                           // PropagateAuth set sees "some-user"
+//
 // OverrideAuthInfo is not safe for concurrent use with code that invokes
 // auth.UserID or auth.Data() within the same request.
 func OverrideAuthInfo(uid auth.UID, data any) {
@@ -43,6 +57,79 @@ func EnableServiceInstanceIsolation() {
 	Singleton.testMgr.SetIsolatedServices(true)
 }
 
+// EnableInMemoryPubsub causes messages published to any topic from this test and
+// any of its sub-tests to be delivered to the topic's subscriptions. (Calling this in
+// a TestMain has the impact of enabling it for all tests in the package.)
+//
+// Publish waits for the subscriptions to process the message before returning,
+// which allows end-to-end flows to be tested without mocking the subscriptions.
+// Any errors returned by a subscription handler will cause the test to fail.
+//
+// As in production, subscription handlers process messages on behalf of the user
+// who published them only if the auth information was propagated with the message
+// (see pubsub.TopicConfig.PropagateAuth); they don't see the auth information of the test.
+//
+// By default, published messages are only recorded for the test (see Topic)
+// and are not delivered to subscriptions.
+func EnableInMemoryPubsub() {
+	Singleton.testMgr.SetInMemoryPubsub(true)
+}
+
+// RedeliveryOptions configures how SimulateRedelivery redelivers messages.
+type RedeliveryOptions struct {
+	// DuplicateRate is the probability, between 0 and 1, of a message
+	// being delivered to a subscription a second time.
+	DuplicateRate float64
+
+	// PrefetchWindow is how many published messages are held back and
+	// then delivered in a random order, as a broker does with the messages
+	// a subscriber has prefetched. If zero or one, messages are delivered
+	// in the order they are published.
+	PrefetchWindow int
+}
+
+// SimulateRedelivery enables in-memory pubsub (see EnableInMemoryPubsub) for this test
+// and any of its sub-tests, delivering messages the way a broker may: sometimes more than once,
+// and out of order within the prefetch window. This helps find subscriptions which are not
+// idempotent or which depend on the order of messages.
+//
+// Deliveries are random, but reproducible: each test draws from its own random source
+// seeded with seed, so a test publishing the same messages sees the same deliveries
+// on every run, regardless of the other tests being run.
+//
+// Publish returns once the messages it caused to be delivered have been processed,
+// which may not include the published message itself. Any messages still held back
+// are delivered when the test ends.
+func SimulateRedelivery(seed int64, opts RedeliveryOptions) {
+	if opts.DuplicateRate < 0 || opts.DuplicateRate > 1 {
+		panic("RedeliveryOptions.DuplicateRate must be between 0 and 1")
+	}
+	if opts.PrefetchWindow < 0 {
+		panic("RedeliveryOptions.PrefetchWindow cannot be negative")
+	}
+	Singleton.testMgr.SetInMemoryPubsub(true)
+	Singleton.testMgr.SetPubsubRedelivery(model.PubsubRedelivery{
+		Seed:           seed,
+		DuplicateRate:  opts.DuplicateRate,
+		PrefetchWindow: opts.PrefetchWindow,
+	})
+}
+
+// SetPubsubLease gives messages delivered by in-memory pubsub (see EnableInMemoryPubsub)
+// and by AssertIdempotent a lease of the given duration for this test
+// and any of its sub-tests, starting when each message is delivered.
+//
+// Like backends which lease messages, the subscription handler's context is
+// cancelled once the lease expires, and pubsub.LeaseDeadline reports when it does.
+// This helps test handlers which checkpoint their work as the lease runs down.
+// A lease of zero delivers messages without a lease, which is the default.
+func SetPubsubLease(lease time.Duration) {
+	if lease < 0 {
+		panic("lease cannot be negative")
+	}
+	Singleton.testMgr.SetPubsubLease(lease)
+}
+
 //publicapigen:keep
 type stringLiteral string
 
@@ -57,7 +144,88 @@ type stringLiteral string
 // and is automatically dropped at the end of the test, and any
 // open connections are automatically closed.
 //
+// To inspect the database of a failed test, set the ENCORE_KEEP_TEST_DB
+// environment variable to 1 when running the tests. The databases of failed
+// tests are then kept instead of dropped, and their connection URLs are
+// written to the test log. The databases of tests that pass are still dropped.
+//
 // The provided name must be a constant string literal (like "mydb").
 func NewTestDatabase(ctx context.Context, name stringLiteral) (*sqldb.Database, error) {
 	return Singleton.db.NewTestDatabase(ctx, string(name))
 }
+
+// PublishCount returns the number of messages published to any topic since
+// the test binary started, or since ResetPublishCount was last called.
+//
+// The count is shared by all tests in the package, so tests relying on it
+// should not be run in parallel with tests that publish messages.
+// To assert on the messages published to a specific topic during the current
+// test, use [Topic] instead.
+func PublishCount() uint64 {
+	return pubsub.Singleton.PublishCount()
+}
+
+// ResetPublishCount resets the count returned by PublishCount to zero.
+// Call it at the start of a test to make assertions on the count independent
+// of the tests that ran before it.
+func ResetPublishCount() {
+	pubsub.Singleton.ResetPublishCount()
+}
+
+// AssertIdempotent asserts that the subscription's handler is idempotent, by delivering
+// msg to it twice as the messaging service would when redelivering a message.
+//
+// The message is processed through the same pipeline as a real delivery, so the handler
+// runs with the same request context, service instances and published message capture
+// (see [Topic]) as it would if the message had been published during the test.
+//
+// The observe function is called after each delivery to capture the observable effect
+// of processing the message, such as rows in a database or messages published to a topic.
+// The current test fails if either delivery returns an error, or if the two observations
+// differ according to reflect.DeepEqual.
+//
+// For example:
+//
+//	et.AssertIdempotent(billing.ChargeSub, &OrderPlaced{ID: id}, func() any {
+//		return et.Topic(billing.Receipts).PublishedMessages()
+//	})
+func AssertIdempotent[T any](sub *pubsub.Subscription[T], msg T, observe func() any) {
+	t := Singleton.testMgr.CurrentTest()
+	t.Helper()
+
+	msgID := fmt.Sprintf("%s/idempotency-check", t.Name())
+	if err := pubsub.DeliverTestMessage(sub, msg, msgID, 1); err != nil {
+		t.Fatalf("AssertIdempotent: first delivery of message %s failed: %v", msgID, err)
+	}
+	first := observe()
+
+	if err := pubsub.DeliverTestMessage(sub, msg, msgID, 2); err != nil {
+		t.Fatalf("AssertIdempotent: redelivery of message %s failed: %v", msgID, err)
+	}
+	second := observe()
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("AssertIdempotent: handler is not idempotent; redelivering message %s changed the observed effect\nafter first delivery: %+v\nafter redelivery:     %+v", msgID, first, second)
+	}
+}
+
+// CaptureMessageSpans starts capturing the trace spans of messages processed by
+// subscriptions during the current test, and returns a function reporting the
+// spans captured so far. Capturing stops when the test completes.
+//
+// Only spans of messages processed within the current test are captured,
+// so tests capturing spans can run in parallel. Capturing has no effect
+// on how traces are recorded outside of tests.
+//
+// For example:
+//
+//	spans := et.CaptureMessageSpans()
+//	et.AssertIdempotent(email.SendWelcome, &SignupEvent{UserID: "u1"}, observe)
+//	for _, span := range spans() {
+//		if span.Type != model.PubSubMessage || span.Subscription != "send-welcome-email" {
+//			t.Errorf("unexpected span: %+v", span)
+//		}
+//	}
+func CaptureMessageSpans() func() []pubsub.MessageSpan {
+	return pubsub.Singleton.CaptureMessageSpans(Singleton.testMgr.CurrentTest())
+}
@@ -17,4 +17,14 @@ func Topic[T any](topic *pubsub.Topic[T]) TopicHelpers[T] {
 type TopicHelpers[T any] interface {
 	// PublishedMessages returns a slice of all messages published during this test on this topic.
 	PublishedMessages() []T
+
+	// PublishedAttributes returns the attributes of each message published during this test
+	// on this topic, in the same order as PublishedMessages. They include the attributes
+	// Encore adds to messages, such as those recording the trace context.
+	PublishedAttributes() []map[string]string
+
+	// AssertAttributes fails the test unless the attributes of every message published
+	// during this test on this topic satisfy pred. It reports each message which doesn't
+	// satisfy pred, and also fails the test if no messages were published at all.
+	AssertAttributes(pred func(attrs map[string]string) bool)
 }
@@ -35,9 +35,11 @@ require (
 	go.encore.dev/platform-sdk v1.1.0
 	go.uber.org/automaxprocs v1.5.3
 	golang.org/x/crypto v0.19.0
+	golang.org/x/net v0.21.0
 	golang.org/x/time v0.5.0
 	google.golang.org/api v0.143.0
 	google.golang.org/genproto/googleapis/api v0.0.0-20230913181813-007df8e322eb
+	google.golang.org/grpc v1.59.0
 	google.golang.org/protobuf v1.31.0
 )
 
@@ -87,7 +89,6 @@ require (
 	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
 	go.opencensus.io v0.24.0 // indirect
-	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/oauth2 v0.12.0 // indirect
 	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
@@ -95,6 +96,5 @@ require (
 	google.golang.org/appengine v1.6.8 // indirect
 	google.golang.org/genproto v0.0.0-20230913181813-007df8e322eb // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230920204549-e6e6cdab5c13 // indirect
-	google.golang.org/grpc v1.59.0 // indirect
 	nhooyr.io/websocket v1.8.7 // indirect
 )
@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// asyncAckAttempts is the number of times an asynchronous
+	// acknowledgement is attempted before giving up on it.
+	asyncAckAttempts = 3
+
+	// asyncAckBackoff is how long to wait after the first failed attempt
+	// at an asynchronous acknowledgement. It grows with each attempt.
+	asyncAckBackoff = 100 * time.Millisecond
+)
+
+// asyncAcker acknowledges messages in the background for subscriptions
+// with AsyncAck enabled, retrying failed acknowledgements.
+//
+// Acknowledgements are only handed to it once their Handler has succeeded,
+// so giving up on one only means the message will be redelivered.
+type asyncAcker struct {
+	log zerolog.Logger
+
+	pending    atomic.Int64     // number of acknowledgements in progress
+	failures   atomic.Uint64    // number of acknowledgements given up on
+	maxLatency atomic.Int64     // longest time an acknowledgement took to complete, as a time.Duration
+	latency    latencyHistogram // the time acknowledgements took to complete
+}
+
+// ack completes the acknowledgement of msgID in the background.
+func (a *asyncAcker) ack(msgID string, ack func() error) {
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Add(-1)
+
+		start := time.Now()
+		for attempt := 1; ; attempt++ {
+			err := ack()
+			if err == nil {
+				latency := time.Since(start)
+				storeMax(&a.maxLatency, int64(latency))
+				a.latency.record(latency)
+				return
+			}
+			if attempt == asyncAckAttempts {
+				a.failures.Add(1)
+				a.log.Warn().Err(err).Str("msg_id", msgID).Int("attempts", attempt).
+					Msg("failed to acknowledge message, it will be redelivered")
+				return
+			}
+			time.Sleep(time.Duration(attempt) * asyncAckBackoff)
+		}
+	}()
+}
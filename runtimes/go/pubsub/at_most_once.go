@@ -0,0 +1,53 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// ackFirst wraps process so messages are acknowledged as soon as they are
+// received, processing them in the background for AtMostOnce topics.
+//
+// Failures are logged rather than returned, as the message has already been
+// acknowledged. At most maxConcurrency messages are processed at once, if
+// positive, holding back the provider from delivering more until one completes.
+// Messages being processed are tracked as running handlers, so shutting down
+// waits for them as usual.
+func (s *Subscription[T]) ackFirst(log zerolog.Logger, maxConcurrency int, process types.RawSubscriptionCallback) types.RawSubscriptionCallback {
+	var slots chan struct{}
+	if maxConcurrency > 0 {
+		slots = make(chan struct{}, maxConcurrency)
+	}
+
+	return func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) error {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// The message outlives the delivery, so process a copy of it with the handler
+		// context, which is only cancelled once shutting down is forced.
+		attrs, data = maps.Clone(attrs), bytes.Clone(data)
+		s.mgr.runningHandlers.Add(1)
+		go func() {
+			defer s.mgr.runningHandlers.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+			if err := process(s.mgr.ctxs.Handler, msgID, publishTime, deliveryAttempt, attrs, data); err != nil {
+				log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+					Msg("failed to process message delivered at most once, dropping it")
+			}
+		}()
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+)
+
+func TestSubscription_AtMostOnce(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(zerolog.SyncWriter(&buf))
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtMostOnce})
+
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			<-release
+			handled <- msg.Value
+			if msg.Value == "fail" {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	})
+	c.Assert(sub.Config().RetryPolicy.MaxRetries, qt.Equals, NoRetries)
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages are acknowledged before the handler has run,
+	// and failures are logged and dropped rather than retried
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"ok"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Value":"fail"}`)), qt.IsNil)
+	close(release)
+	c.Assert([]string{<-handled, <-handled}, qt.ContentEquals, []string{"ok", "fail"})
+
+	// Running handlers are waited on as usual
+	mgr.runningHandlers.Wait()
+	c.Assert(buf.String(), qt.Contains, `"msg_id":"2","delivery_attempt":1,"message":"failed to process message delivered at most once, dropping it"`)
+	c.Assert(mgr.DroppedMessages().ByReason[DropRetriesExhausted], qt.Equals, uint64(1))
+}
+
+func TestSubscription_AtMostOnceRetryPolicy(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtMostOnce})
+
+	// Messages are never retried, so a RetryPolicy would be silently ignored
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler:     func(ctx context.Context, msg *testEvent) error { return nil },
+			RetryPolicy: &RetryPolicy{MaxRetries: 10},
+		})
+	}, qt.PanicMatches, "RetryPolicy cannot be set for subscriptions to AtMostOnce topics")
+}
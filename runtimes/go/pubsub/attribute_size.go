@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+)
+
+// maxAttributeBytes returns the limit the topic's messaging service
+// has on the total size of a message's attributes, or 0 if it has none.
+func maxAttributeBytes(impl types.TopicImplementation) int {
+	if al, ok := impl.(types.AttributeLimiter); ok {
+		return al.MaxAttributeBytes()
+	}
+	return 0
+}
+
+// checkAttributeSize checks the total size of the names and values of attrs,
+// including those Encore sets, is within limit, so that messages which the
+// messaging service would reject fail with a clear error naming the largest
+// attributes. It does nothing if limit is 0.
+func checkAttributeSize(topic string, attrs map[string]string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	total := 0
+	for name, value := range attrs {
+		total += len(name) + len(value)
+	}
+	if total <= limit {
+		return nil
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	size := func(name string) int { return len(name) + len(attrs[name]) }
+	sort.Slice(names, func(i, j int) bool {
+		if si, sj := size(names[i]), size(names[j]); si != sj {
+			return si > sj
+		}
+		return names[i] < names[j]
+	})
+
+	const maxListed = 3
+	largest := make([]string, 0, maxListed)
+	for _, name := range names[:min(len(names), maxListed)] {
+		largest = append(largest, fmt.Sprintf("%s (%d bytes)", name, size(name)))
+	}
+	return errs.B().Code(errs.InvalidArgument).Msgf(
+		"message attributes for topic %s total %d bytes, exceeding the limit of %d bytes; the largest are %s",
+		topic, total, limit, strings.Join(largest, ", ")).Err()
+}
@@ -0,0 +1,45 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/beta/errs"
+)
+
+func TestTopic_AttributeSize(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	fake.maxAttrBytes = 100
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages within the limit are published
+	_, err := topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithAttributes(map[string]string{"tenant": "acme"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.published, qt.Equals, 1)
+
+	// Messages exceeding it are rejected before reaching the messaging service, naming the largest attributes
+	_, err = topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithAttributes(map[string]string{
+		"tenant":  "acme",
+		"headers": strings.Repeat("x", 80),
+		"region":  "eu-west-1",
+	}))
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+	c.Assert(err, qt.ErrorMatches, `.*message attributes for topic topic total 112 bytes, exceeding the limit of 100 bytes; the largest are headers \(87 bytes\), region \(15 bytes\), tenant \(10 bytes\)`)
+	c.Assert(ft.published, qt.Equals, 1)
+}
+
+func TestCheckAttributeSize(t *testing.T) {
+	c := qt.New(t)
+	attrs := map[string]string{"a": strings.Repeat("x", 99)}
+	c.Assert(checkAttributeSize("topic", attrs, 100), qt.IsNil)
+	c.Assert(checkAttributeSize("topic", attrs, 99), qt.ErrorMatches, `.*total 100 bytes, exceeding the limit of 99 bytes; the largest are a \(100 bytes\)`)
+
+	// No limit is enforced if the messaging service has none
+	c.Assert(checkAttributeSize("topic", attrs, 0), qt.IsNil)
+}
@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedAttributePrefix is the prefix of the attributes Encore uses to record
+// information about messages. Messages and publish options cannot set attributes
+// with names starting with it.
+const reservedAttributePrefix = "encore_"
+
+// attributeSource is where an attribute of a message being published was set.
+type attributeSource int
+
+const (
+	// fromMessage attributes are set by the message's `pubsub-attr` fields.
+	fromMessage attributeSource = iota + 1
+
+	// fromOption attributes are set using WithAttributes.
+	fromOption
+
+	// fromEncore attributes are set by Encore, to encode the message
+	// or record how it was published.
+	fromEncore
+)
+
+// attributeSet collects the attributes of a message being published.
+//
+// Every attribute is set through it, so conflicts between the sources of attributes
+// are resolved in one place, following the precedence documented on
+// TopicConfig.MergeAttribute. The first conflict which can't be resolved
+// is reported by Err.
+type attributeSet struct {
+	values  map[string]string
+	sources map[string]attributeSource
+
+	// merge resolves conflicts between attributes set by the message and
+	// by publish options, if set. See TopicConfig.MergeAttribute.
+	merge func(name, messageValue, optionValue string) (string, error)
+
+	err error
+}
+
+func newAttributeSet() *attributeSet {
+	return &attributeSet{
+		values:  make(map[string]string),
+		sources: make(map[string]attributeSource),
+	}
+}
+
+// set sets the attribute name to value, as set by src.
+func (a *attributeSet) set(src attributeSource, name, value string) {
+	if a.err != nil {
+		return
+	}
+
+	if src != fromEncore && strings.HasPrefix(name, reservedAttributePrefix) {
+		a.err = fmt.Errorf("attribute %s is reserved for use by Encore", name)
+		return
+	}
+
+	prev, exists := a.sources[name]
+	switch {
+	case !exists || prev == src:
+		// No conflict to resolve
+	case prev == fromEncore || src == fromEncore:
+		a.err = fmt.Errorf("attribute %s conflicts with the attribute Encore sets to encode the message", name)
+		return
+	case prev == fromMessage && src == fromOption && a.merge != nil:
+		merged, err := a.merge(name, a.values[name], value)
+		if err != nil {
+			a.err = fmt.Errorf("merge attribute %s: %w", name, err)
+			return
+		}
+		value = merged
+	}
+
+	a.values[name] = value
+	a.sources[name] = src
+}
+
+// Err reports the first conflict between attributes which couldn't be resolved, if any.
+func (a *attributeSet) Err() error {
+	return a.err
+}
+
+// clone returns a copy of a, so more attributes can be set without affecting a.
+func (a *attributeSet) clone() *attributeSet {
+	c := newAttributeSet()
+	for name, value := range a.values {
+		c.values[name] = value
+		c.sources[name] = a.sources[name]
+	}
+	c.merge = a.merge
+	c.err = a.err
+	return c
+}
@@ -0,0 +1,110 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/model"
+)
+
+// AuditOutcome describes what a subscription did with a message.
+type AuditOutcome string
+
+const (
+	// AuditProcessed means the Handler processed the message successfully,
+	// and the message was acknowledged.
+	AuditProcessed AuditOutcome = "processed"
+
+	// AuditFailed means the message could not be processed, and it was
+	// negatively acknowledged so that it will be redelivered
+	// (unless the retry policy's MaxRetries has been reached).
+	AuditFailed AuditOutcome = "failed"
+
+	// AuditSkipped means the message was acknowledged without calling the
+	// Handler, for example because it had already been processed or
+	// because it could not be decoded and the DecodeErrorPolicy dropped it.
+	AuditSkipped AuditOutcome = "skipped"
+)
+
+// AuditRecord is a record of a subscription processing a message.
+type AuditRecord struct {
+	Topic        string        // the topic name
+	Subscription string        // the subscription name
+	MessageID    string        // the message ID assigned by the messaging service
+	Attempt      int           // the delivery attempt, starting at 1
+	Outcome      AuditOutcome  // what the subscription did with the message
+	Err          error         // the error processing the message, if any
+	Start        time.Time     // when the subscription received the message
+	Duration     time.Duration // how long processing the message took
+
+	// UserID is the authenticated user the message was processed as, if any.
+	// It is the same type as auth.UID.
+	UserID model.UID
+}
+
+// AuditSink receives an AuditRecord for every message delivered to a subscription.
+//
+// WriteAuditRecord is called after the message has been processed and before it is
+// acknowledged. If it returns an error the error is logged, but the message is
+// acknowledged (or not) the same as if no AuditSink was configured.
+//
+// Implementations must be safe for concurrent use.
+//
+// For example, to record each message in a database:
+//
+//	type dbAuditSink struct{ db *sqldb.Database }
+//
+//	func (s dbAuditSink) WriteAuditRecord(ctx context.Context, rec *pubsub.AuditRecord) error {
+//		var errMsg *string
+//		if rec.Err != nil {
+//			msg := rec.Err.Error()
+//			errMsg = &msg
+//		}
+//		_, err := s.db.Exec(ctx, `
+//			INSERT INTO pubsub_audit (topic, subscription, message_id, attempt, outcome, error, started_at, duration_ms, user_id)
+//			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+//		`, rec.Topic, rec.Subscription, rec.MessageID, rec.Attempt, string(rec.Outcome), errMsg,
+//			rec.Start, rec.Duration.Milliseconds(), string(rec.UserID))
+//		return err
+//	}
+type AuditSink interface {
+	WriteAuditRecord(ctx context.Context, rec *AuditRecord) error
+}
+
+// writeAuditRecord completes rec with the result of processing the message
+// and writes it to sink, logging any error.
+func writeAuditRecord(ctx context.Context, log zerolog.Logger, sink AuditSink, rec *AuditRecord, handled bool, err error) {
+	rec.Duration = time.Since(rec.Start)
+	rec.Err = err
+	switch {
+	case err != nil:
+		rec.Outcome = AuditFailed
+	case handled:
+		rec.Outcome = AuditProcessed
+	default:
+		rec.Outcome = AuditSkipped
+	}
+
+	if auditErr := sink.WriteAuditRecord(ctx, rec); auditErr != nil {
+		log.Err(auditErr).Str("msg_id", rec.MessageID).Int("delivery_attempt", rec.Attempt).
+			Str("outcome", string(rec.Outcome)).Msg("failed to write audit record")
+	}
+}
+
+// requestUserID returns the authenticated user of req, if any.
+func requestUserID(req *model.Request) model.UID {
+	switch {
+	case req == nil:
+		return ""
+	case req.RPCData != nil:
+		return req.RPCData.UserID
+	case req.MsgData != nil:
+		return req.MsgData.UserID
+	case req.Test != nil:
+		return req.Test.UserID
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"encore.dev/appruntime/exported/model"
+)
+
+const (
+	// authUIDAttribute is the attribute name we use to record the user
+	// a message was published on behalf of; see TopicConfig.PropagateAuth
+	authUIDAttribute = "encore_auth_uid"
+
+	// authDataAttribute is the attribute name we use to record the JSON-encoded
+	// auth data of the user a message was published on behalf of
+	authDataAttribute = "encore_auth_data"
+)
+
+// errInvalidAuth is reported when the auth information of a message
+// does not match the application's auth handler.
+var errInvalidAuth = errors.New("invalid auth information")
+
+// SetAuthDataType is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+//
+//publicapigen:drop
+func (mgr *Manager) SetAuthDataType(typ func() reflect.Type) {
+	mgr.authDataType = typ
+}
+
+// requestAuthData returns the auth data of the user a request is made on behalf of, if any.
+func requestAuthData(req *model.Request) any {
+	switch {
+	case req == nil:
+		return nil
+	case req.RPCData != nil:
+		return req.RPCData.AuthData
+	case req.MsgData != nil:
+		return req.MsgData.AuthData
+	case req.Test != nil:
+		return req.Test.AuthData
+	default:
+		return nil
+	}
+}
+
+// marshalAuth records the auth information of req in attrs.
+func (mgr *Manager) marshalAuth(req *model.Request, attrs *attributeSet) error {
+	uid := requestUserID(req)
+	if uid == "" {
+		return nil
+	}
+	attrs.set(fromEncore, authUIDAttribute, string(uid))
+	if data := requestAuthData(req); data != nil {
+		encoded, err := mgr.json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("marshal auth data: %w", err)
+		}
+		attrs.set(fromEncore, authDataAttribute, string(encoded))
+	}
+	return nil
+}
+
+// unmarshalAuth decodes the auth information recorded in attrs by marshalAuth,
+// checking it matches the auth data type of the application's auth handler.
+func (mgr *Manager) unmarshalAuth(attrs map[string]string) (uid model.UID, data any, err error) {
+	uid = model.UID(attrs[authUIDAttribute])
+	encoded, hasData := attrs[authDataAttribute]
+
+	var typ reflect.Type
+	if mgr.authDataType != nil {
+		typ = mgr.authDataType()
+	}
+
+	switch {
+	case uid == "" && hasData:
+		return "", nil, fmt.Errorf("%w: auth data without a user id", errInvalidAuth)
+	case uid == "":
+		return "", nil, nil
+	case typ == nil && hasData:
+		return "", nil, fmt.Errorf("%w: unexpected auth data (auth handler specifies no auth data)", errInvalidAuth)
+	case typ != nil && !hasData:
+		return "", nil, fmt.Errorf("%w: missing auth data (auth handler specifies auth data of type %s)", errInvalidAuth, typ)
+	case typ == nil:
+		return uid, nil, nil
+	}
+
+	ptr := reflect.New(typ.Elem())
+	if err := mgr.json.Unmarshal([]byte(encoded), ptr.Interface()); err != nil {
+		return "", nil, fmt.Errorf("%w: unmarshal auth data: %v", errInvalidAuth, err)
+	}
+	return uid, ptr.Interface(), nil
+}
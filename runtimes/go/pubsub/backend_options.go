@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// validateTopicBackendOptions validates the backend-specific options of a topic.
+func validateTopicBackendOptions(cfg TopicConfig) {
+	if o := cfg.GCP; o != nil {
+		checkNonNegative("GCP.PublishDelayThreshold", o.PublishDelayThreshold)
+		checkNonNegative("GCP.PublishCountThreshold", o.PublishCountThreshold)
+		checkNonNegative("GCP.PublishByteThreshold", o.PublishByteThreshold)
+		checkNonNegative("GCP.PublishTimeout", o.PublishTimeout)
+		checkNonNegative("GCP.MaxAttributeBytes", o.MaxAttributeBytes)
+	}
+	if o := cfg.NSQ; o != nil {
+		checkNonNegative("NSQ.DialTimeout", o.DialTimeout)
+		checkNonNegative("NSQ.WriteTimeout", o.WriteTimeout)
+		checkNonNegative("NSQ.MaxAttributeBytes", o.MaxAttributeBytes)
+	}
+}
+
+// validateSubscriptionBackendOptions validates the backend-specific options of a subscription.
+func validateSubscriptionBackendOptions(gcp *GCPSubscriptionOptions, nsq *NSQSubscriptionOptions) {
+	if gcp != nil {
+		checkNonNegative("GCP.MaxExtension", gcp.MaxExtension)
+		checkNonNegative("GCP.MaxExtensionPeriod", gcp.MaxExtensionPeriod)
+		checkNonNegative("GCP.MinExtensionPeriod", gcp.MinExtensionPeriod)
+		checkNonNegative("GCP.NumGoroutines", gcp.NumGoroutines)
+	}
+	if nsq != nil {
+		checkNonNegative("NSQ.HeartbeatInterval", nsq.HeartbeatInterval)
+		checkNonNegative("NSQ.ReadTimeout", nsq.ReadTimeout)
+		checkNonNegative("NSQ.OutputBufferTimeout", nsq.OutputBufferTimeout)
+	}
+}
+
+func checkNonNegative[V int | time.Duration](name string, v V) {
+	if v < 0 {
+		panic(name + " cannot be negative")
+	}
+}
+
+// warnInactiveBackendOptions logs a warning for the options of each backend
+// which are set but ignored, because the topic is backed by another provider.
+// It does nothing if the topic isn't backed by a provider, as under test.
+func warnInactiveBackendOptions(log zerolog.Logger, provider string, gcp, nsq bool) {
+	if provider == "" {
+		return
+	}
+	for _, b := range []struct {
+		provider string
+		set      bool
+	}{{"gcp", gcp}, {"nsq", nsq}} {
+		if b.set && b.provider != provider {
+			log.Warn().Str("provider", provider).Msgf("ignoring %s options as the topic is not backed by %s", b.provider, b.provider)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+)
+
+func TestBackendOptions(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+
+	// Options for backends other than the topic's provider are ignored with a warning
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		GCP:               &GCPTopicOptions{PublishCountThreshold: 10},
+	})
+	c.Assert(buf.String(), qt.Contains, `"topic":"topic","provider":"fake","message":"ignoring gcp options as the topic is not backed by gcp"`)
+
+	// Subscription options are passed on to the provider before subscribing
+	buf.Reset()
+	opts := &NSQSubscriptionOptions{HeartbeatInterval: 10 * time.Second}
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+		NSQ:     opts,
+	})
+	ft := fake.topics["topic"]
+	c.Assert(ft.options["sub"].NSQ, qt.Equals, opts)
+	c.Assert(ft.options["sub"].GCP, qt.IsNil)
+	c.Assert(buf.String(), qt.Contains, `"subscription":"sub","provider":"fake","message":"ignoring nsq options as the topic is not backed by nsq"`)
+}
+
+func TestBackendOptionsConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+
+	c.Assert(func() {
+		newTopic[*testEvent](mgr, "topic", TopicConfig{GCP: &GCPTopicOptions{PublishTimeout: -1}})
+	}, qt.PanicMatches, "GCP.PublishTimeout cannot be negative")
+
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+			NSQ:     &NSQSubscriptionOptions{ReadTimeout: -time.Second},
+		})
+	}, qt.PanicMatches, "NSQ.ReadTimeout cannot be negative")
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SkipBacklogConfig configures a subscription to skip a stale backlog of
+// messages when it starts, such as after a long outage when the backlog is
+// no longer worth processing.
+//
+// When the subscription starts on an instance, messages published more than
+// OlderThan before then are acknowledged without calling the Handler, and
+// are permanently lost. Newer messages are processed as usual. An error is logged
+// when the subscription starts and when the first message is skipped, and skipped
+// messages are counted in the subscription's Stats.
+//
+// Unlike InitialPosition, which only applies when the subscription is first
+// created, and SeekToTime, which repositions the subscription for every
+// instance, skipping happens on each instance as it receives messages, so it
+// works with every provider. Each instance skips messages published before it
+// started, so instances started later, for example when scaling out, also skip
+// older messages which are still being retried. Remove the configuration once
+// the backlog has been skipped.
+type SkipBacklogConfig struct {
+	// OlderThan is how long before the subscription started on the instance
+	// a message must have been published to be skipped.
+	//
+	// If zero, all messages published before the subscription started are skipped.
+	OlderThan time.Duration
+
+	// AcceptDataLoss acknowledges that skipped messages are never processed.
+	// It guards against skipping messages by accident.
+	//
+	// This field is required.
+	AcceptDataLoss bool
+}
+
+// backlogSkipper acknowledges messages published before a cutoff
+// without processing them.
+type backlogSkipper struct {
+	cutoff  time.Time
+	skipped atomic.Uint64 // number of messages skipped
+}
+
+// newBacklogSkipper creates a backlogSkipper for messages published
+// cfg.OlderThan before now, or returns nil if cfg is nil.
+func newBacklogSkipper(cfg *SkipBacklogConfig, now time.Time) *backlogSkipper {
+	if cfg == nil {
+		return nil
+	}
+	if !cfg.AcceptDataLoss {
+		panic("SkipBacklog.AcceptDataLoss must be set to skip messages")
+	}
+	if cfg.OlderThan < 0 {
+		panic("SkipBacklog.OlderThan cannot be negative")
+	}
+	return &backlogSkipper{cutoff: now.Add(-cfg.OlderThan)}
+}
+
+// skip reports whether a message published at publishTime
+// is part of the backlog being skipped, counting it if so.
+func (b *backlogSkipper) skip(log zerolog.Logger, msgID string, publishTime time.Time) bool {
+	// Messages whose publish time isn't known are processed, to be safe
+	if b == nil || publishTime.IsZero() || !publishTime.Before(b.cutoff) {
+		return false
+	}
+
+	if b.skipped.Add(1) == 1 {
+		log.Error().Str("msg_id", msgID).Time("publish_time", publishTime).Time("cutoff", b.cutoff).
+			Msg("skipping backlog: acknowledging message published before the cutoff without processing it")
+	} else {
+		log.Debug().Str("msg_id", msgID).Time("publish_time", publishTime).Msg("skipping backlog message")
+	}
+	return true
+}
+
+// count returns the number of messages skipped so far.
+func (b *backlogSkipper) count() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.skipped.Load()
+}
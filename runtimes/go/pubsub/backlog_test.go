@@ -0,0 +1,52 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSubscription_SkipBacklog(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var handled []string
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			handled = append(handled, msg.Value)
+			return nil
+		},
+		SkipBacklog: &SkipBacklogConfig{OlderThan: time.Hour, AcceptDataLoss: true},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	now := time.Now()
+
+	// Messages published before the cutoff are acknowledged without being processed
+	c.Assert(ft.subs["sub"](ctx, "1", now.Add(-2*time.Hour), 1, nil, []byte(`{"Value":"stale"}`)), qt.IsNil)
+	c.Assert(ft.subs["sub"](ctx, "2", now.Add(-90*time.Minute), 1, nil, []byte(`{"Value":"stale"}`)), qt.IsNil)
+	c.Assert(handled, qt.HasLen, 0)
+	c.Assert(sub.Stats().BacklogSkipped, qt.Equals, uint64(2))
+
+	// Newer messages, and those without a publish time, are processed as usual
+	c.Assert(ft.subs["sub"](ctx, "3", now.Add(-time.Minute), 1, nil, []byte(`{"Value":"recent"}`)), qt.IsNil)
+	c.Assert(ft.subs["sub"](ctx, "4", time.Time{}, 1, nil, []byte(`{"Value":"unknown"}`)), qt.IsNil)
+	c.Assert(handled, qt.DeepEquals, []string{"recent", "unknown"})
+	c.Assert(sub.Stats().BacklogSkipped, qt.Equals, uint64(2))
+}
+
+func TestSubscription_SkipBacklogRequiresOptIn(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler:     func(ctx context.Context, msg *testEvent) error { return nil },
+			SkipBacklog: &SkipBacklogConfig{OlderThan: time.Hour},
+		})
+	}, qt.PanicMatches, "SkipBacklog.AcceptDataLoss must be set to skip messages")
+}
@@ -0,0 +1,73 @@
+package pubsub
+
+import (
+	"bytes"
+
+	"encore.dev/beta/errs"
+)
+
+// contentTypeAttribute is the attribute name we use to record
+// the content type of Blob messages.
+const contentTypeAttribute = "encore_content_type"
+
+// defaultBlobContentType is the content type of Blob messages which don't set one.
+const defaultBlobContentType = "application/octet-stream"
+
+// Blob is a message type for topics carrying opaque binary data,
+// such as images or files. Blob messages are published as their raw bytes
+// rather than being marshalled to JSON, and are delivered as-is:
+//
+//	var Thumbnails = pubsub.NewTopic[*pubsub.Blob]("thumbnails", pubsub.TopicConfig{
+//		DeliveryGuarantee: pubsub.AtLeastOnce,
+//	})
+//
+//	_, err := Thumbnails.Publish(ctx, &pubsub.Blob{Data: png, ContentType: "image/png"})
+type Blob struct {
+	// Data is the content of the message.
+	Data []byte
+
+	// ContentType is the media type of Data, which is recorded as
+	// a message attribute. If empty, it defaults to "application/octet-stream".
+	ContentType string
+}
+
+// marshalBlob returns the attributes and data of msg if it is a Blob.
+// The topic is only used for error messages.
+func marshalBlob[T any](msg T, topic string) (attrs map[string]string, data []byte, ok bool, err error) {
+	var blob *Blob
+	switch m := any(msg).(type) {
+	case Blob:
+		blob = &m
+	case *Blob:
+		if m == nil {
+			return nil, nil, true, errs.B().Code(errs.InvalidArgument).Msgf("cannot publish a nil blob to topic %s", topic).Err()
+		}
+		blob = m
+	default:
+		return nil, nil, false, nil
+	}
+
+	contentType := blob.ContentType
+	if contentType == "" {
+		contentType = defaultBlobContentType
+	}
+	return map[string]string{contentTypeAttribute: contentType}, blob.Data, true, nil
+}
+
+// unmarshalBlob decodes data into msg if T is a Blob, reporting whether it was.
+func unmarshalBlob[T any](attrs map[string]string, data []byte, msg *T) bool {
+	blob := Blob{Data: bytes.Clone(data), ContentType: attrs[contentTypeAttribute]}
+	if blob.ContentType == "" {
+		blob.ContentType = defaultBlobContentType
+	}
+
+	switch m := any(msg).(type) {
+	case *Blob:
+		*m = blob
+	case **Blob:
+		*m = &blob
+	default:
+		return false
+	}
+	return true
+}
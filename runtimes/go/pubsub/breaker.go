@@ -0,0 +1,79 @@
+package pubsub
+
+import (
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// CircuitBreakerConfig configures a circuit breaker around a subscription's Handler.
+//
+// When the Handler fails FailureThreshold times in a row the breaker opens,
+// and for OpenDuration messages are held without calling the Handler.
+// Once OpenDuration has passed, HalfOpenProbes messages are let through;
+// if they all succeed the breaker closes, and if any fail it opens again.
+//
+// Held messages don't count as Handler failures. A message which can't be held
+// any longer, for instance because its lease expires or the subscription is
+// shutting down, is negatively acknowledged and left queued for redelivery,
+// which most cloud providers still count as a delivery attempt.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive Handler failures
+	// which cause the breaker to open. Defaults to 5.
+	FailureThreshold int
+
+	// OpenDuration is how long the breaker stays open before
+	// letting probe messages through. Defaults to 30 seconds.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of messages let through, and required to succeed,
+	// before an open breaker closes again. Defaults to 1.
+	HalfOpenProbes int
+}
+
+// CircuitBreakerState describes the state of a subscription's circuit breaker.
+type CircuitBreakerState string
+
+const (
+	// CircuitClosed means messages are being passed to the Handler.
+	CircuitClosed CircuitBreakerState = "closed"
+
+	// CircuitOpen means messages are being rejected without calling the Handler.
+	CircuitOpen CircuitBreakerState = "open"
+
+	// CircuitHalfOpen means a limited number of messages are being passed to the
+	// Handler to determine whether it has recovered.
+	CircuitHalfOpen CircuitBreakerState = "half-open"
+)
+
+func newCircuitBreaker(cfg *CircuitBreakerConfig) *utils.CircuitBreaker {
+	if cfg.FailureThreshold < 0 {
+		panic("CircuitBreaker.FailureThreshold cannot be negative")
+	}
+	if cfg.OpenDuration < 0 {
+		panic("CircuitBreaker.OpenDuration cannot be negative")
+	}
+	if cfg.HalfOpenProbes < 0 {
+		panic("CircuitBreaker.HalfOpenProbes cannot be negative")
+	}
+	cfg.FailureThreshold = utils.WithDefaultValue(cfg.FailureThreshold, 5)
+	cfg.OpenDuration = utils.WithDefaultValue(cfg.OpenDuration, 30*time.Second)
+	cfg.HalfOpenProbes = utils.WithDefaultValue(cfg.HalfOpenProbes, 1)
+
+	return utils.NewCircuitBreaker(cfg.FailureThreshold, cfg.OpenDuration, cfg.HalfOpenProbes)
+}
+
+func circuitBreakerState(b *utils.CircuitBreaker) CircuitBreakerState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	switch b.State() {
+	case utils.BreakerOpen:
+		return CircuitOpen
+	case utils.BreakerHalfOpen:
+		return CircuitHalfOpen
+	default:
+		return CircuitClosed
+	}
+}
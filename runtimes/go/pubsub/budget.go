@@ -0,0 +1,30 @@
+package pubsub
+
+// SetBufferBudget limits the total size of messages this instance of the
+// service processes at once across all subscriptions.
+//
+// When the budget is reached, further messages wait for others to complete
+// before being processed. As providers only deliver up to a subscription's
+// MaxConcurrency messages at once, waiting messages stop subscriptions from
+// receiving more, so backlogged subscriptions are throttled in proportion to
+// the messages they have outstanding. A message larger than the budget is
+// still processed once no other messages are.
+//
+// Messages prefetched by a provider's client library before they are
+// delivered to the subscription are not counted; use SetFlowControl to
+// limit those where supported.
+//
+// A budget of 0 removes the limit, which is the default.
+// It panics if maxBytes is negative.
+func (mgr *Manager) SetBufferBudget(maxBytes int64) {
+	if maxBytes < 0 {
+		panic("buffer budget cannot be negative")
+	}
+	mgr.buffered.SetLimit(maxBytes)
+}
+
+// BufferedBytes reports the total size of messages this instance
+// of the service is currently processing across all subscriptions.
+func (mgr *Manager) BufferedBytes() int64 {
+	return mgr.buffered.Used()
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		in, want string
+	}{
+		{`{"b":1,"a":{"d":[3,{"y":true,"x":null}],"c":"s"}}`, `{"a":{"c":"s","d":[3,{"x":null,"y":true}]},"b":1}`},
+		{" { \"a\" : 1.50e3 , \"b\" : [ ] } ", `{"a":1.50e3,"b":[]}`},
+		{`{"html":"<a&b>","uni":"\u00e9"}`, `{"html":"\u003ca\u0026b\u003e","uni":"é"}`},
+		{`"str"`, `"str"`},
+	}
+	for _, tt := range tests {
+		got, err := canonicalJSON([]byte(tt.in))
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(got), qt.Equals, tt.want)
+	}
+
+	_, err := canonicalJSON([]byte(`{"a":`))
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestTopic_CanonicalJSON(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+
+	// Equal values of types whose fields are declared in different orders
+	type orderA struct {
+		ID    string
+		Items map[string]int
+		Note  string
+	}
+	type orderB struct {
+		Note  string
+		Items map[string]int
+		ID    string
+	}
+	a := &orderA{ID: "1", Items: map[string]int{"y": 2, "x": 1}, Note: "gift"}
+	b := &orderB{ID: "1", Items: map[string]int{"x": 1, "y": 2}, Note: "gift"}
+	ctx := context.Background()
+
+	_, err := newTopic[*orderA](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, CanonicalJSON: true}).Publish(ctx, a)
+	c.Assert(err, qt.IsNil)
+	publishedA := fake.topics["topic"].lastData
+	_, err = newTopic[*orderB](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, CanonicalJSON: true}).Publish(ctx, b)
+	c.Assert(err, qt.IsNil)
+	publishedB := fake.topics["topic"].lastData
+
+	// Are published as identical bytes
+	c.Assert(string(publishedA), qt.Equals, `{"ID":"1","Items":{"x":1,"y":2},"Note":"gift"}`)
+	c.Assert(string(publishedB), qt.Equals, string(publishedA))
+}
@@ -0,0 +1,143 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"encore.dev/beta/errs"
+)
+
+// CDCOp is the kind of change described by a change data capture event.
+type CDCOp string
+
+const (
+	CDCCreate   CDCOp = "c" // a row was inserted
+	CDCUpdate   CDCOp = "u" // a row was updated
+	CDCDelete   CDCOp = "d" // a row was deleted
+	CDCRead     CDCOp = "r" // a row was read while snapshotting the table
+	CDCTruncate CDCOp = "t" // the table was truncated
+)
+
+// CDCEvent is a change data capture event in the envelope format
+// used by Debezium and compatible tools:
+//
+//	{"op": "u", "before": {...}, "after": {...}, "source": {...}, "ts_ms": 1700000000000}
+//
+// Envelopes wrapped in a {"schema": ..., "payload": ...} object, as produced
+// when schemas are enabled, are unwrapped automatically.
+//
+// Use CDCEvent as the message type of a topic carrying change events, and
+// CDCHandler to handle them as typed rows. It is used by value rather than
+// as a pointer so that tombstones, which are published as a JSON null,
+// decode to an empty CDCEvent:
+//
+//	var OrderChanges = pubsub.NewTopic[pubsub.CDCEvent]("order-changes", pubsub.TopicConfig{
+//		DeliveryGuarantee: pubsub.AtLeastOnce,
+//	})
+//
+//	var _ = pubsub.NewSubscription(OrderChanges, "update-search-index", pubsub.SubscriptionConfig[pubsub.CDCEvent]{
+//		Handler: pubsub.CDCHandler(UpdateSearchIndex),
+//	})
+//
+//	func UpdateSearchIndex(ctx context.Context, change *pubsub.Change[Order]) error {
+//		// ...
+//	}
+type CDCEvent struct {
+	Op     CDCOp           `json:"op"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	Source map[string]any  `json:"source,omitempty"`
+	TsMs   int64           `json:"ts_ms,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler,
+// unwrapping envelopes which include a schema.
+func (e *CDCEvent) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		// A tombstone
+		*e = CDCEvent{}
+		return nil
+	}
+
+	type envelope CDCEvent
+	var wrapped struct {
+		Payload *envelope `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Payload != nil {
+		*e = CDCEvent(*wrapped.Payload)
+		return nil
+	}
+	return json.Unmarshal(data, (*envelope)(e))
+}
+
+// Change is a change data capture event decoded into typed rows.
+type Change[Row any] struct {
+	// Op is the kind of change.
+	Op CDCOp
+
+	// Before is the row before the change.
+	// It is nil for creates, snapshot reads and truncates, and for
+	// updates from sources which do not capture the previous row.
+	Before *Row
+
+	// After is the row after the change.
+	// It is nil for deletes and truncates.
+	After *Row
+
+	// Source describes where the change came from,
+	// such as the database, table and log position.
+	Source map[string]any
+
+	// Time is when the change was processed by the capture tool,
+	// or the zero time if it was not recorded.
+	Time time.Time
+}
+
+// CDCHandler adapts a handler of typed change data capture events
+// into a Handler for a subscription to a topic of CDCEvent messages.
+//
+// Tombstones, which capture tools publish after a delete so that compacted
+// logs can drop the deleted row's key, carry no change and are acknowledged
+// without calling handler. The delete itself is passed to handler as
+// a Change with Op set to CDCDelete and only Before set.
+//
+// If a row cannot be decoded into Row, the message is negatively acknowledged
+// and retried according to the subscription's RetryPolicy.
+func CDCHandler[Row any](handler func(ctx context.Context, change *Change[Row]) error) func(ctx context.Context, event CDCEvent) error {
+	return func(ctx context.Context, event CDCEvent) error {
+		if event.Op == "" {
+			// A tombstone
+			return nil
+		}
+
+		change := &Change[Row]{Op: event.Op, Source: event.Source}
+		if event.TsMs > 0 {
+			change.Time = time.UnixMilli(event.TsMs)
+		}
+
+		var err error
+		if change.Before, err = decodeCDCRow[Row](event.Before); err != nil {
+			return errs.B().Code(errs.InvalidArgument).Cause(err).Msg("failed to decode row before change").Err()
+		}
+		if change.After, err = decodeCDCRow[Row](event.After); err != nil {
+			return errs.B().Code(errs.InvalidArgument).Cause(err).Msg("failed to decode row after change").Err()
+		}
+
+		return handler(ctx, change)
+	}
+}
+
+// decodeCDCRow decodes a row from a CDC envelope,
+// returning nil if the row is absent.
+func decodeCDCRow[Row any](data json.RawMessage) (*Row, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	row := new(Row)
+	if err := json.Unmarshal(data, row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
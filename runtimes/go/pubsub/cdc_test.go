@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type orderRow struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestCDCHandler(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[CDCEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var changes []*Change[orderRow]
+	NewSubscription(topic, "sub", SubscriptionConfig[CDCEvent]{
+		Handler: CDCHandler(func(ctx context.Context, change *Change[orderRow]) error {
+			changes = append(changes, change)
+			return nil
+		}),
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliver := func(data string) error {
+		return ft.deliver(ctx, "sub", "1", 1, nil, []byte(data))
+	}
+
+	// Updates carry both rows
+	c.Assert(deliver(`{"op":"u","before":{"id":1,"status":"new"},"after":{"id":1,"status":"paid"},"source":{"table":"orders"},"ts_ms":1700000000000}`), qt.IsNil)
+	c.Assert(changes, qt.HasLen, 1)
+	c.Assert(changes[0], qt.DeepEquals, &Change[orderRow]{
+		Op:     CDCUpdate,
+		Before: &orderRow{ID: 1, Status: "new"},
+		After:  &orderRow{ID: 1, Status: "paid"},
+		Source: map[string]any{"table": "orders"},
+		Time:   time.UnixMilli(1700000000000),
+	})
+
+	// Envelopes with a schema are unwrapped
+	c.Assert(deliver(`{"schema":{},"payload":{"op":"c","before":null,"after":{"id":2,"status":"new"}}}`), qt.IsNil)
+	c.Assert(changes, qt.HasLen, 2)
+	c.Assert(changes[1].Op, qt.Equals, CDCCreate)
+	c.Assert(changes[1].Before, qt.IsNil)
+	c.Assert(changes[1].After, qt.DeepEquals, &orderRow{ID: 2, Status: "new"})
+
+	// Deletes only carry the previous row, and the tombstone following them is skipped
+	c.Assert(deliver(`{"op":"d","before":{"id":2,"status":"new"},"after":null}`), qt.IsNil)
+	c.Assert(deliver(`null`), qt.IsNil)
+	c.Assert(changes, qt.HasLen, 3)
+	c.Assert(changes[2].Op, qt.Equals, CDCDelete)
+	c.Assert(changes[2].After, qt.IsNil)
+
+	// Rows which don't match the row type are retried
+	c.Assert(deliver(`{"op":"c","after":{"id":"three"}}`), qt.ErrorMatches, ".*failed to decode row after change.*")
+	c.Assert(changes, qt.HasLen, 3)
+}
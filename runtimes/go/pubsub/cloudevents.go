@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// cloudEventsContentTypeAttribute is the attribute the CloudEvents protocol bindings
+// use to mark messages in the structured format.
+const cloudEventsContentTypeAttribute = "content-type"
+
+// cloudEventsContentType is the media type of CloudEvents in the JSON structured format.
+const cloudEventsContentType = "application/cloudevents+json"
+
+// cloudEventsSpecVersion is the version of the CloudEvents specification we publish.
+const cloudEventsSpecVersion = "1.0"
+
+// errInvalidCloudEvent is reported when a message marked as a CloudEvent
+// is malformed or lacks the required CloudEvents attributes.
+var errInvalidCloudEvent = errors.New("invalid CloudEvent")
+
+// cloudEvent is the JSON structured format of a CloudEvent.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            *time.Time      `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// validateCloudEventsCodec panics if codec is missing required configuration.
+func validateCloudEventsCodec(codec *CloudEventsCodec) {
+	if codec == nil {
+		return
+	}
+	if codec.Source == "" {
+		panic("CloudEvents.Source is required")
+	}
+	if codec.Type == "" {
+		panic("CloudEvents.Type is required")
+	}
+}
+
+// wrapCloudEvent wraps data in a CloudEvent envelope described by codec,
+// and marks it as a CloudEvent in attrs.
+func wrapCloudEvent(codec *CloudEventsCodec, attrs *attributeSet, data []byte, now time.Time) ([]byte, error) {
+	ev := cloudEvent{
+		SpecVersion: cloudEventsSpecVersion,
+		ID:          xid.New().String(),
+		Source:      codec.Source,
+		Type:        codec.Type,
+		Time:        &now,
+	}
+	if contentType, isBlob := attrs.values[contentTypeAttribute]; isBlob {
+		ev.DataContentType = contentType
+		ev.DataBase64 = data
+	} else {
+		ev.DataContentType = "application/json"
+		ev.Data = data
+	}
+
+	wrapped, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	attrs.set(fromEncore, cloudEventsContentTypeAttribute, cloudEventsContentType)
+	return wrapped, nil
+}
+
+// unwrapCloudEvent returns the data of the CloudEvent in data, if attrs mark it as one.
+// Otherwise data is returned as-is.
+func unwrapCloudEvent(attrs map[string]string, data []byte) ([]byte, error) {
+	if attrs[cloudEventsContentTypeAttribute] != cloudEventsContentType {
+		return data, nil
+	}
+
+	var ev cloudEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidCloudEvent, err)
+	}
+	switch {
+	case ev.SpecVersion != cloudEventsSpecVersion:
+		return nil, fmt.Errorf("%w: unsupported specversion %q", errInvalidCloudEvent, ev.SpecVersion)
+	case ev.ID == "":
+		return nil, fmt.Errorf("%w: missing id", errInvalidCloudEvent)
+	case ev.Source == "":
+		return nil, fmt.Errorf("%w: missing source", errInvalidCloudEvent)
+	case ev.Type == "":
+		return nil, fmt.Errorf("%w: missing type", errInvalidCloudEvent)
+	}
+
+	if ev.DataBase64 != nil {
+		return ev.DataBase64, nil
+	}
+	return ev.Data, nil
+}
+
+// unmarshalPublished decodes a message as it was published,
+// unwrapping it first if it was published as a CloudEvent.
+func unmarshalPublished[T any](attrs map[string]string, data []byte) (msg T, err error) {
+	if data, err = unwrapCloudEvent(attrs, data); err != nil {
+		return msg, err
+	}
+	return unmarshalMessage[T](attrs, data)
+}
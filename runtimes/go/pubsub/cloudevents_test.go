@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTopic_CloudEvents(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		CloudEvents:       &CloudEventsCodec{Source: "/orders", Type: "com.example.order.placed"},
+	})
+
+	var (
+		received    []string
+		quarantined []*QuarantinedMessage
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg.Value)
+			return nil
+		},
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages are published in the structured format
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs["content-type"], qt.Equals, "application/cloudevents+json")
+
+	var ev map[string]any
+	c.Assert(json.Unmarshal(ft.lastData, &ev), qt.IsNil)
+	c.Assert(ev["specversion"], qt.Equals, "1.0")
+	c.Assert(ev["id"], qt.Not(qt.Equals), "")
+	c.Assert(ev["source"], qt.Equals, "/orders")
+	c.Assert(ev["type"], qt.Equals, "com.example.order.placed")
+	c.Assert(ev["datacontenttype"], qt.Equals, "application/json")
+	c.Assert(ev["data"], qt.DeepEquals, map[string]any{"Value": "hello"})
+	published, err := time.Parse(time.RFC3339Nano, ev["time"].(string))
+	c.Assert(err, qt.IsNil)
+	c.Assert(time.Since(published) < time.Minute, qt.IsTrue)
+
+	// Subscriptions unwrap the event's data
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"hello"})
+
+	// Events missing required attributes are quarantined
+	attrs := map[string]string{"content-type": "application/cloudevents+json"}
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, attrs, []byte(`{"specversion":"1.0","id":"2","type":"t","data":{"Value":"hello"}}`)), qt.IsNil)
+	c.Assert(received, qt.HasLen, 1)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, "invalid CloudEvent: missing source")
+
+	// Messages which aren't CloudEvents are decoded as usual
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":"plain"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"hello", "plain"})
+
+	// The source and type are required
+	c.Assert(func() {
+		newTopic[*testEvent](mgr, "topic", TopicConfig{CloudEvents: &CloudEventsCodec{Type: "t"}})
+	}, qt.PanicMatches, "CloudEvents.Source is required")
+}
+
+func TestTopic_CloudEventsBlob(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*Blob](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		CloudEvents:       &CloudEventsCodec{Source: "/images", Type: "com.example.image"},
+	})
+
+	var received *Blob
+	NewSubscription(topic, "sub", SubscriptionConfig[*Blob]{
+		Handler: func(ctx context.Context, msg *Blob) error {
+			received = msg
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Blobs are carried as base64 encoded data
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	_, err := topic.Publish(ctx, &Blob{Data: data, ContentType: "image/png"})
+	c.Assert(err, qt.IsNil)
+
+	var ev map[string]any
+	c.Assert(json.Unmarshal(ft.lastData, &ev), qt.IsNil)
+	c.Assert(ev["datacontenttype"], qt.Equals, "image/png")
+	c.Assert(ev["data_base64"], qt.Equals, "iVBORwD/")
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, &Blob{Data: data, ContentType: "image/png"})
+}
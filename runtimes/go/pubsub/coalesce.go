@@ -0,0 +1,147 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CoalesceConfig coalesces messages with the same key which arrive within
+// a short window of each other into a single call to the Handler, for
+// workloads such as notifications where only the latest state of a key
+// matters, or where messages for a key can be merged into one.
+//
+// The first message for a key starts a window of Window. Messages for the
+// same key received by the instance during the window are merged into it
+// using Merge, and once the window ends the Handler is called once with the
+// merged message. Every coalesced message is then acknowledged if the Handler
+// succeeds, or negatively acknowledged and retried according to the
+// RetryPolicy if it fails.
+//
+// Delivery remains at-least-once, with the following caveats:
+//
+//   - Coalescing only happens within a single instance of the service,
+//     among messages received during the window.
+//   - A message whose context is cancelled while waiting for its window to end
+//     is negatively acknowledged, and redelivered even if the Handler later
+//     succeeds with its contents merged in.
+//   - Messages arriving after a window has ended start a new window, whose
+//     Handler call may run concurrently with the previous one, and redelivered
+//     messages may be coalesced with newer ones. Merge should therefore not
+//     assume messages arrive in the order they were published.
+//
+// As messages wait for their window while counting towards the subscription's
+// MaxConcurrency, MaxConcurrency should be large enough for the expected
+// number of messages per window. The subscription's Stats report how many
+// messages were coalesced.
+type CoalesceConfig[T any] struct {
+	// KeyFunc returns the key of a message. Only messages
+	// with the same key are coalesced.
+	//
+	// This field is required.
+	KeyFunc func(msg T) string
+
+	// Window is how long after the first message for a key
+	// other messages for the key are coalesced with it.
+	//
+	// If zero, it defaults to 1 second.
+	Window time.Duration
+
+	// Merge combines a message with the next message coalesced into it,
+	// returning the message to pass to the Handler.
+	//
+	// If nil, the most recently received message is kept.
+	Merge func(prev, next T) T
+}
+
+// errCoalescedHandlerFailed is reported for coalesced messages
+// when the Handler call they were merged into didn't return.
+var errCoalescedHandlerFailed = errors.New("handler of coalesced message failed")
+
+// coalescer merges messages with the same key received within a window.
+type coalescer[T any] struct {
+	keyFunc func(msg T) string
+	window  time.Duration
+	merge   func(prev, next T) T
+
+	// windowEnd returns a channel which receives once a window of d opened now
+	// ends, and a function to stop it early. It is replaced in tests.
+	windowEnd func(d time.Duration) (<-chan time.Time, func() bool)
+
+	mu     sync.Mutex
+	groups map[string]*coalesceGroup[T] // the group accepting messages for each key
+
+	coalesced atomic.Uint64 // number of messages handled by another message's Handler call
+}
+
+// coalesceGroup is the messages for a key coalesced within a window.
+type coalesceGroup[T any] struct {
+	msg  T             // the merged message; guarded by coalescer.mu until the group is closed
+	done chan struct{} // closed once the Handler has returned
+	err  error         // the result of the Handler; valid once done is closed
+}
+
+func newCoalescer[T any](cfg *CoalesceConfig[T]) *coalescer[T] {
+	merge := cfg.Merge
+	if merge == nil {
+		merge = func(prev, next T) T { return next }
+	}
+	return &coalescer[T]{
+		keyFunc: cfg.KeyFunc,
+		window:  cfg.Window,
+		merge:   merge,
+		groups:  make(map[string]*coalesceGroup[T]),
+		windowEnd: func(d time.Duration) (<-chan time.Time, func() bool) {
+			timer := time.NewTimer(d)
+			return timer.C, timer.Stop
+		},
+	}
+}
+
+// handle processes msg as part of the group for its key. The first message
+// of a group waits for the window to end and calls handler with the merged
+// message; the others wait for its result.
+func (c *coalescer[T]) handle(ctx context.Context, msg T, handler func(context.Context, T) error) error {
+	key := c.keyFunc(msg)
+
+	c.mu.Lock()
+	if g, ok := c.groups[key]; ok {
+		g.msg = c.merge(g.msg, msg)
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+
+		select {
+		case <-g.done:
+			return g.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	g := &coalesceGroup[T]{msg: msg, done: make(chan struct{})}
+	c.groups[key] = g
+	c.mu.Unlock()
+
+	// Wait for the window to end before closing the group to new messages
+	ended, stop := c.windowEnd(c.window)
+	select {
+	case <-ended:
+	case <-ctx.Done():
+		stop()
+	}
+	c.mu.Lock()
+	delete(c.groups, key)
+	merged := g.msg
+	c.mu.Unlock()
+
+	// Fail the group unless the handler returns, so a panic isn't acknowledged
+	g.err = errCoalescedHandlerFailed
+	defer close(g.done)
+	if err := ctx.Err(); err != nil {
+		g.err = err
+		return err
+	}
+	g.err = handler(ctx, merged)
+	return g.err
+}
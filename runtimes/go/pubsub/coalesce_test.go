@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// manualWindows makes the windows of a coalescer end only when the test
+// ends them, so tests don't depend on how quickly messages are delivered.
+type manualWindows struct {
+	mu     sync.Mutex
+	end    chan time.Time
+	opened chan struct{} // receives once for each window opened
+}
+
+func newManualWindows[T any](co *coalescer[T]) *manualWindows {
+	w := &manualWindows{end: make(chan time.Time), opened: make(chan struct{}, 16)}
+	co.windowEnd = func(time.Duration) (<-chan time.Time, func() bool) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.opened <- struct{}{}
+		return w.end, func() bool { return true }
+	}
+	return w
+}
+
+// endAll ends the windows opened so far.
+func (w *manualWindows) endAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	close(w.end)
+	w.end = make(chan time.Time)
+}
+
+// waitFor waits for n receives from ch, failing the test if they take too long.
+func waitFor(t testing.TB, ch <-chan struct{}, n int, what string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-ch:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestSubscription_Coalesce(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		mu      sync.Mutex
+		handled []string
+		fail    bool
+	)
+	merged := make(chan struct{}, 16)
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return errors.New("handler failed")
+			}
+			parts := strings.Split(msg.Value, ",")
+			sort.Strings(parts)
+			handled = append(handled, strings.Join(parts, ","))
+			return nil
+		},
+		Coalesce: &CoalesceConfig[*testEvent]{
+			KeyFunc: func(msg *testEvent) string { return msg.Value[:1] },
+			Merge: func(prev, next *testEvent) *testEvent {
+				merged <- struct{}{}
+				return &testEvent{Value: prev.Value + "," + next.Value}
+			},
+		},
+	})
+	windows := newManualWindows(sub.coalesce)
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// deliverAll delivers the messages concurrently, ending the windows
+	// once the given number have opened and the rest have been merged.
+	deliverAll := func(windowCount int, values ...string) []error {
+		errs := make([]error, len(values))
+		var wg sync.WaitGroup
+		for i, v := range values {
+			i, v := i, v
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = ft.deliver(ctx, "sub", fmt.Sprintf("msg-%s", v), 1, nil, []byte(fmt.Sprintf(`{"Value":%q}`, v)))
+			}()
+		}
+		waitFor(t, windows.opened, windowCount, "windows to open")
+		waitFor(t, merged, len(values)-windowCount, "messages to be merged")
+		windows.endAll()
+		wg.Wait()
+		return errs
+	}
+
+	// Messages with the same key are merged into one Handler call
+	for _, err := range deliverAll(2, "a1", "a2", "a3", "b1") {
+		c.Assert(err, qt.IsNil)
+	}
+	sort.Strings(handled)
+	c.Assert(handled, qt.DeepEquals, []string{"a1,a2,a3", "b1"})
+	c.Assert(sub.Stats().Coalesced, qt.Equals, uint64(2))
+
+	// If the Handler fails, all coalesced messages are retried
+	fail = true
+	for _, err := range deliverAll(1, "a4", "a5") {
+		c.Assert(err, qt.ErrorMatches, "handler failed")
+	}
+	c.Assert(sub.Stats().Coalesced, qt.Equals, uint64(3))
+}
+
+func TestCoalescer_LatestWins(t *testing.T) {
+	c := qt.New(t)
+	co := newCoalescer(&CoalesceConfig[string]{
+		KeyFunc: func(msg string) string { return "key" },
+	})
+	windows := newManualWindows(co)
+	merged := make(chan struct{}, 16)
+	latest := co.merge
+	co.merge = func(prev, next string) string {
+		merged <- struct{}{}
+		return latest(prev, next)
+	}
+	ctx := context.Background()
+
+	var got []string
+	handler := func(ctx context.Context, msg string) error {
+		got = append(got, msg)
+		return nil
+	}
+
+	first, second := make(chan error, 1), make(chan error, 1)
+	go func() { first <- co.handle(ctx, "first", handler) }()
+	waitFor(t, windows.opened, 1, "the window to open")
+	go func() { second <- co.handle(ctx, "second", handler) }()
+	waitFor(t, merged, 1, "the message to be merged")
+	windows.endAll()
+	c.Assert(<-first, qt.IsNil)
+	c.Assert(<-second, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []string{"second"})
+
+	// A panicking Handler fails the coalesced messages
+	go func() {
+		defer func() { first <- fmt.Errorf("panicked: %v", recover()) }()
+		_ = co.handle(ctx, "third", func(context.Context, string) error { panic("boom") })
+	}()
+	waitFor(t, windows.opened, 1, "the window to open")
+	go func() { second <- co.handle(ctx, "fourth", handler) }()
+	waitFor(t, merged, 1, "the message to be merged")
+	windows.endAll()
+	c.Assert(<-second, qt.Equals, errCoalescedHandlerFailed)
+	c.Assert(<-first, qt.ErrorMatches, "panicked: boom")
+}
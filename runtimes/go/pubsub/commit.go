@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/utils"
+)
+
+// CommitConfig gates acknowledging a subscription's messages on a commit hook,
+// for subscriptions which buffer the effects of their Handler and write them
+// downstream in batches, such as in a single database transaction.
+//
+// When the Handler returns successfully the message joins the current batch
+// rather than being acknowledged straight away. Once the batch holds MaxMessages
+// messages, or MaxDelay has passed since its first message joined, Hook is called
+// once for the whole batch:
+//
+//   - If Hook returns nil, every message in the batch is acknowledged.
+//   - If Hook returns an error, every message in the batch is negatively
+//     acknowledged and redelivered according to the subscription's RetryPolicy.
+//
+// Ordering guarantees:
+//
+//   - Hook is never called concurrently with itself, and is only called
+//     once all Handlers of the messages in its batch have returned.
+//   - A message is never acknowledged before the Hook of its batch has succeeded.
+//   - Handlers of the next batch may run while Hook is running, so the Hook
+//     must only commit the work it takes ownership of when it starts, for
+//     example by swapping out the buffer the Handlers write to.
+//   - Redelivered messages may arrive out of order and alongside new messages,
+//     and a message whose context is cancelled while waiting for its batch
+//     is redelivered even if the batch later commits successfully.
+//
+// Handlers must therefore be idempotent; combining CommitConfig with
+// DedupByMessageID prevents messages whose batch committed from being
+// processed again. Messages are only recorded in the dedup store once
+// their batch has committed.
+//
+// As messages wait for their batch while still counting towards the subscription's
+// MaxConcurrency, MaxConcurrency should be at least MaxMessages for batches to fill.
+type CommitConfig struct {
+	// Hook commits the work of the Handlers in the current batch.
+	//
+	// This field is required.
+	Hook func(ctx context.Context) error
+
+	// MaxMessages is the number of messages at which a batch is committed.
+	// Defaults to 100.
+	MaxMessages int
+
+	// MaxDelay is the longest time a message waits for its batch
+	// to be committed. Defaults to 1 second.
+	MaxDelay time.Duration
+}
+
+// commitBatcher groups successfully handled messages into batches,
+// committing each batch with the subscription's commit hook.
+type commitBatcher struct {
+	cfg CommitConfig
+
+	commitMu sync.Mutex // held while the hook is running
+
+	mu  sync.Mutex
+	cur *commitBatch // the batch accepting messages, or nil
+}
+
+type commitBatch struct {
+	size  int
+	timer *time.Timer
+	once  sync.Once
+	done  chan struct{} // closed once the batch has been committed
+	err   error         // the result of committing the batch; valid once done is closed
+}
+
+func newCommitBatcher(cfg *CommitConfig) *commitBatcher {
+	if cfg.Hook == nil {
+		panic("Commit.Hook is required")
+	}
+	if cfg.MaxMessages < 0 {
+		panic("Commit.MaxMessages cannot be negative")
+	}
+	if cfg.MaxDelay < 0 {
+		panic("Commit.MaxDelay cannot be negative")
+	}
+	cfg.MaxMessages = utils.WithDefaultValue(cfg.MaxMessages, 100)
+	cfg.MaxDelay = utils.WithDefaultValue(cfg.MaxDelay, time.Second)
+	return &commitBatcher{cfg: *cfg}
+}
+
+// wait adds a successfully handled message to the current batch,
+// and blocks until the batch has been committed or ctx is done.
+func (b *commitBatcher) wait(ctx context.Context) error {
+	b.mu.Lock()
+	batch := b.cur
+	if batch == nil {
+		batch = &commitBatch{done: make(chan struct{})}
+		batch.timer = time.AfterFunc(b.cfg.MaxDelay, func() { b.commit(batch) })
+		b.cur = batch
+	}
+	batch.size++
+	full := batch.size >= b.cfg.MaxMessages
+	if full {
+		b.cur = nil
+	}
+	b.mu.Unlock()
+
+	if full {
+		batch.timer.Stop()
+		go b.commit(batch)
+	}
+
+	select {
+	case <-batch.done:
+		return batch.err
+	case <-ctx.Done():
+		return errs.B().Code(errs.DeadlineExceeded).Cause(ctx.Err()).Msg("message context ended before its batch was committed").Err()
+	}
+}
+
+// commit closes the batch to new messages and calls the commit hook,
+// unless the batch has already been committed.
+func (b *commitBatcher) commit(batch *commitBatch) {
+	batch.once.Do(func() {
+		b.mu.Lock()
+		if b.cur == batch {
+			b.cur = nil
+		}
+		b.mu.Unlock()
+
+		b.commitMu.Lock()
+		defer b.commitMu.Unlock()
+		defer close(batch.done)
+
+		defer func() {
+			if r := recover(); r != nil {
+				batch.err = errs.B().Code(errs.Internal).Msgf("commit hook panicked: %s", r).Err()
+			}
+		}()
+		if err := b.cfg.Hook(context.Background()); err != nil {
+			batch.err = errs.B().Code(errs.Aborted).Cause(err).Msg("failed to commit message batch").Err()
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package pubsub
+
+import "fmt"
+
+// OnConfigError sets a function which decides how to handle a subscription
+// whose configuration doesn't match the application, such as a subscription
+// the environment is configured to run which the application doesn't declare.
+// This usually means the environment's configuration has drifted from the
+// application being deployed.
+//
+// The function is called when the subscription is declared, with the names of the
+// topic and subscription and an error describing the problem. It can stop the
+// service, for example by panicking or logging a fatal error, or return to skip
+// the subscription, so that this instance doesn't process its messages.
+//
+// It must be set before the affected subscriptions are declared.
+// If no function is set, such subscriptions are skipped.
+func (mgr *Manager) OnConfigError(fn func(topic, subscription string, err error)) {
+	mgr.onConfigError = fn
+}
+
+// configError reports a configuration error for a subscription
+// to the function set with OnConfigError, if any.
+func (mgr *Manager) configError(topic, subscription string, err error) {
+	if mgr.onConfigError != nil {
+		mgr.onConfigError(topic, subscription, err)
+	}
+}
+
+// errSubscriptionNotDeclared reports that the environment is configured to run
+// a subscription which the application doesn't declare.
+func errSubscriptionNotDeclared(topic, subscription string) error {
+	return fmt.Errorf("subscription %s to topic %s is configured in the environment but not declared by the application", subscription, topic)
+}
@@ -0,0 +1,158 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"time"
+
+	"encore.dev/appruntime/exported/model"
+)
+
+// msgContextKey is the context key under which the
+// *messageContext of the message being processed is stored.
+type msgContextKey struct{}
+
+// messageContext holds information about the message currently being
+// processed by a subscription handler.
+type messageContext struct {
+	// lease reports when the messaging service will consider the
+	// message unacknowledged and redeliver it, as reported by the provider.
+	// It is nil if the provider does not lease messages.
+	lease func() time.Time
+
+	// deliveryAttempt is the delivery attempt of the message, starting at 1.
+	deliveryAttempt int
+
+	// draining is set once the subscription's Manager begins shutting down.
+	draining *atomic.Bool
+
+	// meta describes the message being processed.
+	meta *MessageMeta
+
+	// pull is the subscription's *pullQueue[T], used by PullHandler.
+	pull any
+
+	// req is the request processing the message.
+	req *model.Request
+
+	// idempotency records the result of processing the message,
+	// if the subscription has an IdempotencyConfig.
+	idempotency *idempotencyRecorder
+
+	// completed is set once the handler reports it has finished
+	// processing the message; see MarkCompleted.
+	completed atomic.Bool
+}
+
+// MessageMeta contains metadata about a message being processed by a subscription.
+// The fields should not be modified by the caller.
+// Additional fields may be added in the future.
+type MessageMeta struct {
+	ID              string            // the message ID assigned by the messaging service
+	Topic           string            // the topic name
+	Subscription    string            // the subscription name
+	Attempt         int               // the delivery attempt, starting at 1
+	PublishTime     time.Time         // when the message was published, according to the messaging service
+	ReceiveTime     time.Time         // when the message was received by this instance
+	Attributes      map[string]string // the message attributes, including those set by Encore
+	SchemaVersion   int               // the schema version the message was published with (see TopicConfig.SchemaVersion)
+	ProducerVersion string            // the version of the application which published the message, if known (see TopicConfig.TagProducerVersion)
+	CorrelationID   string            // the correlation ID the message was published with, if any (see WithCorrelationID)
+}
+
+func withMessageContext(ctx context.Context, mc *messageContext) context.Context {
+	return context.WithValue(ctx, msgContextKey{}, mc)
+}
+
+func messageContextFrom(ctx context.Context) (*messageContext, bool) {
+	mc, ok := ctx.Value(msgContextKey{}).(*messageContext)
+	return mc, ok
+}
+
+// CurrentRequest returns Encore's metadata about the request processing
+// the message currently being handled, for deep integrations such as custom
+// middleware. It is richer than MessageMeta, including for example the decoded
+// message and the user the message is processed on behalf of.
+//
+// The returned value is a copy, so modifying it does not affect the request.
+// It reports false if ctx does not belong to a subscription handler.
+func CurrentRequest(ctx context.Context) (*model.PubSubMsgData, bool) {
+	mc, ok := messageContextFrom(ctx)
+	if !ok || mc.req == nil || mc.req.MsgData == nil {
+		return nil, false
+	}
+	data := *mc.req.MsgData
+	data.Payload = bytes.Clone(data.Payload)
+	return &data, true
+}
+
+// LeaseDeadline reports the time at which the message currently being
+// processed will be considered unacknowledged by the messaging service and
+// be redelivered, taking into account the subscription's AckDeadline and any
+// extensions of the message's lease made by the messaging service's client.
+//
+// Handlers doing variable amounts of work can use it to decide whether
+// to start another unit of work, or to checkpoint and return.
+//
+// It reports false if ctx does not belong to a subscription handler, or
+// if the message was delivered by a backend which does not expose a lease
+// deadline (such as NSQ and push subscriptions).
+func LeaseDeadline(ctx context.Context) (time.Time, bool) {
+	mc, ok := messageContextFrom(ctx)
+	if !ok || mc.lease == nil {
+		return time.Time{}, false
+	}
+	return mc.lease(), true
+}
+
+// IsRedelivery reports whether the message currently being processed
+// has been delivered before, meaning a previous attempt to process it
+// failed or did not complete within the subscription's AckDeadline.
+//
+// Note that with at-least-once delivery, a message may in rare cases be
+// delivered more than once even when IsRedelivery reports false, so handlers
+// should still be idempotent.
+//
+// It reports false if ctx does not belong to a subscription handler.
+func IsRedelivery(ctx context.Context) bool {
+	mc, ok := messageContextFrom(ctx)
+	return ok && mc.deliveryAttempt > 1
+}
+
+// IsDraining reports whether the service is shutting down, and the handler
+// processing the message should wrap up its work.
+//
+// Once shutdown begins no new messages are delivered, and Encore waits for running
+// handlers to return before completing the shutdown. The handler's ctx is only
+// cancelled if they have not returned by the time the shutdown is forced.
+// Handlers doing several units of work can poll IsDraining between them,
+// finishing the current unit but not starting new ones:
+//
+//	for _, item := range msg.Items {
+//		if pubsub.IsDraining(ctx) {
+//			// Return an error so the message is redelivered
+//			// and the remaining items are processed later.
+//			return errs.B().Code(errs.Unavailable).Msg("shutting down").Err()
+//		}
+//		if err := process(ctx, item); err != nil {
+//			return err
+//		}
+//	}
+//
+// It reports false if ctx does not belong to a subscription handler.
+func IsDraining(ctx context.Context) bool {
+	mc, ok := messageContextFrom(ctx)
+	return ok && mc.draining != nil && mc.draining.Load()
+}
+
+// CurrentMessage returns metadata about the message currently being processed.
+//
+// It reports false if ctx does not belong to a subscription handler.
+func CurrentMessage(ctx context.Context) (*MessageMeta, bool) {
+	mc, ok := messageContextFrom(ctx)
+	if !ok || mc.meta == nil {
+		return nil, false
+	}
+	return mc.meta, true
+}
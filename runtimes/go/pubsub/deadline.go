@@ -0,0 +1,28 @@
+package pubsub
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxProcessingTimeAttribute is the attribute name we use to record how long
+// the publisher allows a message to be processed for; see WithMaxProcessingTime.
+const maxProcessingTimeAttribute = "encore_max_processing_time"
+
+// messageProcessingTime returns how long the message may be processed for,
+// as requested by its publisher and bounded by limit if it is positive.
+// It returns 0 if the publisher did not request a processing time.
+func messageProcessingTime(attrs map[string]string, limit time.Duration) (time.Duration, error) {
+	str, ok := attrs[maxProcessingTimeAttribute]
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid max processing time %q", str)
+	}
+	if limit > 0 && d > limit {
+		d = limit
+	}
+	return d, nil
+}
@@ -0,0 +1,130 @@
+package pubsub
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/shared/encoreenv"
+)
+
+// debugSinkEnvVar is the environment variable which, when running locally,
+// names a file each message published by the application is appended to.
+//
+// It is a debugging aid for local development only, to inspect what services
+// publish without access to the messaging service. Messages are still published
+// as usual, and the sink is ignored in any other environment.
+const debugSinkEnvVar = "ENCORE_PUBSUB_DEBUG_SINK"
+
+// debugSinkFlushInterval is how long records are buffered
+// before being written to the debug sink's file.
+const debugSinkFlushInterval = time.Second
+
+// debugSinkRecord is a line of the debug sink's file.
+type debugSinkRecord struct {
+	Time       time.Time         `json:"time"`
+	Topic      string            `json:"topic"`
+	MessageID  string            `json:"message_id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Data       json.RawMessage   `json:"data,omitempty"`
+	DataBase64 []byte            `json:"data_base64,omitempty"` // set instead of Data for messages which aren't JSON
+}
+
+// debugSink appends published messages to a file in the JSON Lines format.
+// See debugSinkEnvVar.
+type debugSink struct {
+	log zerolog.Logger
+
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	flushAt *time.Timer // set while buffered records are waiting to be flushed
+}
+
+// newDebugSinkFromEnv opens the debug sink configured with debugSinkEnvVar,
+// or returns nil if there is none or the application isn't running locally.
+func newDebugSinkFromEnv(envCloud string, log zerolog.Logger) *debugSink {
+	path := encoreenv.Get(debugSinkEnvVar)
+	if path == "" || envCloud != "local" {
+		return nil
+	}
+	sink, err := newDebugSink(path, log)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("pubsub: failed to open debug sink, not recording published messages")
+		return nil
+	}
+	log.Info().Str("path", path).Msg("pubsub: recording published messages to debug sink")
+	return sink
+}
+
+func newDebugSink(path string, log zerolog.Logger) (*debugSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &debugSink{log: log, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// record appends a published message to the sink.
+// Failures are logged, as the sink must not affect publishing.
+func (s *debugSink) record(topic, msgID string, attrs map[string]string, data []byte) {
+	rec := debugSinkRecord{Time: time.Now(), Topic: topic, MessageID: msgID, Attributes: attrs}
+	if json.Valid(data) {
+		rec.Data = data
+	} else {
+		rec.DataBase64 = data
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		s.log.Warn().Err(err).Str("msg_id", msgID).Msg("pubsub: failed to encode message for debug sink")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return // closed
+	}
+	_, _ = s.w.Write(append(line, '\n'))
+	if s.flushAt == nil {
+		s.flushAt = time.AfterFunc(debugSinkFlushInterval, s.flush)
+	}
+}
+
+// flush writes the buffered records to the file.
+func (s *debugSink) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+func (s *debugSink) flushLocked() {
+	if s.flushAt != nil {
+		s.flushAt.Stop()
+		s.flushAt = nil
+	}
+	if s.f == nil {
+		return
+	}
+	if err := s.w.Flush(); err != nil {
+		s.log.Warn().Err(err).Msg("pubsub: failed to write to debug sink")
+	}
+}
+
+// close flushes the buffered records and closes the file.
+func (s *debugSink) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+	if s.f != nil {
+		_ = s.f.Close()
+		s.f = nil
+	}
+}
@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/shared/encoreenv"
+)
+
+func TestManager_DebugSink(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	sink, err := newDebugSink(path, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	mgr.debugSink = sink
+
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ctx := context.Background()
+	id, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+
+	// The message is still published
+	c.Assert(fake.topics["topic"].published, qt.Equals, 1)
+
+	_, err = newTopic[*Blob](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce}).
+		Publish(ctx, &Blob{Data: []byte{0xff, 0x00}, ContentType: "application/octet-stream"})
+	c.Assert(err, qt.IsNil)
+
+	// Messages are recorded in the sink once it's flushed
+	sink.close()
+	f, err := os.Open(path)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+
+	var records []debugSinkRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec debugSinkRecord
+		c.Assert(json.Unmarshal(scanner.Bytes(), &rec), qt.IsNil)
+		records = append(records, rec)
+	}
+	c.Assert(records, qt.HasLen, 2)
+	c.Assert(records[0].Topic, qt.Equals, "topic")
+	c.Assert(records[0].MessageID, qt.Equals, id)
+	c.Assert(string(records[0].Data), qt.JSONEquals, &testEvent{Value: "hello"})
+	c.Assert(records[1].DataBase64, qt.DeepEquals, []byte{0xff, 0x00})
+	c.Assert(records[1].Attributes[contentTypeAttribute], qt.Equals, "application/octet-stream")
+
+	// Messages published after the sink is closed are dropped
+	_, err = topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+}
+
+func TestNewDebugSinkFromEnv(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(newDebugSinkFromEnv("local", zerolog.Nop()), qt.IsNil)
+
+	encoreenv.Set(debugSinkEnvVar, filepath.Join(t.TempDir(), "messages.jsonl"))
+	defer encoreenv.Set(debugSinkEnvVar, "")
+
+	// The sink is only enabled when running locally
+	c.Assert(newDebugSinkFromEnv("gcp", zerolog.Nop()), qt.IsNil)
+	sink := newDebugSinkFromEnv("local", zerolog.Nop())
+	c.Assert(sink, qt.IsNotNil)
+	sink.close()
+}
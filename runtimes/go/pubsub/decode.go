@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/beta/errs"
+)
+
+// DecodeErrorPolicy determines what a subscription does with a message
+// that cannot be decoded into the subscription's message type.
+//
+// Decode errors are handled before the Handler is called, and are
+// independent of the subscription's RetryPolicy.
+type DecodeErrorPolicy int
+
+const (
+	// DecodeErrorRetry negatively acknowledges the message so it is redelivered
+	// according to the subscription's RetryPolicy, the same as a Handler error.
+	//
+	// This is the default for compatibility, but as decode errors are rarely
+	// transient DecodeErrorQuarantine is recommended for new subscriptions.
+	DecodeErrorRetry DecodeErrorPolicy = iota
+
+	// DecodeErrorQuarantine passes the message to the subscription's OnQuarantine
+	// hook and then acknowledges it. If the hook returns an error, the message
+	// is negatively acknowledged so quarantining it is retried.
+	//
+	// If no OnQuarantine hook is set, the message is logged and acknowledged.
+	DecodeErrorQuarantine
+
+	// DecodeErrorDrop acknowledges the message without further processing.
+	DecodeErrorDrop
+)
+
+func (p DecodeErrorPolicy) String() string {
+	switch p {
+	case DecodeErrorRetry:
+		return "retry"
+	case DecodeErrorQuarantine:
+		return "quarantine"
+	case DecodeErrorDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// QuarantinedMessage is a message a subscription has set aside
+// rather than passing to its Handler.
+type QuarantinedMessage struct {
+	Topic        string            // the topic name
+	Subscription string            // the subscription name
+	ID           string            // the message ID assigned by the messaging service
+	Attempt      int               // the delivery attempt, starting at 1
+	PublishTime  time.Time         // when the message was published
+	Attributes   map[string]string // the message attributes
+	Data         []byte            // the raw message body, including any sensitive fields
+	Reason       error             // why the message was quarantined
+}
+
+// handleDecodeError applies the subscription's DecodeErrorPolicy to a message
+// which failed to decode, returning the error to report to the messaging service.
+// logData is the message data with any sensitive fields redacted, for logging.
+func handleDecodeError(ctx context.Context, log zerolog.Logger, policy DecodeErrorPolicy, onQuarantine func(context.Context, *QuarantinedMessage) error, logData []byte, msg *QuarantinedMessage) error {
+	log.Err(msg.Reason).
+		Str("msg_id", msg.ID).
+		Int("delivery_attempt", msg.Attempt).
+		Stringer("decode_error_policy", policy).
+		Msg("failed to unmarshal message")
+
+	switch policy {
+	case DecodeErrorQuarantine:
+		return quarantineMessage(ctx, log, onQuarantine, logData, msg)
+
+	case DecodeErrorDrop:
+		return nil
+
+	default:
+		return errs.B().Code(errs.Internal).Cause(msg.Reason).Msg("failed to unmarshal message").Err()
+	}
+}
+
+// quarantineMessage passes msg to the subscription's OnQuarantine hook, or logs
+// it if there is none. It returns an error if the message could not be quarantined,
+// in which case it should be negatively acknowledged so quarantining it is retried.
+func quarantineMessage(ctx context.Context, log zerolog.Logger, onQuarantine func(context.Context, *QuarantinedMessage) error, logData []byte, msg *QuarantinedMessage) error {
+	if onQuarantine == nil {
+		log.Error().Str("msg_id", msg.ID).Bytes("data", logData).Msg("quarantined message")
+		return nil
+	}
+	if err := onQuarantine(ctx, msg); err != nil {
+		log.Err(err).Str("msg_id", msg.ID).Msg("failed to quarantine message")
+		return errs.B().Code(errs.Internal).Cause(err).Msg("failed to quarantine message").Err()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// DecodeCacheConfig caches decoded messages on the instance that received
+// them, so that when a message is retried and redelivered to the same
+// instance it isn't decoded again. It saves CPU time for subscriptions with
+// large messages which are often retried.
+//
+// A message is cached once it has been decoded, migrated to the current
+// SchemaVersion and validated, and is removed from the cache once it is
+// acknowledged or its last attempt under the RetryPolicy has been made.
+// A redelivered message is only taken from the cache if its data is identical
+// to when it was cached. Redeliveries to other instances decode the message as usual.
+//
+// The cached message is passed to the Handler as-is on every attempt, so the
+// Handler must not modify messages, for example through pointers, when the cache
+// is enabled: a modification would be seen by the following attempts.
+//
+// The subscription's Stats report how often decoding was avoided.
+type DecodeCacheConfig struct {
+	// MaxMessages is the number of decoded messages kept at once.
+	// The least recently delivered messages are evicted first.
+	//
+	// If zero, it defaults to 100.
+	MaxMessages int
+
+	// TTL is how long a decoded message is kept after it was last delivered,
+	// so that messages redelivered to other instances don't linger.
+	// It should be longer than the RetryPolicy's MaxBackoff.
+	//
+	// If zero, it defaults to 15 minutes.
+	TTL time.Duration
+}
+
+// decodeCache caches the decoded messages of a subscription by message ID.
+type decodeCache[T any] struct {
+	seed    maphash.Seed
+	entries *utils.LRU[string, decodedMessage[T]]
+
+	hits   atomic.Uint64 // number of deliveries whose message was taken from the cache
+	misses atomic.Uint64 // number of deliveries whose message had to be decoded
+}
+
+// decodedMessage is a message as decoded from its data.
+type decodedMessage[T any] struct {
+	hash          uint64 // the hash of the data the message was decoded from
+	msg           T
+	schemaVersion int
+}
+
+func newDecodeCache[T any](cfg *DecodeCacheConfig) *decodeCache[T] {
+	return &decodeCache[T]{
+		seed:    maphash.MakeSeed(),
+		entries: utils.NewLRU[string, decodedMessage[T]](cfg.MaxMessages, cfg.TTL),
+	}
+}
+
+// get returns the decoded message with the given ID,
+// if it was cached and decoded from the same data.
+func (c *decodeCache[T]) get(msgID string, data []byte) (msg T, schemaVersion int, ok bool) {
+	if c == nil {
+		return msg, 0, false
+	}
+	if d, found := c.entries.Get(msgID); found && d.hash == maphash.Bytes(c.seed, data) {
+		c.hits.Add(1)
+		return d.msg, d.schemaVersion, true
+	}
+	c.misses.Add(1)
+	return msg, 0, false
+}
+
+// put caches msg, decoded from data, for redeliveries of the message with the given ID.
+func (c *decodeCache[T]) put(msgID string, data []byte, msg T, schemaVersion int) {
+	if c == nil {
+		return
+	}
+	c.entries.Set(msgID, decodedMessage[T]{hash: maphash.Bytes(c.seed, data), msg: msg, schemaVersion: schemaVersion})
+}
+
+// evict removes the message with the given ID from the cache,
+// once it won't be delivered again.
+func (c *decodeCache[T]) evict(msgID string) {
+	if c == nil {
+		return
+	}
+	c.entries.Delete(msgID)
+}
+
+// lastAttempt reports whether a delivery is the last attempt
+// the retry policy makes to process a message.
+func lastAttempt(policy *RetryPolicy, deliveryAttempt int) bool {
+	switch policy.MaxRetries {
+	case NoRetries:
+		return true
+	case InfiniteRetries:
+		return false
+	default:
+		return deliveryAttempt > utils.WithDefaultValue(policy.MaxRetries, 100)
+	}
+}
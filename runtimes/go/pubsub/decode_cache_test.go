@@ -0,0 +1,123 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSubscription_DecodeCache(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		decodes  int // counted by the validator, which runs as messages are decoded
+		fail     = true
+		received []*testEvent
+	)
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg)
+			if fail {
+				return errors.New("downstream unavailable")
+			}
+			return nil
+		},
+		Validator: MessageValidatorFunc(func(data []byte) error {
+			decodes++
+			return nil
+		}),
+		RetryPolicy: &RetryPolicy{MaxRetries: 3},
+		DecodeCache: &DecodeCacheConfig{},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	// Retries of a message reuse the message decoded for the first attempt
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, data), qt.IsNotNil)
+	fail = false
+	c.Assert(ft.deliver(ctx, "sub", "1", 3, nil, data), qt.IsNil)
+	c.Assert(decodes, qt.Equals, 1)
+	c.Assert(received, qt.HasLen, 3)
+	c.Assert(received[1], qt.Equals, received[0])
+	c.Assert(received[2], qt.Equals, received[0])
+	stats := sub.Stats()
+	c.Assert(stats.DecodeCacheHits, qt.Equals, uint64(2))
+	c.Assert(stats.DecodeCacheMisses, qt.Equals, uint64(1))
+
+	// Acknowledged messages are evicted
+	c.Assert(sub.decoded.entries.Len(), qt.Equals, 0)
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(decodes, qt.Equals, 2)
+
+	// So are messages whose last attempt failed
+	fail = true
+	c.Assert(ft.deliver(ctx, "sub", "2", 3, nil, data), qt.IsNotNil)
+	c.Assert(sub.decoded.entries.Len(), qt.Equals, 1)
+	c.Assert(ft.deliver(ctx, "sub", "2", 4, nil, data), qt.IsNotNil)
+	c.Assert(sub.decoded.entries.Len(), qt.Equals, 0)
+	c.Assert(decodes, qt.Equals, 3)
+
+	// A redelivery with different data is decoded again
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, data), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "3", 2, nil, []byte(`{"Value":"changed"}`)), qt.IsNotNil)
+	c.Assert(decodes, qt.Equals, 5)
+	c.Assert(received[len(received)-1].Value, qt.Equals, "changed")
+}
+
+func TestLastAttempt(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(lastAttempt(&RetryPolicy{MaxRetries: NoRetries}, 1), qt.IsTrue)
+	c.Assert(lastAttempt(&RetryPolicy{MaxRetries: InfiniteRetries}, 1000), qt.IsFalse)
+	c.Assert(lastAttempt(&RetryPolicy{MaxRetries: 2}, 2), qt.IsFalse)
+	c.Assert(lastAttempt(&RetryPolicy{MaxRetries: 2}, 3), qt.IsTrue)
+	c.Assert(lastAttempt(&RetryPolicy{}, 100), qt.IsFalse)
+	c.Assert(lastAttempt(&RetryPolicy{}, 101), qt.IsTrue)
+}
+
+// BenchmarkSubscription_DecodeCache measures the CPU time saved by the decode
+// cache for a large message which is retried twice before succeeding.
+func BenchmarkSubscription_DecodeCache(b *testing.B) {
+	run := func(b *testing.B, cache *DecodeCacheConfig) {
+		mgr, fake := newTestManager(b, "topic", "sub")
+		topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+		var calls int
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler: func(ctx context.Context, msg *testEvent) error {
+				if calls++; calls%3 != 0 {
+					return errors.New("retry")
+				}
+				return nil
+			},
+			DecodeCache: cache,
+		})
+		ft := fake.topics["topic"]
+		ctx := context.Background()
+		data := []byte(`{"Value":"` + strings.Repeat("x", 256<<10) + `"}`)
+
+		// Use distinct message IDs so deliveries aren't mistaken for a redelivery storm
+		ids := make([]string, b.N)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i)
+		}
+
+		b.SetBytes(int64(3 * len(data)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for attempt := 1; attempt <= 3; attempt++ {
+				_ = ft.deliver(ctx, "sub", ids[i], attempt, nil, data)
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) { run(b, nil) })
+	b.Run("cached", func(b *testing.B) { run(b, &DecodeCacheConfig{}) })
+}
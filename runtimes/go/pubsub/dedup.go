@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// DedupConfig configures how a subscription deduplicates messages
+// when SubscriptionConfig.DedupByMessageID is set.
+type DedupConfig struct {
+	// Window is how long a successfully processed message ID is
+	// remembered for. Redeliveries of the same message ID within
+	// the window are acknowledged without calling the handler.
+	//
+	// Defaults to 10 minutes.
+	Window time.Duration
+
+	// MaxSize is the maximum number of message IDs the default in-memory
+	// store remembers. Once reached the least recently seen message IDs
+	// are forgotten first.
+	//
+	// It has no effect if Store is set. Defaults to 10,000.
+	MaxSize int
+
+	// Store is where processed message IDs are recorded.
+	//
+	// If nil, a bounded in-memory store is used, which only deduplicates
+	// redeliveries to the same instance of the service. Provide a persistent
+	// store to deduplicate across instances and restarts. Persistent stores
+	// should implement VersionedDedupStore so their records can be migrated
+	// when upgrading Encore.
+	Store DedupStore
+}
+
+// DedupKey identifies a message processed by a subscription.
+type DedupKey struct {
+	Topic        string // the topic name
+	Subscription string // the subscription name
+	MessageID    string // the message ID assigned by the messaging service
+}
+
+// DedupStore records which messages a subscription has processed.
+//
+// Implementations must be safe for concurrent use.
+type DedupStore interface {
+	// Seen reports whether the message identified by key has been
+	// marked as processed and has not yet expired.
+	Seen(ctx context.Context, key DedupKey) (bool, error)
+
+	// MarkProcessed records that the message identified by key was
+	// processed successfully. The record should be kept for at least ttl.
+	MarkProcessed(ctx context.Context, key DedupKey, ttl time.Duration) error
+}
+
+// DedupStoreVersion is the version of the records this version of Encore
+// keeps in a DedupStore. It is increased whenever the records change in a way
+// which requires the data of persistent stores to be migrated, such as when
+// DedupKey gains a field.
+const DedupStoreVersion = 1
+
+// VersionedDedupStore is a DedupStore which persists its records, and can
+// migrate them when they change between versions of Encore.
+//
+// Before a subscription first uses the store it calls Version, and if the store
+// holds records of an older version it calls Migrate to upgrade them to
+// DedupStoreVersion. The store is only used once this succeeds. Until then,
+// messages are processed without being deduplicated and the migration is
+// retried as they are processed, so Migrate must be safe to retry
+// and to call from several instances at once.
+type VersionedDedupStore interface {
+	DedupStore
+
+	// Version returns the version of the records held by the store.
+	// A store which holds no records yet should report DedupStoreVersion.
+	Version(ctx context.Context) (int, error)
+
+	// Migrate upgrades the records held by the store from version from
+	// to version to, which is always newer.
+	Migrate(ctx context.Context, from, to int) error
+}
+
+// migratingDedupStore migrates the records of a VersionedDedupStore
+// to DedupStoreVersion before using it.
+type migratingDedupStore struct {
+	store VersionedDedupStore
+
+	mu       sync.Mutex
+	migrated bool
+}
+
+func newMigratingDedupStore(store VersionedDedupStore) *migratingDedupStore {
+	return &migratingDedupStore{store: store}
+}
+
+// migrate migrates the store to DedupStoreVersion, unless it already has been.
+func (s *migratingDedupStore) migrate(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.migrated {
+		return nil
+	}
+
+	version, err := s.store.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("get dedup store version: %w", err)
+	}
+	switch {
+	case version > DedupStoreVersion:
+		// Records written by a newer version of Encore may not mean what we expect
+		return fmt.Errorf("dedup store holds records of version %d, newer than the supported version %d", version, DedupStoreVersion)
+	case version < DedupStoreVersion:
+		if err := s.store.Migrate(ctx, version, DedupStoreVersion); err != nil {
+			return fmt.Errorf("migrate dedup store from version %d to %d: %w", version, DedupStoreVersion, err)
+		}
+	}
+	s.migrated = true
+	return nil
+}
+
+func (s *migratingDedupStore) Seen(ctx context.Context, key DedupKey) (bool, error) {
+	if err := s.migrate(ctx); err != nil {
+		return false, err
+	}
+	return s.store.Seen(ctx, key)
+}
+
+func (s *migratingDedupStore) MarkProcessed(ctx context.Context, key DedupKey, ttl time.Duration) error {
+	if err := s.migrate(ctx); err != nil {
+		return err
+	}
+	return s.store.MarkProcessed(ctx, key, ttl)
+}
+
+// memoryDedupStore is the default DedupStore, backed by a bounded LRU.
+type memoryDedupStore struct {
+	seen *utils.LRU[DedupKey, struct{}]
+}
+
+func newMemoryDedupStore(maxSize int, ttl time.Duration) *memoryDedupStore {
+	return &memoryDedupStore{seen: utils.NewLRU[DedupKey, struct{}](maxSize, ttl)}
+}
+
+func (s *memoryDedupStore) Seen(_ context.Context, key DedupKey) (bool, error) {
+	_, found := s.seen.Get(key)
+	return found, nil
+}
+
+func (s *memoryDedupStore) MarkProcessed(_ context.Context, key DedupKey, _ time.Duration) error {
+	s.seen.Set(key, struct{}{})
+	return nil
+}
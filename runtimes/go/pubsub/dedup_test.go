@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// versionedDedupStore is a VersionedDedupStore holding records of a given version.
+type versionedDedupStore struct {
+	*memoryDedupStore
+	version    int
+	migrateErr error
+	migrations [][2]int
+}
+
+func (s *versionedDedupStore) Version(ctx context.Context) (int, error) {
+	return s.version, nil
+}
+
+func (s *versionedDedupStore) Migrate(ctx context.Context, from, to int) error {
+	s.migrations = append(s.migrations, [2]int{from, to})
+	if s.migrateErr != nil {
+		return s.migrateErr
+	}
+	s.version = to
+	return nil
+}
+
+func TestSubscription_DedupStoreMigration(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	store := &versionedDedupStore{
+		memoryDedupStore: newMemoryDedupStore(10, time.Minute),
+		version:          0,
+		migrateErr:       errors.New("store unavailable"),
+	}
+	var calls int
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls++
+			return nil
+		},
+		DedupByMessageID: true,
+		Dedup:            &DedupConfig{Store: store},
+	})
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+	ft := fake.topics["topic"]
+
+	// Until the store is migrated, messages are processed without deduplication
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, data), qt.IsNil)
+	c.Assert(calls, qt.Equals, 2)
+	c.Assert(store.version, qt.Equals, 0)
+
+	// Once the migration succeeds the store is used
+	store.migrateErr = nil
+	c.Assert(ft.deliver(ctx, "sub", "1", 3, nil, data), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 4, nil, data), qt.IsNil)
+	c.Assert(calls, qt.Equals, 3)
+	c.Assert(store.version, qt.Equals, DedupStoreVersion)
+
+	// The store is only migrated until the migration succeeds
+	c.Assert(store.migrations, qt.HasLen, 5)
+	for _, m := range store.migrations {
+		c.Assert(m, qt.Equals, [2]int{0, DedupStoreVersion})
+	}
+}
+
+func TestMigratingDedupStore_NewerVersion(t *testing.T) {
+	c := qt.New(t)
+	store := &versionedDedupStore{
+		memoryDedupStore: newMemoryDedupStore(10, time.Minute),
+		version:          DedupStoreVersion + 1,
+	}
+	s := newMigratingDedupStore(store)
+
+	_, err := s.Seen(context.Background(), DedupKey{MessageID: "1"})
+	c.Assert(err, qt.ErrorMatches, "dedup store holds records of version 2, newer than the supported version 1")
+	c.Assert(store.migrations, qt.HasLen, 0)
+}
@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"encore.dev/beta/errs"
+)
+
+// DispatchConfig routes messages with the same key to the same worker,
+// so that messages for a key are processed one at a time and in the order
+// they were received. Handlers can then keep per-key state in memory, such as
+// aggregates, without locking, by sharding it by WorkerFor.
+//
+// Ordering is only guaranteed within a single instance of the service, and only
+// among messages received at the same time: the messaging service may deliver
+// messages for a key out of order, or to different instances, and messages which
+// are redelivered after a failure are processed after the messages which
+// followed them. Use TopicConfig.OrderingAttribute for ordering across instances.
+//
+// Set Ordered to process the messages of a key strictly in order, which
+// combined with an OrderingAttribute gives ordering per key while messages
+// with different keys are still processed in parallel.
+type DispatchConfig[T any] struct {
+	// KeyFunc returns the key of a message.
+	//
+	// This field is required, unless Ordered is set and the topic has an
+	// OrderingAttribute, in which case it defaults to the message's ordering key.
+	KeyFunc func(msg T) string
+
+	// Workers is the number of workers messages are spread across by key.
+	// At most Workers messages are processed at once.
+	//
+	// If zero, it defaults to 16.
+	Workers int
+
+	// Ordered makes the dispatcher process the messages of a key strictly in order:
+	//
+	//   - A message holds its key's worker until its outcome has been returned
+	//     to the messaging service to be acknowledged, not just until its
+	//     Handler returns, so the next message for the key isn't processed before then.
+	//   - If a message fails, the messages for its key which were waiting behind it
+	//     are negatively acknowledged without being processed, so they are redelivered
+	//     after the failed message rather than overtaking it.
+	//
+	// Use it along with TopicConfig.OrderingAttribute, so the messaging service
+	// also delivers and redelivers the messages of a key in order. It cannot
+	// be combined with AsyncAck, which acknowledges messages in the background.
+	// The subscription's Stats report the messages of each key in flight.
+	Ordered bool
+}
+
+// dispatcher serializes the processing of messages with the same key.
+//
+// Each worker is a slot which one message holds while being processed.
+// Handlers still run on the goroutine which received the message,
+// as request tracking is tied to it.
+type dispatcher[T any] struct {
+	keyFunc      func(msg T) string
+	orderingAttr string // the attribute used as the key if there is no keyFunc
+	slots        []chan struct{}
+	load         []atomic.Uint64 // number of messages processed by each worker
+
+	ordered bool
+	mu      sync.Mutex
+	keys    map[string]*dispatchKey // keys with messages in flight, if ordered
+}
+
+// dispatchKey tracks the messages of a key for an ordered dispatcher.
+type dispatchKey struct {
+	inFlight int  // number of messages for the key being processed or waiting
+	failed   bool // whether a message for the key failed while others were waiting
+}
+
+// errEarlierMessageFailed is reported for messages which are not processed
+// because an earlier message with the same key failed.
+var errEarlierMessageFailed = errors.New("an earlier message with the same key failed")
+
+func newDispatcher[T any](cfg *DispatchConfig[T], orderingAttr string) *dispatcher[T] {
+	d := &dispatcher[T]{
+		keyFunc:      cfg.KeyFunc,
+		orderingAttr: orderingAttr,
+		slots:        make([]chan struct{}, cfg.Workers),
+		load:         make([]atomic.Uint64, cfg.Workers),
+		ordered:      cfg.Ordered,
+	}
+	for i := range d.slots {
+		d.slots[i] = make(chan struct{}, 1)
+	}
+	if d.ordered {
+		d.keys = make(map[string]*dispatchKey)
+	}
+	return d
+}
+
+// key returns the key of a message with the given attributes.
+func (d *dispatcher[T]) key(msg T, attrs map[string]string) string {
+	if d.keyFunc == nil {
+		return attrs[d.orderingAttr]
+	}
+	return d.keyFunc(msg)
+}
+
+// acquire waits for the worker of the given key to be free, returning
+// a function to call with the outcome once the message has been processed.
+func (d *dispatcher[T]) acquire(ctx context.Context, key string) (release func(err error), err error) {
+	var k *dispatchKey
+	if d.ordered {
+		d.mu.Lock()
+		if k = d.keys[key]; k == nil {
+			k = &dispatchKey{}
+			d.keys[key] = k
+		}
+		k.inFlight++
+		d.mu.Unlock()
+	}
+
+	i := WorkerFor(key, len(d.slots))
+	select {
+	case d.slots[i] <- struct{}{}:
+	case <-ctx.Done():
+		d.done(key, k, nil)
+		return nil, ctx.Err()
+	}
+
+	if k != nil {
+		d.mu.Lock()
+		failed := k.failed
+		d.mu.Unlock()
+		if failed {
+			d.done(key, k, nil)
+			<-d.slots[i]
+			return nil, errs.B().Cause(errEarlierMessageFailed).Code(errs.Aborted).Msgf("not processing message with key %q to keep it in order", key).Err()
+		}
+	}
+
+	d.load[i].Add(1)
+	return func(err error) {
+		d.done(key, k, err)
+		<-d.slots[i]
+	}, nil
+}
+
+// done records that a message for the key k is no longer in flight,
+// marking the key as failed if it failed while others were waiting.
+// It does nothing if the dispatcher isn't ordered.
+func (d *dispatcher[T]) done(key string, k *dispatchKey, err error) {
+	if k == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil && k.inFlight > 1 {
+		k.failed = true
+	}
+	if k.inFlight--; k.inFlight == 0 {
+		delete(d.keys, key)
+	}
+}
+
+// keysInFlight reports the number of messages in flight for each key,
+// or nil if the dispatcher isn't ordered.
+func (d *dispatcher[T]) keysInFlight() map[string]int {
+	if !d.ordered {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := make(map[string]int, len(d.keys))
+	for key, k := range d.keys {
+		keys[key] = k.inFlight
+	}
+	return keys
+}
+
+// loads reports the number of messages processed by each worker.
+func (d *dispatcher[T]) loads() []uint64 {
+	loads := make([]uint64, len(d.load))
+	for i := range d.load {
+		loads[i] = d.load[i].Load()
+	}
+	return loads
+}
+
+// WorkerFor reports which of a DispatchConfig's workers
+// messages with the given key are routed to.
+func WorkerFor(key string, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
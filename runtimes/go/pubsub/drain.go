@@ -0,0 +1,168 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/utils"
+)
+
+// DrainOptions configures when a call to DrainTo stops.
+type DrainOptions struct {
+	// IdleTimeout is how long to wait without receiving
+	// any messages before considering the subscription drained.
+	//
+	// Defaults to 30 seconds.
+	IdleTimeout time.Duration
+
+	// MaxMessages is the maximum number of messages to pass
+	// to the drain handler. If zero there is no limit.
+	MaxMessages int
+}
+
+// DrainTo temporarily routes the messages this instance of the service receives
+// on the subscription through handler instead of the subscription's Handler,
+// until the subscription is drained or the stop condition in opts is reached.
+// It reports the number of messages handler processed successfully.
+//
+// Messages are processed the same way as with the subscription's Handler:
+// they are traced, acknowledged when handler succeeds and retried according
+// to the subscription's RetryPolicy when it fails. Messages acknowledged by
+// handler are therefore not passed to the subscription's Handler.
+//
+// DrainTo returns once no messages have been received for opts.IdleTimeout,
+// opts.MaxMessages messages have been passed to handler, or ctx is cancelled.
+// It waits for messages being processed by handler to finish before returning,
+// after which messages are passed to the subscription's Handler again.
+//
+// Only one call to DrainTo can be active for a subscription at a time.
+func (s *Subscription[T]) DrainTo(ctx context.Context, handler func(ctx context.Context, msg T) error, opts DrainOptions) (processed int, err error) {
+	if handler == nil {
+		return 0, errs.B().Code(errs.InvalidArgument).Msg("drain handler cannot be nil").Err()
+	}
+	if opts.IdleTimeout < 0 {
+		return 0, errs.B().Code(errs.InvalidArgument).Msg("IdleTimeout cannot be negative").Err()
+	}
+	if opts.MaxMessages < 0 {
+		return 0, errs.B().Code(errs.InvalidArgument).Msg("MaxMessages cannot be negative").Err()
+	}
+	opts.IdleTimeout = utils.WithDefaultValue(opts.IdleTimeout, 30*time.Second)
+
+	if _, ok := s.mgr.lookupSubscription(s.topic.runtimeCfg.EncoreName, s.name); !ok {
+		return 0, errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s is not running on this instance", s.name).Err()
+	}
+
+	d := &drainState[T]{
+		handler:     handler,
+		maxMessages: opts.MaxMessages,
+		changed:     make(chan struct{}, 1),
+	}
+	if !s.drain.CompareAndSwap(nil, d) {
+		return 0, errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s is already being drained", s.name).Err()
+	}
+
+	idle := time.NewTimer(opts.IdleTimeout)
+	defer idle.Stop()
+
+	for !d.limitReached() {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-d.changed:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(opts.IdleTimeout)
+			continue
+		case <-idle.C:
+			if d.active() {
+				// Messages are still being processed, so we're not idle yet
+				idle.Reset(opts.IdleTimeout)
+				continue
+			}
+		}
+		break
+	}
+
+	// Route messages back to the subscription's Handler,
+	// and wait for the messages passed to handler to finish.
+	s.drain.Store(nil)
+	return d.stop(), err
+}
+
+// drainState tracks the messages routed through a DrainTo handler.
+type drainState[T any] struct {
+	handler     func(context.Context, T) error
+	maxMessages int
+	changed     chan struct{} // signalled whenever a message starts or finishes
+
+	mu        sync.Mutex
+	stopped   bool
+	started   int // number of messages passed to handler
+	inFlight  int // number of messages handler is processing
+	succeeded int // number of messages handler processed successfully
+}
+
+// acquire reports whether a message should be passed to the drain handler.
+// If so, release must be called once it has been processed.
+func (d *drainState[T]) acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped || (d.maxMessages > 0 && d.started >= d.maxMessages) {
+		return false
+	}
+	d.started++
+	d.inFlight++
+	d.notify()
+	return true
+}
+
+// release records the outcome of a message passed to the drain handler.
+func (d *drainState[T]) release(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if err == nil {
+		d.succeeded++
+	}
+	d.notify()
+}
+
+// active reports whether any messages are being processed by the drain handler.
+func (d *drainState[T]) active() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight > 0
+}
+
+// limitReached reports whether MaxMessages messages have been processed.
+func (d *drainState[T]) limitReached() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.maxMessages > 0 && d.started >= d.maxMessages && d.inFlight == 0
+}
+
+// stop stops passing messages to the drain handler, waits for the
+// messages it is processing to finish and reports how many succeeded.
+func (d *drainState[T]) stop() int {
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+
+	for d.active() {
+		<-d.changed
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.succeeded
+}
+
+func (d *drainState[T]) notify() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
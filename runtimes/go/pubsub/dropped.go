@@ -0,0 +1,151 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DropReason describes why a message left the normal processing path.
+type DropReason string
+
+const (
+	// DropDecodeError means the message could not be decoded, and was
+	// quarantined or dropped according to the subscription's OnDecodeError.
+	DropDecodeError DropReason = "decode_error"
+
+	// DropRedeliveryStorm means the message was quarantined, or rejected on its
+	// last delivery attempt, as it was caught in a redelivery storm.
+	// See SubscriptionConfig.RedeliveryStorm.
+	DropRedeliveryStorm DropReason = "redelivery_storm"
+
+	// DropRetryDurationExceeded means the message was quarantined as its Handler
+	// failed after the subscription's MaxRetryDuration had passed.
+	DropRetryDurationExceeded DropReason = "retry_duration_exceeded"
+
+	// DropRetriesExhausted means processing the message failed on its last
+	// delivery attempt allowed by the subscription's RetryPolicy, so the
+	// messaging service dead-letters or discards it.
+	DropRetriesExhausted DropReason = "retries_exhausted"
+
+	// DropBacklogSkipped means the message was acknowledged without being processed
+	// as it was published before the cutoff of the subscription's SkipBacklog.
+	DropBacklogSkipped DropReason = "backlog_skipped"
+
+	// DropFilterError means the message was quarantined as
+	// the subscription's FilterExpr couldn't be evaluated for it.
+	DropFilterError DropReason = "filter_error"
+)
+
+// DroppedStats counts the messages this instance of the service stopped processing
+// without their Handler succeeding, and which won't be delivered to the Handler again.
+// Messages excluded by a subscription's FilterExpr are processed as intended,
+// so they are not counted.
+type DroppedStats struct {
+	// Total is the number of messages dropped across all subscriptions.
+	Total uint64
+
+	// ByReason is the number of messages dropped across all subscriptions, keyed by reason.
+	ByReason map[DropReason]uint64
+
+	// Subscriptions is the messages dropped by each subscription
+	// which has dropped any, sorted by topic and subscription.
+	Subscriptions []DroppedSubscriptionStats
+}
+
+// DroppedSubscriptionStats counts the messages dropped by a subscription.
+type DroppedSubscriptionStats struct {
+	Topic        string                // the topic name
+	Subscription string                // the subscription name
+	ByReason     map[DropReason]uint64 // the number of messages dropped, keyed by reason
+}
+
+// dropKey identifies the count of a subscription's messages dropped for a reason.
+type dropKey struct {
+	subscriptionKey
+	reason DropReason
+}
+
+// dropCounter counts the messages dropped by each subscription for each reason.
+type dropCounter struct {
+	counts sync.Map // the *atomic.Uint64 count of each dropKey
+}
+
+// record records a message was dropped by the subscription for reason.
+func (d *dropCounter) record(topic, subscription string, reason DropReason) {
+	key := dropKey{subscriptionKey{topic, subscription}, reason}
+	count, ok := d.counts.Load(key)
+	if !ok {
+		count, _ = d.counts.LoadOrStore(key, new(atomic.Uint64))
+	}
+	count.(*atomic.Uint64).Add(1)
+}
+
+// subscription returns the number of messages dropped by the subscription, keyed by reason,
+// or nil if it hasn't dropped any.
+func (d *dropCounter) subscription(topic, subscription string) map[DropReason]uint64 {
+	var counts map[DropReason]uint64
+	d.counts.Range(func(k, v any) bool {
+		if key := k.(dropKey); key.topic == topic && key.subscription == subscription {
+			if counts == nil {
+				counts = make(map[DropReason]uint64)
+			}
+			counts[key.reason] = v.(*atomic.Uint64).Load()
+		}
+		return true
+	})
+	return counts
+}
+
+// DroppedMessages reports how many messages this instance of the service has
+// dropped since it started, by reason and subscription. Dropped messages are those
+// which stopped being processed without the Handler succeeding, such as messages
+// which couldn't be decoded or which failed on their last delivery attempt.
+func (mgr *Manager) DroppedMessages() DroppedStats {
+	stats := DroppedStats{ByReason: make(map[DropReason]uint64)}
+	bySub := make(map[subscriptionKey]map[DropReason]uint64)
+	mgr.dropped.counts.Range(func(k, v any) bool {
+		key, n := k.(dropKey), v.(*atomic.Uint64).Load()
+		stats.Total += n
+		stats.ByReason[key.reason] += n
+		if bySub[key.subscriptionKey] == nil {
+			bySub[key.subscriptionKey] = make(map[DropReason]uint64)
+		}
+		bySub[key.subscriptionKey][key.reason] = n
+		return true
+	})
+
+	for key, counts := range bySub {
+		stats.Subscriptions = append(stats.Subscriptions, DroppedSubscriptionStats{
+			Topic:        key.topic,
+			Subscription: key.subscription,
+			ByReason:     counts,
+		})
+	}
+	sort.Slice(stats.Subscriptions, func(i, j int) bool {
+		a, b := stats.Subscriptions[i], stats.Subscriptions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Subscription < b.Subscription
+	})
+	return stats
+}
+
+// recordDrop records a message was dropped by the subscription for reason.
+func (s *Subscription[T]) recordDrop(reason DropReason) {
+	s.mgr.dropped.record(s.topic.runtimeCfg.EncoreName, s.name, reason)
+}
+
+// dropped records a message was dropped for reason if err, the outcome of quarantining
+// or dropping the message, is nil. Otherwise the message is redelivered, unless this was
+// its last delivery attempt, in which case it is dropped as its retries are exhausted.
+// It returns err.
+func (s *Subscription[T]) dropped(reason DropReason, deliveryAttempt int, err error) error {
+	if err == nil {
+		s.recordDrop(reason)
+	} else if lastAttempt(s.cfg.RetryPolicy, deliveryAttempt) {
+		s.recordDrop(DropRetriesExhausted)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestManager_DroppedMessages(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			return errors.New("boom")
+		},
+		RetryPolicy:   &RetryPolicy{MaxRetries: 1},
+		OnDecodeError: DecodeErrorDrop,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	c.Assert(mgr.DroppedMessages(), qt.DeepEquals, DroppedStats{ByReason: map[DropReason]uint64{}})
+	c.Assert(sub.Stats().Dropped, qt.IsNil)
+
+	// Messages which fail before their last attempt are retried, so they aren't dropped
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{}`)), qt.IsNotNil)
+	c.Assert(mgr.DroppedMessages().Total, qt.Equals, uint64(0))
+
+	// Unlike those failing on their last attempt, or which can't be decoded
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, []byte(`{}`)), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte(`not json`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`not json`)), qt.IsNil)
+
+	want := map[DropReason]uint64{DropRetriesExhausted: 1, DropDecodeError: 2}
+	c.Assert(mgr.DroppedMessages(), qt.DeepEquals, DroppedStats{
+		Total:    3,
+		ByReason: want,
+		Subscriptions: []DroppedSubscriptionStats{
+			{Topic: "topic", Subscription: "sub", ByReason: want},
+		},
+	})
+	c.Assert(sub.Stats().Dropped, qt.DeepEquals, want)
+}
+
+func TestManager_DroppedMessagesQuarantineFailure(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler:       func(ctx context.Context, msg *testEvent) error { return nil },
+		OnDecodeError: DecodeErrorQuarantine,
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			return errors.New("quarantine unavailable")
+		},
+	})
+
+	// Messages which fail to be quarantined are redelivered, so they aren't dropped
+	c.Assert(fake.topics["topic"].deliver(context.Background(), "sub", "1", 1, nil, []byte(`not json`)), qt.IsNotNil)
+	c.Assert(mgr.DroppedMessages().Total, qt.Equals, uint64(0))
+}
@@ -0,0 +1,183 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+const (
+	// encryptionAlgorithmAttribute is the attribute recording the algorithm
+	// an encrypted message was encrypted with.
+	encryptionAlgorithmAttribute = "encore_enc_alg"
+
+	// encryptionKeyIDAttribute is the attribute recording the ID of the key
+	// the data key of an encrypted message was encrypted with.
+	encryptionKeyIDAttribute = "encore_enc_key_id"
+
+	// encryptionDataKeyAttribute is the attribute recording the
+	// base64 encoded, encrypted data key of an encrypted message.
+	encryptionDataKeyAttribute = "encore_enc_data_key"
+)
+
+// encryptionAlgorithm is the algorithm messages are encrypted with.
+// The ciphertext is prefixed by its nonce, and authenticated along
+// with the topic name so it can't be replayed to other topics.
+const encryptionAlgorithm = "AES-256-GCM"
+
+// maxDecryptedDataKeys is the number of decrypted data keys
+// subscriptions keep, so the KeyProvider isn't called for every message.
+const maxDecryptedDataKeys = 100
+
+// errDecryptionFailed is reported when an encrypted message can't be decrypted.
+var errDecryptionFailed = errors.New("failed to decrypt message")
+
+// messageEncryption encrypts and decrypts the messages of a topic.
+type messageEncryption struct {
+	keys     KeyProvider
+	lifetime time.Duration
+	topic    string
+
+	mu      sync.Mutex
+	current *publishKey // the data key messages are encrypted with; nil until the first publish
+
+	decrypted *utils.LRU[string, cipher.AEAD] // decrypted data keys, by their encrypted form
+}
+
+// publishKey is a data key messages are published with.
+type publishKey struct {
+	aead      cipher.AEAD
+	keyID     string
+	encrypted string // base64 encoded
+	expires   time.Time
+}
+
+// newMessageEncryption validates cfg and creates a messageEncryption
+// for the given topic, or returns nil if cfg is nil.
+func newMessageEncryption(cfg *Encryption, topic string) *messageEncryption {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Keys == nil {
+		panic("Encryption.Keys is required")
+	}
+	if cfg.DataKeyLifetime < 0 {
+		panic("Encryption.DataKeyLifetime cannot be negative")
+	}
+	lifetime := utils.WithDefaultValue(cfg.DataKeyLifetime, 5*time.Minute)
+	return &messageEncryption{
+		keys:      cfg.Keys,
+		lifetime:  lifetime,
+		topic:     topic,
+		decrypted: utils.NewLRU[string, cipher.AEAD](maxDecryptedDataKeys, 0),
+	}
+}
+
+// encrypt encrypts data, recording how to decrypt it in attrs.
+func (e *messageEncryption) encrypt(ctx context.Context, attrs *attributeSet, data []byte) ([]byte, error) {
+	key, err := e.publishKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, key.aead.NonceSize(), key.aead.NonceSize()+len(data)+key.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	attrs.set(fromEncore, encryptionAlgorithmAttribute, encryptionAlgorithm)
+	attrs.set(fromEncore, encryptionKeyIDAttribute, key.keyID)
+	attrs.set(fromEncore, encryptionDataKeyAttribute, key.encrypted)
+	return key.aead.Seal(nonce, nonce, data, []byte(e.topic)), nil
+}
+
+// publishKey returns the data key to encrypt messages with,
+// generating a new one if the current one has expired.
+func (e *messageEncryption) publishKey(ctx context.Context) (*publishKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current != nil && time.Now().Before(e.current.expires) {
+		return e.current, nil
+	}
+
+	dk, err := e.keys.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newDataKeyAEAD(dk.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	e.current = &publishKey{
+		aead:      aead,
+		keyID:     dk.KeyID,
+		encrypted: base64.StdEncoding.EncodeToString(dk.Encrypted),
+		expires:   time.Now().Add(e.lifetime),
+	}
+	return e.current, nil
+}
+
+// decryptMessage decrypts data if attrs mark it as encrypted,
+// using e to decrypt its data key. Otherwise data is returned as-is.
+//
+// Failures to decrypt the message itself wrap errDecryptionFailed,
+// while failures of the KeyProvider are returned as they are.
+func decryptMessage(ctx context.Context, e *messageEncryption, attrs map[string]string, data []byte) ([]byte, error) {
+	alg, encrypted := attrs[encryptionAlgorithmAttribute]
+	if !encrypted {
+		return data, nil
+	}
+	switch {
+	case e == nil:
+		return nil, fmt.Errorf("%w: the topic has no Encryption configured", errDecryptionFailed)
+	case alg != encryptionAlgorithm:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", errDecryptionFailed, alg)
+	}
+
+	encryptedKey := attrs[encryptionDataKeyAttribute]
+	aead, ok := e.decrypted.Get(encryptedKey)
+	if !ok {
+		keyData, err := base64.StdEncoding.DecodeString(encryptedKey)
+		if err != nil || len(keyData) == 0 {
+			return nil, fmt.Errorf("%w: invalid data key", errDecryptionFailed)
+		}
+		keyID := attrs[encryptionKeyIDAttribute]
+		plaintext, err := e.keys.DecryptDataKey(ctx, keyID, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt data key with key %s: %w", keyID, err)
+		}
+		if aead, err = newDataKeyAEAD(plaintext); err != nil {
+			return nil, fmt.Errorf("%w: %w", errDecryptionFailed, err)
+		}
+		e.decrypted.Set(encryptedKey, aead)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: message too short", errDecryptionFailed)
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(e.topic))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+// newDataKeyAEAD returns the cipher for encrypting messages with the data key.
+func newDataKeyAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("data key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,233 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// testKeyProvider is a KeyProvider which keeps its data keys in memory,
+// using a random ID as their encrypted form.
+type testKeyProvider struct {
+	mu         sync.Mutex
+	keys       map[string][]byte
+	generated  int
+	decrypted  int
+	decryptErr error
+}
+
+func newTestKeyProvider() *testKeyProvider {
+	return &testKeyProvider{keys: make(map[string][]byte)}
+}
+
+func (p *testKeyProvider) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	plaintext, id := make([]byte, 32), make([]byte, 16)
+	_, _ = rand.Read(plaintext)
+	_, _ = rand.Read(id)
+	p.keys[string(id)] = plaintext
+	p.generated++
+	return &DataKey{KeyID: "master", Plaintext: plaintext, Encrypted: id}, nil
+}
+
+func (p *testKeyProvider) DecryptDataKey(ctx context.Context, keyID string, encrypted []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.decrypted++
+	if p.decryptErr != nil {
+		return nil, p.decryptErr
+	}
+	plaintext, ok := p.keys[string(encrypted)]
+	if !ok || keyID != "master" {
+		return nil, errors.New("unknown data key")
+	}
+	return plaintext, nil
+}
+
+func TestTopic_Encryption(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	keys := newTestKeyProvider()
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Encryption:        &Encryption{Keys: keys},
+	})
+
+	var (
+		received    []string
+		quarantined []*QuarantinedMessage
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg.Value)
+			return nil
+		},
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages are published encrypted
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	attrs, data := ft.lastAttrs, ft.lastData
+	c.Assert(attrs[encryptionAlgorithmAttribute], qt.Equals, "AES-256-GCM")
+	c.Assert(attrs[encryptionKeyIDAttribute], qt.Equals, "master")
+	c.Assert(attrs[encryptionDataKeyAttribute], qt.Not(qt.Equals), "")
+	c.Assert(bytes.Contains(data, []byte("hello")), qt.IsFalse)
+
+	// Subscriptions decrypt them, reusing the decrypted data key
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, attrs, data), qt.IsNil)
+	_, err = topic.Publish(ctx, &testEvent{Value: "world"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"hello", "world"})
+	c.Assert(keys.generated, qt.Equals, 1)
+	c.Assert(keys.decrypted, qt.Equals, 1)
+
+	// Tampered messages are quarantined
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, attrs, tampered), qt.IsNil)
+	c.Assert(received, qt.HasLen, 2)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, "failed to decrypt message: .*")
+
+	// Messages which aren't encrypted are decoded as usual
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, nil, []byte(`{"Value":"plain"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"hello", "world", "plain"})
+}
+
+func TestTopic_EncryptionKeyRotation(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	keys := newTestKeyProvider()
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Encryption:        &Encryption{Keys: keys, DataKeyLifetime: time.Millisecond},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	first := ft.lastAttrs[encryptionDataKeyAttribute]
+
+	// A new data key is generated once the current one expires
+	time.Sleep(2 * time.Millisecond)
+	_, err = topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(keys.generated, qt.Equals, 2)
+	c.Assert(ft.lastAttrs[encryptionDataKeyAttribute], qt.Not(qt.Equals), first)
+}
+
+func TestSubscription_EncryptionFailures(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	keys := newTestKeyProvider()
+
+	// Publish an encrypted message to decrypt
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Encryption:        &Encryption{Keys: keys},
+	})
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	attrs, data := fake.topics["topic"].lastAttrs, fake.topics["topic"].lastData
+
+	// Failures of the KeyProvider follow the OnDecodeError policy
+	var quarantined []*QuarantinedMessage
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	keys.decryptErr = errors.New("kms unavailable")
+	err = fake.topics["topic"].deliver(ctx, "sub", "1", 1, attrs, data)
+	c.Assert(err, qt.ErrorMatches, ".*failed to decrypt data key with key master: kms unavailable")
+	c.Assert(quarantined, qt.HasLen, 0)
+
+	// Encrypted messages received by a topic without Encryption are quarantined
+	mgr, fake = newTestManager(t, "topic", "sub")
+	plain := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	NewSubscription(plain, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	c.Assert(fake.topics["topic"].deliver(ctx, "sub", "1", 1, attrs, data), qt.IsNil)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, "failed to decrypt message: the topic has no Encryption configured")
+
+	// Encrypted data is never logged
+	c.Assert(string(redactPublished[*testEvent](attrs, data)), qt.Equals, redactedValue)
+}
+
+func TestTopic_EncryptionConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	newTopicWith := func(cfg *Encryption) func() {
+		return func() {
+			newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Encryption: cfg})
+		}
+	}
+
+	c.Assert(newTopicWith(&Encryption{}), qt.PanicMatches, "Encryption.Keys is required")
+	c.Assert(newTopicWith(&Encryption{Keys: newTestKeyProvider(), DataKeyLifetime: -1}), qt.PanicMatches, "Encryption.DataKeyLifetime cannot be negative")
+}
+
+// BenchmarkTopic_Encryption measures the overhead encryption adds
+// to publishing and receiving messages of various sizes.
+func BenchmarkTopic_Encryption(b *testing.B) {
+	run := func(b *testing.B, size int, enc *Encryption) {
+		mgr, fake := newTestManager(b, "topic", "sub")
+		topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Encryption: enc})
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+		})
+		ft := fake.topics["topic"]
+		ctx := context.Background()
+		msg := &testEvent{Value: strings.Repeat("x", size)}
+
+		ids := make([]string, b.N)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i)
+		}
+
+		b.SetBytes(int64(size))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := topic.Publish(ctx, msg); err != nil {
+				b.Fatal(err)
+			}
+			if err := ft.deliver(ctx, "sub", ids[i], 1, ft.lastAttrs, ft.lastData); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	for _, size := range []int{1 << 10, 64 << 10} {
+		b.Run(strconv.Itoa(size>>10)+"KB/plain", func(b *testing.B) { run(b, size, nil) })
+		b.Run(strconv.Itoa(size>>10)+"KB/encrypted", func(b *testing.B) {
+			run(b, size, &Encryption{Keys: newTestKeyProvider()})
+		})
+	}
+}
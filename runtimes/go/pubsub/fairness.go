@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// FairnessConfig schedules the messages of a subscription shared by many
+// tenants fairly between them, so that a burst of messages from one noisy
+// tenant can't starve the others of the subscription's Handlers.
+//
+// The subscription prefetches up to MaxConcurrency messages beyond those being
+// processed. When a Handler finishes, the next message processed is the oldest
+// waiting message of the tenant with the fewest messages being processed,
+// and MaxPerTenant optionally caps how many messages of one tenant are
+// processed at once.
+//
+// Fairness only applies to the messages this instance has already received,
+// not to the messaging service: messages are delivered to the subscription in
+// the messaging service's usual order, so if one tenant accounts for most of
+// a backlog, most messages waiting locally will be that tenant's. Other tenants'
+// messages are still processed as soon as they are received, instead of queueing
+// behind the backlog. Fairness has no effect unless MaxConcurrency is positive.
+//
+// The subscription's Stats report how many messages of each tenant
+// have been processed and are waiting.
+type FairnessConfig[T any] struct {
+	// TenantFunc returns the tenant of a message.
+	//
+	// This field is required.
+	TenantFunc func(msg T) string
+
+	// MaxPerTenant is the most messages of a single tenant
+	// which are processed at once.
+	//
+	// If zero, a tenant may use all of the subscription's MaxConcurrency
+	// while no other tenant's messages are waiting.
+	MaxPerTenant int
+}
+
+// TenantStats contains runtime statistics about
+// the messages of a tenant of a subscription.
+type TenantStats struct {
+	// Processed is the number of the tenant's messages
+	// whose Handler has been called.
+	Processed uint64
+
+	// Waiting is the number of the tenant's messages currently
+	// waiting for a slot within the subscription's MaxConcurrency.
+	Waiting int
+
+	// Wait is the total time the tenant's messages have spent waiting
+	// for a slot within the subscription's MaxConcurrency.
+	Wait time.Duration
+}
+
+// fairScheduler schedules the processing of messages fairly between tenants.
+type fairScheduler[T any] struct {
+	tenantFunc func(msg T) string
+	gate       *utils.FairGate
+
+	mu    sync.Mutex
+	stats map[string]*TenantStats // by tenant
+}
+
+func newFairScheduler[T any](cfg *FairnessConfig[T], maxConcurrency int) *fairScheduler[T] {
+	return &fairScheduler[T]{
+		tenantFunc: cfg.TenantFunc,
+		gate:       utils.NewFairGate(maxConcurrency, cfg.MaxPerTenant),
+		stats:      make(map[string]*TenantStats),
+	}
+}
+
+// acquire waits for msg's turn to be processed, returning how long it waited
+// and a function to call once msg has been processed.
+func (f *fairScheduler[T]) acquire(ctx context.Context, msg T) (wait time.Duration, release func(), err error) {
+	tenant := f.tenantFunc(msg)
+	f.mu.Lock()
+	s := f.stats[tenant]
+	if s == nil {
+		s = &TenantStats{}
+		f.stats[tenant] = s
+	}
+	f.mu.Unlock()
+
+	start := time.Now()
+	err = f.gate.Acquire(ctx, tenant)
+	wait = time.Since(start)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s.Wait += wait
+	if err != nil {
+		return wait, nil, err
+	}
+	s.Processed++
+	return wait, func() { f.gate.Release(tenant) }, nil
+}
+
+// tenantStats reports the statistics of each tenant seen so far.
+func (f *fairScheduler[T]) tenantStats() map[string]TenantStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := make(map[string]TenantStats, len(f.stats))
+	for tenant, s := range f.stats {
+		ts := *s
+		ts.Waiting = f.gate.WaitingFor(tenant)
+		stats[tenant] = ts
+	}
+	return stats
+}
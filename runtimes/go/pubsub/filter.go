@@ -0,0 +1,330 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// messageFilter is a parsed FilterExpr, which matches messages by their attributes.
+type messageFilter interface {
+	// match reports whether the message with the given attributes matches the filter,
+	// or an error wrapping errFilterFailed if the filter can't be evaluated for it.
+	match(attrs map[string]string) (bool, error)
+}
+
+// errFilterFailed is reported when a subscription's FilterExpr can't be evaluated for a message.
+var errFilterFailed = errors.New("failed to evaluate filter")
+
+type (
+	// filterOr matches messages matched by any of its terms.
+	filterOr []messageFilter
+
+	// filterAnd matches messages matched by all of its terms.
+	filterAnd []messageFilter
+
+	// filterNot matches messages not matched by its term.
+	filterNot struct{ term messageFilter }
+
+	// filterHas matches messages which have the attribute, whatever its value.
+	filterHas struct{ key string }
+
+	// filterEquals matches messages whose attribute is equal to value,
+	// or not equal to it if negated. Messages without the attribute never match.
+	filterEquals struct {
+		key, value string
+		negated    bool
+	}
+
+	// filterHasPrefix matches messages whose attribute starts with prefix.
+	filterHasPrefix struct{ key, prefix string }
+)
+
+func (f filterOr) match(attrs map[string]string) (bool, error) {
+	for _, term := range f {
+		if ok, err := term.match(attrs); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (f filterAnd) match(attrs map[string]string) (bool, error) {
+	for _, term := range f {
+		if ok, err := term.match(attrs); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (f filterNot) match(attrs map[string]string) (bool, error) {
+	ok, err := f.term.match(attrs)
+	return !ok && err == nil, err
+}
+
+func (f filterHas) match(attrs map[string]string) (bool, error) {
+	_, ok := attrs[f.key]
+	return ok, nil
+}
+
+func (f filterEquals) match(attrs map[string]string) (bool, error) {
+	value, ok, err := filterValue(attrs, f.key)
+	return ok && (value == f.value) != f.negated, err
+}
+
+func (f filterHasPrefix) match(attrs map[string]string) (bool, error) {
+	value, ok, err := filterValue(attrs, f.key)
+	return ok && strings.HasPrefix(value, f.prefix), err
+}
+
+// filterValue returns the value of the attribute key to compare, if the message has it.
+// Values are compared as strings, like GCP does, so they must be valid UTF-8.
+func filterValue(attrs map[string]string, key string) (value string, ok bool, err error) {
+	value, ok = attrs[key]
+	if ok && !utf8.ValidString(value) {
+		return "", false, fmt.Errorf("%w: attribute %s is not valid UTF-8", errFilterFailed, key)
+	}
+	return value, ok, nil
+}
+
+// parseFilter parses a filter expression in the syntax of GCP Pub/Sub
+// subscription filters. See SubscriptionConfig.FilterExpr.
+func parseFilter(expr string) (messageFilter, error) {
+	p := &filterParser{src: expr}
+	p.next()
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok != "" {
+		return nil, p.errorf("unexpected %q", p.tok)
+	}
+	return f, nil
+}
+
+// filterParser is a recursive descent parser of filter expressions.
+type filterParser struct {
+	src string
+	pos int    // the offset in src after the current token
+	off int    // the offset in src of the current token
+	tok string // the current token, or "" at the end of the expression
+	err error  // the first error encountered while scanning
+}
+
+// next advances to the next token.
+func (p *filterParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	p.off = start
+	switch {
+	case p.pos == len(p.src):
+	case strings.HasPrefix(p.src[p.pos:], "!="):
+		p.pos += 2
+	case strings.ContainsRune("()=,.:", rune(p.src[p.pos])):
+		p.pos++
+	case p.src[p.pos] == '"':
+		// Find the closing quote, skipping escaped characters
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			p.err = p.errorf("unterminated string")
+			p.tok = ""
+			return
+		}
+		p.pos++
+	default:
+		for p.pos < len(p.src) && isFilterIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			p.err = p.errorf("unexpected character %q", p.src[p.pos])
+			p.tok = ""
+			return
+		}
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func isFilterIdentChar(c byte) bool {
+	return c == '_' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// errorf returns an error at the current token, unless scanning has already failed.
+func (p *filterParser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("invalid filter at offset %d: %s", p.off, fmt.Sprintf(format, args...))
+}
+
+// expect consumes the token tok, reporting an error if the current token is different.
+func (p *filterParser) expect(tok string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.tok != tok {
+		if p.tok == "" {
+			return p.errorf("expected %q, got end of filter", tok)
+		}
+		return p.errorf("expected %q, got %q", tok, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *filterParser) parseOr() (messageFilter, error) {
+	return p.parseList("OR", p.parseAnd, func(terms []messageFilter) messageFilter { return filterOr(terms) })
+}
+
+func (p *filterParser) parseAnd() (messageFilter, error) {
+	return p.parseList("AND", p.parseNot, func(terms []messageFilter) messageFilter { return filterAnd(terms) })
+}
+
+// parseList parses terms separated by the operator op.
+func (p *filterParser) parseList(op string, parseTerm func() (messageFilter, error), combine func([]messageFilter) messageFilter) (messageFilter, error) {
+	var terms []messageFilter
+	for {
+		term, err := parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		if p.tok != op {
+			break
+		}
+		p.next()
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return combine(terms), nil
+}
+
+func (p *filterParser) parseNot() (messageFilter, error) {
+	if p.tok == "NOT" {
+		p.next()
+		term, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{term}, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *filterParser) parseTerm() (messageFilter, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	switch p.tok {
+	case "(":
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+
+	case "hasPrefix":
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		key, err := p.parseAttribute(".")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		prefix, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return filterHasPrefix{key: key, prefix: prefix}, p.expect(")")
+
+	case "attributes":
+		p.next()
+		switch p.tok {
+		case ":":
+			p.next()
+			key, err := p.parseKey()
+			return filterHas{key: key}, err
+		case ".":
+			p.next()
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			op := p.tok
+			if op != "=" && op != "!=" {
+				return nil, p.errorf("expected = or != after attribute %s", key)
+			}
+			p.next()
+			value, err := p.parseString()
+			return filterEquals{key: key, value: value, negated: op == "!="}, err
+		}
+		return nil, p.errorf("expected . or : after attributes")
+
+	case "":
+		return nil, p.errorf("unexpected end of filter")
+	}
+	return nil, p.errorf("unexpected %q", p.tok)
+}
+
+// parseAttribute parses a reference to an attribute, "attributes" followed by sep and the key.
+func (p *filterParser) parseAttribute(sep string) (string, error) {
+	if err := p.expect("attributes"); err != nil {
+		return "", err
+	}
+	if err := p.expect(sep); err != nil {
+		return "", err
+	}
+	return p.parseKey()
+}
+
+// parseKey parses the key of an attribute, which is an identifier or a string.
+func (p *filterParser) parseKey() (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if strings.HasPrefix(p.tok, `"`) {
+		return p.parseString()
+	}
+	if p.tok == "" || !isFilterIdentChar(p.tok[0]) {
+		return "", p.errorf("expected attribute key")
+	}
+	key := p.tok
+	p.next()
+	return key, nil
+}
+
+// parseString parses a double-quoted string.
+func (p *filterParser) parseString() (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if !strings.HasPrefix(p.tok, `"`) {
+		return "", p.errorf("expected string")
+	}
+	s, err := strconv.Unquote(p.tok)
+	if err != nil {
+		return "", p.errorf("invalid string %s", p.tok)
+	}
+	p.next()
+	return s, nil
+}
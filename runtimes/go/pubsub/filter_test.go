@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseFilter(t *testing.T) {
+	attrs := map[string]string{"region": "eu-west", "tier": "gold", "event type": "created", "empty": ""}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`attributes:region`, true},
+		{`attributes:missing`, false},
+		{`attributes:empty`, true},
+		{`attributes.tier = "gold"`, true},
+		{`attributes.tier = "silver"`, false},
+		{`attributes.tier != "silver"`, true},
+		{`attributes.missing != "silver"`, false},
+		{`attributes."event type" = "created"`, true},
+		{`hasPrefix(attributes.region, "eu-")`, true},
+		{`hasPrefix(attributes.region, "us-")`, false},
+		{`NOT attributes:missing`, true},
+		{`attributes.tier = "gold" AND attributes:missing`, false},
+		{`attributes.tier = "gold" OR attributes:missing`, true},
+		{`attributes:missing OR (attributes.tier = "gold" AND NOT hasPrefix(attributes.region, "us-"))`, true},
+		{`attributes.region = "eu-\"west\""`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c := qt.New(t)
+			f, err := parseFilter(tt.expr)
+			c.Assert(err, qt.IsNil)
+			got, err := f.match(attrs)
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`attributes`, `invalid filter at offset 10: expected . or : after attributes`},
+		{`attributes.tier`, `invalid filter at offset 15: expected = or != after attribute tier`},
+		{`attributes.tier = gold`, `invalid filter at offset 18: expected string`},
+		{`attributes.tier = "gold`, `invalid filter at offset 18: unterminated string`},
+		{`attributes.tier = "gold" attributes:region`, `invalid filter at offset 25: unexpected "attributes"`},
+		{`(attributes:region`, `invalid filter at offset 18: expected "\)", got end of filter`},
+		{`hasPrefix(attributes:region, "eu")`, `invalid filter at offset 20: expected "\.", got ":"`},
+		{`attributes:region AND`, `invalid filter at offset 21: unexpected end of filter`},
+		{`attributes.tier > "a"`, `invalid filter at offset 16: unexpected character '>'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := parseFilter(tt.expr)
+			c.Assert(err, qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
+
+func TestSubscription_FilterExpr(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var handled []string
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			handled = append(handled, msg.Value)
+			return nil
+		},
+		FilterExpr: `attributes.region = "eu"`,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, map[string]string{"region": "eu"}, []byte(`{"Value":"eu"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, map[string]string{"region": "us"}, []byte(`{"Value":"us"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":"none"}`)), qt.IsNil)
+	c.Assert(handled, qt.DeepEquals, []string{"eu"})
+	c.Assert(sub.Stats().Filtered, qt.Equals, uint64(2))
+
+	// Messages the filter can't be evaluated for are quarantined rather than filtered out
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, map[string]string{"region": "\xff"}, []byte(`{"Value":"bad"}`)), qt.IsNil)
+	c.Assert(handled, qt.DeepEquals, []string{"eu"})
+	c.Assert(sub.Stats().Filtered, qt.Equals, uint64(2))
+	c.Assert(mgr.DroppedMessages().ByReason[DropFilterError], qt.Equals, uint64(1))
+
+	// Invalid expressions are rejected when the subscription is created
+	c.Assert(func() {
+		NewSubscription(topic, "other", SubscriptionConfig[*testEvent]{
+			Handler:    func(ctx context.Context, msg *testEvent) error { return nil },
+			FilterExpr: `attributes.region == "eu"`,
+		})
+	}, qt.PanicMatches, `invalid FilterExpr: invalid filter at offset 19: .*`)
+}
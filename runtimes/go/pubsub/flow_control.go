@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+)
+
+// FlowControl limits how much unacknowledged work a subscription
+// pulls from the messaging service at once.
+type FlowControl = types.FlowControl
+
+// SetFlowControl adjusts the flow control settings of a running subscription
+// on this instance of the service, without redeploying.
+//
+// Zero values leave the corresponding setting unchanged.
+// The new settings are not persisted, and are lost when the instance restarts.
+//
+// It is currently only supported for GCP Pub/Sub pull subscriptions;
+// other providers report an Unimplemented error.
+func (mgr *Manager) SetFlowControl(topic, subscription string, fc FlowControl) error {
+	if fc.MaxOutstandingMessages < -1 {
+		return errs.B().Code(errs.InvalidArgument).Msg("MaxOutstandingMessages must be positive, or -1 for no limit").Err()
+	}
+	if fc.MaxOutstandingBytes < -1 {
+		return errs.B().Code(errs.InvalidArgument).Msg("MaxOutstandingBytes must be positive, or -1 for no limit").Err()
+	}
+
+	impl, ok := mgr.lookupSubscription(topic, subscription)
+	if !ok {
+		return errs.B().Code(errs.NotFound).Msgf("subscription %s on topic %s is not running on this instance", subscription, topic).Err()
+	}
+
+	fcImpl, ok := impl.(types.FlowController)
+	if !ok {
+		return errs.B().Code(errs.Unimplemented).Msgf("the pubsub provider of topic %s does not support adjusting flow control", topic).Err()
+	}
+
+	return fcImpl.SetFlowControl(subscription, fc)
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"encore.dev/beta/errs"
+)
+
+// ReplaceHandler replaces the subscription's Handler with handler for the messages
+// this instance of the service processes from now on, without stopping the subscription.
+// Messages already being processed finish with the previous Handler, and ReplaceHandler
+// waits for them: once it returns successfully the previous Handler is no longer called.
+// If ctx is done before they finish, ReplaceHandler returns ctx's error, but handler
+// has still replaced the previous Handler.
+//
+// handler processes messages of the same type T as the previous Handler, so it must be
+// able to process any message published to the topic. It must be a function, rather
+// than a MethodHandler, and the subscription's other configuration is unchanged.
+//
+// Messages routed through a DrainTo call are not passed to either Handler.
+func (s *Subscription[T]) ReplaceHandler(ctx context.Context, handler func(ctx context.Context, msg T) error) error {
+	if handler == nil {
+		return errs.B().Code(errs.InvalidArgument).Msg("handler cannot be nil").Err()
+	}
+	prev := s.handler.Load()
+	if _, ok := s.mgr.lookupSubscription(s.topic.runtimeCfg.EncoreName, s.name); !ok || prev == nil {
+		return errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s is not running on this instance", s.name).Err()
+	}
+
+	prev = s.handler.Swap(newHandlerState(handler))
+	select {
+	case <-prev.retire():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handlerState is a subscription's Handler, along with the number
+// of messages it is processing so they can be waited for once it's replaced.
+type handlerState[T any] struct {
+	fn func(ctx context.Context, msg T) error
+
+	mu       sync.Mutex
+	inFlight int           // the number of messages being processed by fn
+	retired  bool          // whether fn has been replaced, so no more messages may be processed by it
+	done     chan struct{} // closed once fn is retired and no messages are being processed by it
+}
+
+func newHandlerState[T any](fn func(ctx context.Context, msg T) error) *handlerState[T] {
+	return &handlerState[T]{fn: fn, done: make(chan struct{})}
+}
+
+// acquire reports whether a message may be processed by the handler,
+// in which case release must be called once it has been.
+func (h *handlerState[T]) acquire() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.retired {
+		return false
+	}
+	h.inFlight++
+	return true
+}
+
+// release records a message has been processed by the handler.
+func (h *handlerState[T]) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.inFlight--
+	if h.retired && h.inFlight == 0 {
+		close(h.done)
+	}
+}
+
+// retire stops further messages from being processed by the handler, returning
+// a channel which is closed once the messages being processed by it are done.
+func (h *handlerState[T]) retire() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.retired {
+		h.retired = true
+		if h.inFlight == 0 {
+			close(h.done)
+		}
+	}
+	return h.done
+}
+
+// acquireHandler returns the subscription's current Handler for processing
+// a message, which must be released once the message has been processed.
+func (s *Subscription[T]) acquireHandler() *handlerState[T] {
+	for {
+		// The handler may be replaced between loading and acquiring it,
+		// in which case the replacement is used instead
+		if h := s.handler.Load(); h.acquire() {
+			return h
+		}
+	}
+}
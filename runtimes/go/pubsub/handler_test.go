@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/beta/errs"
+)
+
+func TestSubscription_ReplaceHandler(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			close(started)
+			<-unblock
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Start processing a message with the original handler
+	oldDone := make(chan error, 1)
+	go func() { oldDone <- ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"old"}`)) }()
+	<-started
+
+	// Replacing the handler waits for the message to finish
+	var received []string
+	original := sub.handler.Load()
+	replaced := make(chan error, 1)
+	go func() {
+		replaced <- sub.ReplaceHandler(ctx, func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg.Value)
+			return nil
+		})
+	}()
+	for sub.handler.Load() == original {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Which doesn't stop new messages from being processed by the new handler
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Value":"new"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"new"})
+	select {
+	case <-replaced:
+		c.Fatal("ReplaceHandler returned while the original handler was processing a message")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(unblock)
+	c.Assert(<-oldDone, qt.IsNil)
+	c.Assert(<-replaced, qt.IsNil)
+
+	// The current handler is used until the wait is given up on
+	err := sub.ReplaceHandler(ctx, nil)
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":"again"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"new", "again"})
+}
+
+func TestSubscription_ReplaceHandlerTimeout(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			close(started)
+			<-unblock
+			return errors.New("old handler")
+		},
+	})
+	ft := fake.topics["topic"]
+	done := make(chan error, 1)
+	go func() { done <- ft.deliver(context.Background(), "sub", "1", 1, nil, []byte(`{}`)) }()
+	<-started
+
+	// Giving up on waiting still replaces the handler
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	newHandler := func(ctx context.Context, msg *testEvent) error { return nil }
+	c.Assert(sub.ReplaceHandler(ctx, newHandler), qt.Equals, context.DeadlineExceeded)
+	c.Assert(ft.deliver(context.Background(), "sub", "2", 1, nil, []byte(`{}`)), qt.IsNil)
+
+	close(unblock)
+	c.Assert(<-done, qt.ErrorMatches, ".*old handler")
+}
@@ -0,0 +1,192 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// IdempotencyConfig configures how a subscription records the results of
+// processing messages, so that redeliveries of a message are answered with
+// the recorded result instead of calling the Handler again.
+//
+// When a Handler processes a message successfully, Encore records the result
+// set with SetIdempotencyResult, along with every message the Handler published
+// while processing it (such as a reply). When the same message is delivered again
+// within the TTL, the Handler is not called. Instead the recorded messages are
+// published again, byte for byte, and OnReplay is called with the recorded result.
+//
+// Unlike DedupByMessageID, which only skips duplicates, this keeps the downstream
+// effects of processing a message consistent across redeliveries, for example
+// when the publisher of a request is waiting for its reply.
+//
+// Failed attempts are not recorded, so messages are retried as usual until
+// they are processed successfully.
+type IdempotencyConfig struct {
+	// TTL is how long the result of processing a message is kept for.
+	// Deliveries of the same message after the TTL call the Handler again.
+	//
+	// Defaults to 24 hours.
+	TTL time.Duration
+
+	// KeyAttribute is the name of the message attribute holding the
+	// idempotency key of the message. Messages published more than once
+	// with the same key are considered the same message.
+	//
+	// If empty, or if a message does not have the attribute,
+	// the message ID assigned by the messaging service is used,
+	// which only covers redeliveries made by the messaging service.
+	KeyAttribute string
+
+	// MaxSize is the maximum number of results the default in-memory
+	// store keeps. Once reached the least recently used results are
+	// forgotten first.
+	//
+	// It has no effect if Store is set. Defaults to 10,000.
+	MaxSize int
+
+	// Store is where the results of processing messages are recorded.
+	//
+	// If nil, a bounded in-memory store is used, which only covers
+	// redeliveries to the same instance of the service. Provide a persistent
+	// store to cover redeliveries across instances and restarts.
+	Store IdempotencyStore
+
+	// OnReplay, if set, is called with the recorded result when a message
+	// is answered from the store instead of being processed by the Handler.
+	// It is called after the recorded messages have been published again.
+	OnReplay func(ctx context.Context, key IdempotencyKey, result []byte)
+}
+
+// IdempotencyKey identifies a message processed by a subscription.
+type IdempotencyKey struct {
+	Topic        string // the topic name
+	Subscription string // the subscription name
+	Key          string // the idempotency key of the message, or its message ID
+}
+
+// IdempotencyRecord is the recorded result of processing a message successfully.
+type IdempotencyRecord struct {
+	// Result is the result set by the Handler using SetIdempotencyResult, if any.
+	Result []byte
+
+	// Published are the messages the Handler published while processing
+	// the message, in the order they were published.
+	Published []IdempotentMessage
+}
+
+// IdempotentMessage is a message published by a Handler while processing
+// a message, as it was sent to the messaging service.
+type IdempotentMessage struct {
+	Topic       string            // the name of the topic the message was published to
+	OrderingKey string            // the ordering key the message was published with, if any
+	Attributes  map[string]string // the message attributes, including those set by Encore
+	Data        []byte            // the message data, as sent to the messaging service
+}
+
+// IdempotencyStore records the results of processing messages.
+//
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Get returns the record of the message identified by key.
+	// It returns nil and no error if there is no record, or if it has expired.
+	Get(ctx context.Context, key IdempotencyKey) (*IdempotencyRecord, error)
+
+	// Put records the result of processing the message identified by key.
+	// The record should be kept for at least ttl.
+	Put(ctx context.Context, key IdempotencyKey, rec *IdempotencyRecord, ttl time.Duration) error
+}
+
+// SetIdempotencyResult sets the result of processing the message currently
+// being handled, to be recorded if the Handler returns successfully.
+// Calling it again replaces the result set previously.
+//
+// It has no effect if ctx does not belong to a subscription handler, or if
+// the subscription does not have an IdempotencyConfig.
+func SetIdempotencyResult(ctx context.Context, result []byte) {
+	mc, ok := messageContextFrom(ctx)
+	if !ok || mc.idempotency == nil {
+		return
+	}
+	mc.idempotency.setResult(result)
+}
+
+// idempotencyRecorder collects the record of the message being processed.
+type idempotencyRecorder struct {
+	mu  sync.Mutex
+	rec IdempotencyRecord
+}
+
+func (r *idempotencyRecorder) setResult(result []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Result = bytes.Clone(result)
+}
+
+func (r *idempotencyRecorder) published(msg IdempotentMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Published = append(r.rec.Published, msg)
+}
+
+func (r *idempotencyRecorder) record() *IdempotencyRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.rec
+	return &rec
+}
+
+// recordPublished records a message published by the handler processing
+// the message of ctx, if its subscription records idempotency results.
+func recordPublished(ctx context.Context, topic, orderingKey string, attrs map[string]string, data []byte) {
+	mc, ok := messageContextFrom(ctx)
+	if !ok || mc.idempotency == nil {
+		return
+	}
+	mc.idempotency.published(IdempotentMessage{
+		Topic:       topic,
+		OrderingKey: orderingKey,
+		Attributes:  maps.Clone(attrs),
+		Data:        bytes.Clone(data),
+	})
+}
+
+// replayIdempotent publishes the messages of rec again, as they were first published.
+func (mgr *Manager) replayIdempotent(ctx context.Context, rec *IdempotencyRecord) error {
+	for _, msg := range rec.Published {
+		mgr.subsMu.Lock()
+		impl, ok := mgr.topics[msg.Topic]
+		mgr.subsMu.Unlock()
+		if !ok {
+			return fmt.Errorf("topic %s is not configured on this instance", msg.Topic)
+		}
+		if _, err := impl.PublishMessage(ctx, msg.OrderingKey, msg.Attributes, msg.Data); err != nil {
+			return fmt.Errorf("publish message to %s: %w", msg.Topic, err)
+		}
+	}
+	return nil
+}
+
+// memoryIdempotencyStore is the default IdempotencyStore, backed by a bounded LRU.
+type memoryIdempotencyStore struct {
+	records *utils.LRU[IdempotencyKey, *IdempotencyRecord]
+}
+
+func newMemoryIdempotencyStore(maxSize int, ttl time.Duration) *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: utils.NewLRU[IdempotencyKey, *IdempotencyRecord](maxSize, ttl)}
+}
+
+func (s *memoryIdempotencyStore) Get(_ context.Context, key IdempotencyKey) (*IdempotencyRecord, error) {
+	rec, _ := s.records.Get(key)
+	return rec, nil
+}
+
+func (s *memoryIdempotencyStore) Put(_ context.Context, key IdempotencyKey, rec *IdempotencyRecord, _ time.Duration) error {
+	s.records.Set(key, rec)
+	return nil
+}
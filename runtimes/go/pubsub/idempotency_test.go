@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+func TestSubscription_Idempotency(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	mgr.runtime.PubsubTopics["replies"] = &config.PubsubTopic{EncoreName: "replies"}
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	replies := newTopic[*testEvent](mgr, "replies", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var calls int
+	fail := true
+	var replayed []string
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls++
+			if _, err := replies.Publish(ctx, &testEvent{Value: "reply to " + msg.Value}); err != nil {
+				return err
+			}
+			SetIdempotencyResult(ctx, []byte("done "+msg.Value))
+			if fail {
+				fail = false
+				return errors.New("boom")
+			}
+			return nil
+		},
+		Idempotency: &IdempotencyConfig{
+			KeyAttribute: "key",
+			OnReplay: func(ctx context.Context, key IdempotencyKey, result []byte) {
+				c.Check(key, qt.Equals, IdempotencyKey{Topic: "topic", Subscription: "sub", Key: "a"})
+				replayed = append(replayed, string(result))
+			},
+		},
+	})
+	ctx := context.Background()
+	ft, rt := fake.topics["topic"], fake.topics["replies"]
+	attrs := map[string]string{"key": "a"}
+
+	// Failed attempts are not recorded, so the message is processed again
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, attrs, []byte(`{"Value":"hello"}`)), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, attrs, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(calls, qt.Equals, 2)
+	c.Assert(rt.published, qt.Equals, 2)
+	reply := rt.lastData
+
+	// Redeliveries, and messages published again with the same key, are answered
+	// from the store: the reply is published again without calling the handler
+	rt.lastData = nil
+	c.Assert(ft.deliver(ctx, "sub", "1", 3, attrs, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, attrs, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(calls, qt.Equals, 2)
+	c.Assert(rt.published, qt.Equals, 4)
+	c.Assert(rt.lastData, qt.DeepEquals, reply)
+	c.Assert(replayed, qt.DeepEquals, []string{"done hello", "done hello"})
+
+	// Messages without the key attribute are keyed by their message ID
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"other"}`)), qt.IsNil)
+	c.Assert(calls, qt.Equals, 3)
+
+	// If the reply cannot be published again, the message is retried
+	rt.publishErr = errors.New("unavailable")
+	c.Assert(ft.deliver(ctx, "sub", "1", 4, attrs, []byte(`{"Value":"hello"}`)), qt.IsNotNil)
+	c.Assert(calls, qt.Equals, 3)
+}
+
+func TestSubscription_IdempotencyConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler:     func(ctx context.Context, msg *testEvent) error { return nil },
+			Idempotency: &IdempotencyConfig{TTL: -1},
+		})
+	}, qt.PanicMatches, "Idempotency.TTL cannot be negative")
+}
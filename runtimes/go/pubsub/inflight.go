@@ -0,0 +1,144 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/beta/errs"
+)
+
+// InFlightInfo describes a message whose handler is currently running.
+type InFlightInfo struct {
+	Topic        string    // the topic name
+	Subscription string    // the subscription name
+	MessageID    string    // the message ID assigned by the messaging service
+	Attempt      int       // the delivery attempt, starting at 1
+	Start        time.Time // when the handler started processing the message
+
+	goroutine uint64 // the ID of the goroutine running the handler, or 0 if unknown
+}
+
+// Age reports how long the handler has been processing the message.
+func (i InFlightInfo) Age() time.Duration {
+	return time.Since(i.Start)
+}
+
+// InFlight reports the messages whose handlers are currently running
+// on this instance of the service, oldest first.
+//
+// It is cheap enough to call periodically, for example from a watchdog
+// which alerts when a handler has been running for longer than expected.
+func (mgr *Manager) InFlight() []InFlightInfo {
+	var infos []InFlightInfo
+	mgr.inFlight.Range(func(key, _ any) bool {
+		infos = append(infos, *key.(*InFlightInfo))
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Start.Before(infos[j].Start)
+	})
+	return infos
+}
+
+// trackInFlight records that a handler has started processing a message,
+// returning a function to call once it has finished.
+func (mgr *Manager) trackInFlight(info *InFlightInfo) (done func()) {
+	mgr.inFlight.Store(info, struct{}{})
+	return func() { mgr.inFlight.Delete(info) }
+}
+
+// logBlockedHandlers logs the stack of the goroutine running each handler
+// which is still in flight, so handlers which would block shutdown, for
+// example by waiting on a channel nothing reads from any more, can be diagnosed.
+func (mgr *Manager) logBlockedHandlers(log *zerolog.Logger) {
+	infos := mgr.InFlight()
+	if len(infos) == 0 {
+		return
+	}
+
+	stacks := goroutineStacks()
+	for _, info := range infos {
+		ev := log.Error().Str("topic", info.Topic).Str("subscription", info.Subscription).
+			Str("msg_id", info.MessageID).Int("delivery_attempt", info.Attempt).Dur("age", info.Age())
+		if stack, ok := stacks[info.goroutine]; ok {
+			ev = ev.Str("stack", stack)
+		}
+		ev.Msg("pubsub: handler still running at shutdown, cancelling it")
+	}
+}
+
+// maxStackDumpSize bounds the size of the buffer used to dump goroutine stacks.
+const maxStackDumpSize = 64 << 20
+
+// goroutineStacks returns the stacks of all goroutines, keyed by goroutine ID.
+func goroutineStacks() map[uint64]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDumpSize {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[uint64]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if id, ok := parseGoroutineID([]byte(stack)); ok {
+			stacks[id] = stack
+		}
+	}
+	return stacks
+}
+
+// currentGoroutineID returns the ID of the calling goroutine, or 0 if it can't be determined.
+func currentGoroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	id, _ := parseGoroutineID(buf[:n])
+	return id
+}
+
+// parseGoroutineID parses the ID of a goroutine from its stack,
+// which starts with a line like "goroutine 42 [running]:".
+func parseGoroutineID(stack []byte) (uint64, bool) {
+	rest, ok := bytes.CutPrefix(stack, []byte("goroutine "))
+	if !ok {
+		return 0, false
+	}
+	idStr, _, ok := bytes.Cut(rest, []byte(" "))
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(string(idStr), 10, 64)
+	return id, err == nil
+}
+
+// runWithDeadline calls run in a new goroutine, waiting for it to return until deadline.
+// If the deadline passes first, the context passed to run is cancelled and
+// runWithDeadline returns without waiting for run, reporting abandoned.
+func runWithDeadline(ctx context.Context, deadline time.Time, run func(ctx context.Context) error) (err error, abandoned bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		done <- run(ctx)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err, false
+	case <-timer.C:
+		cancel()
+		return errs.B().Code(errs.DeadlineExceeded).Msg("handler exceeded the subscription's MaxInFlight").Err(), true
+	}
+}
@@ -86,5 +86,12 @@ func (mgr *Manager) NewTopic(_ *config.PubsubProvider, staticCfg types.TopicConf
 		panic(fmt.Sprintf("unable to verify SNS topic attributes (may be missing IAM role allowing access): %v", err))
 	}
 
-	return &topic{mgr.ctxs, mgr.publisherID, snsClient, sqsClient, staticCfg, runtimeCfg}
+	return &topic{
+		ctxs:        mgr.ctxs,
+		publisherID: mgr.publisherID,
+		snsClient:   snsClient,
+		sqsClient:   sqsClient,
+		staticCfg:   staticCfg,
+		runtimeCfg:  runtimeCfg,
+	}
 }
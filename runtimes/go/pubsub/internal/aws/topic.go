@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -29,6 +30,7 @@ type topic struct {
 	sqsClient   *sqs.Client
 	staticCfg   types.TopicConfig
 	runtimeCfg  *config.PubsubTopic
+	asyncAcks   sync.Map // subscription Encore name -> func(msgID string, ack func() error), for those with AsyncAck
 }
 
 var _ types.TopicImplementation = (*topic)(nil)
@@ -141,6 +143,11 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 					// Call the callback, and if there was no error, then we can delete the message
 					msgCtx, cancel := context.WithTimeout(ctx, ackDeadline)
 					defer cancel()
+
+					// The message stays invisible to other consumers for the visibility timeout
+					// given when it was received, which is never extended
+					leaseDeadline := time.Now().Add(ackDeadline)
+					msgCtx = types.WithLease(msgCtx, func() time.Time { return leaseDeadline })
 					err = f(msgCtx, msgWrapper.MessageId, msgWrapper.Timestamp, int(deliveryAttempt), attributes, []byte(msgWrapper.Message))
 					cancel()
 
@@ -159,8 +166,10 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 					if err != nil {
 						logger.Err(err).Str("msg_id", msgWrapper.MessageId).Msg("unable to process message")
 
-						// If there was an error processing the message, apply the backoff policy
+						// If there was an error processing the message, apply the backoff policy,
+						// unless the handler requested a specific delay
 						_, delay := utils.GetDelay(retryPolicy.MaxRetries, retryPolicy.MinBackoff, retryPolicy.MaxBackoff, uint16(deliveryAttempt))
+						delay = utils.RetryDelay(err, delay, retryPolicy.MaxBackoff)
 						_, visibilityChangeErr := t.sqsClient.ChangeMessageVisibility(t.ctxs.Connection, &sqs.ChangeMessageVisibilityInput{
 							QueueUrl:          aws.String(implCfg.ProviderName),
 							ReceiptHandle:     msg.ReceiptHandle,
@@ -171,11 +180,16 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 						}
 					} else {
 						// If the message was processed successfully, delete it from the queue
-						_, err = t.sqsClient.DeleteMessage(t.ctxs.Connection, &sqs.DeleteMessageInput{
-							QueueUrl:      aws.String(implCfg.ProviderName),
-							ReceiptHandle: msg.ReceiptHandle,
-						})
-						if err != nil {
+						deleteMessage := func() error {
+							_, err := t.sqsClient.DeleteMessage(t.ctxs.Connection, &sqs.DeleteMessageInput{
+								QueueUrl:      aws.String(implCfg.ProviderName),
+								ReceiptHandle: msg.ReceiptHandle,
+							})
+							return err
+						}
+						if asyncAck, ok := t.asyncAcks.Load(implCfg.EncoreName); ok {
+							asyncAck.(func(string, func() error))(msgWrapper.MessageId, deleteMessage)
+						} else if err := deleteMessage(); err != nil {
 							logger.Err(err).Str("msg_id", msgWrapper.MessageId).Msg("unable to delete message from SQS queue")
 						}
 					}
@@ -217,3 +231,42 @@ type SNSMessageWrapper struct {
 		Value string `json:"Value"`
 	} `json:"MessageAttributes"`
 }
+
+var _ types.InitialPositioner = (*topic)(nil)
+
+// NativeInitialPosition reports that SQS queues only receive messages
+// published to the SNS topic after the queue was subscribed to it.
+func (t *topic) NativeInitialPosition() types.InitialPosition {
+	return types.InitialPositionLatest
+}
+
+// SupportsInitialPosition reports that SNS does not retain messages,
+// so new subscriptions can only start from the latest message.
+func (t *topic) SupportsInitialPosition(pos types.InitialPosition) bool {
+	return pos == types.InitialPositionLatest
+}
+
+var _ types.DurableConfirmer = (*topic)(nil)
+
+// ConfirmsDurably reports that SNS only responds to a publish request
+// once the message has been stored across multiple availability zones.
+func (t *topic) ConfirmsDurably() bool {
+	return true
+}
+
+var _ types.AsyncAcker = (*topic)(nil)
+
+// SetAsyncAck makes the subscription delete successfully processed messages
+// from its SQS queue in the background.
+func (t *topic) SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool {
+	t.asyncAcks.Store(subscription, ack)
+	return true
+}
+
+var _ types.AttributeLimiter = (*topic)(nil)
+
+// MaxAttributeBytes reports that SNS limits messages, including their
+// attributes, to 256KB.
+func (t *topic) MaxAttributeBytes() int {
+	return 256 << 10
+}
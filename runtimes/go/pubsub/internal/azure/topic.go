@@ -43,6 +43,7 @@ type topic struct {
 	topicCfg   *config.PubsubTopic
 	senderOnce sync.Once
 	_sender    *azservicebus.Sender
+	asyncAcks  sync.Map // subscription Encore name -> func(msgID string, ack func() error), for those with AsyncAck
 }
 
 var _ types.TopicImplementation = (*topic)(nil)
@@ -116,6 +117,16 @@ func (t *topic) processMessage(
 	ctx, cancel := context.WithTimeout(ctx, ackDeadline)
 	defer cancel()
 
+	// The message is locked for this receiver until the lock expires,
+	// unless it is processed for longer than the ackDeadline
+	if msg.LockedUntil != nil {
+		leaseDeadline, _ := ctx.Deadline()
+		if msg.LockedUntil.Before(leaseDeadline) {
+			leaseDeadline = *msg.LockedUntil
+		}
+		ctx = types.WithLease(ctx, func() time.Time { return leaseDeadline })
+	}
+
 	attrs := make(map[string]string, len(msg.ApplicationProperties))
 	for k, v := range msg.ApplicationProperties {
 		attrs[k] = fmt.Sprintf("%v", v)
@@ -123,10 +134,18 @@ func (t *topic) processMessage(
 	retryCount, _ := strconv.ParseInt(fmt.Sprintf("%v", msg.ApplicationProperties[RetryCountAttribute]), 10, 64)
 	deliveryAttempt := retryCount + 1
 	err = f(ctx, msg.MessageID, *msg.EnqueuedTime, int(deliveryAttempt), attrs, msg.Body)
+	if asyncAck, ok := t.asyncAcks.Load(subCfg.EncoreName); ok && err == nil {
+		// Complete the processed message in the background
+		asyncAck.(func(string, func() error))(msg.MessageID, func() error {
+			return receiver.CompleteMessage(t.mgr.ctxs.Connection, msg, nil)
+		})
+		return nil
+	}
 	if err != nil {
 		logger.Warn().Err(err).Msg("failed to process messsage")
 		shouldRetry, backoff := utils.GetDelay(
 			rp.MaxRetries, rp.MinBackoff, rp.MaxBackoff, uint16(deliveryAttempt))
+		backoff = utils.RetryDelay(err, backoff, rp.MaxBackoff)
 		if !shouldRetry {
 			logger.Warn().Msg("deadlettering msg")
 			err = receiver.DeadLetterMessage(t.mgr.ctxs.Connection, msg, &azservicebus.DeadLetterOptions{
@@ -188,3 +207,42 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 	}()
 
 }
+
+var _ types.InitialPositioner = (*topic)(nil)
+
+// NativeInitialPosition reports that Service Bus subscriptions only receive
+// messages sent to the topic after the subscription was created.
+func (t *topic) NativeInitialPosition() types.InitialPosition {
+	return types.InitialPositionLatest
+}
+
+// SupportsInitialPosition reports that Service Bus topics do not retain
+// messages, so new subscriptions can only start from the latest message.
+func (t *topic) SupportsInitialPosition(pos types.InitialPosition) bool {
+	return pos == types.InitialPositionLatest
+}
+
+var _ types.DurableConfirmer = (*topic)(nil)
+
+// ConfirmsDurably reports that Service Bus only completes a send
+// once the message has been committed to the topic.
+func (t *topic) ConfirmsDurably() bool {
+	return true
+}
+
+var _ types.AsyncAcker = (*topic)(nil)
+
+// SetAsyncAck makes the subscription complete successfully processed
+// messages in the background.
+func (t *topic) SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool {
+	t.asyncAcks.Store(subscription, ack)
+	return true
+}
+
+var _ types.AttributeLimiter = (*topic)(nil)
+
+// MaxAttributeBytes reports that Service Bus limits the headers of a message,
+// which include its application properties, to 64KB.
+func (t *topic) MaxAttributeBytes() int {
+	return 64 << 10
+}
@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+var _ types.AsyncAcker = (*topic)(nil)
+
+// SetAsyncAck makes a pull subscription complete the acknowledgements
+// of successfully processed messages in the background once it subscribes.
+// Push-only subscriptions acknowledge messages by responding to the push
+// request, so they don't support it.
+func (t *topic) SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool {
+	if subCfg, ok := t.topicCfg.Subscriptions[subscription]; !ok || subCfg.PushOnly {
+		return false
+	}
+
+	t.receiversMu.Lock()
+	defer t.receiversMu.Unlock()
+	t.asyncAcks[subscription] = ack
+	return true
+}
+
+// asyncAckFunc returns the function completing acknowledgements
+// in the background, or nil if they are waited for.
+func (r *receiver) asyncAckFunc() func(msgID string, ack func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.asyncAck
+}
+
+// ackResultError reports an error if an acknowledgement did not succeed.
+func ackResultError(status pubsub.AcknowledgeStatus, err error) error {
+	if err != nil {
+		return err
+	}
+	if status != pubsub.AcknowledgeStatusSuccess {
+		return fmt.Errorf("acknowledgement failed with status %d", status)
+	}
+	return nil
+}
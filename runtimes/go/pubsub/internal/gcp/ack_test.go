@@ -0,0 +1,42 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/types"
+)
+
+func TestSetAsyncAck(t *testing.T) {
+	c := qt.New(t)
+	mgr, _, client := newTestManager(t)
+	_, err := client.CreateTopic(context.Background(), "topic")
+	c.Assert(err, qt.IsNil)
+
+	gcpCfg := &config.PubsubSubscriptionGCPData{ProjectID: testProject}
+	sub := &config.PubsubSubscription{EncoreName: "sub", ProviderName: "sub", GCP: gcpCfg}
+	push := &config.PubsubSubscription{EncoreName: "push", ProviderName: "push", ID: "push", PushOnly: true, GCP: gcpCfg}
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:    "topic",
+		ProviderName:  "topic",
+		GCP:           &config.PubsubTopicGCPData{ProjectID: testProject},
+		Subscriptions: map[string]*config.PubsubSubscription{"sub": sub, "push": push},
+	})
+	aa := impl.(types.AsyncAcker)
+	ack := func(msgID string, ack func() error) {}
+
+	// Push-only and unknown subscriptions can't acknowledge in the background
+	c.Assert(aa.SetAsyncAck("push", ack), qt.IsFalse)
+	c.Assert(aa.SetAsyncAck("unknown", ack), qt.IsFalse)
+
+	// Pull subscriptions apply it once they subscribe
+	c.Assert(aa.SetAsyncAck("sub", ack), qt.IsTrue)
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, 10*time.Second, &types.RetryPolicy{}, sub, func(context.Context, string, time.Time, int, map[string]string, []byte) error { return nil })
+	c.Assert(impl.(*topic).receivers["sub"].asyncAckFunc(), qt.IsNotNil)
+}
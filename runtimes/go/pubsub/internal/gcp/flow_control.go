@@ -0,0 +1,111 @@
+package gcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+)
+
+var _ types.FlowController = (*topic)(nil)
+
+// receiver tracks the flow control settings of a pull subscription.
+//
+// The GCP library reads the receive settings when Receive is called,
+// so changing them means restarting the active Receive call.
+type receiver struct {
+	mu       sync.Mutex
+	settings types.FlowControl
+	ctx      context.Context    // the context of the active Receive call, if any
+	cancel   context.CancelFunc // cancels the active Receive call, if any
+
+	idleTimeout  time.Duration // how long to receive nothing before going idle, or 0 to never go idle
+	idle         bool          // whether the next or active Receive call only waits for the next message
+	idleStarted  bool          // whether the active Receive call is an idle one
+	inFlight     int           // the number of messages being processed
+	lastReceived time.Time     // when the last message was received, or the active Receive call started
+
+	asyncAck func(msgID string, ack func() error) // completes acknowledgements in the background, if set
+
+	options *types.GCPSubscriptionOptions // the subscription's GCP-specific options, if any
+}
+
+// start applies the current settings to the subscription and returns
+// the context to call Receive with.
+func (r *receiver) start(parent context.Context, subscription *pubsub.Subscription) (context.Context, context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription.ReceiveSettings.MaxOutstandingMessages = r.settings.MaxOutstandingMessages
+	subscription.ReceiveSettings.MaxOutstandingBytes = r.settings.MaxOutstandingBytes
+	subscription.ReceiveSettings.NumGoroutines = pubsub.DefaultReceiveSettings.NumGoroutines
+	if opts := r.options; opts != nil {
+		if opts.MaxExtension > 0 {
+			subscription.ReceiveSettings.MaxExtension = opts.MaxExtension
+		}
+		if opts.MaxExtensionPeriod > 0 {
+			subscription.ReceiveSettings.MaxExtensionPeriod = opts.MaxExtensionPeriod
+		}
+		if opts.MinExtensionPeriod > 0 {
+			subscription.ReceiveSettings.MinExtensionPeriod = opts.MinExtensionPeriod
+		}
+		if opts.NumGoroutines > 0 {
+			subscription.ReceiveSettings.NumGoroutines = opts.NumGoroutines
+		}
+	}
+	if r.idle {
+		// Hold a single stream with room for one message, just to notice when messages arrive
+		subscription.ReceiveSettings.MaxOutstandingMessages = 1
+		subscription.ReceiveSettings.NumGoroutines = 1
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	r.ctx, r.cancel = ctx, cancel
+	r.idleStarted = r.idle
+	r.lastReceived = time.Now()
+	r.watchIdleLocked()
+	return ctx, cancel
+}
+
+func (t *topic) FlowControl(subscription string) (types.FlowControl, bool) {
+	t.receiversMu.Lock()
+	r, ok := t.receivers[subscription]
+	t.receiversMu.Unlock()
+	if !ok {
+		return types.FlowControl{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.settings, true
+}
+
+func (t *topic) SetFlowControl(subscription string, fc types.FlowControl) error {
+	t.receiversMu.Lock()
+	r, ok := t.receivers[subscription]
+	t.receiversMu.Unlock()
+	if !ok {
+		return errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s does not pull messages", subscription).Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if fc.MaxOutstandingMessages != 0 {
+		r.settings.MaxOutstandingMessages = fc.MaxOutstandingMessages
+	}
+	if fc.MaxOutstandingBytes != 0 {
+		r.settings.MaxOutstandingBytes = fc.MaxOutstandingBytes
+	}
+
+	// Restart the active Receive call so the new settings are picked up.
+	// Messages already being processed are unaffected.
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package gcp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/types"
+)
+
+func TestSetFlowControl(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	mgr, srv, client := newTestManager(t)
+
+	// Create the topic and subscription on the fake server
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: gcpTopic, AckDeadline: 10 * time.Second})
+	c.Assert(err, qt.IsNil)
+
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:   "topic",
+		ProviderName: "topic",
+		GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+	})
+
+	const numMsgs = 5
+	var (
+		inFlight, maxInFlight atomic.Int32
+		wg                    sync.WaitGroup
+	)
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, 10*time.Second, &types.RetryPolicy{}, &config.PubsubSubscription{
+		EncoreName:   "sub",
+		ProviderName: "sub",
+		GCP:          &config.PubsubSubscriptionGCPData{ProjectID: testProject},
+	}, func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) error {
+		defer wg.Done()
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	publish := func() {
+		maxInFlight.Store(0)
+		wg.Add(numMsgs)
+		for i := 0; i < numMsgs; i++ {
+			srv.Publish("projects/test-project/topics/topic", []byte("hello"), nil)
+		}
+		wg.Wait()
+	}
+
+	fc := impl.(types.FlowController)
+	got, ok := fc.FlowControl("sub")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(got.MaxOutstandingMessages, qt.Equals, 10)
+
+	// Messages are processed concurrently with the initial settings
+	publish()
+	c.Assert(maxInFlight.Load() > 1, qt.IsTrue)
+
+	// Only allow one message to be processed at a time, keeping the byte limit as is
+	c.Assert(fc.SetFlowControl("sub", types.FlowControl{MaxOutstandingMessages: 1}), qt.IsNil)
+	got, _ = fc.FlowControl("sub")
+	c.Assert(got, qt.Equals, types.FlowControl{
+		MaxOutstandingMessages: 1,
+		MaxOutstandingBytes:    pubsub.DefaultReceiveSettings.MaxOutstandingBytes,
+	})
+
+	publish()
+	c.Assert(maxInFlight.Load(), qt.Equals, int32(1))
+
+	// Unknown subscriptions report an error
+	c.Assert(fc.SetFlowControl("unknown", types.FlowControl{MaxOutstandingMessages: 1}), qt.IsNotNil)
+}
+
+func TestBackendOptions(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	mgr, _, client := newTestManager(t)
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: gcpTopic, AckDeadline: 10 * time.Second})
+	c.Assert(err, qt.IsNil)
+
+	// The publish options are applied to the topic, leaving unset options at their defaults
+	impl := mgr.NewTopic(nil, types.TopicConfig{GCP: &types.GCPTopicOptions{
+		PublishDelayThreshold: 50 * time.Millisecond,
+		PublishCountThreshold: 500,
+	}}, &config.PubsubTopic{
+		EncoreName:   "topic",
+		ProviderName: "topic",
+		GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+	})
+	settings := impl.(*topic).gcpTopic.PublishSettings
+	c.Assert(settings.DelayThreshold, qt.Equals, 50*time.Millisecond)
+	c.Assert(settings.CountThreshold, qt.Equals, 500)
+	c.Assert(settings.ByteThreshold, qt.Equals, pubsub.DefaultPublishSettings.ByteThreshold)
+	c.Assert(impl.(types.AttributeLimiter).MaxAttributeBytes(), qt.Equals, 128000)
+
+	// The receive options are applied when the subscription starts receiving
+	impl.(types.SubscriptionOptioner).SetSubscriptionOptions("sub", types.SubscriptionOptions{GCP: &types.GCPSubscriptionOptions{
+		MaxExtension:  5 * time.Minute,
+		NumGoroutines: 2,
+	}})
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, 10*time.Second, &types.RetryPolicy{}, &config.PubsubSubscription{
+		EncoreName:   "sub",
+		ProviderName: "sub",
+		GCP:          &config.PubsubSubscriptionGCPData{ProjectID: testProject},
+	}, func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) error {
+		return nil
+	})
+
+	r := impl.(*topic).receivers["sub"]
+	subscription := client.Subscription("sub")
+	_, cancel := r.start(ctx, subscription)
+	defer cancel()
+	c.Assert(subscription.ReceiveSettings.MaxExtension, qt.Equals, 5*time.Minute)
+	c.Assert(subscription.ReceiveSettings.NumGoroutines, qt.Equals, 2)
+	c.Assert(subscription.ReceiveSettings.MaxOutstandingMessages, qt.Equals, 10)
+}
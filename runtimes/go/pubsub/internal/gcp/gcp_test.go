@@ -0,0 +1,35 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/utils"
+)
+
+const testProject = "test-project"
+
+// newTestManager creates a Manager talking to a fake GCP Pub/Sub server,
+// and a client to set up topics and subscriptions on it.
+func newTestManager(t *testing.T) (*Manager, *pstest.Server, *pubsub.Client) {
+	t.Helper()
+	c := qt.New(t)
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	client, err := pubsub.NewClient(context.Background(), testProject)
+	c.Assert(err, qt.IsNil)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctxs := utils.NewContexts(context.Background())
+	t.Cleanup(ctxs.CloseConnections)
+	t.Cleanup(ctxs.StopFetchingNewEvents)
+	return NewManager(ctxs, &config.Runtime{}, nil), srv, client
+}
@@ -0,0 +1,89 @@
+package gcp
+
+import (
+	"time"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+var _ types.IdleReleaser = (*topic)(nil)
+
+// received records that a message was received, returning whether it woke up
+// an idle Receive call. If so, done must be called with true once the message
+// has been acknowledged, to restart the Receive call with the full settings.
+func (r *receiver) received() (woke bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight++
+	r.lastReceived = time.Now()
+	if r.idle {
+		r.idle, r.idleStarted = false, false
+		return true
+	}
+	return false
+}
+
+// done records that a message received with received has been processed.
+func (r *receiver) done(woke bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+	r.lastReceived = time.Now()
+
+	// Restart once the message has been acknowledged, so it isn't redelivered
+	if woke && r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// watchIdleLocked arranges for the active Receive call to be restarted
+// as an idle one once nothing has been received for the idle timeout.
+// r.mu must be held.
+func (r *receiver) watchIdleLocked() {
+	if r.idle || r.idleTimeout <= 0 || r.ctx == nil {
+		return
+	}
+	ctx, wait := r.ctx, r.idleTimeout-time.Since(r.lastReceived)
+	time.AfterFunc(wait, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if ctx.Err() != nil || ctx != r.ctx {
+			// The Receive call has since stopped, or been restarted
+			return
+		}
+		if r.inFlight == 0 && time.Since(r.lastReceived) >= r.idleTimeout {
+			r.idle = true
+			r.cancel()
+			return
+		}
+		r.watchIdleLocked()
+	})
+}
+
+func (t *topic) SetIdleTimeout(subscription string, timeout time.Duration) bool {
+	t.receiversMu.Lock()
+	r, ok := t.receivers[subscription]
+	t.receiversMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.idleTimeout = timeout
+	r.watchIdleLocked()
+	return true
+}
+
+func (t *topic) Idle(subscription string) bool {
+	t.receiversMu.Lock()
+	r, ok := t.receivers[subscription]
+	t.receiversMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.idleStarted
+}
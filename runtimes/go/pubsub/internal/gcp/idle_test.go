@@ -0,0 +1,91 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/types"
+)
+
+func TestSetIdleTimeout(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	mgr, srv, client := newTestManager(t)
+
+	// Create the topic and subscription on the fake server
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: gcpTopic, AckDeadline: 10 * time.Second})
+	c.Assert(err, qt.IsNil)
+
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:   "topic",
+		ProviderName: "topic",
+		GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+	})
+
+	received := make(chan string, 10)
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, 10*time.Second, &types.RetryPolicy{}, &config.PubsubSubscription{
+		EncoreName:   "sub",
+		ProviderName: "sub",
+		GCP:          &config.PubsubSubscriptionGCPData{ProjectID: testProject},
+	}, func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) error {
+		received <- string(data)
+		return nil
+	})
+
+	ir := impl.(types.IdleReleaser)
+	c.Assert(ir.SetIdleTimeout("sub", 50*time.Millisecond), qt.IsTrue)
+	c.Assert(ir.SetIdleTimeout("unknown", 50*time.Millisecond), qt.IsFalse)
+
+	waitIdle := func(want bool) {
+		c.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for ir.Idle("sub") != want {
+			if time.Now().After(deadline) {
+				c.Fatalf("subscription did not become idle=%v", want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	receive := func() string {
+		c.Helper()
+		select {
+		case data := <-received:
+			return data
+		case <-time.After(5 * time.Second):
+			c.Fatal("message not received")
+			return ""
+		}
+	}
+
+	// The subscription goes idle once nothing is received for the timeout
+	srv.Publish("projects/test-project/topics/topic", []byte("first"), nil)
+	c.Assert(receive(), qt.Equals, "first")
+	waitIdle(true)
+
+	// And wakes up when the next message arrives, without losing it
+	srv.Publish("projects/test-project/topics/topic", []byte("second"), nil)
+	c.Assert(receive(), qt.Equals, "second")
+	c.Assert(ir.Idle("sub"), qt.IsFalse)
+	waitIdle(true)
+
+	// Each message was processed and acknowledged once
+	select {
+	case data := <-received:
+		c.Fatalf("message %q redelivered", data)
+	default:
+	}
+	for _, msg := range srv.Messages() {
+		c.Assert(msg.Deliveries, qt.Equals, 1)
+		c.Assert(msg.Acks, qt.Equals, 1)
+	}
+}
@@ -0,0 +1,72 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/types"
+)
+
+func TestLease(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	mgr, srv, client := newTestManager(t)
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: gcpTopic, AckDeadline: 10 * time.Second})
+	c.Assert(err, qt.IsNil)
+
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:   "topic",
+		ProviderName: "topic",
+		GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+	})
+
+	type lease struct {
+		deadline time.Time
+		ok       bool
+	}
+	leases := make(chan lease, 1)
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, time.Minute, &types.RetryPolicy{}, &config.PubsubSubscription{
+		EncoreName:   "sub",
+		ProviderName: "sub",
+		GCP:          &config.PubsubSubscriptionGCPData{ProjectID: testProject},
+	}, func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) error {
+		deadline, ok := types.Lease(ctx)
+		if ok {
+			leases <- lease{deadline(), true}
+		} else {
+			leases <- lease{}
+		}
+		return nil
+	})
+
+	// Messages are leased until the ack deadline, as the client extends their lease until then
+	before := time.Now()
+	srv.Publish("projects/test-project/topics/topic", []byte("hello"), nil)
+	var got lease
+	select {
+	case got = <-leases:
+	case <-time.After(10 * time.Second):
+		c.Fatal("timed out waiting for the message to be delivered")
+	}
+	c.Assert(got.ok, qt.IsTrue)
+	c.Assert(!got.deadline.Before(before.Add(time.Minute)) && !got.deadline.After(time.Now().Add(time.Minute)), qt.IsTrue)
+}
+
+func TestLeaseDuration(t *testing.T) {
+	c := qt.New(t)
+
+	// The lease ends at the ack deadline, unless the client stops extending it before
+	c.Assert(leaseDuration(time.Minute, time.Hour), qt.Equals, time.Minute)
+	c.Assert(leaseDuration(time.Hour, 10*time.Minute), qt.Equals, 10*time.Minute)
+	c.Assert(leaseDuration(2*time.Hour, 0), qt.Equals, pubsub.DefaultReceiveSettings.MaxExtension)
+}
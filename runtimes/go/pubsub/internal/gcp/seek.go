@@ -0,0 +1,26 @@
+package gcp
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+)
+
+var _ types.TimeSeeker = (*topic)(nil)
+
+func (t *topic) SeekToTime(ctx context.Context, subscription string, tm time.Time) error {
+	subCfg, ok := t.topicCfg.Subscriptions[subscription]
+	if !ok || subCfg.GCP == nil {
+		return errs.B().Code(errs.NotFound).Msgf("subscription %s is not configured for GCP Pub/Sub", subscription).Err()
+	}
+
+	// Pub/Sub only retains acknowledged messages if the subscription is
+	// configured to; otherwise only unacknowledged messages are redelivered.
+	gcpSub := t.mgr.getClientForProject(subCfg.GCP.ProjectID).Subscription(subCfg.ProviderName)
+	if err := gcpSub.SeekToTime(ctx, tm); err != nil {
+		return errs.B().Code(errs.Unavailable).Cause(err).Msgf("failed to seek subscription %s", subscription).Err()
+	}
+	return nil
+}
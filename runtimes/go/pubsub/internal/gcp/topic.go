@@ -28,9 +28,20 @@ func NewManager(ctxs *utils.Contexts, runtime *config.Runtime, pushRegistry type
 }
 
 type topic struct {
-	mgr      *Manager
-	gcpTopic *pubsub.Topic
-	topicCfg *config.PubsubTopic
+	mgr          *Manager
+	gcpTopic     *pubsub.Topic
+	topicCfg     *config.PubsubTopic
+	maxAttrBytes int           // the maximum total size of the attributes of a message
+	retention    time.Duration // the declared message retention, or 0 for the default
+
+	receiversMu sync.Mutex                                      // receiversMu protects access to the receivers, options and asyncAcks maps
+	receivers   map[string]*receiver                            // A map of subscription name to its pull receiver
+	options     map[string]*types.GCPSubscriptionOptions        // A map of subscription name to its GCP-specific options
+	asyncAcks   map[string]func(msgID string, ack func() error) // A map of subscription name to its background acknowledger, for those with AsyncAck
+
+	declaredMu sync.Mutex                      // declaredMu protects access to the declared and filters maps
+	declared   map[string]declaredSubscription // A map of subscription name to its declared configuration
+	filters    map[string]string               // A map of subscription name to its declared filter
 }
 
 func (mgr *Manager) ProviderName() string { return "gcp" }
@@ -46,6 +57,15 @@ func (mgr *Manager) NewTopic(_ *config.PubsubProvider, staticCfg types.TopicConf
 	// Enable message ordering if we have an ordering key set
 	gcpTopic.EnableMessageOrdering = staticCfg.OrderingAttribute != ""
 
+	// Apply the GCP-specific publish settings, if any
+	maxAttrBytes := defaultMaxAttributeBytes
+	if opts := staticCfg.GCP; opts != nil {
+		applyPublishOptions(&gcpTopic.PublishSettings, opts)
+		if opts.MaxAttributeBytes > 0 {
+			maxAttrBytes = opts.MaxAttributeBytes
+		}
+	}
+
 	// Check we have permissions to interact with the given topic
 	// (note: the call to Topic() above only creates the object, it doesn't verify that we have permissions to interact with it)
 	_, err := gcpTopic.Config(mgr.ctxs.Connection)
@@ -53,7 +73,38 @@ func (mgr *Manager) NewTopic(_ *config.PubsubProvider, staticCfg types.TopicConf
 		panic(fmt.Sprintf("pubsub topic %s status call failed: %s", runtimeCfg.EncoreName, err))
 	}
 
-	return &topic{mgr, gcpTopic, runtimeCfg}
+	return &topic{mgr: mgr, gcpTopic: gcpTopic, topicCfg: runtimeCfg, maxAttrBytes: maxAttrBytes, retention: staticCfg.Retention, receivers: make(map[string]*receiver), options: make(map[string]*types.GCPSubscriptionOptions), asyncAcks: make(map[string]func(string, func() error)), declared: make(map[string]declaredSubscription), filters: make(map[string]string)}
+}
+
+// applyPublishOptions applies the non-zero GCP-specific publish options to settings.
+func applyPublishOptions(settings *pubsub.PublishSettings, opts *types.GCPTopicOptions) {
+	if opts.PublishDelayThreshold > 0 {
+		settings.DelayThreshold = opts.PublishDelayThreshold
+	}
+	if opts.PublishCountThreshold > 0 {
+		settings.CountThreshold = opts.PublishCountThreshold
+	}
+	if opts.PublishByteThreshold > 0 {
+		settings.ByteThreshold = opts.PublishByteThreshold
+	}
+	if opts.PublishTimeout > 0 {
+		settings.Timeout = opts.PublishTimeout
+	}
+}
+
+var _ types.SubscriptionOptioner = (*topic)(nil)
+
+// SetSubscriptionOptions records the GCP-specific options of a subscription,
+// which are applied to its receive settings once it subscribes,
+// and its filter, which CheckTopology verifies against the provisioned one.
+func (t *topic) SetSubscriptionOptions(subscription string, opts types.SubscriptionOptions) {
+	t.receiversMu.Lock()
+	t.options[subscription] = opts.GCP
+	t.receiversMu.Unlock()
+
+	t.declaredMu.Lock()
+	t.filters[subscription] = opts.Filter
+	t.declaredMu.Unlock()
 }
 
 func (t *topic) PublishMessage(ctx context.Context, orderingKey string, attrs map[string]string, data []byte) (id string, err error) {
@@ -76,6 +127,10 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 		panic("GCP subscriptions must have GCP-specific configuration provided, got nil")
 	}
 
+	t.declaredMu.Lock()
+	t.declared[subCfg.EncoreName] = declaredSubscription{ackDeadline: ackDeadline, retryPolicy: retryPolicy, filter: t.filters[subCfg.EncoreName]}
+	t.declaredMu.Unlock()
+
 	// If we have a subscription ID, register a push endpoint for it
 	if subCfg.ID != "" {
 		if gcpCfg.PushServiceAccount != "" {
@@ -94,13 +149,28 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 		if maxConcurrency == 0 {
 			maxConcurrency = 1000 // FIXME(domblack): This retains the old behaviour, but allows user customisation - in a future release we should remove this
 		}
-		subscription.ReceiveSettings.MaxOutstandingMessages = maxConcurrency
+		r := &receiver{settings: types.FlowControl{
+			MaxOutstandingMessages: maxConcurrency,
+			MaxOutstandingBytes:    pubsub.DefaultReceiveSettings.MaxOutstandingBytes,
+		}}
+		t.receiversMu.Lock()
+		r.options = t.options[subCfg.EncoreName]
+		r.asyncAck = t.asyncAcks[subCfg.EncoreName]
+		t.receivers[subCfg.EncoreName] = r
+		t.receiversMu.Unlock()
 
 		// Start the subscription with the GCP library
 		go func() {
 			for t.mgr.ctxs.Fetch.Err() == nil {
-				// Subscribe to the topic to receive messages
-				err := subscription.Receive(t.mgr.ctxs.Fetch, func(_ context.Context, msg *pubsub.Message) {
+				// Subscribe to the topic to receive messages,
+				// restarting whenever the flow control settings change
+				receiveCtx, cancelReceive := r.start(t.mgr.ctxs.Fetch, subscription)
+				lease := leaseDuration(ackDeadline, subscription.ReceiveSettings.MaxExtension)
+				err := subscription.Receive(receiveCtx, func(_ context.Context, msg *pubsub.Message) {
+					// Track activity for releasing resources while idle
+					woke := r.received()
+					defer r.done(woke)
+
 					deliveryAttempt := 1
 					if msg.DeliveryAttempt != nil {
 						deliveryAttempt = *msg.DeliveryAttempt
@@ -109,12 +179,22 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 					// Create a context from the handler context with a deadline of the ackdeadline
 					ctx, cancel := context.WithTimeout(t.mgr.ctxs.Handler, ackDeadline)
 					defer cancel()
+					leaseDeadline := time.Now().Add(lease)
+					ctx = types.WithLease(ctx, func() time.Time { return leaseDeadline })
 
 					var result *pubsub.AckResult
 					if err := f(ctx, msg.ID, msg.PublishTime, deliveryAttempt, msg.Attributes, msg.Data); err != nil {
 						result = msg.NackWithResult()
 					} else {
 						result = msg.AckWithResult()
+
+						// Wait for the acknowledgement to complete in the background, if configured
+						if asyncAck := r.asyncAckFunc(); asyncAck != nil {
+							asyncAck(msg.ID, func() error {
+								return ackResultError(result.Get(t.mgr.ctxs.Connection))
+							})
+							return
+						}
 					}
 
 					res, err := result.Get(t.mgr.ctxs.Connection)
@@ -134,6 +214,7 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 						}
 					}
 				})
+				cancelReceive()
 
 				// If there was an error and we're not shutting down, log it and then sleep for a bit before trying again
 				if err != nil && t.mgr.ctxs.Fetch.Err() == nil {
@@ -144,3 +225,50 @@ func (t *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 		}()
 	}
 }
+
+var _ types.InitialPositioner = (*topic)(nil)
+
+// NativeInitialPosition reports that GCP subscriptions only receive
+// messages published after the subscription was created.
+func (t *topic) NativeInitialPosition() types.InitialPosition {
+	return types.InitialPositionLatest
+}
+
+// SupportsInitialPosition reports that the initial position of GCP subscriptions
+// cannot be changed, as subscriptions are provisioned outside the application.
+func (t *topic) SupportsInitialPosition(pos types.InitialPosition) bool {
+	return pos == types.InitialPositionLatest
+}
+
+var _ types.DurableConfirmer = (*topic)(nil)
+
+// ConfirmsDurably reports that PublishMessage waits for GCP Pub/Sub to
+// acknowledge the message, which it only does once the message is persisted.
+func (t *topic) ConfirmsDurably() bool {
+	return true
+}
+
+// defaultMaxAttributeBytes is the most GCP Pub/Sub accepts as the attributes
+// of a message: at most 100 attributes, with names of up to 256 bytes
+// and values of up to 1024 bytes.
+const defaultMaxAttributeBytes = 100 * (256 + 1024)
+
+var _ types.AttributeLimiter = (*topic)(nil)
+
+// MaxAttributeBytes reports the limit on the attributes of a message,
+// which can be configured with the GCP-specific topic options.
+func (t *topic) MaxAttributeBytes() int {
+	return t.maxAttrBytes
+}
+
+// leaseDuration returns how long a received message is leased for.
+//
+// The GCP client extends the lease of messages being processed until
+// maxExtension has passed, but messages still being processed once the
+// ackDeadline has passed are negatively acknowledged, ending the lease.
+func leaseDuration(ackDeadline, maxExtension time.Duration) time.Duration {
+	if maxExtension <= 0 {
+		maxExtension = pubsub.DefaultReceiveSettings.MaxExtension
+	}
+	return min(ackDeadline, maxExtension)
+}
@@ -0,0 +1,168 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+var _ types.TopologyChecker = (*topic)(nil)
+
+// GCP supports configuring the message retention of topics.
+var _ types.RetentionConfigurer = (*topic)(nil)
+
+func (t *topic) SupportsRetention() bool { return true }
+
+// declaredSubscription is the configuration a subscription was declared with.
+type declaredSubscription struct {
+	ackDeadline time.Duration
+	retryPolicy *types.RetryPolicy
+	filter      string // the subscription's FilterExpr, which GCP applies when provisioned
+}
+
+// The ranges GCP supports for subscription settings, which
+// declared values are clamped to when the subscription is provisioned.
+const (
+	minAckDeadline         = 10 * time.Second
+	maxAckDeadline         = 600 * time.Second
+	maxRetryBackoff        = 600 * time.Second
+	minMaxDeliveryAttempts = 5
+	maxMaxDeliveryAttempts = 100
+	minRetention           = 10 * time.Minute
+	maxRetention           = 31 * 24 * time.Hour
+)
+
+func (t *topic) CheckTopology(ctx context.Context) ([]types.TopologyDrift, error) {
+	topicName := t.topicCfg.EncoreName
+	topicCfg, err := t.gcpTopic.Config(ctx)
+	if status.Code(err) == codes.NotFound {
+		return []types.TopologyDrift{{Kind: types.TopicMissing, Topic: topicName}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get config of topic %s: %w", topicName, err)
+	}
+
+	var drift []types.TopologyDrift
+	if t.retention > 0 {
+		declared, actual := clamp(t.retention, minRetention, maxRetention).String(), "unset"
+		if d := optionalDuration(topicCfg.RetentionDuration); d > 0 {
+			actual = d.String()
+		}
+		if declared != actual {
+			drift = append(drift, types.TopologyDrift{
+				Kind:     types.ConfigMismatch,
+				Topic:    topicName,
+				Field:    "Retention",
+				Declared: declared,
+				Actual:   actual,
+			})
+		}
+	}
+
+	declaredByProviderName := make(map[string]bool, len(t.topicCfg.Subscriptions))
+	for _, subCfg := range t.topicCfg.Subscriptions {
+		declaredByProviderName[subCfg.ProviderName] = true
+		if subCfg.GCP == nil {
+			continue
+		}
+
+		actual, err := t.mgr.getClientForProject(subCfg.GCP.ProjectID).Subscription(subCfg.ProviderName).Config(ctx)
+		if status.Code(err) == codes.NotFound {
+			drift = append(drift, types.TopologyDrift{Kind: types.SubscriptionMissing, Topic: topicName, Subscription: subCfg.EncoreName})
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("get config of subscription %s: %w", subCfg.EncoreName, err)
+		}
+
+		// We can only compare the configuration of subscriptions
+		// this instance has subscribed to.
+		t.declaredMu.Lock()
+		declared, ok := t.declared[subCfg.EncoreName]
+		t.declaredMu.Unlock()
+		if ok {
+			drift = append(drift, compareSubscriptionConfig(topicName, subCfg.EncoreName, declared, actual)...)
+		}
+	}
+
+	// Look for subscriptions the application doesn't know about
+	it := t.gcpTopic.Subscriptions(ctx)
+	for {
+		sub, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("list subscriptions of topic %s: %w", topicName, err)
+		}
+		if !declaredByProviderName[sub.ID()] {
+			drift = append(drift, types.TopologyDrift{Kind: types.SubscriptionExtra, Topic: topicName, Subscription: sub.ID()})
+		}
+	}
+
+	return drift, nil
+}
+
+// compareSubscriptionConfig compares the declared configuration of a subscription,
+// clamped to the ranges GCP supports, against its actual configuration.
+func compareSubscriptionConfig(topicName, subName string, declared declaredSubscription, actual pubsub.SubscriptionConfig) []types.TopologyDrift {
+	var drift []types.TopologyDrift
+	mismatch := func(field, declared, actual string) {
+		if declared != actual {
+			drift = append(drift, types.TopologyDrift{
+				Kind:         types.ConfigMismatch,
+				Topic:        topicName,
+				Subscription: subName,
+				Field:        field,
+				Declared:     declared,
+				Actual:       actual,
+			})
+		}
+	}
+
+	mismatch("AckDeadline", clamp(declared.ackDeadline, minAckDeadline, maxAckDeadline).String(), actual.AckDeadline.String())
+	mismatch("FilterExpr", declared.filter, actual.Filter)
+
+	if rp := declared.retryPolicy; rp != nil {
+		var actualMin, actualMax time.Duration
+		if actual.RetryPolicy != nil {
+			actualMin = optionalDuration(actual.RetryPolicy.MinimumBackoff)
+			actualMax = optionalDuration(actual.RetryPolicy.MaximumBackoff)
+		}
+		mismatch("RetryPolicy.MinBackoff", clamp(rp.MinBackoff, 0, maxRetryBackoff).String(), actualMin.String())
+		mismatch("RetryPolicy.MaxBackoff", clamp(rp.MaxBackoff, 0, maxRetryBackoff).String(), actualMax.String())
+
+		declaredAttempts, actualAttempts := "unlimited", "unlimited"
+		if rp.MaxRetries != types.InfiniteRetries {
+			declaredAttempts = strconv.Itoa(clamp(rp.MaxRetries+1, minMaxDeliveryAttempts, maxMaxDeliveryAttempts))
+		}
+		if actual.DeadLetterPolicy != nil {
+			actualAttempts = strconv.Itoa(actual.DeadLetterPolicy.MaxDeliveryAttempts)
+		}
+		mismatch("RetryPolicy.MaxRetries", declaredAttempts, actualAttempts)
+	}
+
+	return drift
+}
+
+func optionalDuration(d any) time.Duration {
+	if d, ok := d.(time.Duration); ok {
+		return d
+	}
+	return 0
+}
+
+func clamp[T int | time.Duration](v, min, max T) T {
+	if v < min {
+		return min
+	} else if v > max {
+		return max
+	}
+	return v
+}
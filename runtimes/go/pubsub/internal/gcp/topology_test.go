@@ -0,0 +1,146 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/pubsub/internal/types"
+)
+
+func TestCheckTopology(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	mgr, _, client := newTestManager(t)
+
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{
+		Topic:       gcpTopic,
+		AckDeadline: 20 * time.Second,
+		RetryPolicy: &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second, MaximumBackoff: 10 * time.Minute},
+	})
+	c.Assert(err, qt.IsNil)
+	_, err = client.CreateSubscription(ctx, "extra", pubsub.SubscriptionConfig{Topic: gcpTopic})
+	c.Assert(err, qt.IsNil)
+
+	gcpCfg := &config.PubsubSubscriptionGCPData{ProjectID: testProject}
+	sub := &config.PubsubSubscription{EncoreName: "sub", ProviderName: "sub", GCP: gcpCfg}
+	missing := &config.PubsubSubscription{EncoreName: "missing", ProviderName: "missing", GCP: gcpCfg}
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:    "topic",
+		ProviderName:  "topic",
+		GCP:           &config.PubsubTopicGCPData{ProjectID: testProject},
+		Subscriptions: map[string]*config.PubsubSubscription{"sub": sub, "missing": missing},
+	})
+
+	// Declare the subscription with a longer ack deadline than exists at GCP
+	logger := zerolog.Nop()
+	impl.Subscribe(&logger, 10, 30*time.Second, &types.RetryPolicy{
+		MinBackoff: 10 * time.Second,
+		MaxBackoff: 10 * time.Minute,
+		MaxRetries: types.InfiniteRetries,
+	}, sub, func(context.Context, string, time.Time, int, map[string]string, []byte) error { return nil })
+
+	drift, err := impl.(types.TopologyChecker).CheckTopology(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(drift, qt.ContentEquals, []types.TopologyDrift{
+		{Kind: types.SubscriptionMissing, Topic: "topic", Subscription: "missing"},
+		{Kind: types.ConfigMismatch, Topic: "topic", Subscription: "sub", Field: "AckDeadline", Declared: "30s", Actual: "20s"},
+		{Kind: types.SubscriptionExtra, Topic: "topic", Subscription: "extra"},
+	})
+
+	// A topic deleted after startup is reported on its own
+	otherTopic, err := client.CreateTopic(ctx, "other")
+	c.Assert(err, qt.IsNil)
+	impl = mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:   "other",
+		ProviderName: "other",
+		GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+	})
+	c.Assert(otherTopic.Delete(ctx), qt.IsNil)
+	drift, err = impl.(types.TopologyChecker).CheckTopology(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(drift, qt.DeepEquals, []types.TopologyDrift{{Kind: types.TopicMissing, Topic: "other"}})
+}
+
+func TestCheckTopology_Retention(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	mgr, _, client := newTestManager(t)
+
+	check := func(name string, actual, declared time.Duration) []types.TopologyDrift {
+		_, err := client.CreateTopicWithConfig(ctx, name, &pubsub.TopicConfig{RetentionDuration: actual})
+		c.Assert(err, qt.IsNil)
+		impl := mgr.NewTopic(nil, types.TopicConfig{Retention: declared}, &config.PubsubTopic{
+			EncoreName:   name,
+			ProviderName: name,
+			GCP:          &config.PubsubTopicGCPData{ProjectID: testProject},
+		})
+		c.Assert(impl.(types.RetentionConfigurer).SupportsRetention(), qt.IsTrue)
+		drift, err := impl.(types.TopologyChecker).CheckTopology(ctx)
+		c.Assert(err, qt.IsNil)
+		return drift
+	}
+
+	// A retention differing from the declared one is reported
+	c.Assert(check("differs", time.Hour, 2*time.Hour), qt.DeepEquals, []types.TopologyDrift{
+		{Kind: types.ConfigMismatch, Topic: "differs", Field: "Retention", Declared: "2h0m0s", Actual: "1h0m0s"},
+	})
+
+	// Declared retentions are clamped to the range GCP supports
+	c.Assert(check("clamped", 10*time.Minute, time.Minute), qt.HasLen, 0)
+
+	// Topics without a declared retention aren't compared
+	c.Assert(check("undeclared", time.Hour, 0), qt.HasLen, 0)
+}
+
+func TestCheckTopology_Filter(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	mgr, _, client := newTestManager(t)
+
+	gcpTopic, err := client.CreateTopic(ctx, "topic")
+	c.Assert(err, qt.IsNil)
+	for _, id := range []string{"same", "different"} {
+		_, err = client.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{
+			Topic:       gcpTopic,
+			AckDeadline: 30 * time.Second,
+			Filter:      `attributes.region = "eu"`,
+		})
+		c.Assert(err, qt.IsNil)
+	}
+
+	gcpCfg := &config.PubsubSubscriptionGCPData{ProjectID: testProject}
+	same := &config.PubsubSubscription{EncoreName: "same", ProviderName: "same", GCP: gcpCfg}
+	different := &config.PubsubSubscription{EncoreName: "different", ProviderName: "different", GCP: gcpCfg}
+	impl := mgr.NewTopic(nil, types.TopicConfig{}, &config.PubsubTopic{
+		EncoreName:    "topic",
+		ProviderName:  "topic",
+		GCP:           &config.PubsubTopicGCPData{ProjectID: testProject},
+		Subscriptions: map[string]*config.PubsubSubscription{"same": same, "different": different},
+	})
+
+	// Only the subscription declared with a different filter than it was provisioned with is reported
+	logger := zerolog.Nop()
+	for sub, filter := range map[*config.PubsubSubscription]string{same: `attributes.region = "eu"`, different: `attributes.region = "us"`} {
+		impl.(types.SubscriptionOptioner).SetSubscriptionOptions(sub.EncoreName, types.SubscriptionOptions{Filter: filter})
+		impl.Subscribe(&logger, 10, 30*time.Second, nil, sub, func(context.Context, string, time.Time, int, map[string]string, []byte) error { return nil })
+	}
+
+	drift, err := impl.(types.TopologyChecker).CheckTopology(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(drift, qt.DeepEquals, []types.TopologyDrift{{
+		Kind:         types.ConfigMismatch,
+		Topic:        "topic",
+		Subscription: "different",
+		Field:        "FilterExpr",
+		Declared:     `attributes.region = "us"`,
+		Actual:       `attributes.region = "eu"`,
+	}})
+}
@@ -36,6 +36,8 @@ type topic struct {
 	m         sync.Mutex
 	producer  *nsq.Producer
 	consumers map[string]*nsq.Consumer
+	options   *types.NSQTopicOptions                   // the topic's NSQ-specific options, if any
+	subOpts   map[string]*types.NSQSubscriptionOptions // the NSQ-specific options of subscriptions, if any
 }
 
 func (mgr *Manager) ProviderName() string { return "nsq" }
@@ -44,16 +46,28 @@ func (mgr *Manager) Matches(cfg *config.PubsubProvider) bool {
 	return cfg.NSQ != nil
 }
 
-func (mgr *Manager) NewTopic(providerCfg *config.PubsubProvider, _ types.TopicConfig, runtimeCfg *config.PubsubTopic) types.TopicImplementation {
+func (mgr *Manager) NewTopic(providerCfg *config.PubsubProvider, staticCfg types.TopicConfig, runtimeCfg *config.PubsubTopic) types.TopicImplementation {
 	return &topic{
 		mgr:       mgr,
 		name:      runtimeCfg.EncoreName,
 		addr:      providerCfg.NSQ.Host,
 		producer:  nil,
 		consumers: make(map[string]*nsq.Consumer),
+		options:   staticCfg.NSQ,
+		subOpts:   make(map[string]*types.NSQSubscriptionOptions),
 	}
 }
 
+var _ types.SubscriptionOptioner = (*topic)(nil)
+
+// SetSubscriptionOptions records the NSQ-specific options of a subscription,
+// which are applied to its consumer once it subscribes.
+func (l *topic) SetSubscriptionOptions(subscription string, opts types.SubscriptionOptions) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.subOpts[subscription] = opts.NSQ
+}
+
 // messageWrapper is a local representation of a topic published to NSQ.
 // it wraps the raw data with an ID and an Attribute map.
 // It must be synchronized with the e2e-tests/testscript_test.go file.
@@ -86,6 +100,17 @@ func (l *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 	}
 
 	conCfg := getConsumerConfig(maxConcurrency, ackDeadline, retryPolicy)
+	if opts := l.subOpts[implCfg.EncoreName]; opts != nil {
+		if opts.HeartbeatInterval > 0 {
+			conCfg.HeartbeatInterval = opts.HeartbeatInterval
+		}
+		if opts.ReadTimeout > 0 {
+			conCfg.ReadTimeout = opts.ReadTimeout
+		}
+		if opts.OutputBufferTimeout > 0 {
+			conCfg.OutputBufferTimeout = opts.OutputBufferTimeout
+		}
+	}
 	consumer, err := nsq.NewConsumer(l.name, implCfg.EncoreName, conCfg)
 	if err != nil {
 		panic(fmt.Sprintf("unable to setup subscription %s for topic %s: %v", implCfg.EncoreName, l.name, err))
@@ -94,7 +119,7 @@ func (l *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 	consumer.SetLogger(&LogAdapter{Logger: logger}, nsq.LogLevelWarning)
 
 	// create a dedicated handler which forwards messages to the encore subscription
-	consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(m *nsq.Message) error {
+	consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(m *nsq.Message) (err error) {
 		// create a message to unmarshal the raw nsq body into
 		msg := &messageWrapper{}
 
@@ -108,7 +133,8 @@ func (l *topic) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadlin
 					m.Finish()
 					return
 				}
-				m.RequeueWithoutBackoff(delay)
+				// Use the delay requested by the handler, if any
+				m.RequeueWithoutBackoff(utils.RetryDelay(err, delay, retryPolicy.MaxBackoff))
 			}
 		}()
 
@@ -158,6 +184,14 @@ func (l *topic) PublishMessage(ctx context.Context, orderingKey string, attrs ma
 		defer l.m.Unlock()
 		if l.producer == nil {
 			cfg := nsq.NewConfig()
+			if opts := l.options; opts != nil {
+				if opts.DialTimeout > 0 {
+					cfg.DialTimeout = opts.DialTimeout
+				}
+				if opts.WriteTimeout > 0 {
+					cfg.WriteTimeout = opts.WriteTimeout
+				}
+			}
 			producer, err := nsq.NewProducer(l.addr, cfg)
 			if err != nil {
 				return "", errs.B().Cause(err).Code(errs.Internal).Msg("failed to connect to NSQD").Err()
@@ -207,3 +241,49 @@ func getConsumerConfig(maxConcurrency int, ackDeadline time.Duration, retryPolic
 
 	return conCfg
 }
+
+var _ types.InitialPositioner = (*topic)(nil)
+
+// NativeInitialPosition reports that new NSQ channels only receive messages
+// published after the channel was created. The exception is the first channel
+// of a topic, which also receives any messages buffered on the topic.
+func (t *topic) NativeInitialPosition() types.InitialPosition {
+	return types.InitialPositionLatest
+}
+
+// SupportsInitialPosition reports that NSQ does not allow choosing
+// where a new channel starts.
+func (t *topic) SupportsInitialPosition(pos types.InitialPosition) bool {
+	return pos == types.InitialPositionLatest
+}
+
+var _ types.DurableConfirmer = (*topic)(nil)
+
+// ConfirmsDurably reports that nsqd acknowledges a publish once the message
+// is queued, which may be in memory only, so it cannot confirm durability.
+func (t *topic) ConfirmsDurably() bool {
+	return false
+}
+
+var _ types.AsyncAcker = (*topic)(nil)
+
+// SetAsyncAck reports that it is not supported. NSQ consumers never wait
+// for nsqd to respond to an acknowledgement, so there's no latency to take off.
+func (t *topic) SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool {
+	return false
+}
+
+// defaultMaxAttributeBytes is the default maximum size of a message accepted by nsqd,
+// which includes the attributes of the message as they are wrapped along with its data.
+const defaultMaxAttributeBytes = 1 << 20
+
+var _ types.AttributeLimiter = (*topic)(nil)
+
+// MaxAttributeBytes reports the limit on the attributes of a message,
+// which can be configured with the NSQ-specific topic options.
+func (l *topic) MaxAttributeBytes() int {
+	if l.options != nil && l.options.MaxAttributeBytes > 0 {
+		return l.options.MaxAttributeBytes
+	}
+	return defaultMaxAttributeBytes
+}
@@ -3,6 +3,10 @@ package test
 import (
 	"context"
 	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -11,9 +15,9 @@ import (
 	"github.com/rs/zerolog"
 
 	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/exported/model"
 	"encore.dev/appruntime/shared/testsupport"
 	"encore.dev/pubsub/internal/types"
-	"encore.dev/pubsub/internal/utils"
 )
 
 // TestTopic is used during a "encore test" call.
@@ -21,72 +25,178 @@ import (
 // It records all published messages on a per-test basis, allowing a unit test
 // to assert that the correct messages were published.
 //
-// Any messages published to this type of topic _will not_ be passed to subscribers.
+// Any messages published to this type of topic _will not_ be passed to subscribers,
+// unless in-memory pubsub has been enabled for the test.
 type TestTopic[T any] struct {
 	ts          *testsupport.Manager
 	name        string
 	m           sync.RWMutex
 	instances   map[*testing.T]*testInstance[T]
 	subscribers map[string]types.RawSubscriptionCallback
+	unmarshal   func(attrs map[string]string, data []byte) (T, error)
 }
 
-func NewTopic[T any](ts *testsupport.Manager, name string) types.TopicImplementation {
+// NewTopic creates a new TestTopic, which decodes published messages using unmarshal.
+func NewTopic[T any](ts *testsupport.Manager, name string, unmarshal func(attrs map[string]string, data []byte) (T, error)) types.TopicImplementation {
 	return &TestTopic[T]{
 		ts:          ts,
 		name:        name,
+		unmarshal:   unmarshal,
 		instances:   make(map[*testing.T]*testInstance[T]),
 		subscribers: make(map[string]types.RawSubscriptionCallback),
 	}
 }
 
 // PublishMessage will record the message against the test instance
-// and if subscribers are enabled for the test instance, it will also trigger
-// those subscribers. (The default behaviour is subscribers are disabled in tests)
+// and if in-memory pubsub is enabled for the test, it will also deliver
+// the message to all subscribers, waiting for them to process it.
+// (The default behaviour is subscribers are disabled in tests)
+//
+// If the test simulates redelivery, the message may instead be held back
+// and delivered later, possibly more than once.
 func (t *TestTopic[T]) PublishMessage(ctx context.Context, orderingKey string, attrs map[string]string, data []byte) (id string, err error) {
 	if err := ctx.Err(); err != nil {
 		return "", err
 	}
 
 	test := t.ts.CurrentTest()
-	unmarshalled, err := utils.UnmarshalMessage[T](attrs, data)
+	unmarshalled, err := t.unmarshal(attrs, data)
 	if err != nil {
 		test.Fatalf("failed to unmarshal published message: %s", err)
 	}
 
 	instance := t.TestInstance(test)
 
-	msgID, err := instance.publishMessage(unmarshalled)
+	msgID, err := instance.publishMessage(unmarshalled, attrs)
 	if err != nil {
 		return "", err
 	}
 
-	// If subscriptions are enabled for this test, then trigger those subscribers asynchronously
-	// allowing the publishing code to continue as it would in a real system
-	if instance.subscriptionsEnabled {
-		published := time.Now()
+	// If in-memory pubsub is enabled for this test, then trigger those subscribers within the test
+	// and wait for them, so the effects of processing the message are visible once Publish returns
+	if t.ts.GetInMemoryPubsub() {
+		msg := pendingMessage{id: msgID, published: time.Now(), attrs: attrs, data: data}
 
-		for name, sub := range t.subscribers {
-			name := name
-			sub := sub
-			t.ts.RunAsyncCodeInTest(test, func(ctx context.Context) {
-				if err := sub(ctx, msgID, published, 1, attrs, data); err != nil {
-					test.Errorf("an error was returned while processing subscription %s for message %s: %s", name, msgID, err)
-					test.Fail()
+		redelivery := t.ts.GetPubsubRedelivery()
+		if redelivery == nil {
+			t.deliver(test, msg, nil)
+			return msgID, nil
+		}
+
+		// Simulate the broker redelivering messages, delivering any messages
+		// still held back once the test ends
+		ready, heldBack := instance.prefetch(redelivery, msg)
+		duplicate := func() bool { return instance.duplicate(redelivery) }
+		if heldBack {
+			t.ts.AddEndCallback(func(test *testing.T) {
+				for _, msg := range instance.flush(redelivery) {
+					t.deliver(test, msg, duplicate)
 				}
 			})
 		}
+		for _, msg := range ready {
+			t.deliver(test, msg, duplicate)
+		}
 	}
 
 	return msgID, nil
 }
 
-// Subscribe will register a new subscriber for the pub sub topic. By default these will not be called during tests
+// deliver delivers msg to all subscribers within the test, waiting for them to process it.
+// If duplicate is not nil, it is called for each subscriber in turn to decide whether
+// the subscriber is delivered the message a second time.
+func (t *TestTopic[T]) deliver(test *testing.T, msg pendingMessage, duplicate func() bool) {
+	t.m.RLock()
+	subscribers := maps.Clone(t.subscribers)
+	t.m.RUnlock()
+
+	// Decide on the duplicates in a fixed order, so they are reproducible
+	names := make([]string, 0, len(subscribers))
+	for name := range subscribers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		name := name
+		sub := subscribers[name]
+		attempts := 1
+		if duplicate != nil && duplicate() {
+			attempts = 2
+		}
+
+		wg.Add(1)
+		t.ts.RunAsyncCodeInTest(test, func(ctx context.Context) {
+			defer wg.Done()
+			for attempt := 1; attempt <= attempts; attempt++ {
+				if err := t.leased(ctx, func(ctx context.Context) error {
+					return sub(ctx, msg.id, msg.published, attempt, msg.attrs, msg.data)
+				}); err != nil {
+					test.Errorf("an error was returned while processing subscription %s for message %s: %s", name, msg.id, err)
+					test.Fail()
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Subscribe will register a new subscriber for the pub sub topic. By default these will not be called during tests,
+// unless in-memory pubsub has been enabled for the test.
 func (t *TestTopic[T]) Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadline time.Duration, retryPolicy *types.RetryPolicy, implCfg *config.PubsubSubscription, f types.RawSubscriptionCallback) {
 	t.m.Lock()
 	defer t.m.Unlock()
 	t.subscribers[implCfg.EncoreName] = f
 }
 
+// DeliverMessage delivers a message to the named subscriber as the given delivery attempt,
+// blocking until the subscriber has processed it. The message is not recorded as published.
+//
+// Unlike PublishMessage, the subscriber is called even if in-memory pubsub is not enabled
+// for the current test.
+func (t *TestTopic[T]) DeliverMessage(subscription, msgID string, attempt int, attrs map[string]string, data []byte) error {
+	t.m.RLock()
+	sub, found := t.subscribers[subscription]
+	t.m.RUnlock()
+	if !found {
+		return fmt.Errorf("subscription %s not found on topic %s", subscription, t.name)
+	}
+
+	// Run the subscriber the same way PublishMessage does, so it is processed
+	// within the current test, but wait for it to complete.
+	var err error
+	done := make(chan struct{})
+	published := time.Now()
+	t.ts.RunAsyncCodeInTest(t.ts.CurrentTest(), func(ctx context.Context) {
+		defer close(done)
+		err = t.leased(ctx, func(ctx context.Context) error {
+			return sub(ctx, msgID, published, attempt, attrs, data)
+		})
+	})
+	<-done
+	return err
+}
+
+// leased runs f to process a message, bounding its context by the lease
+// set for the current test, as backends which lease messages do.
+func (t *TestTopic[T]) leased(ctx context.Context, f func(ctx context.Context) error) error {
+	if lease := t.ts.GetPubsubLease(); lease > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lease)
+		defer cancel()
+		leaseDeadline, _ := ctx.Deadline()
+		ctx = types.WithLease(ctx, func() time.Time { return leaseDeadline })
+	}
+	return f(ctx)
+}
+
+// ConfirmsDurably reports that published messages are always recorded
+// for the test before PublishMessage returns.
+func (t *TestTopic[T]) ConfirmsDurably() bool {
+	return true
+}
+
 // TestInstance returns this tests specific instance of the topic and creates it if it does not exist
 func (t *TestTopic[T]) TestInstance(test *testing.T) *testInstance[T] {
 	t.m.RLock()
@@ -111,29 +221,109 @@ func (t *TestTopic[T]) TestInstance(test *testing.T) *testInstance[T] {
 // testInstance represents a topic, as it is seen from a test
 // This struct implements test.TestTopic[T] to allow the testing package to interface with it
 type testInstance[T any] struct {
-	topicName            string     // The topic name
-	t                    *testing.T // The test we're running against
-	msgID                int32      // The last message ID we sent (updated atomically)
-	m                    sync.Mutex // Mutex for the published messages
-	messages             []T        // What messages have been published
-	subscriptionsEnabled bool       // If subscriptions are enabled for this test
+	topicName  string              // The topic name
+	t          *testing.T          // The test we're running against
+	msgID      int32               // The last message ID we sent (updated atomically)
+	m          sync.Mutex          // Mutex for the published messages
+	messages   []T                 // What messages have been published
+	attrs      []map[string]string // The attributes of each published message
+	rng        *rand.Rand          // The random source for simulated redelivery, created on first use
+	pending    []pendingMessage    // The messages held back to be delivered out of order
+	flushAtEnd bool                // Whether the held back messages are delivered when the test ends
 }
 
-// publishMessage records the message which was sent, and generates a deterministic message ID
-// which is guaranteed to be unique across all tests
-func (t *testInstance[T]) publishMessage(unmarshalled T) (id string, err error) {
+// pendingMessage is a published message which is yet to be delivered to the subscribers
+type pendingMessage struct {
+	id        string
+	published time.Time
+	attrs     map[string]string
+	data      []byte
+}
+
+// publishMessage records the message which was sent along with its attributes, and generates
+// a deterministic message ID which is guaranteed to be unique across all tests
+func (t *testInstance[T]) publishMessage(unmarshalled T, attrs map[string]string) (id string, err error) {
 	msgID := atomic.AddInt32(&t.msgID, 1)
 
 	t.m.Lock()
 	defer t.m.Unlock()
 	t.messages = append(t.messages, unmarshalled)
+	t.attrs = append(t.attrs, maps.Clone(attrs))
 
 	// we use "/" as the separator to mirror the behaviour of tests and sub tests
 	return fmt.Sprintf("%s/%s/%d", t.t.Name(), t.topicName, msgID), nil
 }
 
+// prefetch holds back msg, returning the messages to deliver now: randomly chosen
+// held back messages, until fewer than the prefetch window are being held back.
+// heldBack reports whether this is the first time messages are held back for the test.
+func (t *testInstance[T]) prefetch(cfg *model.PubsubRedelivery, msg pendingMessage) (ready []pendingMessage, heldBack bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.pending = append(t.pending, msg)
+	for len(t.pending) >= max(cfg.PrefetchWindow, 1) {
+		i := t.random(cfg).Intn(len(t.pending))
+		ready = append(ready, t.pending[i])
+		t.pending = slices.Delete(t.pending, i, i+1)
+	}
+	if len(t.pending) > 0 && !t.flushAtEnd {
+		t.flushAtEnd = true
+		heldBack = true
+	}
+	return ready, heldBack
+}
+
+// flush returns all held back messages in a random order
+func (t *testInstance[T]) flush(cfg *model.PubsubRedelivery) []pendingMessage {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	msgs := t.pending
+	t.pending = nil
+	t.random(cfg).Shuffle(len(msgs), func(i, j int) { msgs[i], msgs[j] = msgs[j], msgs[i] })
+	return msgs
+}
+
+// duplicate reports whether to deliver a message a second time
+func (t *testInstance[T]) duplicate(cfg *model.PubsubRedelivery) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return cfg.DuplicateRate > 0 && t.random(cfg).Float64() < cfg.DuplicateRate
+}
+
+// random returns the random source for simulated redelivery, which is seeded
+// per test so the deliveries of one test don't depend on those of any other.
+// It must be called with t.m held.
+func (t *testInstance[T]) random(cfg *model.PubsubRedelivery) *rand.Rand {
+	if t.rng == nil {
+		t.rng = rand.New(rand.NewSource(cfg.Seed))
+	}
+	return t.rng
+}
+
 func (t *testInstance[T]) PublishedMessages() []T {
 	t.m.Lock()
 	defer t.m.Unlock()
 	return t.messages
 }
+
+func (t *testInstance[T]) PublishedAttributes() []map[string]string {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.attrs
+}
+
+func (t *testInstance[T]) AssertAttributes(pred func(attrs map[string]string) bool) {
+	t.t.Helper()
+	attrs := t.PublishedAttributes()
+	if len(attrs) == 0 {
+		t.t.Errorf("no messages were published to topic %s", t.topicName)
+		return
+	}
+	for i, a := range attrs {
+		if !pred(a) {
+			t.t.Errorf("the attributes of message %d published to topic %s did not satisfy the predicate: %v", i, t.topicName, a)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// leaseKey is the context key under which a provider
+// reports the lease of the message it is delivering.
+type leaseKey struct{}
+
+// WithLease returns a copy of ctx reporting that the message being delivered
+// with it is leased by the messaging service until the time returned by deadline.
+// The deadline is read whenever it is needed, so providers which extend
+// the lease while the message is processed can report the extended deadline.
+//
+// Providers which don't lease messages, or which can't tell when the lease
+// expires, must not call it.
+func WithLease(ctx context.Context, deadline func() time.Time) context.Context {
+	return context.WithValue(ctx, leaseKey{}, deadline)
+}
+
+// Lease returns the lease deadline reported by the provider
+// delivering a message with ctx, if any.
+func Lease(ctx context.Context) (deadline func() time.Time, ok bool) {
+	deadline, ok = ctx.Value(leaseKey{}).(func() time.Time)
+	return deadline, ok
+}
@@ -17,3 +17,123 @@ type TopicImplementation interface {
 	PublishMessage(ctx context.Context, orderingKey string, attrs map[string]string, data []byte) (id string, err error)
 	Subscribe(logger *zerolog.Logger, maxConcurrency int, ackDeadline time.Duration, retryPolicy *RetryPolicy, implCfg *config.PubsubSubscription, f RawSubscriptionCallback)
 }
+
+// FlowController is implemented by topics whose subscriptions
+// support adjusting their flow control while running.
+type FlowController interface {
+	// FlowControl returns the flow control settings of a subscription
+	// by its Encore name, reporting false if the subscription is not receiving messages.
+	FlowControl(subscription string) (FlowControl, bool)
+
+	// SetFlowControl applies new flow control settings to a subscription
+	// by its Encore name.
+	SetFlowControl(subscription string, fc FlowControl) error
+}
+
+// IdleReleaser is implemented by topics whose subscriptions can release
+// most of their resources while they aren't receiving messages.
+type IdleReleaser interface {
+	// SetIdleTimeout makes a subscription by its Encore name release its
+	// resources once no messages have been received for timeout, re-establishing
+	// them when the next message is received. It reports false if the subscription
+	// is not receiving messages.
+	SetIdleTimeout(subscription string, timeout time.Duration) bool
+
+	// Idle reports whether a subscription by its Encore name
+	// has currently released its resources.
+	Idle(subscription string) bool
+}
+
+// AsyncAcker is implemented by topics whose subscriptions can acknowledge
+// successfully processed messages without waiting for the messaging service.
+type AsyncAcker interface {
+	// SetAsyncAck makes a subscription by its Encore name pass the acknowledgement
+	// of each successfully processed message to ack, which completes it in the
+	// background. ack may call the given function again if it returns an error.
+	// Messages which were not processed successfully are still negatively
+	// acknowledged before processing completes.
+	//
+	// It is called before the subscription subscribes, and takes effect when it does.
+	// It reports false if the subscription can't acknowledge messages in the background.
+	SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool
+}
+
+// TopologyChecker is implemented by topics whose provider
+// can report the topology which exists at the provider.
+type TopologyChecker interface {
+	// CheckTopology compares the topic and its subscriptions
+	// against what exists at the provider.
+	CheckTopology(ctx context.Context) ([]TopologyDrift, error)
+}
+
+// RetentionConfigurer is implemented by topics whose provider
+// supports configuring how long messages are retained.
+type RetentionConfigurer interface {
+	// SupportsRetention reports whether the topic's retention can be configured.
+	SupportsRetention() bool
+}
+
+// InitialPositioner is implemented by topics which know
+// where new subscriptions start receiving messages from.
+type InitialPositioner interface {
+	// NativeInitialPosition reports where new subscriptions start
+	// when no initial position is configured.
+	NativeInitialPosition() InitialPosition
+
+	// SupportsInitialPosition reports whether new subscriptions
+	// can be configured to start from the given position.
+	SupportsInitialPosition(pos InitialPosition) bool
+}
+
+// DurableConfirmer is implemented by topics which can report whether
+// the messaging service confirms a publish only once the message is durably stored.
+type DurableConfirmer interface {
+	// ConfirmsDurably reports whether PublishMessage only returns successfully
+	// once the messaging service has durably stored the message.
+	ConfirmsDurably() bool
+}
+
+// TimeSeeker is implemented by topics whose subscriptions
+// can be repositioned to a point in time.
+type TimeSeeker interface {
+	// SeekToTime repositions a subscription by its Encore name, so that
+	// messages published at or after t are delivered again, and messages
+	// published before t are considered acknowledged.
+	SeekToTime(ctx context.Context, subscription string, t time.Time) error
+}
+
+// OffsetSeeker is implemented by topics backed by a log whose
+// subscriptions can be repositioned to an offset within it.
+type OffsetSeeker interface {
+	// SeekToOffset repositions a subscription by its Encore name
+	// to a provider-specific offset within the log.
+	SeekToOffset(ctx context.Context, subscription string, offset string) error
+}
+
+// AttributeLimiter is implemented by topics whose messaging service
+// limits the size of the attributes of a message.
+type AttributeLimiter interface {
+	// MaxAttributeBytes reports the maximum total size in bytes of the names
+	// and values of the attributes of a message published to the topic.
+	MaxAttributeBytes() int
+}
+
+// SubscriptionOptioner is implemented by topics whose provider
+// applies backend-specific options to subscriptions.
+type SubscriptionOptioner interface {
+	// SetSubscriptionOptions sets the backend-specific options of a subscription
+	// by its Encore name. It is called before the subscription subscribes,
+	// and the options are applied when it does.
+	SetSubscriptionOptions(subscription string, opts SubscriptionOptions)
+}
+
+// SubscriptionOptions are the backend-specific options of a subscription.
+// Only the options for the topic's provider are applied.
+type SubscriptionOptions struct {
+	GCP *GCPSubscriptionOptions
+	NSQ *NSQSubscriptionOptions
+
+	// Filter is the subscription's FilterExpr, if any,
+	// for providers which filter messages themselves.
+	Filter string
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"time"
 )
 
@@ -81,6 +82,22 @@ const (
 	// [AWS SQS Quotas]: https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/quotas-messages.html
 	// [GCP PubSub Quotas]: https://cloud.google.com/pubsub/quotas#quotas
 	ExactlyOnce
+
+	// AtMostOnce delivers a message for a subscription to a consumer at most once,
+	// on a best-effort basis. It suits high-volume streams where losing some messages
+	// is acceptable, such as metrics or telemetry, avoiding the overhead of processing
+	// redelivered messages.
+	//
+	// Messages are acknowledged as soon as they are received, before the subscription
+	// handler runs, so they are never redelivered. This means a message is lost if
+	// the handler returns an error or panics, or if the service stops while the
+	// handler is running and does not finish before the shutdown is forced.
+	// Handler errors are logged and counted as dropped messages, but not retried,
+	// so subscriptions to the topic must not set a RetryPolicy.
+	//
+	// The messaging service itself still delivers messages at least once,
+	// so rare duplicates are possible if an acknowledgement is lost.
+	AtMostOnce
 )
 
 // TopicConfig is used when creating a Topic
@@ -133,4 +150,472 @@ type TopicConfig struct {
 	// [AWS SQS Quotas]: https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/quotas-messages.html
 	// [GCP PubSub Quotas]: https://cloud.google.com/pubsub/quotas#resource_limits
 	OrderingAttribute string
+
+	// SchemaVersion is the version of the topic's message schema.
+	// Increment it when the shape of the message type changes in a way
+	// older subscribers or newer publishers need to know about.
+	//
+	// If set, it is recorded in a message attribute on publish, and subscriptions
+	// can use SubscriptionConfig.Upgrade to migrate messages published with
+	// older versions to the current shape before they are decoded.
+	//
+	// If not set, messages are published without a schema version,
+	// which subscriptions treat as version 0.
+	SchemaVersion int
+
+	// TagProducerVersion, if set, records the version of the application which
+	// published each message in a message attribute, so subscribers can tell which
+	// deploy produced a message using MessageMeta.ProducerVersion.
+	//
+	// The version is the revision of the commit the application was built from.
+	// It is off by default to avoid adding an attribute to every message on
+	// high-volume topics.
+	TagProducerVersion bool
+
+	// PublishLimit, if set, limits how fast messages can be published to the
+	// topic, protecting the messaging service from a misbehaving publisher.
+	PublishLimit *PublishLimit
+
+	// PropagateAuth, if set, records the user a message is published on behalf
+	// of, and their auth data, in message attributes. Subscriptions which set
+	// PropagateAuth process such messages on behalf of the same user.
+	//
+	// The auth data is stored in the message in plain text,
+	// so it should not contain secrets.
+	PropagateAuth bool
+
+	// CloudEvents, if set, publishes messages in the CloudEvents JSON
+	// structured format, so they can be consumed by CloudEvents-based systems.
+	// Subscriptions unwrap such messages before decoding them,
+	// regardless of this setting.
+	CloudEvents *CloudEventsCodec
+
+	// CanonicalJSON, if set, publishes messages in a canonical JSON form, so
+	// equal messages are published as identical bytes, for example for
+	// subscribers which hash or sign messages.
+	//
+	// encoding/json already sorts the keys of maps, but the order of struct
+	// fields follows their declaration, so reordering the fields of the message
+	// type, or embedding types, changes the published bytes. In the canonical
+	// form the keys of every object are sorted, there is no insignificant
+	// whitespace, and numbers are kept as encoding/json formats them.
+	// Messages wrapped in CloudEvents have their data canonicalized, but not the
+	// envelope. It has no effect on Blob messages.
+	CanonicalJSON bool
+
+	// Schema, if set, declares the schema of the topic's messages, which
+	// published messages are validated against. The schema is checked when
+	// the topic is declared, so contract mistakes are caught at startup rather
+	// than when a message is first published. See TopicSchema for details.
+	//
+	// If nil, messages are published without being validated.
+	Schema *TopicSchema
+
+	// Encryption, if set, encrypts the data of messages when they are
+	// published and decrypts it when they are received, so it is opaque to
+	// the messaging service. See Encryption for details.
+	//
+	// If nil, messages are published as-is.
+	Encryption *Encryption
+
+	// MergeAttribute, if set, decides the value of an attribute which is set both
+	// by a `pubsub-attr` field of the message and by the WithAttributes publish option.
+	// It is called with the attribute's name and both values, and returns the value
+	// to publish. If it returns an error, the message is not published and Publish
+	// returns the error.
+	//
+	// If not set, the value given to WithAttributes takes precedence.
+	//
+	// Attributes Encore sets to encode messages or to record how they were published
+	// always take precedence over both, and cannot be set by messages or publish options:
+	// doing so makes Publish return an error rather than overwriting either value.
+	// These are attributes whose names start with "encore_", the "type" attribute
+	// of topics with message variants (see VariantAttribute) and the "content-type"
+	// attribute of topics publishing CloudEvents.
+	MergeAttribute func(name, messageValue, optionValue string) (string, error)
+
+	// Owner is the name of the service which owns the topic, and which is
+	// expected to publish to it. It is informational unless EnforceOwnership is set.
+	Owner string
+
+	// AllowedPublishers lists the services, other than the Owner,
+	// which are allowed to publish to the topic.
+	AllowedPublishers []string
+
+	// EnforceOwnership configures how publishes from services other than the
+	// Owner and AllowedPublishers are handled. It requires Owner to be set.
+	//
+	// The publishing service is determined from the request being processed,
+	// so publishes made outside of a request, such as from background
+	// goroutines, are not checked.
+	//
+	// Defaults to OwnershipNotEnforced.
+	EnforceOwnership OwnershipEnforcement
+
+	// Retention is how long the messaging service retains messages published
+	// to the topic, including messages which have been acknowledged. It is
+	// part of the topic's declared topology, which CheckTopology verifies:
+	// topics whose retention at the provider differs are reported, so the
+	// provisioned topic can be updated to match. It isn't applied by Encore.
+	//
+	// Only GCP Pub/Sub supports configuring retention, between 10 minutes
+	// and 31 days; longer or shorter values are clamped to that range.
+	// A warning is logged if it is set for a topic backed by another provider.
+	//
+	// If zero, the provider's default retention is used.
+	Retention time.Duration
+
+	// GCP configures options specific to GCP Pub/Sub, which are applied
+	// when the topic is backed by GCP Pub/Sub and ignored otherwise.
+	// A warning is logged if they are set for a topic backed by another provider.
+	GCP *GCPTopicOptions
+
+	// NSQ configures options specific to NSQ, which are applied
+	// when the topic is backed by NSQ and ignored otherwise.
+	// A warning is logged if they are set for a topic backed by another provider.
+	NSQ *NSQTopicOptions
+}
+
+// GCPTopicOptions configures how messages are published to a topic
+// backed by GCP Pub/Sub. The GCP client library batches published messages,
+// sending a batch once any of the thresholds is reached.
+//
+// Zero values use the client library's defaults.
+type GCPTopicOptions struct {
+	// PublishDelayThreshold is how long a message is batched for
+	// before the batch is sent. The library default is 10ms.
+	PublishDelayThreshold time.Duration
+
+	// PublishCountThreshold is the number of messages a batch
+	// is sent at. The library default is 100.
+	PublishCountThreshold int
+
+	// PublishByteThreshold is the size of the messages in bytes
+	// a batch is sent at. The library default is 1MB.
+	PublishByteThreshold int
+
+	// PublishTimeout is how long publishing a batch is retried for
+	// before failing. The library default is 60 seconds.
+	PublishTimeout time.Duration
+
+	// MaxAttributeBytes is the maximum total size in bytes of the names and
+	// values of a message's attributes, checked before publishing. It defaults
+	// to the most GCP Pub/Sub accepts: 100 attributes with 256 byte names and
+	// 1024 byte values.
+	MaxAttributeBytes int
+}
+
+// NSQTopicOptions configures how messages are published to a topic backed by NSQ.
+//
+// Zero values use the NSQ client library's defaults.
+type NSQTopicOptions struct {
+	// DialTimeout is the timeout for connecting to nsqd.
+	// The library default is 1 second.
+	DialTimeout time.Duration
+
+	// WriteTimeout is the timeout for writing a message to nsqd.
+	// The library default is 1 second.
+	WriteTimeout time.Duration
+
+	// MaxAttributeBytes is the maximum total size in bytes of the names and
+	// values of a message's attributes, checked before publishing. It defaults
+	// to 1MB, the default maximum size of a message accepted by nsqd.
+	MaxAttributeBytes int
+}
+
+// GCPSubscriptionOptions configures how messages are received by a
+// subscription to a topic backed by GCP Pub/Sub. They have no effect on
+// push subscriptions.
+//
+// Zero values use the client library's defaults.
+type GCPSubscriptionOptions struct {
+	// MaxExtension is the longest time the deadline of a message being
+	// processed is extended for, after which GCP Pub/Sub may redeliver it.
+	// The library default is 60 minutes.
+	MaxExtension time.Duration
+
+	// MaxExtensionPeriod is the longest period the deadline of a message
+	// is extended by at a time. The library default is to choose it
+	// based on how long messages take to be processed.
+	MaxExtensionPeriod time.Duration
+
+	// MinExtensionPeriod is the shortest period the deadline of a message
+	// is extended by at a time. The library default is 10 seconds, or
+	// 60 seconds for subscriptions with exactly-once delivery.
+	MinExtensionPeriod time.Duration
+
+	// NumGoroutines is the number of streams receiving messages.
+	// The library default is 10.
+	NumGoroutines int
+}
+
+// NSQSubscriptionOptions configures how messages are received by
+// a subscription to a topic backed by NSQ.
+//
+// Zero values use the NSQ client library's defaults.
+type NSQSubscriptionOptions struct {
+	// HeartbeatInterval is how often nsqd sends heartbeats to the subscription.
+	// The library default is 30 seconds.
+	HeartbeatInterval time.Duration
+
+	// ReadTimeout is the timeout for reading from nsqd.
+	// It must be longer than HeartbeatInterval. The library default is 60 seconds.
+	ReadTimeout time.Duration
+
+	// OutputBufferTimeout is how long nsqd buffers messages before
+	// sending them to the subscription. The library default is 250ms.
+	OutputBufferTimeout time.Duration
+}
+
+// OwnershipEnforcement configures how a topic handles publishes
+// from services which aren't allowed to publish to it.
+type OwnershipEnforcement int
+
+const (
+	// OwnershipNotEnforced allows any service to publish to the topic.
+	OwnershipNotEnforced OwnershipEnforcement = iota
+
+	// OwnershipWarn allows any service to publish to the topic,
+	// but logs a warning the first time a service which isn't
+	// allowed to publish to it does so.
+	OwnershipWarn
+
+	// OwnershipDeny rejects publishes from services which aren't allowed to
+	// publish to the topic, with a PermissionDenied error.
+	OwnershipDeny
+)
+
+// CloudEventsCodec wraps messages in CloudEvents envelopes using the
+// JSON structured format (https://cloudevents.io).
+//
+// The event's id is generated on publish, its time is the time the message
+// was published, and its data is the message as it would otherwise be published.
+// Blob messages are carried as base64 encoded data with their content type.
+type CloudEventsCodec struct {
+	// Source identifies the context in which events are published,
+	// as a URI-reference such as "https://example.com/orders" or "/orders".
+	//
+	// This field is required.
+	Source string
+
+	// Type describes the kind of event, typically
+	// in reverse-DNS form such as "com.example.order.placed".
+	//
+	// This field is required.
+	Type string
+}
+
+// TopicSchema declares the JSON Schema of a topic's messages, to catch
+// messages which break the topic's contract before they are published and
+// to share the contract with subscribers written in other languages.
+//
+// By default the schema is derived from the topic's message type by
+// reflection, following the rules of encoding/json: fields are named by their
+// `json` tags, fields tagged with omitempty are optional and all others are
+// required. Types with custom JSON marshalling are described as accepting any
+// value. Declaring a topic whose message type can't be encoded as JSON, such
+// as one containing channels or functions, panics.
+//
+// Alternatively, JSONSchema sets the schema explicitly, for example when the
+// contract is owned by another team. Declaring the topic then panics if the
+// message type can't conform to it: when the schema requires a property the
+// message type lacks, rejects one it has, or expects a property to have
+// a different JSON type.
+//
+// Published messages are validated against the schema before being published,
+// and Publish returns an InvalidArgument error for messages which don't conform.
+// Schemas can't be used for topics of Blob messages.
+type TopicSchema struct {
+	// JSONSchema is the JSON Schema document messages must conform to.
+	//
+	// If empty, the schema is derived from the topic's message type.
+	JSONSchema string
+
+	// Validator, if set, validates published messages against the schema.
+	//
+	// If nil, messages are validated by Encore's built-in validator, which
+	// supports the "type", "enum", "properties", "required",
+	// "additionalProperties" and "items" keywords and ignores any others.
+	// Use a JSON Schema library for full support of explicit schemas.
+	Validator MessageValidator
+
+	// Registry, if set, registers the schema with a schema registry, such as
+	// GCP Pub/Sub schemas or a Kafka Schema Registry, so that other systems can
+	// look it up. Each instance registers the schema in the background when the
+	// topic is created, retrying with backoff if registering it fails. Until the
+	// schema is registered, Publish returns an Unavailable error.
+	//
+	// If nil, the schema is only used for local validation.
+	Registry SchemaRegistry
+}
+
+// MessageValidator validates messages against a contract, such as a JSON Schema.
+type MessageValidator interface {
+	// ValidateMessage validates the JSON-encoded data of a message,
+	// returning an error describing how it is invalid, if it is.
+	ValidateMessage(data []byte) error
+}
+
+// SchemaRegistry registers the schemas of topics with a schema registry.
+type SchemaRegistry interface {
+	// RegisterSchema registers the JSON Schema of the topic's messages.
+	// It must be idempotent, as it is called by every instance of the service.
+	RegisterSchema(ctx context.Context, topic string, schema []byte) error
+}
+
+// Encryption configures end-to-end encryption of a topic's messages,
+// using envelope encryption: each message is encrypted using AES-256-GCM with
+// a data key, and the data key is published alongside it in encrypted form,
+// so only services which can decrypt the data key using the KeyProvider,
+// typically backed by a KMS, can read the message.
+//
+// Data keys are reused for DataKeyLifetime, so the KeyProvider is not called
+// for every message. The key ID, encrypted data key and algorithm are recorded
+// in message attributes, which are not encrypted, so attributes should not
+// contain sensitive data. Encryption is separate from the security of the
+// connection to the messaging service; it only protects the message data.
+//
+// Subscriptions decrypt messages before decoding them. Messages which can't be
+// decrypted, because they were tampered with or use an unknown algorithm, are
+// counted as decode errors and quarantined, as retrying them won't help; see
+// SubscriptionConfig.OnQuarantine. Failures to decrypt the data key using the
+// KeyProvider are handled according to SubscriptionConfig.OnDecodeError, as they
+// are often temporary. Messages published without encryption, such as before
+// it was enabled, are received as usual.
+type Encryption struct {
+	// Keys provides the data keys messages are encrypted with.
+	//
+	// This field is required.
+	Keys KeyProvider
+
+	// DataKeyLifetime is how long a data key is used to encrypt
+	// messages before a new one is generated.
+	//
+	// If zero, it defaults to 5 minutes.
+	DataKeyLifetime time.Duration
+}
+
+// KeyProvider provides data keys for envelope encryption,
+// typically using a key management service.
+type KeyProvider interface {
+	// GenerateDataKey generates a new 256-bit data key,
+	// returning it both in plaintext and encrypted form.
+	GenerateDataKey(ctx context.Context) (*DataKey, error)
+
+	// DecryptDataKey decrypts a data key generated by GenerateDataKey,
+	// given its key ID and encrypted form.
+	DecryptDataKey(ctx context.Context, keyID string, encrypted []byte) ([]byte, error)
+}
+
+// DataKey is a data key generated by a KeyProvider.
+type DataKey struct {
+	// KeyID identifies the key the data key was encrypted with.
+	KeyID string
+
+	// Plaintext is the data key, which must be 32 bytes long.
+	// It is never published.
+	Plaintext []byte
+
+	// Encrypted is the data key encrypted using the key identified by KeyID.
+	Encrypted []byte
+}
+
+// PublishLimit limits the rate messages are published to a topic,
+// using a token bucket.
+//
+// The limit applies to each instance of the service separately,
+// so the total rate scales with the number of instances.
+type PublishLimit struct {
+	// MaxPerSecond is the number of messages per second
+	// which can be published on average.
+	//
+	// This field is required.
+	MaxPerSecond int
+
+	// Burst is the number of messages which can be published at once
+	// when the topic has been idle, above MaxPerSecond.
+	// If zero, it defaults to MaxPerSecond.
+	Burst int
+
+	// Reject, if set, makes Publish return a ResourceExhausted error
+	// straight away when the limit is exceeded, instead of waiting until
+	// the message can be published within the limit.
+	Reject bool
+}
+
+// FlowControl limits how much unacknowledged work a subscription
+// pulls from the messaging service at once.
+type FlowControl struct {
+	// MaxOutstandingMessages is the maximum number of messages which
+	// have been received but not yet acknowledged.
+	// A value of -1 means no limit.
+	MaxOutstandingMessages int
+
+	// MaxOutstandingBytes is the maximum total size of messages which
+	// have been received but not yet acknowledged.
+	// A value of -1 means no limit.
+	MaxOutstandingBytes int
+}
+
+// TopologyDriftKind describes how a topic or subscription
+// differs from what the application declares.
+type TopologyDriftKind string
+
+const (
+	// TopicMissing means the topic does not exist at the provider.
+	TopicMissing TopologyDriftKind = "topic_missing"
+
+	// SubscriptionMissing means a declared subscription
+	// does not exist at the provider.
+	SubscriptionMissing TopologyDriftKind = "subscription_missing"
+
+	// SubscriptionExtra means a subscription exists at the provider
+	// which the application does not declare.
+	SubscriptionExtra TopologyDriftKind = "subscription_extra"
+
+	// ConfigMismatch means a subscription exists at the provider
+	// but is configured differently than declared.
+	ConfigMismatch TopologyDriftKind = "config_mismatch"
+)
+
+// TopologyDrift is a difference between the topology declared
+// by the application and what exists at the provider.
+type TopologyDrift struct {
+	Kind         TopologyDriftKind
+	Topic        string // the topic name
+	Subscription string // the subscription name, if the drift relates to a subscription
+
+	// Field is the mismatched configuration field, for ConfigMismatch.
+	Field string
+	// Declared and Actual are the declared and actual values
+	// of the mismatched field, for ConfigMismatch.
+	Declared, Actual string
+}
+
+// InitialPosition determines which messages a newly created
+// subscription receives.
+type InitialPosition int
+
+const (
+	// InitialPositionDefault uses the provider's native behaviour.
+	InitialPositionDefault InitialPosition = iota
+
+	// InitialPositionEarliest means a new subscription receives
+	// messages retained on the topic from before it was created.
+	InitialPositionEarliest
+
+	// InitialPositionLatest means a new subscription only receives
+	// messages published after it was created.
+	InitialPositionLatest
+)
+
+func (p InitialPosition) String() string {
+	switch p {
+	case InitialPositionEarliest:
+		return "earliest"
+	case InitialPositionLatest:
+		return "latest"
+	default:
+		return "default"
+	}
 }
@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed means calls are allowed through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means calls are rejected until the open duration has passed.
+	BreakerOpen
+	// BreakerHalfOpen means a limited number of probe calls are allowed
+	// through to determine whether to close the breaker again.
+	BreakerHalfOpen
+)
+
+// CircuitBreaker tracks consecutive failures of an operation and
+// stops allowing calls once a threshold has been reached.
+//
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	halfOpenProbes   int
+	now              func() time.Time
+
+	mu               sync.Mutex
+	state            BreakerState
+	consecutiveFails int
+	openedAt         time.Time
+	probesInFlight   int
+	probeSuccesses   int
+	changed          chan struct{} // closed when a call is reported; nil if nobody is waiting
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker which opens after failureThreshold
+// consecutive failures, stays open for openDuration and then requires halfOpenProbes
+// consecutive successful probes before closing again.
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration, halfOpenProbes int) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		halfOpenProbes:   halfOpenProbes,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call should be made.
+//
+// Every call to Allow which returns true must be followed
+// by exactly one call to Report with the outcome of the call.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.allowLocked()
+}
+
+// Wait waits until Allow would report that a call can be made, and then
+// reports so in the same way, or returns ctx's error if it is done first.
+//
+// Like a call to Allow which returns true, a nil error
+// must be followed by exactly one call to Report.
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if b.allowLocked() {
+			b.mu.Unlock()
+			return nil
+		}
+		if b.changed == nil {
+			b.changed = make(chan struct{})
+		}
+		changed := b.changed
+
+		// An open breaker lets probes through once the open duration has passed,
+		// while a half-open one does once the probes in flight are reported.
+		var (
+			timer  *time.Timer
+			reopen <-chan time.Time
+		)
+		if b.state == BreakerOpen {
+			timer = time.NewTimer(b.openedAt.Add(b.openDuration).Sub(b.now()))
+			reopen = timer.C
+		}
+		b.mu.Unlock()
+
+		var err error
+		select {
+		case <-changed:
+		case <-reopen:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *CircuitBreaker) allowLocked() bool {
+	switch b.state {
+	case BreakerClosed:
+		return true
+
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probesInFlight = 0
+		b.probeSuccesses = 0
+		fallthrough
+
+	case BreakerHalfOpen:
+		// Only allow enough probes through to close the breaker
+		if b.probesInFlight+b.probeSuccesses >= b.halfOpenProbes {
+			return false
+		}
+		b.probesInFlight++
+		return true
+	}
+
+	return false
+}
+
+// Report records the outcome of a call allowed by Allow.
+func (b *CircuitBreaker) Report(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.notifyLocked()
+
+	switch b.state {
+	case BreakerClosed:
+		if success {
+			b.consecutiveFails = 0
+			return
+		}
+		b.consecutiveFails++
+		if b.consecutiveFails >= b.failureThreshold {
+			b.open()
+		}
+
+	case BreakerHalfOpen:
+		b.probesInFlight--
+		if !success {
+			b.open()
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.halfOpenProbes {
+			b.state = BreakerClosed
+			b.consecutiveFails = 0
+		}
+
+	case BreakerOpen:
+		// A call allowed before the breaker opened has completed;
+		// its outcome doesn't change anything.
+	}
+}
+
+// Cancel releases a call allowed by Allow or Wait which was never made,
+// without recording an outcome for it. It is used in place of Report.
+func (b *CircuitBreaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probesInFlight--
+		b.notifyLocked()
+	}
+}
+
+// State reports the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		// The breaker will allow a probe through on the next call
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.consecutiveFails = 0
+}
+
+// notifyLocked wakes up any calls waiting in Wait.
+func (b *CircuitBreaker) notifyLocked() {
+	if b.changed != nil {
+		close(b.changed)
+		b.changed = nil
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+	now := time.Now()
+	b := NewCircuitBreaker(3, time.Minute, 2)
+	b.now = func() time.Time { return now }
+
+	call := func(success bool) bool {
+		if !b.Allow() {
+			return false
+		}
+		b.Report(success)
+		return true
+	}
+
+	// A success resets the consecutive failure count
+	c.Assert(call(false), qt.IsTrue)
+	c.Assert(call(false), qt.IsTrue)
+	c.Assert(call(true), qt.IsTrue)
+	c.Assert(call(false), qt.IsTrue)
+	c.Assert(call(false), qt.IsTrue)
+	c.Assert(b.State(), qt.Equals, BreakerClosed)
+
+	// The third consecutive failure opens the breaker
+	c.Assert(call(false), qt.IsTrue)
+	c.Assert(b.State(), qt.Equals, BreakerOpen)
+	c.Assert(b.Allow(), qt.IsFalse)
+
+	// After the open duration, only the configured number of probes are allowed
+	now = now.Add(time.Minute)
+	c.Assert(b.State(), qt.Equals, BreakerHalfOpen)
+	c.Assert(b.Allow(), qt.IsTrue)
+	c.Assert(b.Allow(), qt.IsTrue)
+	c.Assert(b.Allow(), qt.IsFalse)
+
+	// A probe which is cancelled lets another one through
+	b.Cancel()
+	c.Assert(b.Allow(), qt.IsTrue)
+
+	// A failed probe reopens the breaker
+	b.Report(true)
+	b.Report(false)
+	c.Assert(b.State(), qt.Equals, BreakerOpen)
+	c.Assert(b.Allow(), qt.IsFalse)
+
+	// Enough successful probes close it again
+	now = now.Add(time.Minute)
+	c.Assert(call(true), qt.IsTrue)
+	c.Assert(b.State(), qt.Equals, BreakerHalfOpen)
+	c.Assert(call(true), qt.IsTrue)
+	c.Assert(b.State(), qt.Equals, BreakerClosed)
+	c.Assert(call(true), qt.IsTrue)
+}
+
+func TestCircuitBreaker_Wait(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	b := NewCircuitBreaker(1, 50*time.Millisecond, 1)
+
+	// A closed breaker doesn't wait
+	c.Assert(b.Wait(ctx), qt.IsNil)
+	b.Report(false)
+	c.Assert(b.State(), qt.Equals, BreakerOpen)
+
+	// An open breaker waits until ctx is done
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	c.Assert(b.Wait(shortCtx), qt.Equals, context.DeadlineExceeded)
+
+	// ... or until it lets a probe through once the open duration has passed
+	start := time.Now()
+	c.Assert(b.Wait(ctx), qt.IsNil)
+	c.Assert(time.Since(start) > 20*time.Millisecond, qt.IsTrue)
+	c.Assert(b.State(), qt.Equals, BreakerHalfOpen)
+
+	// A waiter held back by the probe in flight is let through once it succeeds
+	done := make(chan error, 1)
+	go func() { done <- b.Wait(ctx) }()
+	select {
+	case err := <-done:
+		c.Fatalf("Wait returned %v while the probe was in flight", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	b.Report(true)
+	c.Assert(<-done, qt.IsNil)
+	c.Assert(b.State(), qt.Equals, BreakerClosed)
+	b.Report(true)
+}
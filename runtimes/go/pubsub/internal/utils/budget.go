@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// ByteBudget tracks the number of bytes held by concurrent operations,
+// making operations wait while holding more would exceed a limit.
+//
+// An operation is always admitted when nothing else is held, so that
+// a single operation larger than the limit can still make progress.
+//
+// It is safe for concurrent use.
+type ByteBudget struct {
+	mu      sync.Mutex
+	limit   int64         // the maximum bytes held at once, or 0 for no limit
+	used    int64         // the bytes currently held
+	changed chan struct{} // closed and replaced whenever bytes are released or the limit changes
+}
+
+// NewByteBudget creates a ByteBudget with no limit.
+func NewByteBudget() *ByteBudget {
+	return &ByteBudget{changed: make(chan struct{})}
+}
+
+// SetLimit changes the maximum number of bytes held at once.
+// A limit of 0 means there is no limit.
+func (b *ByteBudget) SetLimit(limit int64) {
+	if limit < 0 {
+		panic("ByteBudget limit cannot be negative")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.notifyLocked()
+}
+
+// Acquire waits until n bytes can be held within the limit.
+// Once it returns nil, Release must be called with n when the operation completes.
+// If ctx is done before the bytes are available, Acquire returns ctx.Err().
+func (b *ByteBudget) Acquire(ctx context.Context, n int64) error {
+	for {
+		b.mu.Lock()
+		if b.limit == 0 || b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release returns n bytes held by a completed operation.
+func (b *ByteBudget) Release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	b.notifyLocked()
+}
+
+// Used reports the number of bytes currently held.
+func (b *ByteBudget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+func (b *ByteBudget) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
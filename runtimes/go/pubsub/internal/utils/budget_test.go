@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestByteBudget(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	b := NewByteBudget()
+
+	// Without a limit everything is admitted
+	c.Assert(b.Acquire(ctx, 1000), qt.IsNil)
+	c.Assert(b.Used(), qt.Equals, int64(1000))
+	b.Release(1000)
+
+	// Operations larger than the limit are admitted when nothing else is held
+	b.SetLimit(100)
+	c.Assert(b.Acquire(ctx, 150), qt.IsNil)
+
+	// But others wait until it is released
+	admitted := make(chan struct{})
+	go func() {
+		c.Check(b.Acquire(ctx, 60), qt.IsNil)
+		close(admitted)
+	}()
+	select {
+	case <-admitted:
+		c.Fatal("admitted over the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.Release(150)
+	<-admitted
+	c.Assert(b.Used(), qt.Equals, int64(60))
+
+	// Waiting operations give up when their context is done
+	ctx2, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	c.Assert(b.Acquire(ctx2, 60), qt.Equals, context.DeadlineExceeded)
+	c.Assert(b.Used(), qt.Equals, int64(60))
+
+	// Raising the limit admits waiting operations
+	admitted = make(chan struct{})
+	go func() {
+		c.Check(b.Acquire(ctx, 60), qt.IsNil)
+		close(admitted)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	b.SetLimit(200)
+	<-admitted
+	c.Assert(b.Used(), qt.Equals, int64(120))
+}
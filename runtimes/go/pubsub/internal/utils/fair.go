@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// FairGate limits the number of concurrent operations, and when operations
+// are waiting for a slot, admits one from the key with the fewest operations
+// running, so that no single key can monopolize the slots. Operations with
+// the same key are admitted in the order they started waiting.
+//
+// Optionally, it also limits the number of concurrent operations per key.
+//
+// It is safe for concurrent use.
+type FairGate struct {
+	limit       int
+	limitPerKey int // 0 if unlimited
+
+	mu      sync.Mutex
+	running int
+	keys    map[string]*fairKey
+	order   []string // keys with waiting operations, in the order they started waiting
+	waiting int
+}
+
+// fairKey is the state of the operations with a key.
+type fairKey struct {
+	running int
+	waiting []*fairWaiter
+}
+
+type fairWaiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// NewFairGate creates a FairGate which allows limit concurrent operations,
+// and limitPerKey concurrent operations with the same key.
+// If limitPerKey is zero, only the overall limit applies.
+func NewFairGate(limit, limitPerKey int) *FairGate {
+	if limit <= 0 {
+		panic("FairGate limit must be positive")
+	}
+	if limitPerKey < 0 {
+		panic("FairGate limitPerKey cannot be negative")
+	}
+	return &FairGate{limit: limit, limitPerKey: limitPerKey, keys: make(map[string]*fairKey)}
+}
+
+// Acquire waits for a slot for an operation with the given key.
+// Once it returns nil, Release must be called with the same key when the operation completes.
+// If ctx is done before a slot is available, Acquire returns ctx.Err().
+func (g *FairGate) Acquire(ctx context.Context, key string) error {
+	g.mu.Lock()
+	k := g.keys[key]
+	if k == nil {
+		k = &fairKey{}
+		g.keys[key] = k
+	}
+	if g.running < g.limit && g.waiting == 0 && g.keyAllows(k) {
+		g.running++
+		k.running++
+		g.mu.Unlock()
+		return nil
+	}
+	w := &fairWaiter{ready: make(chan struct{})}
+	if len(k.waiting) == 0 {
+		g.order = append(g.order, key)
+	}
+	k.waiting = append(k.waiting, w)
+	g.waiting++
+	// Slots may be free for other keys while this one is at its limit
+	g.admitLocked()
+	g.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if w.admitted {
+			// We were handed a slot at the same time; pass it on
+			g.releaseLocked(key)
+		} else {
+			g.removeWaiterLocked(key, w)
+		}
+		return ctx.Err()
+	}
+}
+
+// Release frees the slot of a completed operation with the given key,
+// admitting waiting operations, if any.
+func (g *FairGate) Release(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releaseLocked(key)
+}
+
+// Waiting reports the number of operations waiting for a slot.
+func (g *FairGate) Waiting() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.waiting
+}
+
+// WaitingFor reports the number of operations with the given key waiting for a slot.
+func (g *FairGate) WaitingFor(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if k := g.keys[key]; k != nil {
+		return len(k.waiting)
+	}
+	return 0
+}
+
+func (g *FairGate) releaseLocked(key string) {
+	g.running--
+	k := g.keys[key]
+	k.running--
+	g.admitLocked()
+	g.forgetLocked(key, k)
+}
+
+// admitLocked hands free slots to waiting operations, favouring the keys
+// with the fewest operations running.
+func (g *FairGate) admitLocked() {
+	for g.running < g.limit {
+		best := -1
+		var bestKey *fairKey
+		for i, key := range g.order {
+			k := g.keys[key]
+			if g.keyAllows(k) && (bestKey == nil || k.running < bestKey.running) {
+				best, bestKey = i, k
+			}
+		}
+		if bestKey == nil {
+			return
+		}
+
+		key := g.order[best]
+		w := bestKey.waiting[0]
+		bestKey.waiting = bestKey.waiting[1:]
+		g.waiting--
+		g.order = append(g.order[:best], g.order[best+1:]...)
+		if len(bestKey.waiting) > 0 {
+			// Let the other keys go first next time
+			g.order = append(g.order, key)
+		}
+		g.running++
+		bestKey.running++
+		w.admitted = true
+		close(w.ready)
+	}
+}
+
+func (g *FairGate) removeWaiterLocked(key string, w *fairWaiter) {
+	k := g.keys[key]
+	for i, other := range k.waiting {
+		if other == w {
+			k.waiting = append(k.waiting[:i], k.waiting[i+1:]...)
+			g.waiting--
+			break
+		}
+	}
+	if len(k.waiting) == 0 {
+		for i, other := range g.order {
+			if other == key {
+				g.order = append(g.order[:i], g.order[i+1:]...)
+				break
+			}
+		}
+	}
+	g.forgetLocked(key, k)
+}
+
+// forgetLocked drops the state of a key once it has no operations,
+// so the gate doesn't grow with the number of distinct keys seen.
+func (g *FairGate) forgetLocked(key string, k *fairKey) {
+	if k.running == 0 && len(k.waiting) == 0 {
+		delete(g.keys, key)
+	}
+}
+
+func (g *FairGate) keyAllows(k *fairKey) bool {
+	return g.limitPerKey == 0 || k.running < g.limitPerKey
+}
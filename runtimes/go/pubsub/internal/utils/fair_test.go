@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFairGate(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	g := NewFairGate(2, 0)
+
+	// The first operations get slots straight away, even for the same key
+	c.Assert(g.Acquire(ctx, "noisy"), qt.IsNil)
+	c.Assert(g.Acquire(ctx, "noisy"), qt.IsNil)
+
+	// Queue up a burst for the noisy key, followed by a quiet key
+	admitted := make(chan string, 4)
+	queue := func(key string) {
+		n := g.Waiting()
+		go func() {
+			c.Check(g.Acquire(ctx, key), qt.IsNil)
+			admitted <- key
+		}()
+		for g.Waiting() <= n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	queue("noisy")
+	queue("noisy")
+	queue("quiet")
+	c.Assert(g.WaitingFor("noisy"), qt.Equals, 2)
+
+	// The quiet key goes first, as it has nothing running
+	g.Release("noisy")
+	c.Assert(<-admitted, qt.Equals, "quiet")
+	g.Release("noisy")
+	c.Assert(<-admitted, qt.Equals, "noisy")
+	g.Release("quiet")
+	c.Assert(<-admitted, qt.Equals, "noisy")
+	g.Release("noisy")
+	g.Release("noisy")
+
+	// A waiter whose context ends leaves the queue
+	c.Assert(g.Acquire(ctx, "a"), qt.IsNil)
+	c.Assert(g.Acquire(ctx, "a"), qt.IsNil)
+	cancelled, cancel := context.WithCancel(ctx)
+	errc := make(chan error, 1)
+	go func() { errc <- g.Acquire(cancelled, "b") }()
+	for g.Waiting() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	c.Assert(<-errc, qt.Equals, context.Canceled)
+	c.Assert(g.Waiting(), qt.Equals, 0)
+	g.Release("a")
+	g.Release("a")
+
+	// The state of keys with no operations is dropped
+	c.Assert(g.keys, qt.HasLen, 0)
+}
+
+func TestFairGate_LimitPerKey(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	g := NewFairGate(3, 1)
+
+	c.Assert(g.Acquire(ctx, "noisy"), qt.IsNil)
+
+	// Further operations for the key wait despite free slots
+	admitted := make(chan string, 1)
+	go func() {
+		c.Check(g.Acquire(ctx, "noisy"), qt.IsNil)
+		admitted <- "noisy"
+	}()
+	for g.Waiting() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// While other keys are let through
+	c.Assert(g.Acquire(ctx, "quiet"), qt.IsNil)
+	g.Release("quiet")
+
+	g.Release("noisy")
+	c.Assert(<-admitted, qt.Equals, "noisy")
+	g.Release("noisy")
+}
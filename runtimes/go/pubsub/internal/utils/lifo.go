@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// LIFOGate limits the number of concurrent operations, and when operations
+// are waiting for a slot, admits the one with the most recent timestamp first.
+//
+// It is safe for concurrent use.
+type LIFOGate struct {
+	limit int
+
+	mu      sync.Mutex
+	running int
+	waiting lifoQueue
+}
+
+// NewLIFOGate creates a LIFOGate which allows limit concurrent operations.
+func NewLIFOGate(limit int) *LIFOGate {
+	if limit <= 0 {
+		panic("LIFOGate limit must be positive")
+	}
+	return &LIFOGate{limit: limit}
+}
+
+// Acquire waits for a slot for an operation with the given timestamp.
+// Once it returns nil, Release must be called when the operation completes.
+// If ctx is done before a slot is available, Acquire returns ctx.Err().
+func (g *LIFOGate) Acquire(ctx context.Context, ts time.Time) error {
+	g.mu.Lock()
+	if g.running < g.limit && g.waiting.Len() == 0 {
+		g.running++
+		g.mu.Unlock()
+		return nil
+	}
+	w := &lifoWaiter{ts: ts, ready: make(chan struct{})}
+	heap.Push(&g.waiting, w)
+	g.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if w.index < 0 {
+			// We were handed a slot at the same time; pass it on
+			g.releaseLocked()
+		} else {
+			heap.Remove(&g.waiting, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// Release frees the slot of a completed operation,
+// admitting the most recent waiting operation, if any.
+func (g *LIFOGate) Release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releaseLocked()
+}
+
+func (g *LIFOGate) releaseLocked() {
+	if g.waiting.Len() > 0 {
+		// Hand the slot straight to the newest waiter
+		w := heap.Pop(&g.waiting).(*lifoWaiter)
+		close(w.ready)
+		return
+	}
+	g.running--
+}
+
+// Waiting reports the number of operations waiting for a slot.
+func (g *LIFOGate) Waiting() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.waiting.Len()
+}
+
+type lifoWaiter struct {
+	ts    time.Time
+	ready chan struct{}
+	index int // index in the queue, or -1 once admitted
+}
+
+// lifoQueue is a max-heap of waiters ordered by timestamp.
+type lifoQueue []*lifoWaiter
+
+func (q lifoQueue) Len() int           { return len(q) }
+func (q lifoQueue) Less(i, j int) bool { return q[i].ts.After(q[j].ts) }
+func (q lifoQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *lifoQueue) Push(x any) {
+	w := x.(*lifoWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *lifoQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
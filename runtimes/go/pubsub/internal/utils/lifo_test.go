@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLIFOGate(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+	g := NewLIFOGate(1)
+	base := time.Now()
+
+	// The first operation gets a slot straight away
+	c.Assert(g.Acquire(ctx, base), qt.IsNil)
+
+	// Queue up operations, oldest first
+	admitted := make(chan int, 3)
+	for i := 1; i <= 3; i++ {
+		i := i
+		go func() {
+			c.Check(g.Acquire(ctx, base.Add(time.Duration(i)*time.Second)), qt.IsNil)
+			admitted <- i
+		}()
+		for g.Waiting() < i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A waiter whose context ends leaves the queue
+	cancelled, cancel := context.WithCancel(ctx)
+	errc := make(chan error, 1)
+	go func() { errc <- g.Acquire(cancelled, base.Add(time.Hour)) }()
+	for g.Waiting() < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	c.Assert(<-errc, qt.Equals, context.Canceled)
+	c.Assert(g.Waiting(), qt.Equals, 3)
+
+	// Waiters are admitted newest first
+	for _, want := range []int{3, 2, 1} {
+		g.Release()
+		c.Assert(<-admitted, qt.Equals, want)
+	}
+	g.Release()
+
+	// With nothing waiting, slots are freed again
+	c.Assert(g.Acquire(ctx, base), qt.IsNil)
+	g.Release()
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size bounded, least recently used cache where each entry
+// additionally expires after a fixed time-to-live.
+//
+// It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	maxSize int
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	order   *list.List // front is the most recently used entry
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU creates a new LRU which holds at most maxSize entries,
+// each of which expires ttl after it was last set.
+//
+// If ttl <= 0 entries never expire and are only evicted due to size.
+func NewLRU[K comparable, V any](maxSize int, ttl time.Duration) *LRU[K, V] {
+	if maxSize <= 0 {
+		panic("lru: maxSize must be positive")
+	}
+	return &LRU[K, V]{
+		maxSize: maxSize,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[K]*list.Element, maxSize),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value stored for key, if present and not expired.
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return value, false
+	}
+
+	entry := elem.Value.(*lruEntry[K, V])
+	if c.ttl > 0 && !c.now().Before(entry.expires) {
+		c.removeElement(elem)
+		return value, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value for key, replacing any existing value and resetting its expiry.
+// If the cache is full the least recently used entry is evicted.
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if elem, found := c.entries[key]; found {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.maxSize {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Delete removes key from the cache, if present.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.entries[key]; found {
+		c.removeElement(elem)
+	}
+}
+
+// Len reports the number of entries in the cache, including any
+// which have expired but not yet been evicted.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry[K, V])
+	delete(c.entries, entry.key)
+}
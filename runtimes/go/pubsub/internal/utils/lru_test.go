@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := qt.New(t)
+	cache := NewLRU[string, int](2, 0)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	_, ok := cache.Get("a")
+	c.Assert(ok, qt.IsTrue)
+
+	cache.Set("c", 3)
+	c.Assert(cache.Len(), qt.Equals, 2)
+
+	_, ok = cache.Get("b")
+	c.Assert(ok, qt.IsFalse)
+
+	v, ok := cache.Get("a")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(v, qt.Equals, 1)
+
+	v, ok = cache.Get("c")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(v, qt.Equals, 3)
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	c := qt.New(t)
+	now := time.Now()
+	cache := NewLRU[string, int](10, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", 1)
+	now = now.Add(59 * time.Second)
+	_, ok := cache.Get("a")
+	c.Assert(ok, qt.IsTrue)
+
+	// Setting a key again resets its expiry
+	cache.Set("a", 2)
+	now = now.Add(59 * time.Second)
+	v, ok := cache.Get("a")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(v, qt.Equals, 2)
+
+	now = now.Add(time.Second)
+	_, ok = cache.Get("a")
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(cache.Len(), qt.Equals, 0)
+}
+
+func TestLRU_Delete(t *testing.T) {
+	c := qt.New(t)
+	cache := NewLRU[string, int](10, 0)
+
+	cache.Set("a", 1)
+	cache.Delete("a")
+	cache.Delete("missing")
+
+	_, ok := cache.Get("a")
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(cache.Len(), qt.Equals, 0)
+}
@@ -235,6 +235,32 @@ func GetDelay(maxRetries int, minDelay, maxDelay time.Duration, attempt uint16)
 	return true, backoff
 }
 
+// RetryAfterError is returned by subscription handlers to request that
+// a message is retried after a specific delay, instead of the backoff
+// computed from the RetryPolicy.
+type RetryAfterError struct {
+	Delay time.Duration // how long to wait before retrying the message
+	Err   error         // the reason the message failed
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryDelay returns the delay requested by a RetryAfterError in err's chain,
+// clamped to be at most maxDelay, or backoff if err doesn't request one.
+func RetryDelay(err error, backoff, maxDelay time.Duration) time.Duration {
+	var retryAfter *RetryAfterError
+	if !errors.As(err, &retryAfter) {
+		return backoff
+	}
+	return Clamp(retryAfter.Delay, 0, maxDelay)
+}
+
 // WithDefaultValue returns setValue if it is a non zero value, otherwise it returns defaultValue
 func WithDefaultValue[T comparable](setValue, defaultValue T) T {
 	var zeroValue T
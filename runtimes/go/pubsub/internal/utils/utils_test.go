@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime/debug"
@@ -206,3 +207,21 @@ func TestGetDelay(t *testing.T) {
 
 	}
 }
+
+func TestRetryDelay(t *testing.T) {
+	backoff, maxDelay := 10*time.Second, time.Minute
+	cause := errors.New("rate limited")
+
+	// Errors without a requested delay use the backoff
+	Assert(t, RetryDelay(cause, backoff, maxDelay), Equals, backoff)
+	Assert(t, RetryDelay(nil, backoff, maxDelay), Equals, backoff)
+
+	// Requested delays are used, even when wrapped, clamped to the max delay
+	Assert(t, RetryDelay(&RetryAfterError{Delay: 30 * time.Second, Err: cause}, backoff, maxDelay), Equals, 30*time.Second)
+	Assert(t, RetryDelay(fmt.Errorf("wrapped: %w", &RetryAfterError{Delay: time.Second, Err: cause}), backoff, maxDelay), Equals, time.Second)
+	Assert(t, RetryDelay(&RetryAfterError{Delay: time.Hour, Err: cause}, backoff, maxDelay), Equals, maxDelay)
+	Assert(t, RetryDelay(&RetryAfterError{Delay: -time.Second, Err: cause}, backoff, maxDelay), Equals, time.Duration(0))
+
+	// The cause is preserved
+	Assert(t, errors.Is(&RetryAfterError{Delay: time.Second, Err: cause}, cause), IsTrue)
+}
@@ -3,7 +3,9 @@ package pubsub
 import (
 	"context"
 	"net/http"
+	"reflect"
 	"sync"
+	"sync/atomic"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog"
@@ -13,6 +15,7 @@ import (
 	"encore.dev/appruntime/shared/shutdown"
 	"encore.dev/appruntime/shared/testsupport"
 	"encore.dev/beta/errs"
+	"encore.dev/metrics"
 	"encore.dev/pubsub/internal/types"
 	"encore.dev/pubsub/internal/utils"
 )
@@ -25,16 +28,37 @@ type Manager struct {
 	ts         *testsupport.Manager
 	rootLogger zerolog.Logger
 	json       jsoniter.API
+	metrics    *metrics.Registry // where the subscriptions' metrics are recorded, if anywhere
 	providers  []provider
 
-	publishCounter  uint64
+	publishCounter  atomic.Uint64 // number of messages published under test; see PublishCount
 	pushHandlers    map[types.SubscriptionID]http.HandlerFunc
 	runningFetches  sync.WaitGroup
 	runningHandlers sync.WaitGroup
+	draining        atomic.Bool                                 // set once Shutdown has begun; see IsDraining
+	inFlight        sync.Map                                    // the *InFlightInfo of each running handler; see InFlight
+	spanCaptures    sync.Map                                    // the *spanCapture of each test capturing message spans, keyed by *testing.T
+	authDataType    func() reflect.Type                         // the auth handler's data type, if any; see SetAuthDataType
+	initPaused      sync.Map                                    // the service of each subscription paused because it failed to initialize, keyed by subscriptionKey
+	buffered        *utils.ByteBudget                           // bytes of messages being processed across subscriptions; see SetBufferBudget
+	onConfigError   func(topic, subscription string, err error) // handles subscriptions not matching the application; see OnConfigError
+	onHandlerPanic  atomic.Pointer[func(PanicInfo)]             // observes handler panics; see OnHandlerPanic
+	debugSink       *debugSink                                  // records published messages during local development, if enabled
+	dropped         dropCounter                                 // messages dropped by each subscription; see DroppedMessages
+
+	subsMu sync.Mutex                                    // subsMu protects access to the subs and topics maps
+	subs   map[subscriptionKey]types.TopicImplementation // The topic implementation of each active subscription
+	topics map[string]types.TopicImplementation          // The implementation of each topic, keyed by topic name
+}
+
+// subscriptionKey identifies a subscription by its topic and subscription names.
+type subscriptionKey struct {
+	topic        string
+	subscription string
 }
 
 func NewManager(static *config.Static, runtime *config.Runtime, rt *reqtrack.RequestTracker,
-	ts *testsupport.Manager, rootLogger zerolog.Logger, json jsoniter.API) *Manager {
+	ts *testsupport.Manager, rootLogger zerolog.Logger, json jsoniter.API, reg *metrics.Registry) *Manager {
 	mgr := &Manager{
 		ctxs:         utils.NewContexts(context.Background()),
 		static:       static,
@@ -43,7 +67,12 @@ func NewManager(static *config.Static, runtime *config.Runtime, rt *reqtrack.Req
 		ts:           ts,
 		rootLogger:   rootLogger,
 		json:         json,
+		metrics:      reg,
 		pushHandlers: make(map[types.SubscriptionID]http.HandlerFunc),
+		buffered:     utils.NewByteBudget(),
+		subs:         make(map[subscriptionKey]types.TopicImplementation),
+		topics:       make(map[string]types.TopicImplementation),
+		debugSink:    newDebugSinkFromEnv(runtime.EnvCloud, rootLogger),
 	}
 
 	for _, p := range providerRegistry {
@@ -55,29 +84,65 @@ func NewManager(static *config.Static, runtime *config.Runtime, rt *reqtrack.Req
 
 // Shutdown stops the manager from fetching new messages and processing them.
 func (mgr *Manager) Shutdown(p *shutdown.Process) error {
-	// Once it's time to force-close tasks, cancel the base context.
+	return mgr.shutdown(p.Log, p.ForceCloseTasks, p.MarkOutstandingPubSubMessagesCompleted)
+}
+
+// shutdown implements Shutdown using the parts of the shutdown process it depends on:
+// handlers are force-closed once forceCloseTasks is done, and handlersCompleted
+// is called once no handlers are running.
+func (mgr *Manager) shutdown(log *zerolog.Logger, forceCloseTasks context.Context, handlersCompleted func()) error {
+	// Let running handlers know they should wrap up,
+	// well before their contexts are cancelled.
+	mgr.draining.Store(true)
+
+	// Once it's time to force-close tasks, cancel the base context,
+	// logging where any handlers still running are stuck first.
 	go func() {
-		<-p.ForceCloseTasks.Done()
+		<-forceCloseTasks.Done()
+		mgr.logBlockedHandlers(log)
 		mgr.ctxs.CancelHandler()
 	}()
 
-	p.Log.Trace().Msg("pubsub: stop fetching new events")
+	log.Trace().Msg("pubsub: stop fetching new events")
 
 	// Immediately fetching new events.
 	mgr.ctxs.StopFetchingNewEvents()
-	p.Log.Trace().Msg("pubsub: waiting on running fetches")
+	log.Trace().Msg("pubsub: waiting on running fetches")
 	mgr.runningFetches.Wait()
 
 	// Wait for running handlers to finish.
 	mgr.runningHandlers.Wait()
-	p.MarkOutstandingPubSubMessagesCompleted()
+	handlersCompleted()
 
 	// Finally, close all connections to the PubSub providers.
 	mgr.ctxs.CloseConnections()
+	mgr.debugSink.close()
 
 	return nil
 }
 
+// registerSubscription records that a subscription is active on this instance.
+func (mgr *Manager) registerSubscription(topic, subscription string, impl types.TopicImplementation) {
+	mgr.subsMu.Lock()
+	defer mgr.subsMu.Unlock()
+	mgr.subs[subscriptionKey{topic, subscription}] = impl
+}
+
+// registerTopic records the implementation of a topic configured on this instance.
+func (mgr *Manager) registerTopic(topic string, impl types.TopicImplementation) {
+	mgr.subsMu.Lock()
+	defer mgr.subsMu.Unlock()
+	mgr.topics[topic] = impl
+}
+
+// lookupSubscription returns the topic implementation of an active subscription.
+func (mgr *Manager) lookupSubscription(topic, subscription string) (types.TopicImplementation, bool) {
+	mgr.subsMu.Lock()
+	defer mgr.subsMu.Unlock()
+	impl, ok := mgr.subs[subscriptionKey{topic, subscription}]
+	return impl, ok
+}
+
 type provider interface {
 	ProviderName() string
 	Matches(providerCfg *config.PubsubProvider) bool
@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"slices"
+	"sync"
+
+	"encore.dev/beta/errs"
+)
+
+// validateOwnership panics if the ownership configuration of cfg is inconsistent.
+func validateOwnership(cfg TopicConfig) {
+	if cfg.EnforceOwnership < OwnershipNotEnforced || cfg.EnforceOwnership > OwnershipDeny {
+		panic("EnforceOwnership is invalid")
+	}
+	if cfg.EnforceOwnership != OwnershipNotEnforced && cfg.Owner == "" {
+		panic("Owner is required to enforce ownership")
+	}
+}
+
+// publisherCheck enforces which services may publish to a topic.
+type publisherCheck struct {
+	warned sync.Map // services already warned about publishing without being allowed to
+}
+
+// checkPublisher reports an error if the current service is not allowed
+// to publish to the topic, according to its ownership configuration.
+func (t *Topic[T]) checkPublisher() error {
+	cfg := t.staticCfg
+	if cfg.EnforceOwnership == OwnershipNotEnforced {
+		return nil
+	}
+
+	req := t.mgr.rt.Current().Req
+	if req == nil {
+		// We don't know which service is publishing
+		return nil
+	}
+	svc := req.Service()
+	if svc == "" || svc == cfg.Owner || slices.Contains(cfg.AllowedPublishers, svc) {
+		return nil
+	}
+
+	if cfg.EnforceOwnership == OwnershipDeny {
+		return errs.B().Code(errs.PermissionDenied).Msgf("service %s is not allowed to publish to topic %s, which is owned by service %s", svc, t.runtimeCfg.EncoreName, cfg.Owner).Err()
+	}
+	if _, warned := t.publishers.warned.LoadOrStore(svc, true); !warned {
+		t.mgr.rootLogger.Warn().Str("topic", t.runtimeCfg.EncoreName).Str("service", svc).Str("owner", cfg.Owner).
+			Msg("service is not allowed to publish to the topic")
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/beta/errs"
+)
+
+// PanicPolicy determines what happens to a message whose Handler panics.
+//
+// A panic in a function the Handler defers is indistinguishable from a panic
+// in the Handler itself, even if the Handler was returning nil at the time,
+// so by default such messages are retried. Handlers whose side effects must
+// not be repeated can instead report when they are done with MarkCompleted.
+type PanicPolicy int
+
+const (
+	// PanicRetry fails the message, so it is redelivered according to the
+	// subscription's RetryPolicy, the same as a Handler error.
+	PanicRetry PanicPolicy = iota
+
+	// PanicAckCompleted acknowledges the message if the Handler panicked after
+	// calling MarkCompleted, trusting it had finished processing the message,
+	// such as when a deferred function panics after the Handler returns nil.
+	// Messages whose Handler panicked before calling MarkCompleted are retried
+	// as with PanicRetry.
+	PanicAckCompleted
+)
+
+func (p PanicPolicy) String() string {
+	switch p {
+	case PanicRetry:
+		return "retry"
+	case PanicAckCompleted:
+		return "ack-completed"
+	default:
+		return "unknown"
+	}
+}
+
+// MarkCompleted records that the Handler has finished processing the message
+// currently being handled. If the Handler then panics, for example in a function
+// it deferred, the message is acknowledged rather than retried when the
+// subscription's OnPanic is PanicAckCompleted. It has no other effect.
+//
+// It does nothing if ctx does not belong to a subscription handler.
+func MarkCompleted(ctx context.Context) {
+	if mc, ok := messageContextFrom(ctx); ok {
+		mc.completed.Store(true)
+	}
+}
+
+// PanicInfo describes a panic in a subscription Handler.
+// See OnHandlerPanic.
+type PanicInfo struct {
+	// Topic and Subscription are the names of the topic and
+	// subscription whose Handler panicked.
+	Topic        string
+	Subscription string
+
+	// MessageID is the ID of the message being processed,
+	// and Attempt the delivery attempt of the message.
+	MessageID string
+	Attempt   int
+
+	// Value is the value the Handler panicked with.
+	Value any
+
+	// Stack is the stack trace of the goroutine which panicked,
+	// as formatted by runtime/debug.Stack.
+	Stack []byte
+}
+
+// OnHandlerPanic sets a function which is called whenever a subscription
+// Handler panics, for example to report the panic to an error tracking
+// service as it happens. Panicking messages are still retried as usual.
+//
+// The function is called in a separate goroutine so it never delays the
+// processing of messages, and panics within it are recovered.
+// Setting it again replaces the previous function, and setting nil removes it.
+func (mgr *Manager) OnHandlerPanic(fn func(PanicInfo)) {
+	if fn == nil {
+		mgr.onHandlerPanic.Store(nil)
+		return
+	}
+	mgr.onHandlerPanic.Store(&fn)
+}
+
+// handlerPanicked reports a Handler panic to the function set
+// with OnHandlerPanic, if any, without waiting for it.
+func (mgr *Manager) handlerPanicked(info PanicInfo) {
+	fn := mgr.onHandlerPanic.Load()
+	if fn == nil {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				mgr.rootLogger.Error().Interface("panic", r).Str("msg_id", info.MessageID).Msg("OnHandlerPanic function panicked")
+			}
+		}()
+		(*fn)(info)
+	}()
+}
+
+// panicError converts a value recovered from a panicking Handler into the
+// error the message fails with, using convert if set. See
+// SubscriptionConfig.PanicToError.
+func panicError(log zerolog.Logger, recovered any, meta *MessageMeta, convert func(recovered any, meta *MessageMeta) error) (err error) {
+	if convert != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().Interface("panic", r).Str("msg_id", meta.ID).Msg("PanicToError panicked, using the default error")
+				err = defaultPanicError(recovered)
+			}
+		}()
+		if err := convert(recovered, meta); err != nil {
+			return err
+		}
+	}
+	return defaultPanicError(recovered)
+}
+
+// defaultPanicError is the error a message fails with when its Handler panics,
+// unless the subscription sets PanicToError.
+func defaultPanicError(recovered any) error {
+	return errs.B().Code(errs.Internal).Msgf("subscriber panicked: %s", recovered).Err()
+}
@@ -2,6 +2,8 @@
 
 package pubsub
 
+import "context"
+
 // NewTopic is used to declare a Topic. Encore will use static
 // analysis to identify Topics and automatically provision them
 // for you.
@@ -39,3 +41,97 @@ package pubsub
 func NewTopic[T any](name string, cfg TopicConfig) *Topic[T] {
 	return newTopic[T](Singleton, name, cfg)
 }
+
+// SetFlowControl adjusts the flow control settings of a running subscription
+// on this instance of the service, allowing backpressure to be tuned without redeploying.
+//
+// Zero values leave the corresponding setting unchanged. The current settings
+// are reported by the subscription's Stats method.
+//
+// It is currently only supported for GCP Pub/Sub pull subscriptions;
+// other providers report an Unimplemented error.
+func SetFlowControl(topic, subscription string, fc FlowControl) error {
+	return Singleton.SetFlowControl(topic, subscription, fc)
+}
+
+// CheckTopology compares the topics and subscriptions configured on this
+// instance of the service against what exists at the cloud provider,
+// and reports any differences, such as missing subscriptions or
+// mismatched retry policies. It never changes anything at the provider.
+//
+// Currently only GCP Pub/Sub is supported; topics using other
+// providers are skipped.
+func CheckTopology(ctx context.Context) ([]TopologyDrift, error) {
+	return Singleton.CheckTopology(ctx)
+}
+
+// InFlight reports the messages whose handlers are currently running
+// on this instance of the service, oldest first.
+//
+// It can be used to detect stuck handlers, for example:
+//
+//	for _, msg := range pubsub.InFlight() {
+//		if msg.Age() > 10*time.Minute {
+//			rlog.Error("handler appears to be stuck", "subscription", msg.Subscription, "msg_id", msg.MessageID)
+//		}
+//	}
+func InFlight() []InFlightInfo {
+	return Singleton.InFlight()
+}
+
+// SetBufferBudget limits the total size of messages this instance of the
+// service processes at once across all subscriptions, protecting it from
+// running out of memory when many subscriptions have a backlog at once.
+//
+// When the budget is reached, further messages wait for others to complete
+// before being processed, which stops subscriptions receiving more messages
+// from the messaging service. A message larger than the budget is still
+// processed once no other messages are. A budget of 0 removes the limit,
+// which is the default.
+func SetBufferBudget(maxBytes int64) {
+	Singleton.SetBufferBudget(maxBytes)
+}
+
+// OnConfigError sets a function which decides how to handle a subscription
+// whose configuration doesn't match the application, such as a subscription
+// the environment is configured to run which the application doesn't declare.
+// This usually means the environment's configuration has drifted from the
+// application being deployed.
+//
+// The function is called when the subscription is declared, with the names of the
+// topic and subscription and an error describing the problem. It can stop the
+// service, for example by panicking or logging a fatal error, or return to skip
+// the subscription, so that this instance doesn't process its messages.
+//
+// It must be set before the affected subscriptions are declared, for example
+// from a package-level variable initializer in a package imported by the
+// services declaring them. If no function is set, such subscriptions are skipped.
+func OnConfigError(fn func(topic, subscription string, err error)) {
+	Singleton.OnConfigError(fn)
+}
+
+// OnHandlerPanic sets a function which is called whenever a subscription
+// Handler panics, for example to report the panic to an error tracking
+// service as it happens. Panicking messages are still retried as usual.
+//
+// The function is called in a separate goroutine so it never delays the
+// processing of messages, and panics within it are recovered.
+// Setting it again replaces the previous function, and setting nil removes it.
+func OnHandlerPanic(fn func(PanicInfo)) {
+	Singleton.OnHandlerPanic(fn)
+}
+
+// BufferedBytes reports the total size of messages this instance
+// of the service is currently processing across all subscriptions.
+func BufferedBytes() int64 {
+	return Singleton.BufferedBytes()
+}
+
+// DroppedMessages reports how many messages this instance of the service has
+// dropped since it started, by reason and subscription, giving a single view of
+// the messages which left the normal processing path. Dropped messages are those
+// which stopped being processed without their Handler succeeding, for example
+// because they were quarantined or failed on their last delivery attempt.
+func DroppedMessages() DroppedStats {
+	return Singleton.DroppedMessages()
+}
@@ -0,0 +1,45 @@
+package pubsub
+
+import "encore.dev/pubsub/internal/types"
+
+// InitialPosition determines which messages a newly created subscription
+// receives: messages retained on the topic from before it was created,
+// or only messages published afterwards.
+//
+// Not all providers allow choosing the initial position. GCP Pub/Sub,
+// AWS SNS/SQS, Azure Service Bus and NSQ only deliver messages published
+// after a subscription was created.
+type InitialPosition = types.InitialPosition
+
+const (
+	// InitialPositionDefault uses the provider's native behaviour.
+	InitialPositionDefault = types.InitialPositionDefault
+
+	// InitialPositionEarliest starts a new subscription from the
+	// oldest message retained on the topic, where supported.
+	InitialPositionEarliest = types.InitialPositionEarliest
+
+	// InitialPositionLatest starts a new subscription from
+	// the next message published to the topic.
+	InitialPositionLatest = types.InitialPositionLatest
+)
+
+// effectiveInitialPosition resolves the initial position a subscription
+// will have, given the position requested in its config.
+//
+// It reports false if the requested position is not supported,
+// in which case the provider's native position is returned.
+func effectiveInitialPosition(impl types.TopicImplementation, requested InitialPosition) (InitialPosition, bool) {
+	p, ok := impl.(types.InitialPositioner)
+	if !ok {
+		// The provider doesn't tell us where new subscriptions start
+		return InitialPositionDefault, requested == InitialPositionDefault
+	}
+
+	if requested == InitialPositionDefault {
+		return p.NativeInitialPosition(), true
+	} else if p.SupportsInitialPosition(requested) {
+		return requested, true
+	}
+	return p.NativeInitialPosition(), false
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"encore.dev/beta/errs"
+)
+
+// PublishResult is the outcome of publishing a message
+// to one of the topics passed to PublishAll.
+type PublishResult struct {
+	// Topic is the name of the topic.
+	Topic string
+
+	// ID is the unique message ID assigned by the topic.
+	// It is empty if publishing failed.
+	ID string
+
+	// Err is the error publishing to the topic, if any.
+	Err error
+}
+
+// PublishAll publishes a message to each of the given topics, returning
+// one PublishResult per topic in the same order as the topics were given.
+//
+// The message is only marshalled once, and is then published to each topic in turn.
+// If publishing to any topic fails, PublishAll returns an error, but it still
+// attempts to publish to the remaining topics.
+//
+// Publishing to multiple topics is not atomic: when an error is returned the message
+// may have been published to some of the topics but not others. Use the per-topic
+// results to determine which topics to retry. If the message must be delivered to
+// either all topics or none, store it transactionally alongside your other state
+// (for example in an outbox table) and publish it from there until it succeeds.
+//
+// Example:
+//
+//	results, err := pubsub.PublishAll(ctx, &OrderPlaced{ID: id}, Billing, Shipping)
+//	if err != nil {
+//		for _, r := range results {
+//			if r.Err != nil {
+//				rlog.Error("failed to publish", "topic", r.Topic, "err", r.Err)
+//			}
+//		}
+//	}
+func PublishAll[T any](ctx context.Context, msg T, topics ...*Topic[T]) ([]PublishResult, error) {
+	results := make([]PublishResult, len(topics))
+	names := make([]string, len(topics))
+	for i, t := range topics {
+		if t == nil || t.runtimeCfg == nil || t.topic == nil {
+			return nil, errs.B().Code(errs.Unimplemented).Msg("pubsub topic was not created using pubsub.NewTopic").Err()
+		}
+		names[i] = t.runtimeCfg.EncoreName
+		results[i].Topic = names[i]
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	attrs, data, err := marshalMessage(msg, strings.Join(names, ", "))
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	var failures []error
+	for i, t := range topics {
+		// Each topic adds its own attributes, so give each its own copy.
+		results[i].ID, results[i].Err = t.publishEncoded(ctx, attrs.clone(), data, publishOptions{})
+		if results[i].Err != nil {
+			failed = append(failed, names[i])
+			failures = append(failures, results[i].Err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, errs.B().Cause(errors.Join(failures...)).Code(errs.Unavailable).
+			Msgf("failed to publish message to %d of %d topics: %s", len(failed), len(topics), strings.Join(failed, ", ")).Err()
+	}
+	return results, nil
+}
@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/beta/errs"
+)
+
+func TestPublishAll(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "first", "sub")
+	mgr.runtime.PubsubTopics["second"] = &config.PubsubTopic{EncoreName: "second"}
+
+	first := newTopic[*testEvent](mgr, "first", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	second := newTopic[*testEvent](mgr, "second", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ctx := context.Background()
+
+	results, err := PublishAll(ctx, &testEvent{Value: "hello"}, first, second)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.DeepEquals, []PublishResult{
+		{Topic: "first", ID: "msg-id"},
+		{Topic: "second", ID: "msg-id"},
+	})
+	c.Assert(string(fake.topics["first"].lastData), qt.Equals, `{"Value":"hello"}`)
+	c.Assert(string(fake.topics["second"].lastData), qt.Equals, `{"Value":"hello"}`)
+
+	// A failure on one topic doesn't stop publishing to the others
+	fake.topics["first"].publishErr = errors.New("unavailable")
+	results, err = PublishAll(ctx, &testEvent{Value: "again"}, first, second)
+	c.Assert(errs.Code(err), qt.Equals, errs.Unavailable)
+	c.Assert(results, qt.HasLen, 2)
+	c.Assert(results[0].Err, qt.IsNotNil)
+	c.Assert(results[0].ID, qt.Equals, "")
+	c.Assert(results[1].Err, qt.IsNil)
+	c.Assert(fake.topics["first"].published, qt.Equals, 1)
+	c.Assert(fake.topics["second"].published, qt.Equals, 2)
+}
@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+
+	"encore.dev/beta/errs"
+)
+
+// TopicStats contains runtime statistics about a topic
+// for the current instance of the service.
+// Additional fields may be added in the future.
+type TopicStats struct {
+	// Throttled is the number of publishes which exceeded the topic's
+	// PublishLimit, and were either delayed or rejected.
+	Throttled uint64
+}
+
+// Stats returns runtime statistics about the topic.
+func (t *Topic[T]) Stats() TopicStats {
+	return TopicStats{
+		Throttled: t.throttled.Load(),
+	}
+}
+
+// newPublishQuota creates the token bucket enforcing limit,
+// or nil if there is no limit.
+func newPublishQuota(limit *PublishLimit) *rate.Limiter {
+	if limit == nil {
+		return nil
+	}
+	if limit.MaxPerSecond <= 0 {
+		panic("PublishLimit.MaxPerSecond must be positive")
+	}
+	if limit.Burst < 0 {
+		panic("PublishLimit.Burst cannot be negative")
+	}
+	burst := limit.Burst
+	if burst == 0 {
+		burst = limit.MaxPerSecond
+	}
+	return rate.NewLimiter(rate.Limit(limit.MaxPerSecond), burst)
+}
+
+// waitPublishQuota waits until a message can be published within the topic's
+// PublishLimit, or if the limit is configured to reject, reports an error.
+func (t *Topic[T]) waitPublishQuota(ctx context.Context) error {
+	if t.publishQuota == nil || t.publishQuota.Allow() {
+		return nil
+	}
+
+	t.throttled.Add(1)
+	if t.staticCfg.PublishLimit.Reject {
+		return errs.B().Code(errs.ResourceExhausted).Msgf("publish rate limit exceeded for topic %s", t.runtimeCfg.EncoreName).Err()
+	}
+	return t.publishQuota.Wait(ctx)
+}
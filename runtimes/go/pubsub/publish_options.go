@@ -0,0 +1,108 @@
+package pubsub
+
+import (
+	"time"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// PublishOption configures how a message is published.
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	durableConfirm    bool
+	maxProcessingTime time.Duration
+	attributes        map[string]string
+	correlationID     string
+}
+
+func newPublishOptions(opts []PublishOption) publishOptions {
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDurableConfirm requires that PublishWithOptions only returns successfully if the
+// messaging service's confirmation of the publish means the message is durably stored,
+// rather than merely accepted. It doesn't make the publish wait for anything further:
+// where the messaging service's confirmation doesn't imply durable storage,
+// the publish is rejected with a FailedPrecondition error instead.
+//
+// By messaging service used by the environment:
+//
+//   - GCP Pub/Sub only confirms a publish once the message is persisted,
+//     so the option has no effect.
+//   - AWS SNS only confirms a publish once the message is stored across
+//     multiple availability zones, so the option has no effect.
+//   - Azure Service Bus only completes a send once the message is committed
+//     to the topic, so the option has no effect.
+//   - NSQ confirms a publish once the message is queued, possibly only in memory,
+//     so the message is rejected without being published.
+//   - Encore Cloud doesn't report durability, so the message is
+//     rejected without being published.
+//   - In tests, published messages are always recorded, so the option has no effect.
+func WithDurableConfirm() PublishOption {
+	return func(o *publishOptions) {
+		o.durableConfirm = true
+	}
+}
+
+// WithMaxProcessingTime sets how long subscribers may take to process the message,
+// measured from when they receive it. The context passed to subscription
+// handlers is cancelled once it passes, so that messages with a known
+// processing budget can be retried rather than holding up the subscription.
+//
+// Subscriptions can cap the processing time of messages using their
+// MaxProcessingTime. It panics if d is not positive.
+func WithMaxProcessingTime(d time.Duration) PublishOption {
+	if d <= 0 {
+		panic("max processing time must be positive")
+	}
+	return func(o *publishOptions) {
+		o.maxProcessingTime = d
+	}
+}
+
+// WithAttributes adds attributes to the published message, in addition to
+// those set by the message's `pubsub-attr` fields. If it is used more than
+// once, the attributes are combined, with later values taking precedence.
+//
+// Names starting with "encore_" are reserved for Encore, and Publish returns
+// an error if they are used. See TopicConfig.MergeAttribute for how attributes
+// also set by the message are resolved.
+func WithAttributes(attrs map[string]string) PublishOption {
+	return func(o *publishOptions) {
+		if o.attributes == nil {
+			o.attributes = make(map[string]string, len(attrs))
+		}
+		for name, value := range attrs {
+			o.attributes[name] = value
+		}
+	}
+}
+
+// WithCorrelationID sets the correlation ID of the message, such as one received
+// from an upstream system in an HTTP header. It takes precedence over the correlation
+// ID Encore otherwise propagates from the request publishing the message, while the
+// trace context is still propagated as usual.
+//
+// Subscribers can read it from MessageMeta.CorrelationID, and it is included in
+// the logs of the handler processing the message as x_correlation_id, as are
+// the requests the handler makes. It panics if id is empty.
+func WithCorrelationID(id string) PublishOption {
+	if id == "" {
+		panic("correlation id cannot be empty")
+	}
+	return func(o *publishOptions) {
+		o.correlationID = id
+	}
+}
+
+// confirmsDurably reports whether the topic's messaging service
+// only confirms a publish once the message is durably stored.
+func confirmsDurably(topic types.TopicImplementation) bool {
+	dc, ok := topic.(types.DurableConfirmer)
+	return ok && dc.ConfirmsDurably()
+}
@@ -0,0 +1,220 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/beta/errs"
+)
+
+// durableTopic is a fakeTopic whose provider confirms
+// publishes once messages are durably stored.
+type durableTopic struct{ *fakeTopic }
+
+func (durableTopic) ConfirmsDurably() bool { return true }
+
+func TestPublish_WithDurableConfirm(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Providers which can't confirm durability don't publish the message
+	_, err := topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithDurableConfirm())
+	c.Assert(errs.Code(err), qt.Equals, errs.FailedPrecondition)
+	c.Assert(ft.published, qt.Equals, 0)
+
+	// But publishing without the option is unaffected
+	_, err = topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.published, qt.Equals, 1)
+
+	topic.topic = durableTopic{ft}
+	id, err := topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithDurableConfirm())
+	c.Assert(err, qt.IsNil)
+	c.Assert(id, qt.Equals, "msg-id")
+	c.Assert(ft.published, qt.Equals, 2)
+}
+
+func TestPublish_WithMaxProcessingTime(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var remaining time.Duration
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			remaining = 0
+			if deadline, ok := ctx.Deadline(); ok {
+				remaining = time.Until(deadline)
+			}
+			return ctx.Err()
+		},
+		MaxProcessingTime: time.Minute,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages without a processing time aren't bounded
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[maxProcessingTimeAttribute], qt.Equals, "")
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(remaining, qt.Equals, time.Duration(0))
+
+	// The requested processing time bounds the handler's context
+	_, err = topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithMaxProcessingTime(10*time.Second))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[maxProcessingTimeAttribute], qt.Equals, "10s")
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(remaining > 9*time.Second && remaining <= 10*time.Second, qt.IsTrue)
+
+	// But is capped by the subscription
+	_, err = topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithMaxProcessingTime(time.Hour))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(remaining > 59*time.Second && remaining <= time.Minute, qt.IsTrue)
+
+	// Invalid processing times are ignored
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, map[string]string{maxProcessingTimeAttribute: "soon"}, ft.lastData), qt.IsNil)
+	c.Assert(remaining, qt.Equals, time.Duration(0))
+
+	// Exceeding the processing time fails the message
+	c.Assert(ft.deliver(ctx, "sub", "5", 1, map[string]string{maxProcessingTimeAttribute: "1ns"}, ft.lastData), qt.ErrorMatches, ".*deadline exceeded.*")
+}
+
+type regionEvent struct {
+	Region string `pubsub-attr:"region"`
+	Value  string
+}
+
+func TestPublish_WithAttributes(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*regionEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Attributes are added to those set by the message
+	_, err := topic.PublishWithOptions(ctx, &regionEvent{Region: "eu"}, WithAttributes(map[string]string{"tenant": "acme"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs, qt.DeepEquals, map[string]string{"region": "eu", "tenant": "acme"})
+
+	// By default, attributes given as options take precedence over the message
+	_, err = topic.PublishWithOptions(ctx, &regionEvent{Region: "eu"}, WithAttributes(map[string]string{"region": "us"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs["region"], qt.Equals, "us")
+
+	// Reserved attributes can't be set
+	_, err = topic.PublishWithOptions(ctx, &regionEvent{Region: "eu"}, WithAttributes(map[string]string{"encore_schema_version": "2"}))
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+	c.Assert(err, qt.ErrorMatches, ".*invalid message attributes for topic topic: attribute encore_schema_version is reserved for use by Encore")
+	c.Assert(ft.published, qt.Equals, 2)
+}
+
+func TestPublish_MergeAttribute(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*regionEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		MergeAttribute: func(name, messageValue, optionValue string) (string, error) {
+			if optionValue == "invalid" {
+				return "", errs.B().Msg("bad region").Err()
+			}
+			return messageValue + "," + optionValue, nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Conflicting attributes are merged by the hook
+	_, err := topic.PublishWithOptions(ctx, &regionEvent{Region: "eu"}, WithAttributes(map[string]string{"region": "us", "tenant": "acme"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs, qt.DeepEquals, map[string]string{"region": "eu,us", "tenant": "acme"})
+
+	// Errors from the hook are returned without publishing
+	_, err = topic.PublishWithOptions(ctx, &regionEvent{Region: "eu"}, WithAttributes(map[string]string{"region": "invalid"}))
+	c.Assert(err, qt.ErrorMatches, ".*merge attribute region: .*bad region")
+	c.Assert(ft.published, qt.Equals, 1)
+}
+
+func TestPublish_AttributeConflicts(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		CloudEvents:       &CloudEventsCodec{Source: "/orders", Type: "com.example.order.placed"},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Attributes Encore sets to encode the message aren't overwritten
+	_, err := topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithAttributes(map[string]string{"content-type": "text/plain"}))
+	c.Assert(err, qt.ErrorMatches, ".*attribute content-type conflicts with the attribute Encore sets to encode the message")
+	c.Assert(ft.published, qt.Equals, 0)
+
+	// Nor can messages set reserved attributes using fields
+	type reservedEvent struct {
+		Version string `pubsub-attr:"encore_schema_version"`
+	}
+	_, _, err = marshalMessage(&reservedEvent{Version: "2"}, "topic")
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+	c.Assert(err, qt.ErrorMatches, ".*attribute encore_schema_version is reserved for use by Encore")
+}
+
+func TestPublish_WithCorrelationID(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var meta *MessageMeta
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			meta, _ = CurrentMessage(ctx)
+			mgr.rt.Current().Req.Logger.Info().Msg("handling message")
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	_, err := topic.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithCorrelationID("upstream-123"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[extCorrelationIDAttribute], qt.Equals, "upstream-123")
+
+	// The correlation ID is exposed to the handler and included in its logs
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(meta.CorrelationID, qt.Equals, "upstream-123")
+	c.Assert(buf.String(), qt.Contains, `"x_correlation_id":"upstream-123","message":"handling message"`)
+
+	c.Assert(func() { WithCorrelationID("") }, qt.PanicMatches, "correlation id cannot be empty")
+}
+
+func TestTopicRef_PublishOptions(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Publisher keeps its original signature so existing implementations still satisfy it
+	ref := TopicRef[Publisher[*testEvent]](topic)
+	_, err := ref.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ref.Meta().Name, qt.Equals, "topic")
+
+	// While PublisherWithOptions accepts publish options
+	optRef := TopicRef[PublisherWithOptions[*testEvent]](topic)
+	_, err = optRef.PublishWithOptions(ctx, &testEvent{Value: "hello"}, WithAttributes(map[string]string{"tenant": "acme"}))
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs["tenant"], qt.Equals, "acme")
+	c.Assert(ft.published, qt.Equals, 2)
+}
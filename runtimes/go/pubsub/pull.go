@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"encore.dev/beta/errs"
+)
+
+// Message is a message received from a subscription using Receive.
+//
+// Exactly one of Ack or Nack must be called once the message has been processed.
+// Until then the message counts towards the subscription's MaxConcurrency,
+// and prevents the service from shutting down gracefully.
+type Message[T any] struct {
+	// Payload is the decoded message.
+	Payload T
+
+	// Meta contains metadata about the message.
+	// The fields should not be modified by the caller.
+	Meta *MessageMeta
+
+	ctx  context.Context
+	once sync.Once
+	done chan error // receives the result of processing the message
+}
+
+// Context returns the context of the message, which is cancelled when the
+// message's lease expires or the service is forced to shut down. It can be passed
+// to functions such as LeaseDeadline, IsRedelivery and CurrentMessage.
+//
+// Once the context is done, the message is redelivered regardless
+// of whether Ack is called.
+func (m *Message[T]) Context() context.Context {
+	return m.ctx
+}
+
+// Ack acknowledges the message, so it is not delivered again.
+// Calls after the first call to Ack or Nack have no effect.
+func (m *Message[T]) Ack() {
+	m.once.Do(func() { m.done <- nil })
+}
+
+// Nack negatively acknowledges the message, causing it to be redelivered
+// according to the subscription's RetryPolicy.
+// Calls after the first call to Ack or Nack have no effect.
+func (m *Message[T]) Nack() {
+	m.once.Do(func() {
+		m.done <- errs.B().Code(errs.Aborted).Msg("message was negatively acknowledged").Err()
+	})
+}
+
+// PullHandler returns a Handler for subscriptions which are consumed using
+// Subscription.Receive instead of having messages pushed to a Handler.
+//
+// For example:
+//
+//	var Jobs = pubsub.NewSubscription(JobTopic, "job-workers", pubsub.SubscriptionConfig[*Job]{
+//		Handler: pubsub.PullHandler[*Job](),
+//	})
+func PullHandler[T any]() func(ctx context.Context, msg T) error {
+	return func(ctx context.Context, msg T) error {
+		mc, ok := messageContextFrom(ctx)
+		if !ok {
+			return errs.B().Code(errs.Internal).Msg("pull handler called outside of a subscription").Err()
+		}
+		q, ok := mc.pull.(*pullQueue[T])
+		if !ok {
+			return errs.B().Code(errs.Internal).Msg("pull handler used with a subscription of a different message type").Err()
+		}
+		return q.offer(ctx, mc.meta, msg)
+	}
+}
+
+// Receive waits for the next message delivered to this instance of the service
+// on the subscription, for subscriptions whose Handler is PullHandler.
+//
+// The caller takes ownership of the message and must call its Ack or Nack method
+// once it has been processed. Messages are delivered to Receive as the backend
+// subscription receives them; the subscription's MaxConcurrency limits the number
+// of messages which can be received but not yet acknowledged.
+//
+// Receive returns an error if ctx is done before a message is received,
+// or once the service begins shutting down. Messages which have been received
+// but not acknowledged delay the shutdown until they are acknowledged
+// or their lease expires.
+//
+// Push-style Handlers are simpler, and are traced, retried and limited by
+// MaxConcurrency without further work, so they should be preferred for most
+// subscriptions. Receive is intended for workers with their own concurrency
+// model, such as a fixed pool of goroutines sharing an expensive resource,
+// or which need to hold on to several messages before acknowledging them.
+func (s *Subscription[T]) Receive(ctx context.Context) (*Message[T], error) {
+	if s.pull == nil {
+		return nil, errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s is not running on this instance", s.name).Err()
+	}
+
+	select {
+	case m := <-s.pull.messages:
+		return m, nil
+	case <-s.mgr.ctxs.Fetch.Done():
+		return nil, errs.B().Code(errs.Unavailable).Msgf("subscription %s is shutting down", s.name).Err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pullQueue hands messages from the backend subscription to callers of Receive.
+type pullQueue[T any] struct {
+	messages chan *Message[T]
+	stopped  <-chan struct{} // closed once the service stops fetching new messages
+}
+
+func newPullQueue[T any](mgr *Manager) *pullQueue[T] {
+	return &pullQueue[T]{
+		messages: make(chan *Message[T]),
+		stopped:  mgr.ctxs.Fetch.Done(),
+	}
+}
+
+// offer waits for msg to be received, and then for it to be acknowledged,
+// returning the error to report to the messaging service.
+func (q *pullQueue[T]) offer(ctx context.Context, meta *MessageMeta, msg T) error {
+	m := &Message[T]{Payload: msg, Meta: meta, ctx: ctx, done: make(chan error, 1)}
+
+	select {
+	case q.messages <- m:
+	case <-q.stopped:
+		return errs.B().Code(errs.Unavailable).Msg("subscription is shutting down").Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-m.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
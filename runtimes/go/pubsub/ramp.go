@@ -0,0 +1,118 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RecoveryRampConfig limits the rate at which a subscription calls its
+// Handler while it recovers, so that the backlog built up during an incident
+// doesn't overwhelm a downstream dependency which has only just recovered.
+//
+// The ramp starts when the subscription starts on an instance, such as after
+// a restart, and when the subscription resumes calling its Handler after
+// being paused: when its circuit breaker closes again, or when its service
+// initializes after failing to. The rate starts at InitialPerSecond and
+// increases linearly to TargetPerSecond over Duration, after which the
+// Handler is called as fast as MaxConcurrency allows.
+//
+// The rate applies to each instance of the service separately, and messages
+// wait for their turn while counting towards MaxConcurrency. Messages which
+// can't be processed before their AckDeadline passes are negatively
+// acknowledged and redelivered. The subscription's Stats report the
+// current rate of the ramp.
+type RecoveryRampConfig struct {
+	// InitialPerSecond is the number of messages per second
+	// processed when the ramp starts.
+	//
+	// This field is required.
+	InitialPerSecond int
+
+	// TargetPerSecond is the number of messages per second processed
+	// by the end of the ramp. It must be at least InitialPerSecond.
+	//
+	// This field is required.
+	TargetPerSecond int
+
+	// Duration is how long the ramp takes to reach TargetPerSecond.
+	//
+	// If zero, it defaults to 1 minute.
+	Duration time.Duration
+}
+
+// recoveryRamp limits the rate messages are processed at after recovering,
+// increasing the rate linearly over the ramp's duration.
+type recoveryRamp struct {
+	initial  float64
+	target   float64
+	duration time.Duration
+
+	mu      sync.Mutex
+	start   time.Time // when the current ramp started; the zero time once it has ended
+	limiter *rate.Limiter
+}
+
+func newRecoveryRamp(cfg *RecoveryRampConfig) *recoveryRamp {
+	return &recoveryRamp{
+		initial:  float64(cfg.InitialPerSecond),
+		target:   float64(cfg.TargetPerSecond),
+		duration: cfg.Duration,
+		limiter:  rate.NewLimiter(rate.Limit(cfg.InitialPerSecond), 1),
+	}
+}
+
+// restart starts the ramp over from its initial rate at now.
+func (r *recoveryRamp) restart(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start = now
+	r.limiter.SetLimitAt(now, rate.Limit(r.initial))
+}
+
+// rateLocked returns the rate of the ramp at now, ending the ramp
+// and returning 0 if its duration has passed or it isn't running.
+func (r *recoveryRamp) rateLocked(now time.Time) float64 {
+	if r.start.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(r.start)
+	if elapsed >= r.duration {
+		r.start = time.Time{}
+		return 0
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return r.initial + (r.target-r.initial)*float64(elapsed)/float64(r.duration)
+}
+
+// currentRate reports the rate messages are currently limited to,
+// or 0 if the ramp isn't running.
+func (r *recoveryRamp) currentRate() float64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rateLocked(time.Now())
+}
+
+// wait waits until the ramp allows another message to be processed.
+func (r *recoveryRamp) wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	now := time.Now()
+	limit := r.rateLocked(now)
+	if limit == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	r.limiter.SetLimitAt(now, rate.Limit(limit))
+	r.mu.Unlock()
+	return r.limiter.Wait(ctx)
+}
@@ -0,0 +1,94 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRecoveryRamp_Rate(t *testing.T) {
+	c := qt.New(t)
+	r := newRecoveryRamp(&RecoveryRampConfig{InitialPerSecond: 10, TargetPerSecond: 110, Duration: time.Minute})
+	start := time.Now()
+
+	// Nothing is limited until the ramp starts
+	c.Assert(r.rateLocked(start), qt.Equals, 0.0)
+
+	r.restart(start)
+	c.Assert(r.rateLocked(start), qt.Equals, 10.0)
+	c.Assert(r.rateLocked(start.Add(15*time.Second)), qt.Equals, 35.0)
+	c.Assert(r.rateLocked(start.Add(30*time.Second)), qt.Equals, 60.0)
+
+	// The ramp ends once its duration has passed
+	c.Assert(r.rateLocked(start.Add(time.Minute)), qt.Equals, 0.0)
+	c.Assert(r.rateLocked(start.Add(30*time.Second)), qt.Equals, 0.0)
+
+	// Restarting starts over from the initial rate
+	r.restart(start.Add(2 * time.Minute))
+	c.Assert(r.rateLocked(start.Add(2*time.Minute)), qt.Equals, 10.0)
+}
+
+func TestSubscription_RecoveryRamp(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var fail bool
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if fail {
+				return errors.New("downstream unavailable")
+			}
+			return nil
+		},
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Millisecond},
+		RecoveryRamp:   &RecoveryRampConfig{InitialPerSecond: 50, TargetPerSecond: 50, Duration: time.Hour},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	// The ramp starts with the subscription, limiting the rate messages are processed at
+	c.Assert(sub.Stats().RecoveryRate, qt.Equals, 50.0)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c.Assert(ft.deliver(ctx, "sub", "msg", 1, nil, data), qt.IsNil)
+	}
+	c.Assert(time.Since(start) >= 4*20*time.Millisecond, qt.IsTrue)
+
+	// End the ramp, then trip the circuit breaker
+	sub.ramp.restart(time.Now().Add(-time.Hour))
+	c.Assert(sub.Stats().RecoveryRate, qt.Equals, 0.0)
+	fail = true
+	c.Assert(ft.deliver(ctx, "sub", "msg", 1, nil, data), qt.IsNotNil)
+	c.Assert(sub.Stats().CircuitBreaker, qt.Equals, CircuitOpen)
+	c.Assert(sub.Stats().RecoveryRate, qt.Equals, 0.0)
+
+	// The ramp starts over once the breaker closes
+	time.Sleep(2 * time.Millisecond)
+	fail = false
+	c.Assert(ft.deliver(ctx, "sub", "msg", 1, nil, data), qt.IsNil)
+	c.Assert(sub.Stats().CircuitBreaker, qt.Equals, CircuitClosed)
+	c.Assert(sub.Stats().RecoveryRate, qt.Equals, 50.0)
+}
+
+func TestSubscription_RecoveryRampConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	newSub := func(cfg *RecoveryRampConfig) func() {
+		return func() {
+			NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+				Handler:      func(ctx context.Context, msg *testEvent) error { return nil },
+				RecoveryRamp: cfg,
+			})
+		}
+	}
+
+	c.Assert(newSub(&RecoveryRampConfig{TargetPerSecond: 10}), qt.PanicMatches, "RecoveryRamp.InitialPerSecond must be positive")
+	c.Assert(newSub(&RecoveryRampConfig{InitialPerSecond: 10, TargetPerSecond: 5}), qt.PanicMatches, "RecoveryRamp.TargetPerSecond must be at least InitialPerSecond")
+	c.Assert(newSub(&RecoveryRampConfig{InitialPerSecond: 10, TargetPerSecond: 10, Duration: -1}), qt.PanicMatches, "RecoveryRamp.Duration cannot be negative")
+}
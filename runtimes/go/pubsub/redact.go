@@ -0,0 +1,163 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces the value of sensitive fields in redacted payloads.
+const redactedValue = `"[redacted]"`
+
+// redaction describes which fields of a message type are sensitive,
+// and must be redacted from message payloads before they are logged or traced.
+//
+// Fields are marked as sensitive with the `pubsub:"sensitive"` struct tag:
+//
+//	type UserSignedUp struct {
+//		UserID string
+//		Email  string `pubsub:"sensitive"`
+//	}
+type redaction struct {
+	sensitive map[string]bool       // lower-cased JSON names of sensitive fields
+	nested    map[string]*redaction // lower-cased JSON names of struct fields containing sensitive fields
+}
+
+// redactions caches the redaction for each message type.
+// A nil *redaction means the type has no sensitive fields.
+var redactions sync.Map // reflect.Type -> *redaction
+
+// redactionFor returns the redaction for typ, or nil if it has no sensitive fields.
+func redactionFor(typ reflect.Type) *redaction {
+	if typ == nil {
+		return nil
+	}
+	if r, ok := redactions.Load(typ); ok {
+		return r.(*redaction)
+	}
+	r := buildRedaction(typ, make(map[reflect.Type]bool))
+	redactions.Store(typ, r)
+	return r
+}
+
+func buildRedaction(typ reflect.Type, visiting map[reflect.Type]bool) *redaction {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || visiting[typ] {
+		return nil
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	r := &redaction{sensitive: make(map[string]bool), nested: make(map[string]*redaction)}
+	r.addFields(typ, visiting)
+	if len(r.sensitive) == 0 && len(r.nested) == 0 {
+		return nil
+	}
+	return r
+}
+
+// addFields adds the sensitive fields of the struct type typ to r.
+func (r *redaction) addFields(typ reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Fields of embedded structs are promoted, unless the struct is named in JSON
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.addFields(embedded, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = strings.ToLower(name)
+
+		if field.Tag.Get("pubsub") == "sensitive" {
+			r.sensitive[name] = true
+		} else if nested := buildRedaction(field.Type, visiting); nested != nil {
+			r.nested[name] = nested
+		}
+	}
+}
+
+// apply returns data with the values of sensitive fields replaced.
+// If data is not a JSON object it cannot be safely inspected,
+// so the whole payload is redacted.
+func (r *redaction) apply(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return []byte(redactedValue)
+	}
+	for key, val := range obj {
+		name := strings.ToLower(key)
+		if r.sensitive[name] {
+			obj[key] = json.RawMessage(redactedValue)
+		} else if nested := r.nested[name]; nested != nil && string(val) != "null" {
+			obj[key] = nested.apply(val)
+		}
+	}
+
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return []byte(redactedValue)
+	}
+	return out
+}
+
+// redactMessage returns the JSON-encoded message data, with any sensitive
+// fields of the message type T redacted so it can be logged or traced.
+func redactMessage[T any](attrs map[string]string, data []byte) []byte {
+	return messageRedaction[T](attrs).apply(data)
+}
+
+// redactPublished is like redactMessage, but for message data as it was
+// published, which may be wrapped in a CloudEvent. The event's data is redacted
+// and returned; if it can't be unwrapped, data is redacted entirely
+// if the message type has sensitive fields. Encrypted data is always
+// redacted entirely, as it isn't readable anyway.
+func redactPublished[T any](attrs map[string]string, data []byte) []byte {
+	if _, encrypted := attrs[encryptionAlgorithmAttribute]; encrypted {
+		return []byte(redactedValue)
+	}
+	r := messageRedaction[T](attrs)
+	payload, err := unwrapCloudEvent(attrs, data)
+	if err != nil {
+		if r != nil {
+			return []byte(redactedValue)
+		}
+		return data
+	}
+	return r.apply(payload)
+}
+
+// messageRedaction returns the redaction for messages of type T with the given attributes.
+func messageRedaction[T any](attrs map[string]string) *redaction {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Interface {
+		// Use the concrete type of the message's variant, if known
+		typ = nil
+		if set := variantsOf[T](); set != nil {
+			typ = set.variantType(attrs[VariantAttribute])
+		}
+	}
+	return redactionFor(typ)
+}
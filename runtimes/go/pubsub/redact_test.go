@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type redactAddress struct {
+	Street string `json:"street" pubsub:"sensitive"`
+	City   string `json:"city"`
+}
+
+type redactAudit struct {
+	Token string `pubsub:"sensitive"`
+}
+
+type redactEvent struct {
+	redactAudit
+	UserID  string         `json:"user_id"`
+	Email   string         `json:"email" pubsub:"sensitive"`
+	Address *redactAddress `json:"address"`
+	Ignored string         `json:"-" pubsub:"sensitive"`
+}
+
+func TestRedactMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "sensitive_fields",
+			data: `{"Token":"secret","user_id":"u1","email":"a@b.com","address":{"street":"1 Main St","city":"Stockholm"}}`,
+			want: `{"Token":"[redacted]","address":{"city":"Stockholm","street":"[redacted]"},"email":"[redacted]","user_id":"u1"}`,
+		},
+		{
+			name: "null_nested",
+			data: `{"user_id":"u1","email":"a@b.com","address":null}`,
+			want: `{"address":null,"email":"[redacted]","user_id":"u1"}`,
+		},
+		{
+			name: "not_an_object",
+			data: `"a@b.com"`,
+			want: `"[redacted]"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got := redactMessage[*redactEvent](nil, []byte(tt.data))
+			c.Assert(string(got), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestRedactMessage_NoSensitiveFields(t *testing.T) {
+	c := qt.New(t)
+	data := []byte(`{"Value":"hello"}`)
+	c.Assert(string(redactMessage[*testEvent](nil, data)), qt.Equals, string(data))
+	c.Assert(redactionFor(nil), qt.IsNil)
+}
@@ -28,6 +28,23 @@ type Publisher[T any] interface {
 	Meta() TopicMeta
 }
 
+// PublisherWithOptions is like [Publisher] but additionally allows
+// customizing how each message is published using PublishOptions,
+// such as [WithAttributes].
+//
+// For example:
+//
+//	var MyTopic = pubsub.NewTopic[Msg](...)
+//	var ref = pubsub.TopicRef[pubsub.PublisherWithOptions[Msg]](MyTopic)
+//	ref.PublishWithOptions(ctx, msg, pubsub.WithAttributes(attrs))
+type PublisherWithOptions[T any] interface {
+	Publisher[T]
+
+	// PublishWithOptions publishes a message to the topic,
+	// customizing how it's published using opts.
+	PublishWithOptions(ctx context.Context, msg T, opts ...PublishOption) (id string, err error)
+}
+
 // TopicRef returns an interface reference to a topic,
 // that can be freely passed around within a service
 // without being subject to Encore's typical static analysis
@@ -45,9 +62,26 @@ type Publisher[T any] interface {
 //	var ref = pubsub.TopicRef[pubsub.Publisher[Msg]](MyTopic)
 //	// ref.Publish(...) can now be used to publish messages to MyTopic.
 func TopicRef[P TopicPerms[T], T any](topic *Topic[T]) P {
-	return any(topicRef[T]{Topic: topic}).(P)
+	if p, ok := any(topicRef[T]{topic: topic}).(P); ok {
+		return p
+	}
+	return any(topicRefWithOptions[T]{Topic: topic}).(P)
 }
 
+// topicRef implements Publisher.
 type topicRef[T any] struct {
+	topic *Topic[T]
+}
+
+func (r topicRef[T]) Publish(ctx context.Context, msg T) (id string, err error) {
+	return r.topic.Publish(ctx, msg)
+}
+
+func (r topicRef[T]) Meta() TopicMeta {
+	return r.topic.Meta()
+}
+
+// topicRefWithOptions implements PublisherWithOptions.
+type topicRefWithOptions[T any] struct {
 	*Topic[T]
 }
@@ -0,0 +1,21 @@
+package pubsub
+
+import (
+	"github.com/rs/zerolog"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// warnUnsupportedRetention logs a warning if the topic declares a retention
+// its provider doesn't support configuring, so it can't be enforced.
+// It does nothing if the topic isn't backed by a provider, as under test.
+func warnUnsupportedRetention(log zerolog.Logger, provider string, impl types.TopicImplementation, cfg TopicConfig) {
+	if provider == "" || cfg.Retention == 0 {
+		return
+	}
+	if rc, ok := impl.(types.RetentionConfigurer); ok && rc.SupportsRetention() {
+		return
+	}
+	log.Warn().Str("provider", provider).Dur("retention", cfg.Retention).
+		Msgf("ignoring Retention as it is not supported by %s, messages are retained for the provider's default period", provider)
+}
@@ -0,0 +1,29 @@
+package pubsub
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+)
+
+func TestTopic_Retention(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+
+	// Retention is ignored with a warning by providers which can't configure it
+	newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Retention: time.Hour})
+	c.Assert(buf.String(), qt.Contains, `"topic":"topic","provider":"fake","retention":3600000,"message":"ignoring Retention as it is not supported by fake, messages are retained for the provider's default period"`)
+
+	buf.Reset()
+	newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	c.Assert(buf.String(), qt.Equals, "")
+
+	c.Assert(func() {
+		newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Retention: -time.Hour})
+	}, qt.PanicMatches, "Retention cannot be negative")
+}
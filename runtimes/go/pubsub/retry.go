@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.dev/pubsub/internal/utils"
+)
+
+// RetryAfter returns an error wrapping cause, which when returned by a
+// subscription Handler makes the message be retried after d, instead of
+// the backoff the subscription's RetryPolicy would use for this attempt.
+// It is useful for honoring a Retry-After header from a downstream service:
+//
+//	if resp.StatusCode == http.StatusTooManyRequests {
+//		return pubsub.RetryAfter(retryAfter(resp), errRateLimited)
+//	}
+//
+// The delay is clamped to the RetryPolicy's MaxBackoff. The attempt still
+// counts towards MaxRetries, and later attempts which fail with other errors
+// use the RetryPolicy's backoff as usual.
+//
+// Encore does not add jitter to the delay, and neither does it to the backoff
+// computed from the RetryPolicy, so messages failing with the same delay are
+// retried at around the same time. When many messages can fail on the same
+// downstream limit, consider adding jitter to d to spread out their retries.
+//
+// It is not supported by providers which apply the RetryPolicy themselves,
+// namely GCP Pub/Sub and Encore Cloud, where the RetryPolicy's backoff is used.
+func RetryAfter(d time.Duration, cause error) error {
+	return &utils.RetryAfterError{Delay: d, Err: cause}
+}
+
+// errRetryDurationExceeded is reported when a message is quarantined
+// because it failed after its subscription's MaxRetryDuration.
+var errRetryDurationExceeded = errors.New("message failed after its MaxRetryDuration")
+
+// retryDurationExceededErr returns the reason a message which failed
+// with err after maxDuration is quarantined, wrapping both
+// errRetryDurationExceeded and err.
+func retryDurationExceededErr(maxDuration time.Duration, err error) error {
+	return fmt.Errorf("%w of %s: %w", errRetryDurationExceeded, maxDuration, err)
+}
+
+// retryDurationExceeded reports whether a message published at publishTime
+// has been retried for longer than maxDuration at now. It never has if
+// maxDuration is zero or the publish time isn't known.
+func retryDurationExceeded(publishTime, now time.Time, maxDuration time.Duration) bool {
+	return maxDuration > 0 && !publishTime.IsZero() && now.Sub(publishTime) >= maxDuration
+}
@@ -0,0 +1,52 @@
+package pubsub
+
+import (
+	"errors"
+	"strconv"
+
+	"encore.dev/beta/errs"
+)
+
+// schemaVersionAttribute is the attribute name we use to record
+// the schema version of the topic a message was published with.
+const schemaVersionAttribute = "encore_schema_version"
+
+// errUnknownSchemaVersion is reported when a message was published with
+// a schema version newer than the subscription knows about.
+var errUnknownSchemaVersion = errors.New("unknown schema version")
+
+// messageSchemaVersion returns the schema version a message was published with,
+// or 0 if it was published without one.
+func messageSchemaVersion(attrs map[string]string) (int, error) {
+	str, ok := attrs[schemaVersionAttribute]
+	if !ok {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(str)
+	if err != nil || version < 0 {
+		return 0, errs.B().Cause(errUnknownSchemaVersion).Code(errs.InvalidArgument).Msgf("invalid schema version %q", str).Err()
+	}
+	return version, nil
+}
+
+// upgradeMessage migrates the data of a message published with an older
+// schema version to the current version using upgrade, if set.
+//
+// Messages published with a newer schema version than current are rejected
+// with errUnknownSchemaVersion, as this subscriber cannot know their shape.
+func upgradeMessage(current, version int, upgrade func(version int, raw []byte) ([]byte, error), data []byte) ([]byte, error) {
+	switch {
+	case version > current:
+		return nil, errs.B().Cause(errUnknownSchemaVersion).Code(errs.InvalidArgument).
+			Msgf("message has schema version %d, which is newer than the topic's schema version %d", version, current).Err()
+	case version == current || upgrade == nil:
+		return data, nil
+	}
+
+	upgraded, err := upgrade(version, data)
+	if err != nil {
+		return nil, errs.B().Cause(err).Code(errs.InvalidArgument).
+			Msgf("failed to upgrade message from schema version %d to %d", version, current).Err()
+	}
+	return upgraded, nil
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+)
+
+// SeekToTime repositions the subscription so that messages published at or
+// after t are delivered again, and messages published before t are considered
+// acknowledged. It can be used to reprocess messages after a bad deploy, or to
+// skip a backlog of messages which are no longer relevant.
+//
+// The subscription is repositioned for every instance of the service,
+// not just this one. Messages being processed when the seek happens may be
+// delivered again, so handlers should be idempotent. Note that messages
+// skipped by DedupByMessageID within its dedup window are not reprocessed.
+//
+// It is currently only supported for GCP Pub/Sub, and only redelivers
+// acknowledged messages if the subscription is configured to retain them;
+// other providers report an Unimplemented error.
+func (s *Subscription[T]) SeekToTime(ctx context.Context, t time.Time) error {
+	impl, err := s.runningImpl()
+	if err != nil {
+		return err
+	}
+	seeker, ok := impl.(types.TimeSeeker)
+	if !ok {
+		return errs.B().Code(errs.Unimplemented).Msgf("the pubsub provider of topic %s does not support seeking to a time", s.topic.runtimeCfg.EncoreName).Err()
+	}
+	return seeker.SeekToTime(ctx, s.name, t)
+}
+
+// SeekToOffset repositions the subscription to an offset within the log of
+// a log-based messaging service, such as a Kafka offset. The format of the
+// offset is specific to the provider.
+//
+// The same considerations as for SeekToTime apply.
+//
+// None of the currently supported providers are log-based,
+// so it always reports an Unimplemented error.
+func (s *Subscription[T]) SeekToOffset(ctx context.Context, offset string) error {
+	impl, err := s.runningImpl()
+	if err != nil {
+		return err
+	}
+	seeker, ok := impl.(types.OffsetSeeker)
+	if !ok {
+		return errs.B().Code(errs.Unimplemented).Msgf("the pubsub provider of topic %s does not support seeking to an offset", s.topic.runtimeCfg.EncoreName).Err()
+	}
+	return seeker.SeekToOffset(ctx, s.name, offset)
+}
+
+// runningImpl returns the topic implementation of the subscription,
+// if it is running on this instance.
+func (s *Subscription[T]) runningImpl() (types.TopicImplementation, error) {
+	impl, ok := s.mgr.lookupSubscription(s.topic.runtimeCfg.EncoreName, s.name)
+	if !ok {
+		return nil, errs.B().Code(errs.FailedPrecondition).Msgf("subscription %s is not running on this instance", s.name).Err()
+	}
+	return impl, nil
+}
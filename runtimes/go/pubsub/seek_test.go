@@ -0,0 +1,45 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/beta/errs"
+)
+
+// seekableTopic is a fakeTopic whose provider supports seeking to a time.
+type seekableTopic struct {
+	*fakeTopic
+	seekedTo map[string]time.Time
+}
+
+func (t seekableTopic) SeekToTime(ctx context.Context, subscription string, tm time.Time) error {
+	t.seekedTo[subscription] = tm
+	return nil
+}
+
+func TestSubscription_Seek(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ctx := context.Background()
+	handler := func(ctx context.Context, msg *testEvent) error { return nil }
+
+	// Subscriptions which aren't running on this instance can't be repositioned
+	other := NewSubscription(topic, "other", SubscriptionConfig[*testEvent]{Handler: handler})
+	c.Assert(errs.Code(other.SeekToTime(ctx, time.Now())), qt.Equals, errs.FailedPrecondition)
+
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+	c.Assert(errs.Code(sub.SeekToTime(ctx, time.Now())), qt.Equals, errs.Unimplemented)
+	c.Assert(errs.Code(sub.SeekToOffset(ctx, "42")), qt.Equals, errs.Unimplemented)
+
+	seekable := seekableTopic{fake.topics["topic"], make(map[string]time.Time)}
+	mgr.registerSubscription("topic", "sub", seekable)
+	to := time.Now().Add(-time.Hour)
+	c.Assert(sub.SeekToTime(ctx, to), qt.IsNil)
+	c.Assert(seekable.seekedTo["sub"], qt.Equals, to)
+	c.Assert(errs.Code(sub.SeekToOffset(ctx, "42")), qt.Equals, errs.Unimplemented)
+}
@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/apisdk/service"
+	"encore.dev/appruntime/shared/health"
+	"encore.dev/beta/errs"
+)
+
+// serviceInitPause is how long a subscription stops calling its Handler
+// after the Handler's service failed to initialize, before trying again.
+const serviceInitPause = 5 * time.Second
+
+// waitForServiceInit holds a message while the subscription is paused as its
+// service failed to initialize, until the service initializes or the pause
+// ends, so that retrying the initialization doesn't spend the message's
+// delivery attempts. The message is only negatively acknowledged if ctx
+// ends first, such as when its lease can no longer be extended.
+func (s *Subscription[T]) waitForServiceInit(ctx context.Context, service string) error {
+	for {
+		until := s.initPausedUntil.Load()
+		wait := time.Until(time.Unix(0, until))
+		if until == 0 || wait <= 0 {
+			return nil
+		}
+
+		resumed := s.initResumedChan()
+		t := time.NewTimer(wait)
+		select {
+		case <-resumed:
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return errs.B().Code(errs.Unavailable).Cause(ctx.Err()).Msgf("subscription is paused until service %s initializes", service).Err()
+		}
+		t.Stop()
+	}
+}
+
+// initResumedChan returns a channel which is closed when the
+// subscription next resumes after its service initializes.
+func (s *Subscription[T]) initResumedChan() <-chan struct{} {
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
+	if s.initResumed == nil {
+		s.initResumed = make(chan struct{})
+	}
+	return s.initResumed
+}
+
+// trackServiceInit pauses the subscription if the Handler failed because its
+// service could not be initialized, and resumes it once the Handler no longer does.
+func (s *Subscription[T]) trackServiceInit(log *zerolog.Logger, service string, err error) {
+	key := subscriptionKey{topic: s.topic.runtimeCfg.EncoreName, subscription: s.name}
+	if isServiceInitErr(err) {
+		s.initFailures.Add(1)
+		if s.initPausedUntil.Swap(time.Now().Add(serviceInitPause).UnixNano()) == 0 {
+			s.mgr.initPaused.Store(key, service)
+			log.Error().Err(err).Str("service", service).Dur("retry_in", serviceInitPause).
+				Msg("pausing subscription as its service failed to initialize")
+		}
+		return
+	}
+
+	if s.initPausedUntil.Load() != 0 && s.initPausedUntil.Swap(0) != 0 {
+		s.mgr.initPaused.Delete(key)
+		s.initMu.Lock()
+		if s.initResumed != nil {
+			close(s.initResumed)
+			s.initResumed = nil
+		}
+		s.initMu.Unlock()
+		log.Info().Str("service", service).Msg("resuming subscription as its service has initialized")
+		if s.ramp != nil {
+			s.ramp.restart(time.Now())
+		}
+	}
+}
+
+// isServiceInitErr reports whether err was caused by a service failing to initialize.
+func isServiceInitErr(err error) bool {
+	return errors.Is(err, service.ErrInitFailed)
+}
+
+// HealthCheck reports subscriptions which are paused
+// because their service failed to initialize.
+func (mgr *Manager) HealthCheck(ctx context.Context) []health.CheckResult {
+	var paused []string
+	mgr.initPaused.Range(func(key, svc any) bool {
+		k := key.(subscriptionKey)
+		paused = append(paused, fmt.Sprintf("%s/%s (service %s)", k.topic, k.subscription, svc))
+		return true
+	})
+	if len(paused) == 0 {
+		return []health.CheckResult{{Name: "pubsub.subscriptions"}}
+	}
+
+	sort.Strings(paused)
+	return []health.CheckResult{{
+		Name: "pubsub.subscriptions",
+		Err:  fmt.Errorf("the following subscriptions are paused as their service failed to initialize: %s", strings.Join(paused, ", ")),
+	}}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import "time"
+
+// Age reports how long ago the message was published, as of when it was received.
+//
+// As the publish time is set by the messaging service or the publisher, and the
+// receive time by the subscriber, clock skew between them can make a message
+// appear to have been published in the future. Age is never negative;
+// such messages are reported as having an age of zero.
+func (m *MessageMeta) Age() time.Duration {
+	return messageAge(m.PublishTime, m.ReceiveTime)
+}
+
+// messageAge returns how long before now the message was published,
+// clamped to zero if publishTime is in the future due to clock skew.
+func messageAge(publishTime, now time.Time) time.Duration {
+	if publishTime.IsZero() {
+		return 0
+	}
+	if age := now.Sub(publishTime); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// clockSkew reports how far publishTime is ahead of now, and whether
+// that exceeds tolerance. Messages can't be received before they're
+// published, so this indicates the clocks involved disagree.
+func clockSkew(publishTime, now time.Time, tolerance time.Duration) (skew time.Duration, skewed bool) {
+	if publishTime.IsZero() {
+		return 0, false
+	}
+	skew = publishTime.Sub(now)
+	return skew, skew > tolerance
+}
@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SubscriptionSLIs are service level indicators of how well a subscription
+// processes messages, for tracking SLOs. They cover the messages processed by
+// this instance of the service since it started.
+type SubscriptionSLIs struct {
+	// Processed is the number of messages whose Handler has completed,
+	// and Succeeded is how many of them it processed successfully.
+	Processed uint64
+	Succeeded uint64
+
+	// SuccessRate is the fraction of processed messages which succeeded.
+	// It is 1 if no messages have been processed.
+	SuccessRate float64
+
+	// LatencyP50, LatencyP95 and LatencyP99 are percentiles of the end-to-end
+	// latency of successfully processed messages: the time from a message being
+	// published until its Handler completed. They are estimated from a histogram,
+	// rounding up by at most 10%, and are zero if no messages have succeeded.
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+
+	// Freshness is how long ago the newest successfully processed message was
+	// published. It keeps growing while no newer messages are processed, so it
+	// reflects how up to date the subscription's processing is. It is zero
+	// if no messages have succeeded.
+	Freshness time.Duration
+}
+
+// sliTracker measures the SLIs of a subscription.
+type sliTracker struct {
+	succeeded       atomic.Uint64
+	failed          atomic.Uint64
+	latency         latencyHistogram
+	newestPublished atomic.Int64 // unix nanos when the newest processed message was published, or 0
+}
+
+// record records the outcome of processing a message published at publishTime.
+func (t *sliTracker) record(publishTime, now time.Time, err error) {
+	if err != nil {
+		t.failed.Add(1)
+		return
+	}
+	t.succeeded.Add(1)
+	if publishTime.IsZero() {
+		return
+	}
+	t.latency.record(messageAge(publishTime, now))
+	storeMax(&t.newestPublished, publishTime.UnixNano())
+}
+
+// snapshot returns the SLIs as of now.
+func (t *sliTracker) snapshot(now time.Time) SubscriptionSLIs {
+	succeeded := t.succeeded.Load()
+	slis := SubscriptionSLIs{
+		Processed:   succeeded + t.failed.Load(),
+		Succeeded:   succeeded,
+		SuccessRate: 1,
+		LatencyP50:  t.latency.quantile(0.50),
+		LatencyP95:  t.latency.quantile(0.95),
+		LatencyP99:  t.latency.quantile(0.99),
+	}
+	if slis.Processed > 0 {
+		slis.SuccessRate = float64(succeeded) / float64(slis.Processed)
+	}
+	if nanos := t.newestPublished.Load(); nanos != 0 {
+		slis.Freshness = messageAge(time.Unix(0, nanos), now)
+	}
+	return slis
+}
+
+const (
+	// latencyBucketsPerDoubling is the number of histogram buckets each time the
+	// latency doubles, which bounds the error of estimated percentiles to 2^(1/8)-1, about 9%.
+	latencyBucketsPerDoubling = 8
+
+	// latencyBuckets is the number of histogram buckets, covering latencies
+	// from a millisecond up to 2^30 milliseconds, about 12 days.
+	latencyBuckets = 30*latencyBucketsPerDoubling + 1
+)
+
+// latencyHistogram is a histogram of latencies with exponentially growing buckets,
+// so it uses the same small amount of memory however many latencies it records.
+type latencyHistogram struct {
+	total  atomic.Uint64
+	counts [latencyBuckets]atomic.Uint64
+}
+
+// record adds d to the histogram.
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[latencyBucket(d)].Add(1)
+	h.total.Add(1)
+}
+
+// quantile estimates the q-quantile of the recorded latencies, as the upper
+// bound of the bucket it falls in. It returns 0 if no latencies were recorded.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	total := h.total.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i := range h.counts {
+		seen += h.counts[i].Load()
+		if seen >= rank {
+			return latencyBucketBound(i)
+		}
+	}
+	// Latencies recorded concurrently may be counted in total but not yet in their bucket
+	return latencyBucketBound(latencyBuckets - 1)
+}
+
+// latencyBucket returns the index of the bucket d falls in.
+// Latencies beyond the last bucket are counted in it.
+func latencyBucket(d time.Duration) int {
+	if d <= time.Millisecond {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(float64(d)/float64(time.Millisecond)) * latencyBucketsPerDoubling))
+	return min(i, latencyBuckets-1)
+}
+
+// latencyBucketBound returns the upper bound of the bucket with index i.
+func latencyBucketBound(i int) time.Duration {
+	return time.Duration(float64(time.Millisecond) * math.Exp2(float64(i)/latencyBucketsPerDoubling))
+}
@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSubscription_SLIs(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "fail" {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// No messages have been processed yet
+	c.Assert(sub.Stats().SLIs, qt.Equals, SubscriptionSLIs{SuccessRate: 1})
+
+	deliver := func(id, value string, age time.Duration) {
+		_ = ft.subs["sub"](ctx, id, time.Now().Add(-age), 1, nil, []byte(`{"Value":"`+value+`"}`))
+	}
+	for i := 0; i < 98; i++ {
+		deliver("ok", "ok", time.Second)
+	}
+	deliver("slow", "ok", time.Minute)
+	deliver("fail", "fail", time.Hour)
+
+	slis := sub.Stats().SLIs
+	c.Assert(slis.Processed, qt.Equals, uint64(100))
+	c.Assert(slis.Succeeded, qt.Equals, uint64(99))
+	c.Assert(slis.SuccessRate, qt.Equals, 0.99)
+
+	// Latencies are estimated within 10%, ignoring the failed message
+	within := func(got, want time.Duration) bool { return got >= want && got <= want+want/10 }
+	c.Assert(within(slis.LatencyP50, time.Second), qt.IsTrue, qt.Commentf("p50 %s", slis.LatencyP50))
+	c.Assert(within(slis.LatencyP95, time.Second), qt.IsTrue, qt.Commentf("p95 %s", slis.LatencyP95))
+	c.Assert(within(slis.LatencyP99, time.Minute), qt.IsTrue, qt.Commentf("p99 %s", slis.LatencyP99))
+
+	// Freshness is the age of the newest processed message
+	c.Assert(slis.Freshness >= time.Second && slis.Freshness < time.Minute, qt.IsTrue)
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	c := qt.New(t)
+	var h latencyHistogram
+	c.Assert(h.quantile(0.5), qt.Equals, time.Duration(0))
+
+	// Tiny latencies fall in the first bucket, huge ones in the last
+	h.record(time.Microsecond)
+	c.Assert(h.quantile(0.5), qt.Equals, time.Millisecond)
+	h.record(365 * 24 * time.Hour)
+	c.Assert(h.quantile(1), qt.Equals, latencyBucketBound(latencyBuckets-1))
+
+	// Every latency is within its bucket's bounds
+	for _, d := range []time.Duration{2 * time.Millisecond, 7 * time.Millisecond, 1500 * time.Millisecond, time.Hour} {
+		i := latencyBucket(d)
+		c.Assert(d <= latencyBucketBound(i), qt.IsTrue)
+		c.Assert(d > latencyBucketBound(i-1), qt.IsTrue)
+	}
+}
@@ -0,0 +1,259 @@
+package pubsub
+
+import (
+	"sync/atomic"
+	"time"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// SubscriptionStats contains runtime statistics about a subscription
+// for the current instance of the service.
+// Additional fields may be added in the future.
+type SubscriptionStats struct {
+	// CircuitBreaker is the state of the subscription's circuit breaker.
+	// It is CircuitClosed if no circuit breaker is configured.
+	CircuitBreaker CircuitBreakerState
+
+	// DecodeErrors is the number of messages which could not be
+	// decoded into the subscription's message type.
+	DecodeErrors uint64
+
+	// ClockSkewed is the number of messages received with a publish time
+	// further in the future than the subscription's ClockSkewTolerance.
+	ClockSkewed uint64
+
+	// MaxInFlightExceeded is the number of messages negatively acknowledged
+	// because their Handler was still running when the subscription's
+	// MaxInFlight passed.
+	MaxInFlightExceeded uint64
+
+	// RedeliveryStorms is the number of deliveries rejected or quarantined
+	// because their message was delivered more often than the subscription's
+	// RedeliveryStorm configuration allows. This indicates the messaging
+	// service is misbehaving, so it should be alerted on when non-zero.
+	RedeliveryStorms uint64
+
+	// RetryDurationExceeded is the number of messages quarantined because
+	// their Handler failed after the subscription's MaxRetryDuration had passed.
+	RetryDurationExceeded uint64
+
+	// Filtered is the number of messages acknowledged without being
+	// processed because the subscription's FilterExpr excluded them.
+	Filtered uint64
+
+	// BacklogSkipped is the number of messages acknowledged without being
+	// processed because they were published before the cutoff of the
+	// subscription's SkipBacklog configuration.
+	BacklogSkipped uint64
+
+	// ServiceInitFailures is the number of messages whose Handler failed
+	// because the service it belongs to could not be initialized.
+	ServiceInitFailures uint64
+
+	// PausedForServiceInit reports whether the subscription is currently
+	// paused because its service failed to initialize. While paused, messages
+	// are held without calling the Handler, and the Handler is tried again
+	// every few seconds until the service initializes. Held messages are only
+	// negatively acknowledged if they can't be held any longer, such as when
+	// the subscription shuts down.
+	PausedForServiceInit bool
+
+	// LastReceived is when this instance last received a message
+	// for the subscription. It is the zero time if none has been received.
+	LastReceived time.Time
+
+	// Idle reports whether the subscription has currently released its
+	// resources because no messages have been received for its IdleTimeout.
+	Idle bool
+
+	// LastProcessedAge is how long ago the most recently processed message
+	// was published, when its processing started.
+	LastProcessedAge time.Duration
+
+	// MaxProcessedAge is the longest time any message processed
+	// by this instance waited between being published and processed.
+	MaxProcessedAge time.Duration
+
+	// ConcurrencyWait is the total time messages have spent waiting for
+	// a slot within the subscription's MaxConcurrency before their Handler
+	// was called. Consistently high waits suggest raising MaxConcurrency
+	// or scaling out.
+	//
+	// Most providers enforce MaxConcurrency themselves by not receiving more
+	// messages than it allows, and that time is not included; it only covers
+	// waits for subscriptions with LIFO or Fairness enabled, which limit
+	// concurrency locally.
+	ConcurrencyWait time.Duration
+
+	// MaxConcurrencyWait is the longest time any message has spent
+	// waiting for a slot within the subscription's MaxConcurrency.
+	// See ConcurrencyWait for when waits are measured.
+	MaxConcurrencyWait time.Duration
+
+	// PendingAcks is the number of acknowledgements of processed messages
+	// currently being completed in the background. It is always zero
+	// unless the subscription has AsyncAck enabled.
+	PendingAcks int64
+
+	// AckFailures is the number of messages whose asynchronous acknowledgement
+	// failed after retrying, so they will be redelivered.
+	AckFailures uint64
+
+	// MaxAckLatency is the longest time an asynchronous acknowledgement took to
+	// complete, including retries. It is the latency AsyncAck takes off the
+	// processing of messages at worst.
+	MaxAckLatency time.Duration
+
+	// AckLatencyP50 and AckLatencyP99 are percentiles of the time asynchronous
+	// acknowledgements took to complete, including retries, estimated like
+	// the latencies of SubscriptionSLIs. As messages no longer wait for them,
+	// they measure the latency AsyncAck takes off the processing of a typical
+	// message and of the slowest messages respectively.
+	AckLatencyP50 time.Duration
+	AckLatencyP99 time.Duration
+
+	// BufferedBytes is the size of the messages this instance is currently
+	// processing for the subscription. See SetBufferBudget.
+	BufferedBytes int64
+
+	// DispatchLoad is the number of messages processed by each of
+	// the subscription's Dispatch workers, indexed by worker.
+	// It is nil if the subscription does not dispatch messages by key.
+	DispatchLoad []uint64
+
+	// KeysInFlight is the number of messages of each key currently being
+	// processed or waiting for their key's worker, keyed by key. It is nil
+	// unless the subscription dispatches messages with Dispatch.Ordered set.
+	KeysInFlight map[string]int
+
+	// Coalesced is the number of messages which were coalesced into another
+	// message's Handler call, rather than the Handler being called for them.
+	// It is always zero unless the subscription has Coalesce configured.
+	Coalesced uint64
+
+	// Tenants is the statistics of each tenant whose messages this instance
+	// has received, keyed by tenant. It is nil unless the subscription has
+	// Fairness in effect.
+	Tenants map[string]TenantStats
+
+	// DecodeCacheHits is the number of deliveries whose message was taken
+	// from the subscription's DecodeCache rather than being decoded again.
+	// DecodeCacheMisses is the number of deliveries whose message had to be
+	// decoded. Both are zero unless the subscription has DecodeCache configured.
+	DecodeCacheHits   uint64
+	DecodeCacheMisses uint64
+
+	// RecoveryRate is the number of messages per second the subscription's
+	// RecoveryRamp currently limits processing to. It is zero unless a ramp
+	// is in progress.
+	RecoveryRate float64
+
+	// FlowControl is the subscription's current flow control settings.
+	// It is nil if the subscription's provider does not support
+	// adjusting flow control, or the subscription does not pull messages.
+	FlowControl *FlowControl
+
+	// InitialPosition is where the subscription started receiving messages
+	// from when it was created. It is InitialPositionDefault if the
+	// subscription's provider does not report it.
+	InitialPosition InitialPosition
+
+	// SLIs are the subscription's service level indicators,
+	// such as its success rate and end-to-end latency.
+	SLIs SubscriptionSLIs
+
+	// Dropped is the number of messages this instance stopped processing without
+	// the Handler succeeding, keyed by reason. It is nil if none were dropped.
+	// See Manager.DroppedMessages for the messages dropped across subscriptions.
+	Dropped map[DropReason]uint64
+}
+
+// Stats returns runtime statistics about the subscription.
+func (s *Subscription[T]) Stats() SubscriptionStats {
+	stats := SubscriptionStats{
+		CircuitBreaker:        circuitBreakerState(s.breaker),
+		DecodeErrors:          s.decodeErrors.Load(),
+		ClockSkewed:           s.clockSkewed.Load(),
+		MaxInFlightExceeded:   s.maxInFlightExceeded.Load(),
+		RedeliveryStorms:      s.redeliveryStorms.Load(),
+		RetryDurationExceeded: s.retryExpired.Load(),
+		BacklogSkipped:        s.backlog.count(),
+		Filtered:              s.filtered.Load(),
+		ServiceInitFailures:   s.initFailures.Load(),
+		PausedForServiceInit:  s.initPausedUntil.Load() != 0,
+		LastProcessedAge:      time.Duration(s.lastAge.Load()),
+		MaxProcessedAge:       time.Duration(s.maxAge.Load()),
+		ConcurrencyWait:       time.Duration(s.totalWait.Load()),
+		MaxConcurrencyWait:    time.Duration(s.maxWait.Load()),
+		BufferedBytes:         s.bufferedBytes.Load(),
+		RecoveryRate:          s.ramp.currentRate(),
+		InitialPosition:       s.initialPosition,
+		SLIs:                  s.sli.snapshot(time.Now()),
+		Dropped:               s.mgr.dropped.subscription(s.topic.runtimeCfg.EncoreName, s.name),
+	}
+
+	if nanos := s.lastReceived.Load(); nanos != 0 {
+		stats.LastReceived = time.Unix(0, nanos)
+	}
+
+	if ir, ok := s.topic.topic.(types.IdleReleaser); ok {
+		stats.Idle = ir.Idle(s.name)
+	}
+
+	if s.acks != nil {
+		stats.PendingAcks = s.acks.pending.Load()
+		stats.AckFailures = s.acks.failures.Load()
+		stats.MaxAckLatency = time.Duration(s.acks.maxLatency.Load())
+		stats.AckLatencyP50 = s.acks.latency.quantile(0.50)
+		stats.AckLatencyP99 = s.acks.latency.quantile(0.99)
+	}
+
+	if s.dispatch != nil {
+		stats.DispatchLoad = s.dispatch.loads()
+		stats.KeysInFlight = s.dispatch.keysInFlight()
+	}
+
+	if s.coalesce != nil {
+		stats.Coalesced = s.coalesce.coalesced.Load()
+	}
+
+	if s.decoded != nil {
+		stats.DecodeCacheHits = s.decoded.hits.Load()
+		stats.DecodeCacheMisses = s.decoded.misses.Load()
+	}
+
+	if s.fair != nil {
+		stats.Tenants = s.fair.tenantStats()
+	}
+
+	if fc, ok := s.topic.topic.(types.FlowController); ok {
+		if settings, ok := fc.FlowControl(s.name); ok {
+			stats.FlowControl = &settings
+		}
+	}
+
+	return stats
+}
+
+// recordAge records the age of a message as its processing starts.
+func (s *Subscription[T]) recordAge(age time.Duration) {
+	s.lastAge.Store(int64(age))
+	storeMax(&s.maxAge, int64(age))
+}
+
+// recordWait records how long a message waited for a concurrency slot.
+func (s *Subscription[T]) recordWait(wait time.Duration) {
+	s.totalWait.Add(int64(wait))
+	storeMax(&s.maxWait, int64(wait))
+}
+
+// storeMax stores v in x if it is larger than the current value.
+func storeMax(x *atomic.Int64, v int64) {
+	for {
+		cur := x.Load()
+		if v <= cur || x.CompareAndSwap(cur, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"encore.dev/metrics"
+	"encore.dev/pubsub/internal/utils"
+)
+
+// RedeliveryStormConfig configures how a subscription protects itself
+// against the messaging service redelivering the same message in a tight loop,
+// for example due to a bug in the messaging service.
+//
+// A message delivered to an instance more than MaxDeliveries times within
+// Window is considered to be caught in a redelivery storm. Its deliveries are
+// negatively acknowledged without calling the Handler, leaving the message to
+// the messaging service's retry policy and dead-letter queue, unless Quarantine
+// is set. Either way an error is logged, and each such delivery is counted in
+// the subscription's Stats and in the e_pubsub_redelivery_storms_total metric,
+// so storms can be alerted on.
+type RedeliveryStormConfig struct {
+	// MaxDeliveries is the number of times a single message may be delivered
+	// to this instance within Window before it is dead-lettered.
+	//
+	// Redeliveries made according to the RetryPolicy are spaced out by its
+	// backoff, so this should be comfortably above the number of retries
+	// the RetryPolicy makes within Window.
+	//
+	// Defaults to 100.
+	MaxDeliveries int
+
+	// Window is the period deliveries of a message are counted over,
+	// starting from its first delivery.
+	//
+	// Defaults to 1 minute.
+	Window time.Duration
+
+	// Quarantine, if set, quarantines messages caught in a storm and acknowledges
+	// them, rather than negatively acknowledging them. See OnQuarantine.
+	//
+	// If the subscription has no OnQuarantine hook, the message is logged
+	// and acknowledged, so it is lost.
+	Quarantine bool
+}
+
+// stormLabels are the labels of the redelivery storms metric.
+type stormLabels struct {
+	topic        string
+	subscription string
+}
+
+// newStormCounter returns the counter of the subscription's deliveries caught in
+// redelivery storms, or nil if the manager doesn't record metrics.
+func newStormCounter(mgr *Manager, topic, subscription string, svcNum uint16) *metrics.Counter[uint64] {
+	if mgr.metrics == nil {
+		return nil
+	}
+	group := metrics.NewCounterGroupInternal[stormLabels, uint64](mgr.metrics, "e_pubsub_redelivery_storms_total", metrics.CounterConfig{
+		EncoreInternal_LabelMapper: func(labels stormLabels) []metrics.KeyValue {
+			return []metrics.KeyValue{
+				{Key: "topic", Value: labels.topic},
+				{Key: "subscription", Value: labels.subscription},
+			}
+		},
+		EncoreInternal_SvcNum: svcNum,
+	})
+	return group.With(stormLabels{topic: topic, subscription: subscription})
+}
+
+// errRedeliveryStorm is reported when a message is delivered too many times in a short period.
+var errRedeliveryStorm = errors.New("message redelivered too many times")
+
+// maxStormTrackedMessages is the number of message IDs whose deliveries are
+// counted at once. Messages caught in a storm are delivered continuously,
+// so they stay among the most recently delivered.
+const maxStormTrackedMessages = 10_000
+
+// stormDetector counts deliveries of each message ID to detect redelivery storms.
+type stormDetector struct {
+	maxDeliveries int
+	window        time.Duration
+
+	mu         sync.Mutex // serializes counting deliveries
+	deliveries *utils.LRU[string, stormCount]
+}
+
+// stormCount is the number of deliveries of a message within the window starting at start.
+type stormCount struct {
+	start time.Time
+	count int
+}
+
+func newStormDetector(cfg *RedeliveryStormConfig) *stormDetector {
+	return &stormDetector{
+		maxDeliveries: cfg.MaxDeliveries,
+		window:        cfg.Window,
+		deliveries:    utils.NewLRU[string, stormCount](maxStormTrackedMessages, cfg.Window),
+	}
+}
+
+// delivered records a delivery of msgID at now, returning an error
+// wrapping errRedeliveryStorm if it has been delivered too many times.
+// A nil detector never reports a storm.
+func (d *stormDetector) delivered(msgID string, now time.Time) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c, ok := d.deliveries.Get(msgID)
+	if !ok || now.Sub(c.start) > d.window {
+		c = stormCount{start: now}
+	}
+	c.count++
+	d.deliveries.Set(msgID, c)
+
+	if c.count > d.maxDeliveries {
+		return fmt.Errorf("%w: delivered %d times within %s", errRedeliveryStorm, c.count, d.window)
+	}
+	return nil
+}
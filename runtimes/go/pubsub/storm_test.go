@@ -0,0 +1,124 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/beta/errs"
+	"encore.dev/metrics"
+)
+
+func TestSubscription_RedeliveryStorm(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+	mgr.metrics = metrics.NewRegistry(mgr.rt, 1)
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var handled int
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			handled++
+			return nil
+		},
+		RedeliveryStorm: &RedeliveryStormConfig{MaxDeliveries: 5, Window: time.Hour},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	// Rapid redeliveries are processed up to the limit
+	for i := 0; i < 5; i++ {
+		c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	}
+	c.Assert(handled, qt.Equals, 5)
+
+	// Beyond it the message is negatively acknowledged without calling the handler,
+	// leaving it to the messaging service's retry policy and dead-letter queue
+	err := ft.deliver(ctx, "sub", "1", 2, nil, data)
+	c.Assert(errs.Code(err), qt.Equals, errs.Aborted)
+	c.Assert(err, qt.ErrorMatches, ".*message caught in a redelivery storm.*")
+	c.Assert(handled, qt.Equals, 5)
+	c.Assert(sub.Stats().RedeliveryStorms, qt.Equals, uint64(1))
+	c.Assert(buf.String(), qt.Contains, `"message":"message caught in a redelivery storm, negatively acknowledging it"`)
+	c.Assert(buf.String(), qt.Not(qt.Contains), "failed to unmarshal message")
+
+	// And counted in the metric, so storms can be alerted on
+	var storms []uint64
+	for _, m := range mgr.metrics.Collect() {
+		if m.Info.Name() == "e_pubsub_redelivery_storms_total" {
+			c.Assert(m.Labels, qt.DeepEquals, []metrics.KeyValue{{Key: "topic", Value: "topic"}, {Key: "subscription", Value: "sub"}})
+			storms = append(storms, m.Val.([]uint64)...)
+		}
+	}
+	c.Assert(storms, qt.DeepEquals, []uint64{1})
+
+	// Other messages are unaffected
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, data), qt.IsNil)
+	c.Assert(handled, qt.Equals, 6)
+}
+
+func TestSubscription_RedeliveryStormQuarantine(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var quarantined []*QuarantinedMessage
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler:         func(ctx context.Context, msg *testEvent) error { return nil },
+		RedeliveryStorm: &RedeliveryStormConfig{MaxDeliveries: 1, Window: time.Hour, Quarantine: true},
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, data), qt.IsNil)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].ID, qt.Equals, "1")
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, "message redelivered too many times: delivered 2 times within 1h0m0s")
+}
+
+func TestSubscription_RedeliveryStormDisabled(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var handled int
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			handled++
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+
+	// Without a RedeliveryStorm config, hot retry loops are processed as usual
+	for i := 0; i < 200; i++ {
+		c.Assert(ft.deliver(context.Background(), "sub", "1", i+1, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	}
+	c.Assert(handled, qt.Equals, 200)
+}
+
+func TestStormDetector_Window(t *testing.T) {
+	c := qt.New(t)
+	d := newStormDetector(&RedeliveryStormConfig{MaxDeliveries: 2, Window: time.Minute})
+	now := time.Now()
+
+	c.Assert(d.delivered("1", now), qt.IsNil)
+	c.Assert(d.delivered("1", now.Add(time.Second)), qt.IsNil)
+	c.Assert(d.delivered("1", now.Add(2*time.Second)), qt.IsNotNil)
+
+	// Deliveries are counted afresh once the window has passed
+	c.Assert(d.delivered("1", now.Add(2*time.Minute)), qt.IsNil)
+}
@@ -2,7 +2,11 @@ package pubsub
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
@@ -11,16 +15,54 @@ import (
 	"encore.dev/appruntime/exported/model"
 	"encore.dev/appruntime/exported/trace2"
 	"encore.dev/beta/errs"
+	"encore.dev/metrics"
 	"encore.dev/pubsub/internal/noop"
+	"encore.dev/pubsub/internal/types"
 	"encore.dev/pubsub/internal/utils"
 )
 
 // Subscription represents a subscription to a Topic.
 type Subscription[T any] struct {
-	topic *Topic[T]
-	name  string
-	cfg   SubscriptionConfig[T]
-	mgr   *Manager
+	topic   *Topic[T]
+	name    string
+	cfg     SubscriptionConfig[T]
+	mgr     *Manager
+	breaker *utils.CircuitBreaker // nil if no circuit breaker is configured
+	lifo    *utils.LIFOGate       // nil unless LIFO is in effect
+	fair    *fairScheduler[T]     // nil unless Fairness is in effect
+
+	dispatch *dispatcher[T]  // nil if no dispatcher is configured
+	coalesce *coalescer[T]   // nil unless Coalesce is configured
+	backlog  *backlogSkipper // nil unless SkipBacklog is configured
+	acks     *asyncAcker     // nil unless AsyncAck is in effect
+	ramp     *recoveryRamp   // nil unless RecoveryRamp is configured
+	decoded  *decodeCache[T] // nil unless DecodeCache is configured
+
+	decodeErrors        atomic.Uint64 // number of messages which failed to decode
+	clockSkewed         atomic.Uint64 // number of messages published further in the future than ClockSkewTolerance
+	maxInFlightExceeded atomic.Uint64 // number of messages nacked because their handler exceeded MaxInFlight
+	redeliveryStorms    atomic.Uint64 // number of deliveries rejected or quarantined as the message was caught in a redelivery storm
+	retryExpired        atomic.Uint64 // number of messages quarantined as they failed after MaxRetryDuration
+	filtered            atomic.Uint64 // number of messages acknowledged without processing as FilterExpr excluded them
+	lastAge             atomic.Int64  // age of the most recently processed message, as a time.Duration
+	maxAge              atomic.Int64  // age of the oldest processed message, as a time.Duration
+	bufferedBytes       atomic.Int64  // bytes of messages currently being processed
+	lastReceived        atomic.Int64  // unix nanos when the most recent message was received, or 0
+	initPausedUntil     atomic.Int64  // unix nanos until which the handler is paused as its service failed to initialize, or 0
+	initFailures        atomic.Uint64 // number of messages which failed as the handler's service failed to initialize
+	initMu              sync.Mutex    // protects initResumed
+	initResumed         chan struct{} // closed when the subscription resumes after its service initializes; nil if nobody is waiting
+	totalWait           atomic.Int64  // total time messages waited for a concurrency slot, as a time.Duration
+	maxWait             atomic.Int64  // longest time a message waited for a concurrency slot, as a time.Duration
+
+	sli sliTracker // measures the subscription's SLIs
+
+	drain   atomic.Pointer[drainState[T]]   // the active DrainTo call, if any
+	handler atomic.Pointer[handlerState[T]] // the current Handler; nil if the subscription isn't running on this instance
+
+	initialPosition InitialPosition // the effective initial position of the subscription
+
+	pull *pullQueue[T] // messages waiting for Receive; nil if the subscription isn't running on this instance
 }
 
 // NewSubscription is used to declare a Subscription to a topic. The passed in handler will be called
@@ -30,7 +72,8 @@ type Subscription[T any] struct {
 // calls to this function made outside a package level variable declaration will result
 // in a compiler error.
 //
-// The subscription name must be unique for that topic. Subscription names must be defined
+// The subscription name must be unique for that topic, though subscriptions to different topics
+// may share a name; declaring it twice on the same topic panics. Subscription names must be defined
 // in kebab-case (lowercase alphanumerics and hyphen separated). The subscription name must start with a letter
 // and end with either a letter or number. It cannot be longer than 63 characters.
 //
@@ -64,14 +107,33 @@ func NewSubscription[T any](topic *Topic[T], name string, cfg SubscriptionConfig
 		panic("pubsub topic was not created using pubsub.NewTopic")
 	}
 
+	// Subscription names only need to be unique within their topic.
+	// If the subscription's configuration is invalid, the name is released again.
+	if _, dup := topic.subscriptions.LoadOrStore(name, struct{}{}); dup {
+		panic(fmt.Sprintf("subscription %s is already declared on topic %s", name, topic.runtimeCfg.EncoreName))
+	}
+	declared := false
+	defer func() {
+		if !declared {
+			topic.subscriptions.Delete(name)
+		}
+	}()
+
 	mgr := topic.mgr
 	if _, isNoop := topic.topic.(*noop.Topic); isNoop {
 		// no-op means no-op!
+		declared = true
 		return &Subscription[T]{topic: topic, name: name, cfg: cfg, mgr: mgr}
 	}
 
 	// Set default config values for missing values
-	if cfg.RetryPolicy == nil {
+	if topic.staticCfg.DeliveryGuarantee == AtMostOnce {
+		// Messages are acknowledged before they are processed, so are never retried
+		if cfg.RetryPolicy != nil {
+			panic("RetryPolicy cannot be set for subscriptions to AtMostOnce topics")
+		}
+		cfg.RetryPolicy = &RetryPolicy{MaxRetries: NoRetries}
+	} else if cfg.RetryPolicy == nil {
 		cfg.RetryPolicy = &RetryPolicy{
 			MaxRetries: 100,
 		}
@@ -85,26 +147,194 @@ func NewSubscription[T any](topic *Topic[T], name string, cfg SubscriptionConfig
 	cfg.RetryPolicy.MinBackoff = utils.WithDefaultValue(cfg.RetryPolicy.MinBackoff, 10*time.Second)
 	cfg.RetryPolicy.MaxBackoff = utils.WithDefaultValue(cfg.RetryPolicy.MaxBackoff, 10*time.Minute)
 
+	if cfg.MaxProcessingTime < 0 {
+		panic("MaxProcessingTime cannot be negative")
+	}
+
+	if cfg.MaxInFlight < 0 {
+		panic("MaxInFlight cannot be negative")
+	}
+	if cfg.MaxRetryDuration < 0 {
+		panic("MaxRetryDuration cannot be negative")
+	}
+
+	var filter messageFilter
+	if cfg.FilterExpr != "" {
+		var err error
+		if filter, err = parseFilter(cfg.FilterExpr); err != nil {
+			panic(fmt.Sprintf("invalid FilterExpr: %v", err))
+		}
+	}
+
+	if cfg.IdleTimeout < 0 {
+		panic("IdleTimeout cannot be negative")
+	}
+
+	if cfg.ClockSkewTolerance < 0 {
+		panic("ClockSkewTolerance cannot be negative")
+	}
+	cfg.ClockSkewTolerance = utils.WithDefaultValue(cfg.ClockSkewTolerance, 5*time.Second)
+
 	if cfg.AckDeadline == 0 {
 		cfg.AckDeadline = 30 * time.Second
 	} else if cfg.AckDeadline < 0 {
 		panic("AckDeadline cannot be negative")
 	}
 
+	var dedupStore DedupStore
+	if cfg.DedupByMessageID {
+		if cfg.Dedup == nil {
+			cfg.Dedup = &DedupConfig{}
+		}
+		if cfg.Dedup.Window < 0 {
+			panic("Dedup.Window cannot be negative")
+		}
+		if cfg.Dedup.MaxSize < 0 {
+			panic("Dedup.MaxSize cannot be negative")
+		}
+		cfg.Dedup.Window = utils.WithDefaultValue(cfg.Dedup.Window, 10*time.Minute)
+		cfg.Dedup.MaxSize = utils.WithDefaultValue(cfg.Dedup.MaxSize, 10_000)
+
+		switch store := cfg.Dedup.Store.(type) {
+		case nil:
+			dedupStore = newMemoryDedupStore(cfg.Dedup.MaxSize, cfg.Dedup.Window)
+		case VersionedDedupStore:
+			dedupStore = newMigratingDedupStore(store)
+		default:
+			dedupStore = store
+		}
+	}
+
+	var idempotencyStore IdempotencyStore
+	if cfg.Idempotency != nil {
+		if cfg.Idempotency.TTL < 0 {
+			panic("Idempotency.TTL cannot be negative")
+		}
+		if cfg.Idempotency.MaxSize < 0 {
+			panic("Idempotency.MaxSize cannot be negative")
+		}
+		cfg.Idempotency.TTL = utils.WithDefaultValue(cfg.Idempotency.TTL, 24*time.Hour)
+		cfg.Idempotency.MaxSize = utils.WithDefaultValue(cfg.Idempotency.MaxSize, 10_000)
+
+		idempotencyStore = cfg.Idempotency.Store
+		if idempotencyStore == nil {
+			idempotencyStore = newMemoryIdempotencyStore(cfg.Idempotency.MaxSize, cfg.Idempotency.TTL)
+		}
+	}
+
+	var breaker *utils.CircuitBreaker
+	if cfg.CircuitBreaker != nil {
+		breaker = newCircuitBreaker(cfg.CircuitBreaker)
+	}
+
+	var committer *commitBatcher
+	if cfg.Commit != nil {
+		committer = newCommitBatcher(cfg.Commit)
+	}
+
+	var dispatch *dispatcher[T]
+	if cfg.Dispatch != nil {
+		if cfg.Dispatch.KeyFunc == nil && (!cfg.Dispatch.Ordered || topic.staticCfg.OrderingAttribute == "") {
+			panic("Dispatch.KeyFunc is required")
+		}
+		if cfg.Dispatch.Workers < 0 {
+			panic("Dispatch.Workers cannot be negative")
+		}
+		if cfg.Dispatch.Ordered && cfg.AsyncAck {
+			panic("Dispatch.Ordered cannot be combined with AsyncAck")
+		}
+		dispatchCfg := *cfg.Dispatch
+		dispatchCfg.Workers = utils.WithDefaultValue(dispatchCfg.Workers, 16)
+		dispatch = newDispatcher(&dispatchCfg, topic.staticCfg.OrderingAttribute)
+	}
+
+	var coalesce *coalescer[T]
+	if cfg.Coalesce != nil {
+		if cfg.Coalesce.KeyFunc == nil {
+			panic("Coalesce.KeyFunc is required")
+		}
+		if cfg.Coalesce.Window < 0 {
+			panic("Coalesce.Window cannot be negative")
+		}
+		if cfg.Dispatch != nil {
+			panic("Coalesce cannot be combined with Dispatch")
+		}
+		coalesceCfg := *cfg.Coalesce
+		coalesceCfg.Window = utils.WithDefaultValue(coalesceCfg.Window, time.Second)
+		coalesce = newCoalescer(&coalesceCfg)
+	}
+
+	if cfg.Fairness != nil {
+		if cfg.Fairness.TenantFunc == nil {
+			panic("Fairness.TenantFunc is required")
+		}
+		if cfg.Fairness.MaxPerTenant < 0 {
+			panic("Fairness.MaxPerTenant cannot be negative")
+		}
+		if cfg.LIFO {
+			panic("Fairness cannot be combined with LIFO")
+		}
+	}
+
+	validateSubscriptionBackendOptions(cfg.GCP, cfg.NSQ)
+
+	var decoded *decodeCache[T]
+	if cfg.DecodeCache != nil {
+		if cfg.DecodeCache.MaxMessages < 0 {
+			panic("DecodeCache.MaxMessages cannot be negative")
+		}
+		if cfg.DecodeCache.TTL < 0 {
+			panic("DecodeCache.TTL cannot be negative")
+		}
+		cacheCfg := *cfg.DecodeCache
+		cacheCfg.MaxMessages = utils.WithDefaultValue(cacheCfg.MaxMessages, 100)
+		cacheCfg.TTL = utils.WithDefaultValue(cacheCfg.TTL, 15*time.Minute)
+		decoded = newDecodeCache[T](&cacheCfg)
+	}
+
+	var ramp *recoveryRamp
+	if cfg.RecoveryRamp != nil {
+		if cfg.RecoveryRamp.InitialPerSecond <= 0 {
+			panic("RecoveryRamp.InitialPerSecond must be positive")
+		}
+		if cfg.RecoveryRamp.TargetPerSecond < cfg.RecoveryRamp.InitialPerSecond {
+			panic("RecoveryRamp.TargetPerSecond must be at least InitialPerSecond")
+		}
+		if cfg.RecoveryRamp.Duration < 0 {
+			panic("RecoveryRamp.Duration cannot be negative")
+		}
+		rampCfg := *cfg.RecoveryRamp
+		rampCfg.Duration = utils.WithDefaultValue(rampCfg.Duration, time.Minute)
+		ramp = newRecoveryRamp(&rampCfg)
+	}
+
+	var storms *stormDetector
+	if cfg.RedeliveryStorm != nil {
+		if cfg.RedeliveryStorm.MaxDeliveries < 0 {
+			panic("RedeliveryStorm.MaxDeliveries cannot be negative")
+		}
+		if cfg.RedeliveryStorm.Window < 0 {
+			panic("RedeliveryStorm.Window cannot be negative")
+		}
+		cfg.RedeliveryStorm.MaxDeliveries = utils.WithDefaultValue(cfg.RedeliveryStorm.MaxDeliveries, 100)
+		cfg.RedeliveryStorm.Window = utils.WithDefaultValue(cfg.RedeliveryStorm.Window, time.Minute)
+		storms = newStormDetector(cfg.RedeliveryStorm)
+	}
+
 	subscription, staticCfg, exists := topic.getSubscriptionConfig(name)
 	if !exists {
 		// Noop subscription
+		declared = true
 		return &Subscription[T]{topic: topic, name: name, cfg: cfg, mgr: mgr}
 	}
 
-	panicCatchWrapper := func(ctx context.Context, msg T) (err error) {
-		defer func() {
-			if err2 := recover(); err2 != nil {
-				err = errs.B().Code(errs.Internal).Msgf("subscriber panicked: %s", err2).Err()
-			}
-		}()
-
-		return cfg.Handler(ctx, msg)
+	sub := &Subscription[T]{topic: topic, name: name, cfg: cfg, mgr: mgr, breaker: breaker, dispatch: dispatch, coalesce: coalesce, pull: newPullQueue[T](mgr)}
+	sub.handler.Store(newHandlerState(cfg.Handler))
+	sub.backlog = newBacklogSkipper(cfg.SkipBacklog, time.Now())
+	sub.decoded = decoded
+	if ramp != nil {
+		sub.ramp = ramp
+		ramp.restart(time.Now())
 	}
 
 	log := mgr.rootLogger.With().
@@ -115,24 +345,327 @@ func NewSubscription[T any](topic *Topic[T], name string, cfg SubscriptionConfig
 
 	tracingEnabled := mgr.rt.TracingEnabled()
 
+	panicCatchWrapper := func(ctx context.Context, handler func(context.Context, T) error, msg T, mc *messageContext) (err error) {
+		meta := mc.meta
+		defer func() {
+			if err2 := recover(); err2 != nil {
+				if cfg.OnPanic == PanicAckCompleted && mc.completed.Load() {
+					// Trust the handler's report that it had finished processing the message
+					log.Error().Interface("panic", err2).Str("msg_id", meta.ID).Int("delivery_attempt", meta.Attempt).
+						Msg("handler panicked after completing the message, acknowledging it")
+					err = nil
+				} else {
+					err = panicError(log, err2, meta, cfg.PanicToError)
+				}
+				mgr.handlerPanicked(PanicInfo{
+					Topic:        topic.runtimeCfg.EncoreName,
+					Subscription: name,
+					MessageID:    meta.ID,
+					Attempt:      meta.Attempt,
+					Value:        err2,
+					Stack:        debug.Stack(),
+				})
+			}
+		}()
+
+		return handler(ctx, msg)
+	}
+
+	var stormCounter *metrics.Counter[uint64]
+	if storms != nil {
+		stormCounter = newStormCounter(mgr, topic.runtimeCfg.EncoreName, name, staticCfg.SvcNum)
+	}
+
+	pos, supported := effectiveInitialPosition(topic.topic, cfg.InitialPosition)
+	if !supported {
+		log.Warn().Stringer("initial_position", cfg.InitialPosition).Stringer("effective_position", pos).
+			Msg("initial position is not supported by the pubsub provider, using its native behaviour")
+	}
+	sub.initialPosition = pos
+
+	if sub.backlog != nil {
+		log.Error().Time("cutoff", sub.backlog.cutoff).
+			Msg("skipping backlog: messages published before the cutoff will be acknowledged without being processed")
+	}
+
+	// In LIFO mode we prefetch a window of extra messages and
+	// let the newest through first when the handlers are busy.
+	providerConcurrency := cfg.MaxConcurrency
+	if cfg.LIFO {
+		if cfg.MaxConcurrency > 0 {
+			sub.lifo = utils.NewLIFOGate(cfg.MaxConcurrency)
+			providerConcurrency = 2 * cfg.MaxConcurrency
+		} else {
+			log.Warn().Int("max_concurrency", cfg.MaxConcurrency).Msg("LIFO requires a positive MaxConcurrency, processing messages in delivery order")
+		}
+	}
+
+	// Likewise with Fairness, letting the tenants with the
+	// fewest messages being processed through first.
+	if cfg.Fairness != nil {
+		if cfg.MaxConcurrency > 0 {
+			sub.fair = newFairScheduler(cfg.Fairness, cfg.MaxConcurrency)
+			providerConcurrency = 2 * cfg.MaxConcurrency
+		} else {
+			log.Warn().Int("max_concurrency", cfg.MaxConcurrency).Msg("Fairness requires a positive MaxConcurrency, processing messages in delivery order")
+		}
+	}
+
+	// Pass on the backend-specific options before subscribing, so they're applied when subscribing
+	warnInactiveBackendOptions(log, topic.provider, cfg.GCP != nil, cfg.NSQ != nil)
+	if so, ok := topic.topic.(types.SubscriptionOptioner); ok && (cfg.GCP != nil || cfg.NSQ != nil || cfg.FilterExpr != "") {
+		so.SetSubscriptionOptions(subscription.EncoreName, types.SubscriptionOptions{GCP: cfg.GCP, NSQ: cfg.NSQ, Filter: cfg.FilterExpr})
+	}
+
+	// Set up asynchronous acknowledgements before subscribing, so no message is received without them
+	if cfg.AsyncAck {
+		acks := &asyncAcker{log: log}
+		if aa, ok := topic.topic.(types.AsyncAcker); ok && aa.SetAsyncAck(subscription.EncoreName, acks.ack) {
+			sub.acks = acks
+		} else {
+			log.Warn().Msg("asynchronous acknowledgement is not supported by the pubsub provider, acknowledging messages synchronously")
+		}
+	}
+
 	// Subscribe to the topic
-	topic.topic.Subscribe(&log, cfg.MaxConcurrency, cfg.AckDeadline, cfg.RetryPolicy, subscription, func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) (err error) {
+	var process types.RawSubscriptionCallback = func(ctx context.Context, msgID string, publishTime time.Time, deliveryAttempt int, attrs map[string]string, data []byte) (err error) {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
+
+		// Hold off processing while too many bytes of messages are buffered,
+		// which stops the provider from receiving more until others complete.
+		size := int64(len(data))
+		if err := mgr.buffered.Acquire(ctx, size); err != nil {
+			return err
+		}
+		sub.bufferedBytes.Add(size)
+		defer func() {
+			sub.bufferedBytes.Add(-size)
+			mgr.buffered.Release(size)
+		}()
+
 		mgr.runningHandlers.Add(1)
 		defer mgr.runningHandlers.Done()
 
-		if !mgr.static.Testing {
-			// Under test we're already inside an operation
+		receiveTime := time.Now()
+		sub.lastReceived.Store(receiveTime.UnixNano())
+		if skew, skewed := clockSkew(publishTime, receiveTime, cfg.ClockSkewTolerance); skewed {
+			sub.clockSkewed.Add(1)
+			log.Warn().Str("msg_id", msgID).Time("publish_time", publishTime).Dur("skew", skew).
+				Msg("message publish time is in the future, clocks may be skewed")
+		}
+
+		var (
+			handled   bool      // whether the handler was called
+			handledAs model.UID // the user the handler was called as
+		)
+		if cfg.AuditSink != nil {
+			rec := &AuditRecord{
+				Topic:        topic.runtimeCfg.EncoreName,
+				Subscription: subscription.EncoreName,
+				MessageID:    msgID,
+				Attempt:      deliveryAttempt,
+				Start:        time.Now(),
+			}
+			defer func() {
+				rec.UserID = handledAs
+				writeAuditRecord(ctx, log, cfg.AuditSink, rec, handled, err)
+			}()
+		}
+
+		if sub.backlog.skip(log, msgID, publishTime) {
+			return nil
+		}
+		if filter != nil {
+			if matched, err := filter.match(attrs); err != nil {
+				// Acknowledging the message as filtered out could silently lose a message meant for us
+				log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+					Msg("failed to evaluate the subscription's filter, quarantining the message")
+				return sub.dropped(DropFilterError, deliveryAttempt, quarantineMessage(ctx, log, cfg.OnQuarantine, redactPublished[T](attrs, data), &QuarantinedMessage{
+					Topic:        topic.runtimeCfg.EncoreName,
+					Subscription: subscription.EncoreName,
+					ID:           msgID,
+					Attempt:      deliveryAttempt,
+					PublishTime:  publishTime,
+					Attributes:   attrs,
+					Data:         data,
+					Reason:       err,
+				}))
+			} else if !matched {
+				sub.filtered.Add(1)
+				return nil
+			}
+		}
+
+		var dedupKey DedupKey
+		if dedupStore != nil {
+			dedupKey = DedupKey{Topic: topic.runtimeCfg.EncoreName, Subscription: subscription.EncoreName, MessageID: msgID}
+			if seen, err := dedupStore.Seen(ctx, dedupKey); err != nil {
+				// Fail open; processing a duplicate is better than never processing the message
+				log.Warn().Err(err).Str("msg_id", msgID).Msg("failed to check dedup store, processing message")
+			} else if seen {
+				log.Debug().Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).Msg("skipping already processed message")
+				return nil
+			}
+		}
+
+		var idempotencyKey IdempotencyKey
+		var idempotency *idempotencyRecorder
+		if idempotencyStore != nil {
+			key := attrs[cfg.Idempotency.KeyAttribute]
+			if cfg.Idempotency.KeyAttribute == "" || key == "" {
+				key = msgID
+			}
+			idempotencyKey = IdempotencyKey{Topic: topic.runtimeCfg.EncoreName, Subscription: subscription.EncoreName, Key: key}
+			if rec, err := idempotencyStore.Get(ctx, idempotencyKey); err != nil {
+				// Fail open, like the dedup store
+				log.Warn().Err(err).Str("msg_id", msgID).Msg("failed to check idempotency store, processing message")
+			} else if rec != nil {
+				if err := mgr.replayIdempotent(ctx, rec); err != nil {
+					log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+						Msg("failed to publish recorded messages of already processed message")
+					return err
+				}
+				log.Debug().Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).Msg("answered already processed message from idempotency store")
+				if cfg.Idempotency.OnReplay != nil {
+					cfg.Idempotency.OnReplay(ctx, idempotencyKey, rec.Result)
+				}
+				return nil
+			}
+			idempotency = &idempotencyRecorder{}
+		}
+
+		if err := storms.delivered(msgID, receiveTime); err != nil {
+			// Assume the messaging service is misbehaving and stop processing the message
+			sub.redeliveryStorms.Add(1)
+			if stormCounter != nil {
+				stormCounter.Increment()
+			}
+			if !cfg.RedeliveryStorm.Quarantine {
+				log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+					Msg("message caught in a redelivery storm, negatively acknowledging it")
+				return sub.dropped(DropRedeliveryStorm, deliveryAttempt,
+					errs.B().Code(errs.Aborted).Cause(err).Msg("message caught in a redelivery storm").Err())
+			}
+			log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+				Msg("message caught in a redelivery storm, quarantining it")
+			return sub.dropped(DropRedeliveryStorm, deliveryAttempt, quarantineMessage(ctx, log, cfg.OnQuarantine, redactPublished[T](attrs, data), &QuarantinedMessage{
+				Topic:        topic.runtimeCfg.EncoreName,
+				Subscription: subscription.EncoreName,
+				ID:           msgID,
+				Attempt:      deliveryAttempt,
+				PublishTime:  publishTime,
+				Attributes:   attrs,
+				Data:         data,
+				Reason:       err,
+			}))
+		}
+
+		if sub.lifo != nil {
+			waitStart := time.Now()
+			if err := sub.lifo.Acquire(ctx, publishTime); err != nil {
+				return err
+			}
+			defer sub.lifo.Release()
+			sub.recordWait(time.Since(waitStart))
+		}
+		sub.recordAge(messageAge(publishTime, time.Now()))
+
+		if !mgr.rt.InOperation() {
+			// Under test, or when a handler publishes to a topic which delivers
+			// messages synchronously, we're already inside an operation
 			mgr.rt.BeginOperation()
 			defer mgr.rt.FinishOperation()
 		}
 
-		msg, err := utils.UnmarshalMessage[T](attrs, data)
+		// Decrypt encrypted messages, unwrap messages published as CloudEvents, and migrate messages
+		// published with older schema versions, before decoding them,
+		// unless the message was already decoded for an earlier attempt
+		msg, schemaVersion, cached := sub.decoded.get(msgID, data)
+		if !cached {
+			var payload []byte
+			payload, err = decryptMessage(ctx, topic.encryption, attrs, data)
+			if err == nil {
+				payload, err = unwrapCloudEvent(attrs, payload)
+			}
+			if err == nil {
+				schemaVersion, err = messageSchemaVersion(attrs)
+			}
+			if err == nil {
+				var upgraded []byte
+				upgraded, err = upgradeMessage(topic.staticCfg.SchemaVersion, schemaVersion, cfg.Upgrade, payload)
+				if err == nil {
+					msg, err = unmarshalMessage[T](attrs, upgraded)
+				}
+				if err == nil {
+					err = validateMessage(cfg.Validator, upgraded)
+				}
+			}
+			if err == nil {
+				sub.decoded.put(msgID, data, msg, schemaVersion)
+			}
+		}
+		if sub.decoded != nil {
+			// Keep the decoded message until it won't be delivered again
+			defer func() {
+				if err == nil || lastAttempt(cfg.RetryPolicy, deliveryAttempt) {
+					sub.decoded.evict(msgID)
+				}
+			}()
+		}
+		var (
+			authUID  model.UID
+			authData any
+		)
+		if err == nil && cfg.PropagateAuth {
+			if authUID, authData, err = mgr.unmarshalAuth(attrs); err != nil {
+				err = errs.B().Cause(err).Code(errs.InvalidArgument).Msg("failed to unmarshal auth information").Err()
+			}
+		}
 		if err != nil {
-			log.Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).Msg("failed to unmarshal message")
-			return errs.B().Code(errs.Internal).Cause(err).Msg("failed to unmarshal message").Err()
+			sub.decodeErrors.Add(1)
+			policy := cfg.OnDecodeError
+			if errors.Is(err, errUnknownVariant) || errors.Is(err, errUnknownSchemaVersion) || errors.Is(err, errInvalidAuth) || errors.Is(err, errInvalidMessage) || errors.Is(err, errInvalidCloudEvent) || errors.Is(err, errDecryptionFailed) {
+				// Retrying won't help with a variant, schema version or auth information we don't know about,
+				// or a message which breaks the subscription's contract, isn't a valid CloudEvent or can't be decrypted
+				policy = DecodeErrorQuarantine
+			}
+			return sub.dropped(DropDecodeError, deliveryAttempt, handleDecodeError(ctx, log, policy, cfg.OnQuarantine, redactPublished[T](attrs, data), &QuarantinedMessage{
+				Topic:        topic.runtimeCfg.EncoreName,
+				Subscription: subscription.EncoreName,
+				ID:           msgID,
+				Attempt:      deliveryAttempt,
+				PublishTime:  publishTime,
+				Attributes:   attrs,
+				Data:         data,
+				Reason:       err,
+			}))
+		}
+
+		// Hold the message while the breaker is open, rather than spending its retries on a
+		// failing handler. If it can't be held any longer it's left queued for redelivery.
+		breakerReported := true
+		if breaker != nil {
+			if err := breaker.Wait(ctx); err != nil {
+				return errs.B().Code(errs.Unavailable).Cause(err).Msg("subscription circuit breaker is open").Err()
+			}
+			breakerReported = false
+			defer func() {
+				if !breakerReported {
+					breaker.Cancel()
+				}
+			}()
+		}
+
+		if sub.fair != nil {
+			// Wait for the message's turn among the tenants' messages
+			wait, release, err := sub.fair.acquire(ctx, msg)
+			if err != nil {
+				return err
+			}
+			defer release()
+			sub.recordWait(wait)
 		}
 
 		logCtx := log.With()
@@ -182,7 +715,9 @@ func NewSubscription[T any](topic *Topic[T], name string, cfg SubscriptionConfig
 				Attempt:        deliveryAttempt,
 				Published:      publishTime,
 				DecodedPayload: msg,
-				Payload:        marshalParams(mgr.json, msg),
+				Payload:        redactMessage[T](attrs, marshalParams(mgr.json, msg)),
+				UserID:         authUID,
+				AuthData:       authData,
 			},
 			DefLoc: staticCfg.TraceIdx,
 			SvcNum: staticCfg.SvcNum,
@@ -204,40 +739,236 @@ func NewSubscription[T any](topic *Topic[T], name string, cfg SubscriptionConfig
 			}
 		}
 
+		// Hold back messages while ramping up after recovering
+		if err := sub.ramp.wait(ctx); err != nil {
+			return err
+		}
+
+		// Don't spend the message's retries on a service which can't handle it yet
+		if err := sub.waitForServiceInit(ctx, staticCfg.Service); err != nil {
+			return err
+		}
+
+		// Wait for the worker of the message's key, if dispatching by key.
+		// Ordered dispatchers hold the worker until the message's outcome is returned to be acknowledged.
+		releaseDispatch := func() {}
+		if sub.dispatch != nil {
+			var release func(error)
+			if release, err = sub.dispatch.acquire(ctx, sub.dispatch.key(msg, attrs)); err != nil {
+				return err
+			}
+			if sub.dispatch.ordered {
+				defer func() { release(err) }()
+			} else {
+				releaseDispatch = func() { release(nil) }
+			}
+		}
+
+		// Restore the request we're nested within, if any, once the message's request finishes
+		restoreReq := mgr.rt.SaveRequest()
 		mgr.rt.BeginRequest(req)
 		curr := mgr.rt.Current()
 		if curr.Trace != nil {
-			curr.Trace.PubsubMessageSpanStart(req, curr.Goctr)
+			// Tracing failures must not stop the message from being processed
+			safeTrace(log, msgID, "span start", func() {
+				curr.Trace.PubsubMessageSpanStart(req, curr.Goctr)
+
+				if fields := traceAttributeFields(attrs, cfg.TraceAttributes); len(fields) > 0 {
+					curr.Trace.LogMessage(trace2.LogMessageParams{
+						EventParams: trace2.EventParams{
+							TraceID: req.TraceID,
+							SpanID:  req.SpanID,
+							Goid:    curr.Goctr,
+						},
+						Level:  model.LevelDebug,
+						Msg:    "message attributes",
+						Fields: fields,
+					})
+				}
+			})
+		}
+
+		// Backends which lease messages bound the context they pass us by the
+		// ack deadline, so the context deadline is when the lease expires.
+		mc := &messageContext{
+			req:             req,
+			deliveryAttempt: deliveryAttempt,
+			draining:        &mgr.draining,
+			pull:            sub.pull,
+			idempotency:     idempotency,
+			meta: &MessageMeta{
+				ID:              msgID,
+				Topic:           topic.runtimeCfg.EncoreName,
+				Subscription:    subscription.EncoreName,
+				Attempt:         deliveryAttempt,
+				PublishTime:     publishTime,
+				ReceiveTime:     receiveTime,
+				Attributes:      attrs,
+				SchemaVersion:   schemaVersion,
+				ProducerVersion: attrs[producerVersionAttribute],
+				CorrelationID:   extCorrelationID,
+			},
+		}
+		if lease, ok := types.Lease(ctx); ok {
+			mc.lease = lease
 		}
 
-		err = panicCatchWrapper(ctx, msg)
+		// Bound the handler by the processing time requested by the publisher, if any
+		if d, err := messageProcessingTime(attrs, cfg.MaxProcessingTime); err != nil {
+			log.Warn().Err(err).Str("msg_id", msgID).Msg("ignoring invalid max processing time")
+		} else if d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, receiveTime.Add(d))
+			defer cancel()
+		}
 
-		if curr.Trace != nil {
-			resp := &model.Response{
-				Duration:   time.Since(req.Start),
-				Err:        err,
-				HTTPStatus: errs.HTTPStatus(err),
+		// Route the message to the handler of an active DrainTo call, if any,
+		// or else the current Handler, which ReplaceHandler may be replacing
+		var (
+			handler        func(ctx context.Context, msg T) error
+			releaseHandler = func() {}
+		)
+		drain := sub.drain.Load()
+		if drain != nil && drain.acquire() {
+			handler = drain.handler
+		} else {
+			drain = nil
+			current := sub.acquireHandler()
+			handler, releaseHandler = current.fn, current.release
+			if coalesce != nil {
+				handler = func(ctx context.Context, msg T) error {
+					return coalesce.handle(ctx, msg, current.fn)
+				}
+			}
+		}
+
+		runHandler := func(ctx context.Context) error {
+			defer releaseDispatch()
+			defer releaseHandler()
+			// Track the handler from the goroutine it runs in, so its stack can be found
+			defer mgr.trackInFlight(&InFlightInfo{
+				Topic:        topic.runtimeCfg.EncoreName,
+				Subscription: subscription.EncoreName,
+				MessageID:    msgID,
+				Attempt:      deliveryAttempt,
+				Start:        time.Now(),
+				goroutine:    currentGoroutineID(),
+			})()
+			return panicCatchWrapper(withMessageContext(ctx, mc), handler, msg, mc)
+		}
+		if cfg.MaxInFlight > 0 {
+			var abandoned bool
+			err, abandoned = runWithDeadline(ctx, receiveTime.Add(cfg.MaxInFlight), runHandler)
+			if abandoned {
+				sub.maxInFlightExceeded.Add(1)
+				log.Error().Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).Dur("max_in_flight", cfg.MaxInFlight).
+					Msg("handler exceeded MaxInFlight, negatively acknowledging the message while the handler is still running")
 			}
-			curr.Trace.PubsubMessageSpanEnd(trace2.PubsubMessageSpanEndParams{
-				EventParams: trace2.EventParams{
-					TraceID: req.TraceID,
-					SpanID:  req.SpanID,
-				},
-				Req:  req,
-				Resp: resp,
+		} else {
+			err = runHandler(ctx)
+		}
+		handled = true
+		sub.trackServiceInit(&log, staticCfg.Service, err)
+		handledAs = requestUserID(mgr.rt.Current().Req)
+
+		if err == nil && drain == nil && committer != nil {
+			// Only ack once the work of the handler has been committed
+			err = committer.wait(ctx)
+		}
+
+		if drain != nil {
+			drain.release(err)
+		}
+
+		if curr.Trace != nil {
+			safeTrace(log, msgID, "span end", func() {
+				traceErr := traceError(err)
+				resp := &model.Response{
+					Duration:   time.Since(req.Start),
+					Err:        traceErr,
+					HTTPStatus: errs.HTTPStatus(traceErr),
+				}
+				curr.Trace.PubsubMessageSpanEnd(trace2.PubsubMessageSpanEndParams{
+					EventParams: trace2.EventParams{
+						TraceID: req.TraceID,
+						SpanID:  req.SpanID,
+					},
+					Req:  req,
+					Resp: resp,
+				})
 			})
 		}
+		if mgr.static.Testing {
+			mgr.recordTestSpan(req, err)
+		}
 		mgr.rt.FinishRequest(false)
+		restoreReq()
+		sub.sli.record(publishTime, time.Now(), err)
+
+		if breaker != nil {
+			wasClosed := breaker.State() == utils.BreakerClosed
+			breaker.Report(err == nil)
+			breakerReported = true
+			if !wasClosed && breaker.State() == utils.BreakerClosed && sub.ramp != nil {
+				log.Info().Msg("circuit breaker closed, ramping up the rate of processing messages")
+				sub.ramp.restart(time.Now())
+			}
+		}
 
+		if err == nil && dedupStore != nil {
+			if markErr := dedupStore.MarkProcessed(ctx, dedupKey, cfg.Dedup.Window); markErr != nil {
+				log.Warn().Err(markErr).Str("msg_id", msgID).Msg("failed to record message in dedup store")
+			}
+		}
+		if err == nil && idempotency != nil {
+			if putErr := idempotencyStore.Put(ctx, idempotencyKey, idempotency.record(), cfg.Idempotency.TTL); putErr != nil {
+				log.Warn().Err(putErr).Str("msg_id", msgID).Msg("failed to record message in idempotency store")
+			}
+		}
+
+		if err != nil && retryDurationExceeded(publishTime, time.Now(), cfg.MaxRetryDuration) {
+			// Stop retrying the message, however many attempts it has left
+			sub.retryExpired.Add(1)
+			log.Error().Err(err).Str("msg_id", msgID).Int("delivery_attempt", deliveryAttempt).
+				Time("publish_time", publishTime).Dur("max_retry_duration", cfg.MaxRetryDuration).
+				Msg("message failed after its MaxRetryDuration, quarantining it")
+			return sub.dropped(DropRetryDurationExceeded, deliveryAttempt, quarantineMessage(ctx, log, cfg.OnQuarantine, redactPublished[T](attrs, data), &QuarantinedMessage{
+				Topic:        topic.runtimeCfg.EncoreName,
+				Subscription: subscription.EncoreName,
+				ID:           msgID,
+				Attempt:      deliveryAttempt,
+				PublishTime:  publishTime,
+				Attributes:   attrs,
+				Data:         data,
+				Reason:       retryDurationExceededErr(cfg.MaxRetryDuration, err),
+			}))
+		}
+
+		if err != nil && lastAttempt(cfg.RetryPolicy, deliveryAttempt) {
+			sub.recordDrop(DropRetriesExhausted)
+		}
 		return err
-	})
+	}
+	if topic.staticCfg.DeliveryGuarantee == AtMostOnce {
+		process = sub.ackFirst(log, providerConcurrency, process)
+	}
+	topic.topic.Subscribe(&log, providerConcurrency, cfg.AckDeadline, cfg.RetryPolicy, subscription, process)
+
+	mgr.registerSubscription(topic.runtimeCfg.EncoreName, subscription.EncoreName, topic.topic)
+
+	if cfg.IdleTimeout > 0 {
+		if ir, ok := topic.topic.(types.IdleReleaser); !ok || !ir.SetIdleTimeout(subscription.EncoreName, cfg.IdleTimeout) {
+			log.Warn().Dur("idle_timeout", cfg.IdleTimeout).Msg("idle timeout is not supported by the pubsub provider, keeping resources while idle")
+		}
+	}
 
 	if !mgr.static.Testing {
 		// Log the subscription registration - unless we're in unit tests
 		log.Info().Msg("registered subscription")
 	}
 
-	return &Subscription[T]{topic: topic, name: name, cfg: cfg, mgr: mgr}
+	declared = true
+	return sub
 }
 
 // SubscriptionMeta contains metadata about a subscription.
@@ -265,8 +996,14 @@ func (t *Subscription[T]) Meta() SubscriptionMeta[T] {
 
 // Config returns the subscription's configuration.
 // It must not be modified by the caller.
+//
+// Its Handler is the subscription's current Handler, as set by ReplaceHandler.
 func (s *Subscription[T]) Config() SubscriptionConfig[T] {
-	return s.cfg
+	cfg := s.cfg
+	if h := s.handler.Load(); h != nil {
+		cfg.Handler = h.fn
+	}
+	return cfg
 }
 
 func (t *Topic[T]) getSubscriptionConfig(name string) (cfg *config.PubsubSubscription, staticCfg *config.StaticPubsubSubscription, ok bool) {
@@ -285,8 +1022,11 @@ func (t *Topic[T]) getSubscriptionConfig(name string) (cfg *config.PubsubSubscri
 		return nil, nil, false
 	}
 
-	staticCfg, ok = t.mgr.static.PubsubTopics[t.runtimeCfg.EncoreName].Subscriptions[name]
+	if staticTopic := t.mgr.static.PubsubTopics[t.runtimeCfg.EncoreName]; staticTopic != nil {
+		staticCfg, ok = staticTopic.Subscriptions[name]
+	}
 	if !ok {
+		t.mgr.configError(t.runtimeCfg.EncoreName, name, errSubscriptionNotDeclared(t.runtimeCfg.EncoreName, name))
 		return nil, nil, false
 	}
 
@@ -0,0 +1,2104 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/mock/gomock"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/apisdk/service"
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/exported/model"
+	"encore.dev/appruntime/exported/trace2"
+	"encore.dev/appruntime/shared/reqtrack"
+	"encore.dev/appruntime/shared/testsupport"
+	"encore.dev/appruntime/shared/traceprovider/mock_trace"
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/types"
+	"encore.dev/pubsub/internal/utils"
+)
+
+type testEvent struct {
+	Value string
+}
+
+// fakeProvider is a provider whose topics record subscriptions
+// so tests can deliver messages to them directly.
+type fakeProvider struct {
+	mu           sync.Mutex
+	topics       map[string]*fakeTopic
+	maxAttrBytes int // the limit on message attributes reported by new topics
+}
+
+func (p *fakeProvider) ProviderName() string                  { return "fake" }
+func (p *fakeProvider) Matches(_ *config.PubsubProvider) bool { return true }
+
+func (p *fakeProvider) NewTopic(_ *config.PubsubProvider, _ TopicConfig, runtimeCfg *config.PubsubTopic) types.TopicImplementation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := &fakeTopic{subs: make(map[string]types.RawSubscriptionCallback), maxAttrBytes: p.maxAttrBytes}
+	p.topics[runtimeCfg.EncoreName] = t
+	return t
+}
+
+type fakeTopic struct {
+	mu         sync.Mutex
+	subs       map[string]types.RawSubscriptionCallback
+	published  int
+	lastAttrs  map[string]string
+	lastData   []byte
+	publishErr error // if set, returned by PublishMessage
+
+	// deliverOnPublish makes PublishMessage deliver messages to the subscriptions
+	// synchronously before returning, as an in-memory backend would.
+	deliverOnPublish bool
+
+	maxConcurrency int                                  // as passed to Subscribe
+	options        map[string]types.SubscriptionOptions // as passed to SetSubscriptionOptions
+	maxAttrBytes   int                                  // reported by MaxAttributeBytes
+}
+
+func (t *fakeTopic) MaxAttributeBytes() int { return t.maxAttrBytes }
+
+func (t *fakeTopic) SetSubscriptionOptions(subscription string, opts types.SubscriptionOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.options == nil {
+		t.options = make(map[string]types.SubscriptionOptions)
+	}
+	t.options[subscription] = opts
+}
+
+func (t *fakeTopic) PublishMessage(ctx context.Context, orderingKey string, attrs map[string]string, data []byte) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.publishErr != nil {
+		return "", t.publishErr
+	}
+	t.published++
+	t.lastAttrs = attrs
+	t.lastData = data
+	if t.deliverOnPublish {
+		msgID := fmt.Sprintf("msg-%d", t.published)
+		subs := make([]string, 0, len(t.subs))
+		for name := range t.subs {
+			subs = append(subs, name)
+		}
+		t.mu.Unlock()
+		defer t.mu.Lock()
+		for _, name := range subs {
+			if err := t.deliver(ctx, name, msgID, 1, attrs, data); err != nil {
+				return "", err
+			}
+		}
+		return msgID, nil
+	}
+	return "msg-id", nil
+}
+
+func (t *fakeTopic) Subscribe(_ *zerolog.Logger, maxConcurrency int, _ time.Duration, _ *types.RetryPolicy, implCfg *config.PubsubSubscription, f types.RawSubscriptionCallback) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxConcurrency = maxConcurrency
+	t.subs[implCfg.EncoreName] = f
+}
+
+// deliver delivers a message to the named subscription as the messaging service would.
+func (t *fakeTopic) deliver(ctx context.Context, sub, msgID string, attempt int, attrs map[string]string, data []byte) error {
+	t.mu.Lock()
+	f := t.subs[sub]
+	t.mu.Unlock()
+	return f(ctx, msgID, time.Now(), attempt, attrs, data)
+}
+
+// newTestManager creates a Manager configured with a single topic and subscription
+// backed by a fakeProvider.
+func newTestManager(t testing.TB, topicName, subName string) (*Manager, *fakeProvider) {
+	t.Helper()
+
+	static := &config.Static{
+		PubsubTopics: map[string]*config.StaticPubsubTopic{
+			topicName: {Subscriptions: map[string]*config.StaticPubsubSubscription{
+				subName: {Service: "svc", SvcNum: 1},
+			}},
+		},
+	}
+	runtime := &config.Runtime{
+		PubsubProviders: []*config.PubsubProvider{{}},
+		PubsubTopics: map[string]*config.PubsubTopic{
+			topicName: {
+				EncoreName: topicName,
+				Subscriptions: map[string]*config.PubsubSubscription{
+					subName: {EncoreName: subName},
+				},
+			},
+		},
+	}
+
+	logger := zerolog.Nop()
+	rt := reqtrack.New(logger, nil, nil)
+	ts := testsupport.NewManager(static, rt, logger)
+	mgr := NewManager(static, runtime, rt, ts, logger, jsoniter.ConfigCompatibleWithStandardLibrary, nil)
+
+	fake := &fakeProvider{topics: make(map[string]*fakeTopic)}
+	mgr.providers = []provider{fake}
+	return mgr, fake
+}
+
+func TestSubscription_DedupByMessageID(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var calls atomic.Int32
+	fail := true
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls.Add(1)
+			if fail {
+				fail = false
+				return context.DeadlineExceeded
+			}
+			return nil
+		},
+		DedupByMessageID: true,
+	})
+
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+	ft := fake.topics["topic"]
+
+	// A failed delivery must not be recorded, so the redelivery is processed
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, data), qt.IsNil)
+	c.Assert(calls.Load(), qt.Equals, int32(2))
+
+	// Once processed, a forced redelivery of the same ID is skipped
+	c.Assert(ft.deliver(ctx, "sub", "1", 3, nil, data), qt.IsNil)
+	c.Assert(calls.Load(), qt.Equals, int32(2))
+
+	// Other message IDs are still processed
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, data), qt.IsNil)
+	c.Assert(calls.Load(), qt.Equals, int32(3))
+}
+
+func TestSubscription_LeaseDeadline(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		gotDeadline time.Time
+		gotOK       bool
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			gotDeadline, gotOK = LeaseDeadline(ctx)
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	data := []byte(`{"Value":"hello"}`)
+
+	// Backends with leases report their lease, including any extensions
+	lease := time.Now().Add(time.Minute)
+	ctx := types.WithLease(context.Background(), func() time.Time { return lease })
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(gotOK, qt.IsTrue)
+	c.Assert(gotDeadline, qt.Equals, lease)
+
+	lease = lease.Add(time.Minute)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, data), qt.IsNil)
+	c.Assert(gotDeadline, qt.Equals, lease)
+
+	// Other deadlines on the context aren't reported as leases
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, data), qt.IsNil)
+	c.Assert(gotOK, qt.IsFalse)
+
+	// Push backends don't expose a lease
+	c.Assert(ft.deliver(context.Background(), "sub", "4", 1, nil, data), qt.IsNil)
+	c.Assert(gotOK, qt.IsFalse)
+
+	// Outside of a handler there is no lease
+	_, ok := LeaseDeadline(context.Background())
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestSubscription_CircuitBreaker(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	calls := 0
+	var handlerErr error = errors.New("downstream unavailable")
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls++
+			return handlerErr
+		},
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond},
+	})
+	c.Assert(sub.Stats().CircuitBreaker, qt.Equals, CircuitClosed)
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, data), qt.IsNotNil)
+	c.Assert(calls, qt.Equals, 2)
+	c.Assert(sub.Stats().CircuitBreaker, qt.Equals, CircuitOpen)
+
+	// Once open, messages are held without calling the handler,
+	// and left queued if they can't be held any longer
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := ft.deliver(shortCtx, "sub", "3", 1, nil, data)
+	c.Assert(errs.Code(err), qt.Equals, errs.Unavailable)
+	c.Assert(calls, qt.Equals, 2)
+
+	// A held message is let through as a probe once the breaker goes half-open
+	handlerErr = nil
+	start := time.Now()
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, nil, data), qt.IsNil)
+	c.Assert(time.Since(start) > 20*time.Millisecond, qt.IsTrue)
+	c.Assert(calls, qt.Equals, 3)
+	c.Assert(sub.Stats().CircuitBreaker, qt.Equals, CircuitClosed)
+}
+
+func TestSubscription_OnDecodeError(t *testing.T) {
+	badData := []byte(`{"Value":1}`)
+	tests := []struct {
+		name         string
+		policy       DecodeErrorPolicy
+		onQuarantine func(context.Context, *QuarantinedMessage) error
+		wantErr      bool
+		wantQuar     bool
+	}{
+		{name: "retry", policy: DecodeErrorRetry, wantErr: true},
+		{name: "drop", policy: DecodeErrorDrop},
+		{name: "quarantine_no_hook", policy: DecodeErrorQuarantine},
+		{
+			name:         "quarantine",
+			policy:       DecodeErrorQuarantine,
+			onQuarantine: func(context.Context, *QuarantinedMessage) error { return nil },
+			wantQuar:     true,
+		},
+		{
+			name:         "quarantine_failed",
+			policy:       DecodeErrorQuarantine,
+			onQuarantine: func(context.Context, *QuarantinedMessage) error { return errors.New("store unavailable") },
+			wantErr:      true,
+			wantQuar:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			mgr, fake := newTestManager(t, "topic", "sub")
+			topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+			var quarantined *QuarantinedMessage
+			cfg := SubscriptionConfig[*testEvent]{
+				Handler: func(ctx context.Context, msg *testEvent) error {
+					t.Fatal("handler called with undecodable message")
+					return nil
+				},
+				OnDecodeError: tt.policy,
+			}
+			if tt.onQuarantine != nil {
+				cfg.OnQuarantine = func(ctx context.Context, msg *QuarantinedMessage) error {
+					quarantined = msg
+					return tt.onQuarantine(ctx, msg)
+				}
+			}
+			sub := NewSubscription(topic, "sub", cfg)
+
+			err := fake.topics["topic"].deliver(context.Background(), "sub", "1", 2, nil, badData)
+			c.Assert(err != nil, qt.Equals, tt.wantErr)
+			c.Assert(sub.Stats().DecodeErrors, qt.Equals, uint64(1))
+
+			if tt.wantQuar {
+				c.Assert(quarantined, qt.IsNotNil)
+				c.Assert(quarantined.Topic, qt.Equals, "topic")
+				c.Assert(quarantined.Subscription, qt.Equals, "sub")
+				c.Assert(quarantined.ID, qt.Equals, "1")
+				c.Assert(quarantined.Attempt, qt.Equals, 2)
+				c.Assert(quarantined.Data, qt.DeepEquals, badData)
+				c.Assert(quarantined.Reason, qt.IsNotNil)
+			} else {
+				c.Assert(quarantined, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestManager_SetFlowControl(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+	})
+
+	err := mgr.SetFlowControl("topic", "sub", FlowControl{MaxOutstandingMessages: -2})
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+
+	err = mgr.SetFlowControl("topic", "unknown", FlowControl{MaxOutstandingMessages: 1})
+	c.Assert(errs.Code(err), qt.Equals, errs.NotFound)
+
+	// The fake provider doesn't support flow control
+	err = mgr.SetFlowControl("topic", "sub", FlowControl{MaxOutstandingMessages: 1})
+	c.Assert(errs.Code(err), qt.Equals, errs.Unimplemented)
+	c.Assert(sub.Stats().FlowControl, qt.IsNil)
+}
+
+func TestSubscription_DrainTo(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var handled, drained []string
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			handled = append(handled, msg.Value)
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliver := func(value string) error {
+		return ft.deliver(ctx, "sub", value, 1, nil, []byte(`{"Value":"`+value+`"}`))
+	}
+
+	// Messages are routed through the drain handler until MaxMessages is reached
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		n, err := sub.DrainTo(ctx, func(ctx context.Context, msg *testEvent) error {
+			drained = append(drained, msg.Value)
+			if msg.Value == "fail" {
+				return errors.New("fail")
+			}
+			return nil
+		}, DrainOptions{MaxMessages: 3, IdleTimeout: time.Minute})
+		done <- result{n, err}
+	}()
+	for sub.drain.Load() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := sub.DrainTo(ctx, func(context.Context, *testEvent) error { return nil }, DrainOptions{})
+	c.Assert(errs.Code(err), qt.Equals, errs.FailedPrecondition)
+
+	c.Assert(deliver("a"), qt.IsNil)
+	c.Assert(deliver("fail"), qt.IsNotNil)
+	c.Assert(deliver("b"), qt.IsNil)
+	res := <-done
+	c.Assert(res.err, qt.IsNil)
+	c.Assert(res.n, qt.Equals, 2)
+	c.Assert(drained, qt.DeepEquals, []string{"a", "fail", "b"})
+
+	// Afterwards messages go to the subscription's handler again
+	c.Assert(deliver("c"), qt.IsNil)
+	c.Assert(handled, qt.DeepEquals, []string{"c"})
+
+	// Draining stops once the subscription has been idle
+	n, err := sub.DrainTo(ctx, func(context.Context, *testEvent) error { return nil }, DrainOptions{IdleTimeout: 10 * time.Millisecond})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 0)
+
+	// And when the context is cancelled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sub.DrainTo(cancelled, func(context.Context, *testEvent) error { return nil }, DrainOptions{})
+	c.Assert(err, qt.Equals, context.Canceled)
+}
+
+func TestSubscription_IsRedelivery(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var redelivery bool
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			redelivery = IsRedelivery(ctx)
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(redelivery, qt.IsFalse)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, data), qt.IsNil)
+	c.Assert(redelivery, qt.IsTrue)
+
+	// Outside of a handler nothing is being redelivered
+	c.Assert(IsRedelivery(ctx), qt.IsFalse)
+}
+
+func TestSubscription_CurrentRequest(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		got *model.PubSubMsgData
+		ok  bool
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			got, ok = CurrentRequest(ctx)
+			if ok {
+				// Modifying the result doesn't affect the request
+				got.Payload[0] = 'x'
+				again, _ := CurrentRequest(ctx)
+				c.Check(string(again.Payload), qt.Equals, `{"Value":"hello"}`)
+			}
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	published := time.Now().Add(-time.Second)
+	c.Assert(ft.subs["sub"](ctx, "1", published, 2, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(got.Service, qt.Equals, "svc")
+	c.Assert(got.Topic, qt.Equals, "topic")
+	c.Assert(got.Subscription, qt.Equals, "sub")
+	c.Assert(got.MessageID, qt.Equals, "1")
+	c.Assert(got.Attempt, qt.Equals, 2)
+	c.Assert(got.Published.Equal(published), qt.IsTrue)
+	c.Assert(got.DecodedPayload, qt.DeepEquals, &testEvent{Value: "hello"})
+
+	// Outside of a handler there is no pubsub request
+	_, ok = CurrentRequest(ctx)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestSubscription_RetryAfter(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	errRateLimited := errors.New("rate limited")
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			return RetryAfter(30*time.Second, errRateLimited)
+		},
+		RetryPolicy: &RetryPolicy{MaxBackoff: time.Minute},
+	})
+
+	// The requested delay reaches the provider, which uses it instead of the backoff
+	err := fake.topics["topic"].deliver(context.Background(), "sub", "1", 1, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(errors.Is(err, errRateLimited), qt.IsTrue)
+	c.Assert(utils.RetryDelay(err, 10*time.Second, time.Minute), qt.Equals, 30*time.Second)
+}
+
+func TestSubscription_MaxRetryDuration(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+
+	errFailed := errors.New("handler failed")
+	var quarantined []*QuarantinedMessage
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			return errFailed
+		},
+		MaxRetryDuration: time.Hour,
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	handler := fake.topics["topic"].subs["sub"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	// Messages within the duration are retried
+	err := handler(ctx, "1", time.Now().Add(-time.Minute), 3, nil, data)
+	c.Assert(errors.Is(err, errFailed), qt.IsTrue)
+	c.Assert(quarantined, qt.HasLen, 0)
+
+	// Messages whose publish time isn't known are retried
+	err = handler(ctx, "2", time.Time{}, 3, nil, data)
+	c.Assert(errors.Is(err, errFailed), qt.IsTrue)
+	c.Assert(quarantined, qt.HasLen, 0)
+
+	// Messages failing after the duration are quarantined, whatever their attempt
+	c.Assert(handler(ctx, "3", time.Now().Add(-2*time.Hour), 2, nil, data), qt.IsNil)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].ID, qt.Equals, "3")
+	c.Assert(errors.Is(quarantined[0].Reason, errFailed), qt.IsTrue)
+	c.Assert(errors.Is(quarantined[0].Reason, errRetryDurationExceeded), qt.IsTrue)
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, "message failed after its MaxRetryDuration of 1h0m0s: handler failed")
+	c.Assert(sub.Stats().RetryDurationExceeded, qt.Equals, uint64(1))
+	c.Assert(buf.String(), qt.Contains, `"message":"message failed after its MaxRetryDuration, quarantining it"`)
+	c.Assert(buf.String(), qt.Not(qt.Contains), "failed to unmarshal message")
+}
+
+func TestSubscription_TracingFailure(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+
+	// Use a trace implementation which panics when recording message spans
+	traceMock := mock_trace.NewMockLogger(gomock.NewController(t))
+	traceMock.EXPECT().PubsubMessageSpanStart(gomock.Any(), gomock.Any()).Do(func(*model.Request, uint32) {
+		panic("trace buffer full")
+	}).AnyTimes()
+	traceMock.EXPECT().PubsubMessageSpanEnd(gomock.Any()).Do(func(trace2.PubsubMessageSpanEndParams) {
+		panic("trace buffer full")
+	}).AnyTimes()
+	traceMock.EXPECT().MarkDone().AnyTimes()
+	traceMock.EXPECT().WaitAndClear().AnyTimes() // called when the trace is streamed
+	mgr.rt = reqtrack.New(zerolog.Nop(), nil, mock_trace.NewMockFactory(traceMock))
+
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var processed int
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			processed++
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	data := []byte(`{"Value":"hello"}`)
+	c.Assert(ft.deliver(context.Background(), "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(ft.deliver(context.Background(), "sub", "2", 1, nil, data), qt.IsNil)
+	c.Assert(processed, qt.Equals, 2)
+}
+
+// auditSinkFunc adapts a function to the AuditSink interface.
+type auditSinkFunc func(ctx context.Context, rec *AuditRecord) error
+
+func (f auditSinkFunc) WriteAuditRecord(ctx context.Context, rec *AuditRecord) error {
+	return f(ctx, rec)
+}
+
+func TestSubscription_AuditSink(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var records []*AuditRecord
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "fail" {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+		DedupByMessageID: true,
+		OnDecodeError:    DecodeErrorDrop,
+		AuditSink: auditSinkFunc(func(ctx context.Context, rec *AuditRecord) error {
+			records = append(records, rec)
+			return errors.New("audit store unavailable")
+		}),
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Audit failures must not change whether messages are acked
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Value":"fail"}`)), qt.IsNotNil)
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":1}`)), qt.IsNil)
+
+	c.Assert(records, qt.HasLen, 4)
+	for _, rec := range records {
+		c.Assert(rec.Topic, qt.Equals, "topic")
+		c.Assert(rec.Subscription, qt.Equals, "sub")
+		c.Assert(rec.Start.IsZero(), qt.IsFalse)
+	}
+
+	c.Assert(records[0].MessageID, qt.Equals, "1")
+	c.Assert(records[0].Attempt, qt.Equals, 1)
+	c.Assert(records[0].Outcome, qt.Equals, AuditProcessed)
+	c.Assert(records[0].Err, qt.IsNil)
+
+	// The redelivery is skipped by deduplication
+	c.Assert(records[1].MessageID, qt.Equals, "1")
+	c.Assert(records[1].Attempt, qt.Equals, 2)
+	c.Assert(records[1].Outcome, qt.Equals, AuditSkipped)
+
+	c.Assert(records[2].MessageID, qt.Equals, "2")
+	c.Assert(records[2].Outcome, qt.Equals, AuditFailed)
+	c.Assert(records[2].Err, qt.ErrorMatches, "handler failed")
+
+	// The undecodable message is dropped
+	c.Assert(records[3].MessageID, qt.Equals, "3")
+	c.Assert(records[3].Outcome, qt.Equals, AuditSkipped)
+}
+
+func TestSubscription_IsDraining(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var draining bool
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			draining = IsDraining(ctx)
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, data), qt.IsNil)
+	c.Assert(draining, qt.IsFalse)
+
+	// Shutdown marks the manager as draining before it waits for handlers
+	mgr.draining.Store(true)
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, data), qt.IsNil)
+	c.Assert(draining, qt.IsTrue)
+
+	// Outside of a handler there is nothing to drain
+	c.Assert(IsDraining(ctx), qt.IsFalse)
+}
+
+func TestSubscription_SchemaVersion(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, SchemaVersion: 2})
+
+	var (
+		received    *testEvent
+		meta        *MessageMeta
+		quarantined *QuarantinedMessage
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = msg
+			meta, _ = CurrentMessage(ctx)
+			return nil
+		},
+		Upgrade: func(version int, raw []byte) ([]byte, error) {
+			// Version 1 called the field "Val"
+			if version == 1 {
+				return bytes.Replace(raw, []byte(`"Val"`), []byte(`"Value"`), 1), nil
+			}
+			return raw, nil
+		},
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = msg
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Publishing records the topic's schema version
+	_, err := topic.Publish(ctx, &testEvent{Value: "current"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[schemaVersionAttribute], qt.Equals, "2")
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, &testEvent{Value: "current"})
+	c.Assert(meta.SchemaVersion, qt.Equals, 2)
+
+	// Older messages are upgraded before being decoded
+	old := map[string]string{schemaVersionAttribute: "1"}
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, old, []byte(`{"Val":"old"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, &testEvent{Value: "old"})
+	c.Assert(meta.ID, qt.Equals, "2")
+	c.Assert(meta.Topic, qt.Equals, "topic")
+	c.Assert(meta.Subscription, qt.Equals, "sub")
+	c.Assert(meta.SchemaVersion, qt.Equals, 1)
+
+	// Messages from the future are quarantined
+	received = nil
+	future := map[string]string{schemaVersionAttribute: "3"}
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, future, []byte(`{"Value":"future"}`)), qt.IsNil)
+	c.Assert(received, qt.IsNil)
+	c.Assert(quarantined, qt.IsNotNil)
+	c.Assert(quarantined.ID, qt.Equals, "3")
+	c.Assert(quarantined.Reason, qt.ErrorMatches, ".*newer than the topic's schema version 2.*")
+
+	// Outside of a handler there is no current message
+	_, ok := CurrentMessage(ctx)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestSubscription_Commit(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		mu        sync.Mutex
+		buffered  []string
+		committed [][]string
+		commitErr error
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			buffered = append(buffered, msg.Value)
+			return nil
+		},
+		DedupByMessageID: true,
+		Commit: &CommitConfig{
+			Hook: func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if commitErr != nil {
+					buffered = nil
+					return commitErr
+				}
+				committed = append(committed, buffered)
+				buffered = nil
+				return nil
+			},
+			MaxMessages: 2,
+			MaxDelay:    time.Hour,
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliverBatch := func(ids ...string) []error {
+		errs := make([]error, len(ids))
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				errs[i] = ft.deliver(ctx, "sub", id, 1, nil, []byte(`{"Value":"`+id+`"}`))
+			}(i, id)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// Messages are acked together once their batch commits
+	for _, err := range deliverBatch("1", "2") {
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(committed, qt.HasLen, 1)
+	c.Assert(committed[0], qt.HasLen, 2)
+
+	// A failed commit nacks every message in the batch, and doesn't mark them as processed
+	commitErr = errors.New("tx aborted")
+	for _, err := range deliverBatch("3", "4") {
+		c.Assert(err, qt.ErrorMatches, ".*failed to commit message batch: tx aborted")
+	}
+	commitErr = nil
+	for _, err := range deliverBatch("3", "4") {
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(committed, qt.HasLen, 2)
+
+	// Messages already committed are skipped without joining a batch
+	c.Assert(ft.deliver(ctx, "sub", "1", 2, nil, []byte(`{"Value":"1"}`)), qt.IsNil)
+	c.Assert(committed, qt.HasLen, 2)
+
+	// A message whose context ends before its batch commits is nacked
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	c.Assert(ft.deliver(timeoutCtx, "sub", "5", 1, nil, []byte(`{"Value":"5"}`)), qt.ErrorMatches, ".*message context ended before its batch was committed.*")
+}
+
+func TestSubscription_CommitMaxDelay(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var commits atomic.Int32
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error { return nil },
+		Commit: &CommitConfig{
+			Hook: func(ctx context.Context) error {
+				commits.Add(1)
+				return nil
+			},
+			MaxMessages: 10,
+			MaxDelay:    10 * time.Millisecond,
+		},
+	})
+
+	// A batch which never fills is committed after MaxDelay
+	ft := fake.topics["topic"]
+	c.Assert(ft.deliver(context.Background(), "sub", "1", 1, nil, []byte(`{"Value":"1"}`)), qt.IsNil)
+	c.Assert(commits.Load(), qt.Equals, int32(1))
+}
+
+func TestSubscription_ClockSkew(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var meta *MessageMeta
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			meta, _ = CurrentMessage(ctx)
+			return nil
+		},
+		ClockSkewTolerance: time.Minute,
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+	deliverAt := func(publishTime time.Time) error {
+		return ft.subs["sub"](ctx, "1", publishTime, 1, nil, data)
+	}
+
+	// Messages from the past have a positive age
+	c.Assert(deliverAt(time.Now().Add(-time.Hour)), qt.IsNil)
+	c.Assert(meta.Age() >= time.Hour, qt.IsTrue)
+	c.Assert(sub.Stats().ClockSkewed, qt.Equals, uint64(0))
+
+	// Skew within the tolerance is not counted, but the age is clamped to zero
+	c.Assert(deliverAt(time.Now().Add(30*time.Second)), qt.IsNil)
+	c.Assert(meta.Age(), qt.Equals, time.Duration(0))
+	c.Assert(sub.Stats().ClockSkewed, qt.Equals, uint64(0))
+
+	// Skew beyond the tolerance is counted, and the message is still processed
+	meta = nil
+	c.Assert(deliverAt(time.Now().Add(time.Hour)), qt.IsNil)
+	c.Assert(meta, qt.IsNotNil)
+	c.Assert(meta.Age(), qt.Equals, time.Duration(0))
+	c.Assert(sub.Stats().ClockSkewed, qt.Equals, uint64(1))
+}
+
+func TestSubscription_MaxInFlight(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	unblock := make(chan struct{})
+	finished := make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "fast" {
+				return nil
+			}
+			// A wedged handler which ignores its context
+			defer close(finished)
+			<-unblock
+			return nil
+		},
+		MaxInFlight: 50 * time.Millisecond,
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliver := func(value string) error {
+		return ft.deliver(ctx, "sub", value, 1, nil, []byte(`{"Value":"`+value+`"}`))
+	}
+
+	// Handlers which return in time are unaffected
+	c.Assert(deliver("fast"), qt.IsNil)
+	c.Assert(sub.Stats().MaxInFlightExceeded, qt.Equals, uint64(0))
+
+	// Wedged handlers are abandoned and the message nacked
+	start := time.Now()
+	c.Assert(deliver("wedged"), qt.ErrorMatches, ".*exceeded the subscription's MaxInFlight.*")
+	c.Assert(time.Since(start) < 5*time.Second, qt.IsTrue)
+	c.Assert(sub.Stats().MaxInFlightExceeded, qt.Equals, uint64(1))
+
+	// The message stays in flight until the handler actually returns
+	c.Assert(mgr.InFlight(), qt.HasLen, 1)
+	close(unblock)
+	<-finished
+	for i := 0; i < 100 && len(mgr.InFlight()) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(mgr.InFlight(), qt.HasLen, 0)
+}
+
+func TestSubscription_Receive(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: PullHandler[*testEvent](),
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliverAsync := func(msgID string) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- ft.deliver(ctx, "sub", msgID, 1, nil, []byte(`{"Value":"`+msgID+`"}`))
+		}()
+		return result
+	}
+
+	// Acked messages are acknowledged to the messaging service
+	result := deliverAsync("1")
+	msg, err := sub.Receive(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(msg.Payload.Value, qt.Equals, "1")
+	c.Assert(msg.Meta.ID, qt.Equals, "1")
+	meta, ok := CurrentMessage(msg.Context())
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(meta.ID, qt.Equals, "1")
+	msg.Ack()
+	msg.Nack() // no effect after Ack
+	c.Assert(<-result, qt.IsNil)
+
+	// Nacked messages are redelivered
+	result = deliverAsync("2")
+	msg, err = sub.Receive(ctx)
+	c.Assert(err, qt.IsNil)
+	msg.Nack()
+	c.Assert(<-result, qt.ErrorMatches, ".*message was negatively acknowledged")
+
+	// Receive stops waiting when its context is done
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = sub.Receive(timeoutCtx)
+	c.Assert(err, qt.Equals, context.DeadlineExceeded)
+
+	// Once shutdown begins, Receive returns and unreceived messages are nacked
+	mgr.ctxs.StopFetchingNewEvents()
+	_, err = sub.Receive(ctx)
+	c.Assert(err, qt.ErrorMatches, ".*subscription sub is shutting down")
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":"3"}`)), qt.ErrorMatches, ".*subscription is shutting down")
+}
+
+func TestSubscription_LIFO(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	started, unblock := make(chan struct{}), make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "first" {
+				close(started)
+				<-unblock
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, msg.Value)
+			return nil
+		},
+		MaxConcurrency: 1,
+		LIFO:           true,
+	})
+
+	// The provider is asked to prefetch a window of messages to reorder
+	ft := fake.topics["topic"]
+	c.Assert(ft.maxConcurrency, qt.Equals, 2)
+
+	ctx := context.Background()
+	now := time.Now()
+	var wg sync.WaitGroup
+	deliverAt := func(value string, published time.Time) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(ft.subs["sub"](ctx, value, published, 1, nil, []byte(`{"Value":"`+value+`"}`)), qt.IsNil)
+		}()
+	}
+
+	// Occupy the only slot, then build up a backlog
+	deliverAt("first", now.Add(-time.Hour))
+	<-started
+	for i, value := range []string{"old", "middle", "new"} {
+		deliverAt(value, now.Add(time.Duration(i-3)*time.Minute))
+		for sub.lifo.Waiting() < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	c.Assert(sub.Stats().ConcurrencyWait < 20*time.Millisecond, qt.IsTrue)
+	time.Sleep(20 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+	c.Assert(processed, qt.DeepEquals, []string{"first", "new", "middle", "old"})
+
+	// The age of processed messages is tracked
+	stats := sub.Stats()
+	c.Assert(stats.MaxProcessedAge >= time.Hour, qt.IsTrue)
+	c.Assert(stats.LastProcessedAge >= 3*time.Minute, qt.IsTrue)
+	c.Assert(stats.LastProcessedAge < time.Hour, qt.IsTrue)
+
+	// So is the time spent waiting for a slot
+	c.Assert(stats.MaxConcurrencyWait >= 20*time.Millisecond, qt.IsTrue)
+	c.Assert(stats.ConcurrencyWait >= 3*20*time.Millisecond, qt.IsTrue)
+}
+
+func TestSubscription_Fairness(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	started, unblock := make(chan struct{}), make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "noisy-blocking" {
+				close(started)
+				<-unblock
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, msg.Value)
+			return nil
+		},
+		MaxConcurrency: 1,
+		Fairness: &FairnessConfig[*testEvent]{
+			TenantFunc: func(msg *testEvent) string {
+				tenant, _, _ := strings.Cut(msg.Value, "-")
+				return tenant
+			},
+		},
+	})
+
+	// The provider is asked to prefetch a window of messages to schedule
+	ft := fake.topics["topic"]
+	c.Assert(ft.maxConcurrency, qt.Equals, 2)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	deliver := func(value string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(ft.deliver(ctx, "sub", value, 1, nil, []byte(`{"Value":"`+value+`"}`)), qt.IsNil)
+		}()
+	}
+
+	// Occupy the only slot with the noisy tenant, then build up
+	// a backlog dominated by it, with the quiet tenant's messages last
+	deliver("noisy-blocking")
+	<-started
+	values := []string{"noisy-1", "noisy-2", "noisy-3", "noisy-4", "noisy-5", "noisy-6", "quiet-1", "quiet-2"}
+	for i, value := range values {
+		deliver(value)
+		for sub.fair.gate.Waiting() < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	stats := sub.Stats()
+	c.Assert(stats.Tenants["noisy"].Waiting, qt.Equals, 6)
+	c.Assert(stats.Tenants["quiet"].Waiting, qt.Equals, 2)
+
+	close(unblock)
+	wg.Wait()
+
+	// The quiet tenant takes turns with the noisy tenant,
+	// rather than waiting for its backlog
+	c.Assert(processed, qt.DeepEquals, []string{
+		"noisy-1", "quiet-1", "noisy-2", "quiet-2", "noisy-3", "noisy-4", "noisy-5", "noisy-6",
+	})
+
+	// Each tenant's processing is tracked
+	stats = sub.Stats()
+	c.Assert(stats.Tenants, qt.HasLen, 2)
+	c.Assert(stats.Tenants["noisy"].Processed, qt.Equals, uint64(7))
+	c.Assert(stats.Tenants["noisy"].Waiting, qt.Equals, 0)
+	c.Assert(stats.Tenants["quiet"].Processed, qt.Equals, uint64(2))
+	c.Assert(stats.Tenants["quiet"].Wait > 0, qt.IsTrue)
+}
+
+func TestSubscription_FairnessMaxPerTenant(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var running, maxRunning atomic.Int64
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			storeMax(&maxRunning, n)
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		},
+		MaxConcurrency: 4,
+		Fairness: &FairnessConfig[*testEvent]{
+			TenantFunc:   func(msg *testEvent) string { return msg.Value },
+			MaxPerTenant: 2,
+		},
+	})
+
+	// A single tenant can't use all of MaxConcurrency
+	ft := fake.topics["topic"]
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Check(ft.deliver(context.Background(), "sub", strconv.Itoa(i), 1, nil, []byte(`{"Value":"noisy"}`)), qt.IsNil)
+		}(i)
+	}
+	wg.Wait()
+	c.Assert(maxRunning.Load(), qt.Equals, int64(2))
+}
+
+func TestSubscription_FairnessConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	handler := func(ctx context.Context, msg *testEvent) error { return nil }
+	tenant := func(msg *testEvent) string { return msg.Value }
+
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler, Fairness: &FairnessConfig[*testEvent]{}})
+	}, qt.PanicMatches, "Fairness.TenantFunc is required")
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler, Fairness: &FairnessConfig[*testEvent]{TenantFunc: tenant, MaxPerTenant: -1}})
+	}, qt.PanicMatches, "Fairness.MaxPerTenant cannot be negative")
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler, LIFO: true, Fairness: &FairnessConfig[*testEvent]{TenantFunc: tenant}})
+	}, qt.PanicMatches, "Fairness cannot be combined with LIFO")
+}
+
+func TestManager_BufferBudget(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	started, unblock := make(chan string, 2), make(chan struct{})
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			started <- msg.Value
+			<-unblock
+			return nil
+		},
+	})
+
+	data := []byte(`{"Value":"hello"}`)
+	mgr.SetBufferBudget(int64(len(data)) + 1)
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for _, id := range []string{"1", "2"} {
+		id := id
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(ft.deliver(ctx, "sub", id, 1, nil, data), qt.IsNil)
+		}()
+	}
+
+	// Only one message fits within the budget at once
+	<-started
+	select {
+	case <-started:
+		c.Fatal("processed messages over the budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.Assert(mgr.BufferedBytes(), qt.Equals, int64(len(data)))
+	c.Assert(sub.Stats().BufferedBytes, qt.Equals, int64(len(data)))
+
+	close(unblock)
+	wg.Wait()
+	c.Assert(mgr.BufferedBytes(), qt.Equals, int64(0))
+	c.Assert(sub.Stats().BufferedBytes, qt.Equals, int64(0))
+}
+
+type testAuthData struct {
+	Role string
+}
+
+func TestSubscription_PropagateAuth(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	mgr.SetAuthDataType(func() reflect.Type { return reflect.TypeOf(&testAuthData{}) })
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, PropagateAuth: true})
+
+	var (
+		uid  model.UID
+		data any
+	)
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			req := mgr.rt.Current().Req
+			uid, data = requestUserID(req), requestAuthData(req)
+			return nil
+		},
+		PropagateAuth: true,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	publishAs := func(uid model.UID, data any) {
+		mgr.rt.BeginOperation()
+		defer mgr.rt.FinishOperation()
+		mgr.rt.BeginRequest(&model.Request{RPCData: &model.RPCData{UserID: uid, AuthData: data}})
+		_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+		c.Assert(err, qt.IsNil)
+	}
+
+	// The publisher's auth information is restored in the handler
+	publishAs("user-1", &testAuthData{Role: "admin"})
+	c.Assert(ft.lastAttrs[authUIDAttribute], qt.Equals, "user-1")
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(uid, qt.Equals, model.UID("user-1"))
+	c.Assert(data, qt.DeepEquals, &testAuthData{Role: "admin"})
+
+	// Messages published without a user are processed without one
+	publishAs("", nil)
+	c.Assert(ft.lastAttrs[authUIDAttribute], qt.Equals, "")
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(uid, qt.Equals, model.UID(""))
+	c.Assert(data, qt.IsNil)
+
+	// Auth data which doesn't match the auth handler is rejected
+	uid = "unchanged"
+	attrs := map[string]string{authUIDAttribute: "user-2"}
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, attrs, ft.lastData), qt.IsNil) // quarantined
+	c.Assert(uid, qt.Equals, model.UID("unchanged"))
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, map[string]string{authUIDAttribute: "user-2", authDataAttribute: "[]"}, ft.lastData), qt.IsNil)
+	c.Assert(uid, qt.Equals, model.UID("unchanged"))
+}
+
+func TestSubscription_PropagateAuthUnderTest(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, PropagateAuth: true})
+
+	var uid model.UID
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			uid = requestUserID(mgr.rt.Current().Req)
+			return nil
+		},
+		PropagateAuth: true,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Publish from a test which overrides its auth information,
+	// delivering messages within the test as in-memory pubsub does
+	mgr.rt.BeginOperation()
+	defer mgr.rt.FinishOperation()
+	mgr.rt.BeginRequest(&model.Request{Test: &model.TestData{UserID: "tester"}})
+
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[authUIDAttribute], qt.Equals, "tester")
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(uid, qt.Equals, model.UID("tester"))
+
+	// Messages without auth information don't inherit the test's
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, ft.lastData), qt.IsNil)
+	c.Assert(uid, qt.Equals, model.UID(""))
+	c.Assert(requestUserID(mgr.rt.Current().Req), qt.Equals, model.UID("tester"))
+}
+
+func TestSubscription_ServiceInitFailure(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	calls, initialized := 0, false
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls++
+			if !initialized {
+				return errs.B().Code(errs.Internal).Cause(service.ErrInitFailed).Msg("service svc").Err()
+			}
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliver := func(msgID string) error {
+		return ft.deliver(ctx, "sub", msgID, 1, nil, []byte(`{"Value":"hello"}`))
+	}
+
+	// A failure to initialize the service pauses the subscription
+	c.Assert(deliver("1"), qt.ErrorMatches, ".*initialization failed.*")
+	c.Assert(sub.Stats().PausedForServiceInit, qt.IsTrue)
+	c.Assert(sub.Stats().ServiceInitFailures, qt.Equals, uint64(1))
+	c.Assert(mgr.HealthCheck(ctx)[0].Err, qt.ErrorMatches, ".*topic/sub.*")
+
+	// While paused, messages are held without calling the handler,
+	// and are only negatively acknowledged if they can't be held any longer
+	leaseCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err := ft.deliver(leaseCtx, "sub", "2", 1, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(errs.Code(err), qt.Equals, errs.Unavailable)
+	c.Assert(err, qt.ErrorMatches, ".*subscription is paused until service svc initializes.*")
+	c.Assert(calls, qt.Equals, 1)
+
+	// Once the pause has passed the handler is tried again
+	sub.initPausedUntil.Store(time.Now().Add(10 * time.Millisecond).UnixNano())
+	c.Assert(deliver("3"), qt.ErrorMatches, ".*initialization failed.*")
+	c.Assert(calls, qt.Equals, 2)
+	c.Assert(sub.Stats().ServiceInitFailures, qt.Equals, uint64(2))
+
+	// Held messages are processed as soon as the service initializes,
+	// and the subscription resumes
+	held := make(chan error, 1)
+	go func() { held <- deliver("4") }()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		sub.initMu.Lock()
+		waiting := sub.initResumed != nil
+		sub.initMu.Unlock()
+		if waiting {
+			break
+		} else if time.Now().After(deadline) {
+			c.Fatal("timed out waiting for the message to be held")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(calls, qt.Equals, 2)
+
+	initialized = true
+	log := zerolog.Nop()
+	sub.trackServiceInit(&log, "svc", nil)
+	c.Assert(<-held, qt.IsNil)
+	c.Assert(calls, qt.Equals, 3)
+	c.Assert(sub.Stats().PausedForServiceInit, qt.IsFalse)
+	c.Assert(mgr.HealthCheck(ctx)[0].Err, qt.IsNil)
+}
+
+func TestSubscription_Dispatch(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		mu        sync.Mutex
+		processed = make(map[string][]string) // key -> values in processing order
+		active    = make(map[string]int)      // key -> running handlers
+		overlaps  int
+	)
+	keyOf := func(msg *testEvent) string { return msg.Value[:1] }
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			key := keyOf(msg)
+			mu.Lock()
+			active[key]++
+			if active[key] > 1 {
+				overlaps++
+			}
+			processed[key] = append(processed[key], msg.Value)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active[key]--
+			mu.Unlock()
+			return nil
+		},
+		Dispatch: &DispatchConfig[*testEvent]{KeyFunc: keyOf, Workers: 4},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		for _, key := range []string{"a", "b", "c"} {
+			value := fmt.Sprintf("%s%d", key, i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Check(ft.deliver(ctx, "sub", value, 1, nil, []byte(`{"Value":"`+value+`"}`)), qt.IsNil)
+			}()
+		}
+		// Let the messages queue up for their workers in order
+		time.Sleep(2 * time.Millisecond)
+	}
+	wg.Wait()
+
+	// Messages with the same key are never processed concurrently, and keep their order
+	c.Assert(overlaps, qt.Equals, 0)
+	c.Assert(processed["a"], qt.DeepEquals, []string{"a0", "a1", "a2", "a3", "a4"})
+	c.Assert(processed["b"], qt.HasLen, 5)
+
+	// And the load of each worker is reported
+	load := sub.Stats().DispatchLoad
+	c.Assert(load, qt.HasLen, 4)
+	var total uint64
+	for _, n := range load {
+		total += n
+	}
+	c.Assert(total, qt.Equals, uint64(15))
+	c.Assert(load[WorkerFor("a", 4)] >= 5, qt.IsTrue)
+}
+
+func TestSubscription_DispatchOrdered(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, OrderingAttribute: "key"})
+
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	started, unblock := make(chan struct{}), make(chan error)
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			mu.Lock()
+			processed = append(processed, msg.Value)
+			mu.Unlock()
+			if msg.Value == "a1" {
+				started <- struct{}{}
+				return <-unblock
+			}
+			return nil
+		},
+		Dispatch: &DispatchConfig[*testEvent]{Workers: 4, Ordered: true},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	deliver := func(key, value string) error {
+		return ft.deliver(ctx, "sub", value, 1, map[string]string{"key": key}, []byte(`{"Value":"`+value+`"}`))
+	}
+	waitInFlight := func(key string, n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for sub.Stats().KeysInFlight[key] != n {
+			if time.Now().After(deadline) {
+				c.Fatalf("timed out waiting for %d messages of key %s in flight", n, key)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Messages are keyed by their ordering key, and queue behind the message being processed
+	results := make(chan error, 2)
+	go func() { results <- deliver("a", "a1") }()
+	<-started
+	go func() { results <- deliver("a", "a2") }()
+	waitInFlight("a", 2)
+
+	// Messages with other keys are processed meanwhile
+	c.Assert(deliver("b", "b1"), qt.IsNil)
+	c.Assert(sub.Stats().KeysInFlight, qt.DeepEquals, map[string]int{"a": 2})
+
+	// When a message fails, the messages waiting behind it aren't processed, so they stay in order
+	unblock <- errors.New("handler failed")
+	c.Assert(<-results, qt.ErrorMatches, ".*handler failed")
+	c.Assert(<-results, qt.ErrorMatches, `.*not processing message with key "a" to keep it in order: an earlier message with the same key failed`)
+	c.Assert(processed, qt.DeepEquals, []string{"a1", "b1"})
+	c.Assert(sub.Stats().KeysInFlight, qt.DeepEquals, map[string]int{})
+
+	// Once the key has no messages in flight, its messages are processed again
+	go func() { results <- deliver("a", "a1") }()
+	<-started
+	unblock <- nil
+	c.Assert(<-results, qt.IsNil)
+	c.Assert(deliver("a", "a2"), qt.IsNil)
+	c.Assert(processed, qt.DeepEquals, []string{"a1", "b1", "a1", "a2"})
+}
+
+func TestSubscription_DispatchOrderedConfig(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	newSub := func(topic *Topic[*testEvent], cfg SubscriptionConfig[*testEvent]) func() {
+		return func() {
+			cfg.Handler = func(ctx context.Context, msg *testEvent) error { return nil }
+			NewSubscription(topic, "sub", cfg)
+		}
+	}
+	unordered := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ordered := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, OrderingAttribute: "key"})
+
+	// The key can only default to the ordering key if the topic has one
+	c.Assert(newSub(unordered, SubscriptionConfig[*testEvent]{Dispatch: &DispatchConfig[*testEvent]{Ordered: true}}), qt.PanicMatches, "Dispatch.KeyFunc is required")
+	c.Assert(newSub(ordered, SubscriptionConfig[*testEvent]{Dispatch: &DispatchConfig[*testEvent]{}}), qt.PanicMatches, "Dispatch.KeyFunc is required")
+	c.Assert(newSub(ordered, SubscriptionConfig[*testEvent]{
+		Dispatch: &DispatchConfig[*testEvent]{Ordered: true},
+		AsyncAck: true,
+	}), qt.PanicMatches, "Dispatch.Ordered cannot be combined with AsyncAck")
+}
+
+func TestManager_InFlight(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			started <- struct{}{}
+			<-unblock
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	c.Assert(mgr.InFlight(), qt.HasLen, 0)
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"1", "2"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			c.Check(ft.deliver(ctx, "sub", id, 3, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+		}(id)
+		<-started
+	}
+
+	// Running handlers are reported oldest first
+	inFlight := mgr.InFlight()
+	c.Assert(inFlight, qt.HasLen, 2)
+	c.Assert(inFlight[0].MessageID, qt.Equals, "1")
+	c.Assert(inFlight[1].MessageID, qt.Equals, "2")
+	c.Assert(inFlight[0].Topic, qt.Equals, "topic")
+	c.Assert(inFlight[0].Subscription, qt.Equals, "sub")
+	c.Assert(inFlight[0].Attempt, qt.Equals, 3)
+	c.Assert(inFlight[0].Age() > 0, qt.IsTrue)
+
+	// And removed once they finish
+	close(unblock)
+	wg.Wait()
+	c.Assert(mgr.InFlight(), qt.HasLen, 0)
+}
+
+func TestManager_LogBlockedHandlers(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			close(started)
+			<-unblock
+			return nil
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fake.topics["topic"].deliver(context.Background(), "sub", "blocked", 1, nil, []byte(`{"Value":"hello"}`))
+	}()
+	<-started
+
+	// The stack of the blocked handler is logged
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+	mgr.logBlockedHandlers(&log)
+	c.Assert(buf.String(), qt.Contains, `"msg_id":"blocked"`)
+	c.Assert(buf.String(), qt.Contains, "TestManager_LogBlockedHandlers.func1")
+
+	close(unblock)
+	c.Assert(<-done, qt.IsNil)
+
+	// Nothing is logged once no handlers are running
+	buf.Reset()
+	mgr.logBlockedHandlers(&log)
+	c.Assert(buf.String(), qt.Equals, "")
+}
+
+func TestManager_Shutdown(t *testing.T) {
+	// newBlockedManager returns a manager processing a message whose handler
+	// blocks until unblock is closed or its context is cancelled.
+	newBlockedManager := func(c *qt.C) (mgr *Manager, unblock chan struct{}, delivered chan error) {
+		mgr, fake := newTestManager(c.TB, "topic", "sub")
+		topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+		started := make(chan struct{})
+		unblock, delivered = make(chan struct{}), make(chan error, 1)
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler: func(ctx context.Context, msg *testEvent) error {
+				c.Check(IsDraining(ctx), qt.IsFalse)
+				close(started)
+				select {
+				case <-unblock:
+					c.Check(IsDraining(ctx), qt.IsTrue)
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+
+		// Deliver the message as providers do, with the manager's handler context
+		go func() {
+			delivered <- fake.topics["topic"].deliver(mgr.ctxs.Handler, "sub", "1", 1, nil, []byte(`{"Value":"hello"}`))
+		}()
+		<-started
+		return mgr, unblock, delivered
+	}
+
+	// shutdownAsync shuts mgr down, force-closing handlers after forceCloseTasksAfter,
+	// and returns a context which is cancelled once its handlers have completed.
+	shutdownAsync := func(mgr *Manager, forceCloseTasksAfter time.Duration) (done chan error, handlersCompleted context.Context) {
+		forceCloseTasks, cancelForceClose := context.WithTimeout(context.Background(), forceCloseTasksAfter)
+		handlersCompleted, markHandlersCompleted := context.WithCancel(context.Background())
+		t.Cleanup(cancelForceClose)
+		t.Cleanup(markHandlersCompleted)
+
+		log := zerolog.Nop()
+		done = make(chan error, 1)
+		go func() { done <- mgr.shutdown(&log, forceCloseTasks, markHandlersCompleted) }()
+		return done, handlersCompleted
+	}
+
+	t.Run("waits_for_handlers", func(t *testing.T) {
+		c := qt.New(t)
+		mgr, unblock, delivered := newBlockedManager(c)
+		done, handlersCompleted := shutdownAsync(mgr, time.Hour)
+
+		// Shutdown doesn't complete while the handler is running
+		select {
+		case <-done:
+			c.Fatal("Shutdown returned while a handler was running")
+		case <-time.After(50 * time.Millisecond):
+		}
+		c.Assert(handlersCompleted.Err(), qt.IsNil)
+
+		// But does once it finishes
+		close(unblock)
+		c.Assert(<-delivered, qt.IsNil)
+		c.Assert(<-done, qt.IsNil)
+		c.Assert(handlersCompleted.Err(), qt.IsNotNil)
+	})
+
+	t.Run("force_closes_handlers", func(t *testing.T) {
+		c := qt.New(t)
+		mgr, _, delivered := newBlockedManager(c)
+		done, handlersCompleted := shutdownAsync(mgr, 50*time.Millisecond)
+
+		// Once it's time to force-close tasks, the handler's context is cancelled
+		c.Assert(<-delivered, qt.ErrorIs, context.Canceled)
+		c.Assert(<-done, qt.IsNil)
+		c.Assert(handlersCompleted.Err(), qt.IsNotNil)
+	})
+}
+
+func TestManager_CaptureMessageSpans(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			if msg.Value == "fail" {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	mgr.static.Testing = true
+
+	// Messages are attributed to the test they're processed in.
+	// The subscription's request replaces the test's request, as when the test support delivers messages.
+	deliverInTest := func(test *testing.T, msgID, value string) error {
+		mgr.rt.BeginOperation()
+		defer mgr.rt.FinishOperation()
+		mgr.rt.BeginRequest(&model.Request{Test: &model.TestData{Current: test}})
+		return ft.deliver(context.Background(), "sub", msgID, 1, nil, []byte(`{"Value":"`+value+`"}`))
+	}
+
+	var spans func() []MessageSpan
+	t.Run("capturing", func(t *testing.T) {
+		spans = mgr.CaptureMessageSpans(t)
+		c.Assert(deliverInTest(t, "1", "hello"), qt.IsNil)
+		c.Assert(deliverInTest(t, "2", "fail"), qt.IsNotNil)
+
+		got := spans()
+		c.Assert(got, qt.HasLen, 2)
+		c.Assert(got[0].Type, qt.Equals, model.PubSubMessage)
+		c.Assert(got[0].Service, qt.Equals, "svc")
+		c.Assert(got[0].Topic, qt.Equals, "topic")
+		c.Assert(got[0].Subscription, qt.Equals, "sub")
+		c.Assert(got[0].MessageID, qt.Equals, "1")
+		c.Assert(got[0].Err, qt.IsNil)
+		c.Assert(got[1].MessageID, qt.Equals, "2")
+		c.Assert(got[1].Err, qt.ErrorMatches, ".*handler failed")
+	})
+
+	// Other tests aren't captured, and capturing stops when the test completes
+	t.Run("not capturing", func(t *testing.T) {
+		c.Assert(deliverInTest(t, "3", "hello"), qt.IsNil)
+	})
+	c.Assert(spans(), qt.HasLen, 2)
+	_, capturing := mgr.spanCaptures.Load(t)
+	c.Assert(capturing, qt.IsFalse)
+}
+
+// positionedTopic is a fakeTopic whose provider only supports
+// starting new subscriptions from the latest message.
+type positionedTopic struct{ *fakeTopic }
+
+func (positionedTopic) NativeInitialPosition() types.InitialPosition {
+	return types.InitialPositionLatest
+}
+
+func (positionedTopic) SupportsInitialPosition(pos types.InitialPosition) bool {
+	return pos == types.InitialPositionLatest
+}
+
+func TestEffectiveInitialPosition(t *testing.T) {
+	c := qt.New(t)
+	plain := &fakeTopic{}
+	positioned := positionedTopic{plain}
+
+	tests := []struct {
+		impl          types.TopicImplementation
+		requested     InitialPosition
+		want          InitialPosition
+		wantSupported bool
+	}{
+		{plain, InitialPositionDefault, InitialPositionDefault, true},
+		{plain, InitialPositionEarliest, InitialPositionDefault, false},
+		{positioned, InitialPositionDefault, InitialPositionLatest, true},
+		{positioned, InitialPositionLatest, InitialPositionLatest, true},
+		{positioned, InitialPositionEarliest, InitialPositionLatest, false},
+	}
+	for _, tt := range tests {
+		got, supported := effectiveInitialPosition(tt.impl, tt.requested)
+		c.Assert(got, qt.Equals, tt.want)
+		c.Assert(supported, qt.Equals, tt.wantSupported)
+	}
+}
+
+// idleTopic is a fakeTopic whose subscriptions can release their resources while idle.
+type idleTopic struct {
+	*fakeTopic
+	timeouts map[string]time.Duration
+	idle     bool
+}
+
+func (t *idleTopic) SetIdleTimeout(subscription string, timeout time.Duration) bool {
+	t.timeouts[subscription] = timeout
+	return true
+}
+
+func (t *idleTopic) Idle(subscription string) bool { return t.idle }
+
+func TestSubscription_IdleTimeout(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	it := &idleTopic{fakeTopic: ft, timeouts: make(map[string]time.Duration)}
+	topic.topic = it
+
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler:     func(ctx context.Context, msg *testEvent) error { return nil },
+		IdleTimeout: time.Minute,
+	})
+	c.Assert(it.timeouts, qt.DeepEquals, map[string]time.Duration{"sub": time.Minute})
+
+	stats := sub.Stats()
+	c.Assert(stats.LastReceived.IsZero(), qt.IsTrue)
+	c.Assert(stats.Idle, qt.IsFalse)
+
+	before := time.Now()
+	c.Assert(ft.deliver(context.Background(), "sub", "1", 1, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	it.idle = true
+	stats = sub.Stats()
+	c.Assert(stats.LastReceived.Before(before), qt.IsFalse)
+	c.Assert(stats.Idle, qt.IsTrue)
+}
+
+// asyncAckTopic is a fakeTopic whose subscriptions can acknowledge messages in the background.
+type asyncAckTopic struct {
+	*fakeTopic
+	acks map[string]func(msgID string, ack func() error)
+}
+
+func (t *asyncAckTopic) SetAsyncAck(subscription string, ack func(msgID string, ack func() error)) bool {
+	t.acks[subscription] = ack
+	return true
+}
+
+func TestSubscription_AsyncAck(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	at := &asyncAckTopic{fakeTopic: fake.topics["topic"], acks: make(map[string]func(string, func() error))}
+	topic.topic = at
+
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler:  func(ctx context.Context, msg *testEvent) error { return nil },
+		AsyncAck: true,
+	})
+	ack := at.acks["sub"]
+	c.Assert(ack, qt.IsNotNil)
+
+	waitForAcks := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for sub.Stats().PendingAcks > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.Assert(sub.Stats().PendingAcks, qt.Equals, int64(0))
+	}
+
+	// Failed acknowledgements are retried
+	var attempts atomic.Int32
+	ack("1", func() error {
+		if attempts.Add(1) < 2 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	waitForAcks()
+	c.Assert(attempts.Load(), qt.Equals, int32(2))
+	stats := sub.Stats()
+	c.Assert(stats.AckFailures, qt.Equals, uint64(0))
+	c.Assert(stats.MaxAckLatency >= asyncAckBackoff, qt.IsTrue)
+	c.Assert(stats.AckLatencyP99 >= asyncAckBackoff, qt.IsTrue)
+
+	// Until they are given up on, leaving the message to be redelivered
+	attempts.Store(0)
+	ack("2", func() error {
+		attempts.Add(1)
+		return errors.New("unavailable")
+	})
+	waitForAcks()
+	c.Assert(attempts.Load(), qt.Equals, int32(asyncAckAttempts))
+	c.Assert(sub.Stats().AckFailures, qt.Equals, uint64(1))
+}
+
+func TestManager_OnConfigError(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	handler := func(ctx context.Context, msg *testEvent) error { return nil }
+
+	// The environment runs a subscription the application doesn't declare
+	delete(mgr.static.PubsubTopics["topic"].Subscriptions, "sub")
+
+	// By default it's skipped
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+	c.Assert(fake.topics["topic"].subs["sub"], qt.IsNil)
+
+	// Unless the handler decides otherwise
+	var reported []string
+	mgr.OnConfigError(func(topic, subscription string, err error) {
+		reported = append(reported, topic+"/"+subscription)
+		panic(err)
+	})
+	topic = newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce}) // as "sub" is already declared on the first
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+	}, qt.PanicMatches, "subscription sub to topic topic is configured in the environment but not declared by the application")
+	c.Assert(reported, qt.DeepEquals, []string{"topic/sub"})
+
+	// Subscriptions the environment doesn't run on this instance aren't errors
+	NewSubscription(topic, "other", SubscriptionConfig[*testEvent]{Handler: handler})
+	c.Assert(reported, qt.HasLen, 1)
+}
+
+func TestManager_OnHandlerPanic(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			panic("boom")
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	panics := make(chan PanicInfo, 1)
+	mgr.OnHandlerPanic(func(info PanicInfo) { panics <- info })
+
+	err := ft.deliver(ctx, "sub", "1", 3, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: boom")
+
+	select {
+	case info := <-panics:
+		c.Assert(info.Topic, qt.Equals, "topic")
+		c.Assert(info.Subscription, qt.Equals, "sub")
+		c.Assert(info.MessageID, qt.Equals, "1")
+		c.Assert(info.Attempt, qt.Equals, 3)
+		c.Assert(info.Value, qt.Equals, "boom")
+		c.Assert(string(info.Stack), qt.Contains, "TestManager_OnHandlerPanic")
+	case <-time.After(5 * time.Second):
+		c.Fatal("OnHandlerPanic was not called")
+	}
+
+	// Panics in the function itself don't affect processing
+	mgr.OnHandlerPanic(func(info PanicInfo) { panic("reporting failed") })
+	err = ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: boom")
+}
+
+func TestSubscription_PanicToError(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var convert func(recovered any, meta *MessageMeta) error
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			panic("boom")
+		},
+		PanicToError: func(recovered any, meta *MessageMeta) error {
+			return convert(recovered, meta)
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+	data := []byte(`{"Value":"hello"}`)
+
+	// The panic is converted using the subscription's function
+	convert = func(recovered any, meta *MessageMeta) error {
+		return errs.B().Code(errs.Unavailable).Msgf("%s panicked processing %s: %v", meta.Subscription, meta.ID, recovered).Err()
+	}
+	err := ft.deliver(ctx, "sub", "1", 1, nil, data)
+	c.Assert(errs.Code(err), qt.Equals, errs.Unavailable)
+	c.Assert(err, qt.ErrorMatches, "unavailable: sub panicked processing 1: boom")
+
+	// Unless it fails to produce an error
+	convert = func(recovered any, meta *MessageMeta) error { return nil }
+	err = ft.deliver(ctx, "sub", "2", 1, nil, data)
+	c.Assert(errs.Code(err), qt.Equals, errs.Internal)
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: boom")
+
+	convert = func(recovered any, meta *MessageMeta) error { panic("convert failed") }
+	err = ft.deliver(ctx, "sub", "3", 1, nil, data)
+	c.Assert(errs.Code(err), qt.Equals, errs.Internal)
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: boom")
+}
+
+func TestSubscription_OnPanic(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var calls int
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			calls++
+			defer func() {
+				// Panics after the handler has returned, such as when cleaning up
+				panic("cleanup failed")
+			}()
+			if msg.Value == "done" {
+				MarkCompleted(ctx)
+			}
+			return nil
+		},
+		OnPanic: PanicAckCompleted,
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Messages the handler reported completing are acknowledged
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"done"}`)), qt.IsNil)
+
+	// Others are retried, as whether the handler returned can't be told apart
+	err := ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: cleanup failed")
+
+	// Completion is tracked per delivery
+	err = ft.deliver(ctx, "sub", "2", 2, nil, []byte(`{"Value":"hello"}`))
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: cleanup failed")
+	c.Assert(calls, qt.Equals, 3)
+
+	// By default all panics are retried
+	topic = newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			defer func() { panic("cleanup failed") }()
+			MarkCompleted(ctx)
+			return nil
+		},
+	})
+	err = fake.topics["topic"].deliver(ctx, "sub", "3", 1, nil, []byte(`{"Value":"done"}`))
+	c.Assert(err, qt.ErrorMatches, ".*subscriber panicked: cleanup failed")
+}
+
+func TestSubscription_ReentrantDelivery(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ft := fake.topics["topic"]
+	ft.deliverOnPublish = true
+
+	// The handler publishes to the topic it subscribes to, so the message
+	// it publishes is processed within its own processing
+	var received []string
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg.Value)
+			if msg.Value != "ping" {
+				return nil
+			}
+			msgID := mgr.rt.Current().Req.MsgData.MessageID
+			if _, err := topic.Publish(ctx, &testEvent{Value: "pong"}); err != nil {
+				return err
+			}
+			// The handler's request is still current once the nested message is processed
+			if req := mgr.rt.Current().Req; req == nil || req.MsgData.MessageID != msgID {
+				return errors.New("request not restored")
+			}
+			return nil
+		},
+	})
+
+	_, err := topic.Publish(context.Background(), &testEvent{Value: "ping"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"ping", "pong"})
+
+	// The operation was finished once processing completed
+	c.Assert(mgr.rt.InOperation(), qt.IsFalse)
+}
+
+func TestNewSubscription_DuplicateName(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	other := newTopic[*testEvent](mgr, "other", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	handler := func(ctx context.Context, msg *testEvent) error { return nil }
+
+	// A subscription whose configuration is invalid doesn't take its name
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler, MaxProcessingTime: -1})
+	}, qt.PanicMatches, "MaxProcessingTime cannot be negative")
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+
+	// Subscriptions on different topics may share a name, but not on the same topic
+	NewSubscription(other, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+	c.Assert(func() {
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{Handler: handler})
+	}, qt.PanicMatches, "subscription sub is already declared on topic topic")
+}
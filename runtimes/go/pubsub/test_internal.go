@@ -1,6 +1,11 @@
 package pubsub
 
 import (
+	"sync"
+	"testing"
+	"time"
+
+	"encore.dev/appruntime/exported/model"
 	"encore.dev/pubsub/internal/test"
 )
 
@@ -19,3 +24,114 @@ func GetTestTopicInstance[T any](topic *Topic[T]) any {
 	}
 	return testTopic.TestInstance(req.Test.Current)
 }
+
+// DeliverTestMessage is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+func DeliverTestMessage[T any](sub *Subscription[T], msg T, msgID string, attempt int) error {
+	testTopic, ok := sub.topic.topic.(*test.TestTopic[T])
+	if !ok {
+		panic("DeliverTestMessage not called with a test topic")
+	}
+
+	attrs, data, err := marshalMessage(msg, sub.topic.runtimeCfg.EncoreName)
+	if err != nil {
+		return err
+	}
+	return testTopic.DeliverMessage(sub.name, msgID, attempt, attrs.values, data)
+}
+
+// PublishCount is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+//
+// It returns the number of messages published since the application started
+// or ResetPublishCount was last called. It always returns 0 outside of tests.
+func (mgr *Manager) PublishCount() uint64 {
+	if !mgr.static.Testing {
+		return 0
+	}
+	return mgr.publishCounter.Load()
+}
+
+// ResetPublishCount is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+//
+// It resets the count returned by PublishCount. It does nothing outside of tests.
+func (mgr *Manager) ResetPublishCount() {
+	if !mgr.static.Testing {
+		return
+	}
+	mgr.publishCounter.Store(0)
+}
+
+// MessageSpan describes the trace span of a message processed by a subscription,
+// as captured during a test by CaptureMessageSpans.
+type MessageSpan struct {
+	Type          model.RequestType // the request type; always model.PubSubMessage
+	TraceID       model.TraceID     // the trace ID of the span
+	ParentTraceID model.TraceID     // the trace ID of the publishing request, if any
+	Service       string            // the service the subscription belongs to
+	Topic         string            // the topic name
+	Subscription  string            // the subscription name
+	MessageID     string            // the message ID
+	Attempt       int               // the delivery attempt, starting at 1
+	Start         time.Time         // when the span started
+	Duration      time.Duration     // how long the span lasted
+	Err           error             // the error the span ended with, if any
+}
+
+// spanCapture records the message spans of a single test.
+type spanCapture struct {
+	mu    sync.Mutex
+	spans []MessageSpan
+}
+
+// CaptureMessageSpans is an internal API for Encore. This function should
+// never be directly called as it is considered an unstable API and Encore
+// can change it at any time
+//
+// It starts recording the spans of messages processed during the given test,
+// returning a function which reports the spans recorded so far.
+// Recording stops when the test completes.
+func (mgr *Manager) CaptureMessageSpans(t *testing.T) func() []MessageSpan {
+	capture := &spanCapture{}
+	mgr.spanCaptures.Store(t, capture)
+	t.Cleanup(func() { mgr.spanCaptures.Delete(t) })
+
+	return func() []MessageSpan {
+		capture.mu.Lock()
+		defer capture.mu.Unlock()
+		return append([]MessageSpan(nil), capture.spans...)
+	}
+}
+
+// recordTestSpan records the span of a message processed during a test,
+// if the test is capturing spans.
+func (mgr *Manager) recordTestSpan(req *model.Request, err error) {
+	if req.Test == nil {
+		return
+	}
+	v, ok := mgr.spanCaptures.Load(req.Test.Current)
+	if !ok {
+		return
+	}
+
+	capture := v.(*spanCapture)
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	capture.spans = append(capture.spans, MessageSpan{
+		Type:          req.Type,
+		TraceID:       req.TraceID,
+		ParentTraceID: req.ParentTraceID,
+		Service:       req.MsgData.Service,
+		Topic:         req.MsgData.Topic,
+		Subscription:  req.MsgData.Subscription,
+		MessageID:     req.MsgData.MessageID,
+		Attempt:       req.MsgData.Attempt,
+		Start:         req.Start,
+		Duration:      time.Since(req.Start),
+		Err:           err,
+	})
+}
@@ -3,6 +3,12 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
 
 	"encore.dev/appruntime/exported/config"
 	"encore.dev/appruntime/exported/model"
@@ -21,6 +27,14 @@ import (
 //
 // Each subscription will receive a copy of each message published to the topic.
 //
+// Message fields tagged with `pubsub:"sensitive"` are redacted from the message
+// payloads Encore records in traces and logs. They are still delivered to subscribers.
+//
+//	type UserSignedUp struct {
+//		UserID string
+//		Email  string `pubsub:"sensitive"`
+//	}
+//
 // See NewTopic for more information on how to declare a Topic.
 type Topic[T any] struct {
 	mgr            *Manager
@@ -28,16 +42,50 @@ type Topic[T any] struct {
 	runtimeCfg     *config.PubsubTopic // The config for this running instance of the application
 	topic          types.TopicImplementation
 	publishLimiter limiter.Limiter
+	publishQuota   *rate.Limiter // enforces the topic's PublishLimit, if set
+	throttled      atomic.Uint64 // number of publishes which exceeded the PublishLimit
+	publishers     publisherCheck
+	schema         *topicSchema       // nil unless the topic declares a Schema
+	encryption     *messageEncryption // nil unless the topic has Encryption configured
+	provider       string             // the name of the provider backing the topic, empty if none
+	maxAttrBytes   int                // the provider's limit on the size of message attributes, 0 if none
+	subscriptions  sync.Map           // the names of the subscriptions declared on the topic
 }
 
 func newTopic[T any](mgr *Manager, name string, cfg TopicConfig) *Topic[T] {
+	if cfg.SchemaVersion < 0 {
+		panic("SchemaVersion cannot be negative")
+	}
+	if cfg.Retention < 0 {
+		panic("Retention cannot be negative")
+	}
+	validateCloudEventsCodec(cfg.CloudEvents)
+	validateOwnership(cfg)
+	validateTopicBackendOptions(cfg)
+	quota := newPublishQuota(cfg.PublishLimit)
+	schema := newTopicSchema[T](cfg.Schema)
+	schema.registerInBackground(mgr.ctxs.Connection, mgr.rootLogger.With().Str("topic", name).Logger(), name)
+	encryption := newMessageEncryption(cfg.Encryption, name)
+
 	if mgr.static.Testing {
+		unmarshal := unmarshalPublished[T]
+		if encryption != nil {
+			unmarshal = func(attrs map[string]string, data []byte) (msg T, err error) {
+				if data, err = decryptMessage(context.Background(), encryption, attrs, data); err != nil {
+					return msg, err
+				}
+				return unmarshalPublished[T](attrs, data)
+			}
+		}
 		return &Topic[T]{
 			staticCfg:      cfg,
 			mgr:            mgr,
 			runtimeCfg:     &config.PubsubTopic{EncoreName: name},
-			topic:          test.NewTopic[T](mgr.ts, name),
+			topic:          test.NewTopic[T](mgr.ts, name, unmarshal),
 			publishLimiter: limiter.New(nil), // Create a no-op limiter
+			publishQuota:   quota,
+			schema:         schema,
+			encryption:     encryption,
 		}
 	}
 
@@ -52,6 +100,9 @@ func newTopic[T any](mgr *Manager, name string, cfg TopicConfig) *Topic[T] {
 			runtimeCfg:     &config.PubsubTopic{EncoreName: name},
 			topic:          &noop.Topic{},
 			publishLimiter: limiter.New(nil), // Create a no-op limiter
+			publishQuota:   quota,
+			schema:         schema,
+			encryption:     encryption,
 		}
 	}
 
@@ -61,13 +112,22 @@ func newTopic[T any](mgr *Manager, name string, cfg TopicConfig) *Topic[T] {
 	tried := make([]string, 0, len(mgr.providers))
 	for _, p := range mgr.providers {
 		if p.Matches(provider) {
+			log := mgr.rootLogger.With().Str("topic", name).Logger()
+			warnInactiveBackendOptions(log, p.ProviderName(), cfg.GCP != nil, cfg.NSQ != nil)
 			impl := p.NewTopic(provider, cfg, topic)
+			warnUnsupportedRetention(log, p.ProviderName(), impl, cfg)
+			mgr.registerTopic(name, impl)
 			return &Topic[T]{
 				staticCfg:      cfg,
 				mgr:            mgr,
 				runtimeCfg:     topic,
 				topic:          impl,
 				publishLimiter: limiter.New(topic.Limiter),
+				publishQuota:   quota,
+				schema:         schema,
+				encryption:     encryption,
+				provider:       p.ProviderName(),
+				maxAttrBytes:   maxAttributeBytes(impl),
 			}
 		}
 		tried = append(tried, p.ProviderName())
@@ -86,14 +146,21 @@ type TopicMeta struct {
 	Name string
 	// Config is the topic's configuration.
 	Config TopicConfig
+	// Schema is the JSON Schema of the topic's messages,
+	// or nil if its configuration doesn't declare a Schema.
+	Schema []byte
 }
 
 // Meta returns metadata about the topic.
 func (t *Topic[T]) Meta() TopicMeta {
-	return TopicMeta{
+	meta := TopicMeta{
 		Name:   t.runtimeCfg.EncoreName,
 		Config: t.staticCfg,
 	}
+	if t.schema != nil {
+		meta.Schema = t.schema.doc
+	}
+	return meta
 }
 
 // Publish will publish a message to the topic and returns a unique message ID for the message.
@@ -102,7 +169,15 @@ func (t *Topic[T]) Meta() TopicMeta {
 //
 // If an error is returned, it is probable that the message failed to be published, however it is possible
 // that the message could still be received by subscriptions to the topic.
+//
+// Use PublishWithOptions to customize how the message is published.
 func (t *Topic[T]) Publish(ctx context.Context, msg T) (id string, err error) {
+	return t.PublishWithOptions(ctx, msg)
+}
+
+// PublishWithOptions is like Publish, but customizes how the message
+// is published using PublishOptions, such as WithAttributes.
+func (t *Topic[T]) PublishWithOptions(ctx context.Context, msg T, opts ...PublishOption) (id string, err error) {
 	if ctx.Err() != nil {
 		return "", ctx.Err()
 	}
@@ -111,48 +186,163 @@ func (t *Topic[T]) Publish(ctx context.Context, msg T) (id string, err error) {
 		return "", errs.B().Code(errs.Unimplemented).Msg("pubsub topic was not created using pubsub.NewTopic").Err()
 	}
 
-	// Extract the message attributes
-	attrs, err := utils.MarshalFields(msg, utils.AttrTag)
+	attrs, data, err := marshalMessage(msg, t.runtimeCfg.EncoreName)
 	if err != nil {
-		return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to extract message attributes for topic %s", t.runtimeCfg.EncoreName).Err()
+		return "", err
+	}
+
+	return t.publishEncoded(ctx, attrs, data, newPublishOptions(opts))
+}
+
+// marshalMessage extracts the message attributes and marshals the message to JSON,
+// or for Blob messages, uses their data as-is.
+// The topic is only used for error messages.
+func marshalMessage[T any](msg T, topic string) (attrs *attributeSet, data []byte, err error) {
+	attrs = newAttributeSet()
+	if blobAttrs, data, ok, err := marshalBlob(msg, topic); ok {
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, value := range blobAttrs {
+			attrs.set(fromEncore, name, value)
+		}
+		return attrs, data, nil
 	}
 
-	// Marshal the message to JSON
-	data, err := json.Marshal(msg)
+	fields, err := utils.MarshalFields(msg, utils.AttrTag)
 	if err != nil {
-		return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to marshal message to JSON for topic %s", t.runtimeCfg.EncoreName).Err()
+		return nil, nil, errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to extract message attributes for topic %s", topic).Err()
+	}
+	for name, value := range fields {
+		attrs.set(fromMessage, name, value)
 	}
 
-	// Add the ordering attribute if it is set
-	var orderingKey string
-	if t.staticCfg.OrderingAttribute != "" {
-		value, found := attrs[t.staticCfg.OrderingAttribute]
-		if !found {
-			// This is checked statically, so this should never happen
-			return "", errs.B().Code(errs.InvalidArgument).Msgf("ordering attribute %s not found in message for topic %s", t.staticCfg.OrderingAttribute, t.runtimeCfg.EncoreName).Err()
+	// Identify which variant the message is, if the message type has variants
+	if set := variantsOf[T](); set != nil {
+		name, ok := set.variantName(msg)
+		if !ok {
+			return nil, nil, errs.B().Code(errs.InvalidArgument).Msgf("message type %T is not a registered variant for topic %s", msg, topic).Err()
 		}
+		attrs.set(fromEncore, VariantAttribute, name)
+	}
+	if err := attrs.Err(); err != nil {
+		return nil, nil, errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("invalid message attributes for topic %s", topic).Err()
+	}
 
-		if value == "" {
-			return "", errs.B().Code(errs.InvalidArgument).Msgf("ordering attribute %s cannot be an empty string for topic %s", t.staticCfg.OrderingAttribute, t.runtimeCfg.EncoreName).Err()
+	data, err = json.Marshal(msg)
+	if err != nil {
+		return nil, nil, errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to marshal message to JSON for topic %s", topic).Err()
+	}
+
+	return attrs, data, nil
+}
+
+// publishEncoded publishes an already marshalled message to the topic.
+// It takes ownership of attrs, which it adds the attributes set by
+// publish options and Encore to.
+func (t *Topic[T]) publishEncoded(ctx context.Context, attrs *attributeSet, data []byte, opts publishOptions) (id string, err error) {
+	if opts.durableConfirm && !confirmsDurably(t.topic) {
+		return "", errs.B().Code(errs.FailedPrecondition).Msgf("durable confirmation is not supported by the messaging service for topic %s", t.runtimeCfg.EncoreName).Err()
+	}
+	if err := t.checkPublisher(); err != nil {
+		return "", err
+	}
+
+	if t.schema != nil {
+		if err := validateMessage(t.schema.validator, data); err != nil {
+			return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("message does not conform to the schema of topic %s", t.runtimeCfg.EncoreName).Err()
 		}
+		if !t.schema.isRegistered() {
+			return "", errs.B().Code(errs.Unavailable).Msgf("the schema of topic %s has not been registered yet", t.runtimeCfg.EncoreName).Err()
+		}
+	}
 
-		orderingKey = value
+	// Add the attributes set using publish options
+	attrs.merge = t.staticCfg.MergeAttribute
+	for name, value := range opts.attributes {
+		attrs.set(fromOption, name, value)
 	}
 
 	// Add the correlation ID to the attributes
 	if req := t.mgr.rt.Current().Req; req != nil {
 		// Pass our trace ID through, so the subscribers can mark their traces as children of this trace
 		if req.TraceID != (model.TraceID{}) {
-			attrs[parentTraceIDAttribute] = req.TraceID.String()
+			attrs.set(fromEncore, parentTraceIDAttribute, req.TraceID.String())
 		}
 
 		if req.ExtCorrelationID != "" {
 			// If we have a correlation ID from the request, use that
-			attrs[extCorrelationIDAttribute] = req.ExtCorrelationID
+			attrs.set(fromEncore, extCorrelationIDAttribute, req.ExtCorrelationID)
 		} else if req.TraceID != (model.TraceID{}) {
 			// Otherwise this is the first request in the event chain, so this trace ID becomes the correlation ID
-			attrs[extCorrelationIDAttribute] = req.TraceID.String()
+			attrs.set(fromEncore, extCorrelationIDAttribute, req.TraceID.String())
+		}
+	}
+	if opts.correlationID != "" {
+		// A correlation ID supplied by the caller takes precedence
+		attrs.set(fromEncore, extCorrelationIDAttribute, opts.correlationID)
+	}
+
+	if t.staticCfg.SchemaVersion > 0 {
+		attrs.set(fromEncore, schemaVersionAttribute, strconv.Itoa(t.staticCfg.SchemaVersion))
+	}
+	if t.staticCfg.PropagateAuth {
+		if err := t.mgr.marshalAuth(t.mgr.rt.Current().Req, attrs); err != nil {
+			return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to propagate auth information for topic %s", t.runtimeCfg.EncoreName).Err()
+		}
+	}
+	if opts.maxProcessingTime > 0 {
+		attrs.set(fromEncore, maxProcessingTimeAttribute, opts.maxProcessingTime.String())
+	}
+	if t.staticCfg.TagProducerVersion {
+		if version := t.mgr.static.AppCommit.AsRevisionString(); version != "" {
+			attrs.set(fromEncore, producerVersionAttribute, version)
+		}
+	}
+
+	// Canonicalize the message before it is traced or wrapped, so subscribers see the same bytes
+	if _, isBlob := attrs.values[contentTypeAttribute]; t.staticCfg.CanonicalJSON && !isBlob {
+		if data, err = canonicalJSON(data); err != nil {
+			return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to canonicalize message for topic %s", t.runtimeCfg.EncoreName).Err()
+		}
+	}
+
+	// The message is traced as-is, so it is redacted as usual, but published wrapped
+	// in a CloudEvent and encrypted if configured
+	published := data
+	if t.staticCfg.CloudEvents != nil {
+		published, err = wrapCloudEvent(t.staticCfg.CloudEvents, attrs, data, time.Now())
+		if err != nil {
+			return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("failed to wrap message in a CloudEvent for topic %s", t.runtimeCfg.EncoreName).Err()
+		}
+	}
+	if t.encryption != nil {
+		if published, err = t.encryption.encrypt(ctx, attrs, published); err != nil {
+			return "", errs.B().Cause(err).Code(errs.Unavailable).Msgf("failed to encrypt message for topic %s", t.runtimeCfg.EncoreName).Err()
+		}
+	}
+
+	if err := attrs.Err(); err != nil {
+		return "", errs.B().Cause(err).Code(errs.InvalidArgument).Msgf("invalid message attributes for topic %s", t.runtimeCfg.EncoreName).Err()
+	}
+	if err := checkAttributeSize(t.runtimeCfg.EncoreName, attrs.values, t.maxAttrBytes); err != nil {
+		return "", err
+	}
+
+	// Add the ordering attribute if it is set
+	var orderingKey string
+	if t.staticCfg.OrderingAttribute != "" {
+		value, found := attrs.values[t.staticCfg.OrderingAttribute]
+		if !found {
+			// This is checked statically, so this should never happen
+			return "", errs.B().Code(errs.InvalidArgument).Msgf("ordering attribute %s not found in message for topic %s", t.staticCfg.OrderingAttribute, t.runtimeCfg.EncoreName).Err()
 		}
+
+		if value == "" {
+			return "", errs.B().Code(errs.InvalidArgument).Msgf("ordering attribute %s cannot be an empty string for topic %s", t.staticCfg.OrderingAttribute, t.runtimeCfg.EncoreName).Err()
+		}
+
+		orderingKey = value
 	}
 
 	// Start the trace span
@@ -165,16 +355,21 @@ func (t *Topic[T]) Publish(ctx context.Context, msg T) (id string, err error) {
 				SpanID:  curr.Req.SpanID,
 				Goid:    curr.Goctr,
 			},
-			Topic:   t.runtimeCfg.EncoreName,
-			Message: data,
-			Stack:   stack.Build(1),
+			Topic:       t.runtimeCfg.EncoreName,
+			Message:     redactMessage[T](attrs.values, data),
+			Stack:       stack.Build(2), // skip publishEncoded and its caller
+			OrderingKey: orderingKey,
+			PayloadSize: len(published),
 		})
 	}
 
-	// Publish once the rate limiter allows it
-	if err = t.publishLimiter.Wait(ctx); err == nil {
+	// Publish once the rate limiters allow it
+	if err = t.waitPublishQuota(ctx); err == nil {
+		err = t.publishLimiter.Wait(ctx)
+	}
+	if err == nil {
 		// Publish to the clouds topic
-		id, err = t.topic.PublishMessage(ctx, orderingKey, attrs, data)
+		id, err = t.topic.PublishMessage(ctx, orderingKey, attrs.values, published)
 	}
 
 	// End the trace span
@@ -192,8 +387,18 @@ func (t *Topic[T]) Publish(ctx context.Context, msg T) (id string, err error) {
 	}
 
 	if err != nil {
-		return "", errs.B().Cause(err).Code(errs.Unavailable).Msgf("failed to publish message to %s", t.runtimeCfg.EncoreName).Err()
+		code := errs.Unavailable
+		if errs.Code(err) == errs.ResourceExhausted {
+			// Keep rejections by the PublishLimit distinguishable from outages
+			code = errs.ResourceExhausted
+		}
+		return "", errs.B().Cause(err).Code(code).Msgf("failed to publish message to %s", t.runtimeCfg.EncoreName).Err()
 	}
 
+	t.mgr.publishCounter.Add(1)
+	recordPublished(ctx, t.runtimeCfg.EncoreName, orderingKey, attrs.values, published)
+	if t.mgr.debugSink != nil {
+		t.mgr.debugSink.record(t.runtimeCfg.EncoreName, id, attrs.values, redactMessage[T](attrs.values, data))
+	}
 	return id, nil
 }
@@ -0,0 +1,557 @@
+package pubsub
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// jsonSchemaDialect is the JSON Schema dialect of schemas derived from message types.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+const (
+	// schemaRegisterBackoff is how long to wait before retrying
+	// a failed schema registration. It doubles with each attempt.
+	schemaRegisterBackoff = time.Second
+
+	// schemaRegisterMaxBackoff is the longest wait between schema registration attempts.
+	schemaRegisterMaxBackoff = time.Minute
+)
+
+// topicSchema is the schema of a topic's messages, as declared by TopicConfig.Schema.
+type topicSchema struct {
+	doc       []byte // the JSON Schema document
+	schema    any    // the decoded JSON Schema document
+	validator MessageValidator
+	registry  SchemaRegistry
+
+	registered atomic.Bool // whether the schema has been registered with the registry
+}
+
+// newTopicSchema checks the schema declared by cfg against the message type T,
+// or returns nil if cfg is nil. It panics if T can't conform to the schema.
+func newTopicSchema[T any](cfg *TopicSchema) *topicSchema {
+	if cfg == nil {
+		return nil
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ == reflect.TypeOf(Blob{}) || typ == reflect.TypeOf(&Blob{}) {
+		panic("Schema cannot be used for topics of Blob messages")
+	}
+
+	derived, err := deriveSchema(typ, make(map[reflect.Type]bool))
+	if err != nil {
+		panic(fmt.Sprintf("cannot derive the schema of message type %s: %v", typ, err))
+	}
+
+	s := &topicSchema{schema: derived, validator: cfg.Validator, registry: cfg.Registry}
+	if cfg.JSONSchema != "" {
+		var explicit any
+		if err := json.Unmarshal([]byte(cfg.JSONSchema), &explicit); err != nil {
+			panic("invalid Schema.JSONSchema: " + err.Error())
+		}
+		if err := checkConforms(derived, explicit, ""); err != nil {
+			panic(fmt.Sprintf("message type %s does not conform to Schema.JSONSchema: %v", typ, err))
+		}
+		s.schema = explicit
+		s.doc = []byte(cfg.JSONSchema)
+	} else {
+		derived["$schema"] = jsonSchemaDialect
+		if s.doc, err = json.Marshal(derived); err != nil {
+			panic(fmt.Sprintf("cannot encode the schema of message type %s: %v", typ, err))
+		}
+	}
+
+	if s.validator == nil {
+		s.validator = MessageValidatorFunc(func(data []byte) error {
+			var v any
+			if err := json.Unmarshal(data, &v); err != nil {
+				return err
+			}
+			return validateSchema(s.schema, v, "")
+		})
+	}
+	return s
+}
+
+// registerInBackground registers the schema with the schema registry, if there is one,
+// retrying with backoff until it succeeds or ctx is cancelled.
+func (s *topicSchema) registerInBackground(ctx context.Context, log zerolog.Logger, topic string) {
+	if s == nil || s.registry == nil {
+		return
+	}
+	go func() {
+		backoff := schemaRegisterBackoff
+		for {
+			err := s.registry.RegisterSchema(ctx, topic, s.doc)
+			if err == nil {
+				s.registered.Store(true)
+				return
+			} else if ctx.Err() != nil {
+				return
+			}
+
+			log.Warn().Err(err).Dur("retry_in", backoff).Msg("failed to register the schema of the topic, retrying")
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(2*backoff, schemaRegisterMaxBackoff)
+		}
+	}()
+}
+
+// isRegistered reports whether messages can be published using the schema,
+// which requires it to have been registered if there is a schema registry.
+func (s *topicSchema) isRegistered() bool {
+	return s.registry == nil || s.registered.Load()
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// deriveSchema derives the JSON Schema of the JSON encoding of values of type t,
+// as encoded by encoding/json. inProgress holds the types being derived, to
+// stop at recursive types, which are described as accepting any value.
+func deriveSchema(t reflect.Type, inProgress map[reflect.Type]bool) (map[string]any, error) {
+	if t.Kind() == reflect.Pointer {
+		elem, err := deriveSchema(t.Elem(), inProgress)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(elem), nil
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case implements(t, jsonMarshalerType):
+		// We can't know what it marshals to
+		return map[string]any{}, nil
+	case implements(t, textMarshalerType):
+		return map[string]any{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !implements(t.Elem(), jsonMarshalerType) && !implements(t.Elem(), textMarshalerType) {
+			return map[string]any{"type": []any{"string", "null"}, "contentEncoding": "base64"}, nil
+		}
+		items, err := deriveSchema(t.Elem(), inProgress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": []any{"array", "null"}, "items": items}, nil
+
+	case reflect.Array:
+		items, err := deriveSchema(t.Elem(), inProgress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items, "minItems": t.Len(), "maxItems": t.Len()}, nil
+
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !implements(t.Key(), textMarshalerType) {
+				return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+			}
+		}
+		values, err := deriveSchema(t.Elem(), inProgress)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": []any{"object", "null"}, "additionalProperties": values}, nil
+
+	case reflect.Struct:
+		if inProgress[t] {
+			return map[string]any{}, nil
+		}
+		inProgress[t] = true
+		defer delete(inProgress, t)
+		return deriveStructSchema(t, inProgress)
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// schemaField is a field of a struct's JSON encoding.
+type schemaField struct {
+	name     string
+	tagged   bool // whether the name comes from a json tag
+	depth    int  // how deeply the field is embedded
+	required bool
+	schema   map[string]any
+}
+
+// deriveStructSchema derives the JSON Schema of a struct, resolving
+// the fields of embedded structs the way encoding/json does.
+func deriveStructSchema(t reflect.Type, inProgress map[reflect.Type]bool) (map[string]any, error) {
+	var fields []schemaField
+	if err := collectFields(t, 0, true, inProgress, &fields); err != nil {
+		return nil, err
+	}
+
+	// Fields at shallower depths hide deeper ones, and fields at the same
+	// depth hide each other unless exactly one of them is tagged
+	byName := make(map[string][]schemaField)
+	var names []string
+	for _, f := range fields {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	props := make(map[string]any)
+	required := []any{}
+	for _, name := range names {
+		f, ok := dominantField(byName[name])
+		if !ok {
+			continue
+		}
+		props[name] = f.schema
+		if f.required {
+			required = append(required, name)
+		}
+	}
+	sort.Slice(required, func(i, j int) bool { return required[i].(string) < required[j].(string) })
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+func collectFields(t reflect.Type, depth int, required bool, inProgress map[reflect.Type]bool, fields *[]schemaField) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			embeddedPtr := ft.Kind() == reflect.Pointer
+			if embeddedPtr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !implements(ft, jsonMarshalerType) && !implements(ft, textMarshalerType) {
+				if embeddedPtr && !sf.IsExported() {
+					// encoding/json ignores pointers to unexported struct types
+					continue
+				}
+				// The fields of nil embedded pointers are omitted
+				if err := collectFields(ft, depth+1, required && !embeddedPtr, inProgress, fields); err != nil {
+					return fmt.Errorf("%s: %w", sf.Name, err)
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		schema, err := deriveSchema(sf.Type, inProgress)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		if hasTagOption(opts, "string") {
+			switch sf.Type.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64, reflect.String:
+				schema = map[string]any{"type": "string"}
+			}
+		}
+
+		f := schemaField{
+			name:     name,
+			tagged:   name != "",
+			depth:    depth,
+			required: required && !hasTagOption(opts, "omitempty"),
+			schema:   schema,
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+// dominantField returns the field which is encoded among fields with the same name,
+// reporting false if none is.
+func dominantField(fields []schemaField) (schemaField, bool) {
+	minDepth := fields[0].depth
+	for _, f := range fields {
+		minDepth = min(minDepth, f.depth)
+	}
+	var candidates []schemaField
+	for _, f := range fields {
+		if f.depth == minDepth {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	var tagged []schemaField
+	for _, f := range candidates {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return schemaField{}, false
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || (t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(iface))
+}
+
+// nullable returns schema extended to also accept null.
+func nullable(schema map[string]any) map[string]any {
+	switch typ := schema["type"].(type) {
+	case string:
+		schema["type"] = []any{typ, "null"}
+	case []any:
+		for _, t := range typ {
+			if t == "null" {
+				return schema
+			}
+		}
+		schema["type"] = append(typ, "null")
+	}
+	return schema
+}
+
+// checkConforms checks whether values described by the derived schema can
+// conform to the explicit schema, reporting the first way they can't.
+// Only properties and JSON types are compared.
+func checkConforms(derived map[string]any, explicit any, path string) error {
+	switch explicit := explicit.(type) {
+	case bool:
+		if !explicit {
+			return fmt.Errorf("%s: the schema accepts no values", schemaPath(path))
+		}
+		return nil
+	case map[string]any:
+		if dTypes, eTypes := schemaTypes(derived), schemaTypes(explicit); dTypes != nil && eTypes != nil {
+			overlap := false
+			for _, dt := range dTypes {
+				for _, et := range eTypes {
+					overlap = overlap || typeAllows(et, dt)
+				}
+			}
+			if !overlap {
+				return fmt.Errorf("%s: has type %s, but the schema expects %s",
+					schemaPath(path), strings.Join(dTypes, " or "), strings.Join(eTypes, " or "))
+			}
+		}
+
+		dProps, isStruct := derived["properties"].(map[string]any)
+		eProps, _ := explicit["properties"].(map[string]any)
+		if isStruct {
+			if required, ok := explicit["required"].([]any); ok {
+				for _, name := range required {
+					if name, ok := name.(string); ok {
+						if _, ok := dProps[name]; !ok {
+							return fmt.Errorf("%s: lacks required property %q", schemaPath(path), name)
+						}
+					}
+				}
+			}
+			if additional, ok := explicit["additionalProperties"].(bool); ok && !additional {
+				for name := range dProps {
+					if _, ok := eProps[name]; !ok {
+						return fmt.Errorf("%s: has property %q, which the schema does not allow", schemaPath(path), name)
+					}
+				}
+			}
+			for name, eProp := range eProps {
+				if dProp, ok := dProps[name].(map[string]any); ok {
+					if err := checkConforms(dProp, eProp, path+"."+name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if dItems, ok := derived["items"].(map[string]any); ok {
+			if eItems, ok := explicit["items"]; ok {
+				return checkConforms(dItems, eItems, path+"[]")
+			}
+		}
+	}
+	return nil
+}
+
+// validateSchema validates the decoded JSON value v against schema,
+// supporting a subset of JSON Schema; see TopicSchema.Validator.
+func validateSchema(schema, v any, path string) error {
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			return fmt.Errorf("%s: not allowed by the schema", schemaPath(path))
+		}
+		return nil
+	case map[string]any:
+		if types := schemaTypes(schema); types != nil {
+			actual := jsonType(v)
+			ok := false
+			for _, t := range types {
+				ok = ok || typeAllows(t, actual)
+			}
+			if !ok {
+				return fmt.Errorf("%s: expected %s, got %s", schemaPath(path), strings.Join(types, " or "), actual)
+			}
+		}
+
+		if enum, ok := schema["enum"].([]any); ok {
+			found := false
+			for _, e := range enum {
+				found = found || reflect.DeepEqual(e, v)
+			}
+			if !found {
+				return fmt.Errorf("%s: value is not one of the values allowed by the schema", schemaPath(path))
+			}
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			props, _ := schema["properties"].(map[string]any)
+			if required, ok := schema["required"].([]any); ok {
+				for _, name := range required {
+					if name, ok := name.(string); ok {
+						if _, ok := v[name]; !ok {
+							return fmt.Errorf("%s: missing required property %q", schemaPath(path), name)
+						}
+					}
+				}
+			}
+			names := make([]string, 0, len(v))
+			for name := range v {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				propSchema, ok := props[name]
+				if !ok {
+					if propSchema, ok = schema["additionalProperties"]; !ok {
+						continue
+					}
+				}
+				if err := validateSchema(propSchema, v[name], path+"."+name); err != nil {
+					return err
+				}
+			}
+		case []any:
+			if items, ok := schema["items"]; ok {
+				if _, isTuple := items.([]any); !isTuple {
+					for i, item := range v {
+						if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes returns the JSON types a schema allows,
+// or nil if it doesn't restrict them.
+func schemaTypes(schema map[string]any) []string {
+	switch typ := schema["type"].(type) {
+	case string:
+		return []string{typ}
+	case []any:
+		types := make([]string, 0, len(typ))
+		for _, t := range typ {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// typeAllows reports whether values of JSON type actual
+// conform to the JSON Schema type expected.
+func typeAllows(expected, actual string) bool {
+	return expected == actual || (expected == "number" && actual == "integer")
+}
+
+// jsonType returns the JSON Schema type of the decoded JSON value v.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// schemaPath describes the location of a value within a message for errors.
+func schemaPath(path string) string {
+	return "message" + path
+}
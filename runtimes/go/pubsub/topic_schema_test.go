@@ -0,0 +1,250 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/beta/errs"
+)
+
+type schemaAddress struct {
+	City string `json:"city"`
+}
+
+type SchemaAudit struct {
+	CreatedBy string
+	Hidden    string // hidden by the field of the same name in schemaEvent
+}
+
+type schemaEvent struct {
+	SchemaAudit
+	ID       string            `json:"id"`
+	Count    int               `json:"count,omitempty"`
+	Price    float64           `json:"price,string"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Address  *schemaAddress    `json:"address,omitempty"`
+	Raw      []byte            `json:"raw,omitempty"`
+	Created  time.Time         `json:"created"`
+	Hidden   bool
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func TestDeriveSchema(t *testing.T) {
+	c := qt.New(t)
+	schema, err := deriveSchema(reflect.TypeOf(&schemaEvent{}), make(map[reflect.Type]bool))
+	c.Assert(err, qt.IsNil)
+
+	got, err := json.Marshal(schema)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.JSONEquals, map[string]any{
+		"type":                 []any{"object", "null"},
+		"additionalProperties": false,
+		"required":             []any{"CreatedBy", "Hidden", "created", "id", "price", "tags"},
+		"properties": map[string]any{
+			"CreatedBy": map[string]any{"type": "string"},
+			"Hidden":    map[string]any{"type": "boolean"},
+			"id":        map[string]any{"type": "string"},
+			"count":     map[string]any{"type": "integer"},
+			"price":     map[string]any{"type": "string"},
+			"tags":      map[string]any{"type": []any{"array", "null"}, "items": map[string]any{"type": "string"}},
+			"labels":    map[string]any{"type": []any{"object", "null"}, "additionalProperties": map[string]any{"type": "string"}},
+			"raw":       map[string]any{"type": []any{"string", "null"}, "contentEncoding": "base64"},
+			"created":   map[string]any{"type": "string", "format": "date-time"},
+			"address": map[string]any{
+				"type":                 []any{"object", "null"},
+				"additionalProperties": false,
+				"required":             []any{"city"},
+				"properties":           map[string]any{"city": map[string]any{"type": "string"}},
+			},
+		},
+	})
+
+	// Recursive types accept any value where they recur
+	type node struct {
+		Next *node
+	}
+	schema, err = deriveSchema(reflect.TypeOf(node{}), make(map[reflect.Type]bool))
+	c.Assert(err, qt.IsNil)
+	c.Assert(schema["properties"], qt.DeepEquals, map[string]any{"Next": map[string]any{}})
+
+	// Types which can't be encoded as JSON are rejected
+	type invalid struct {
+		C chan int
+	}
+	_, err = deriveSchema(reflect.TypeOf(invalid{}), make(map[reflect.Type]bool))
+	c.Assert(err, qt.ErrorMatches, "field C: unsupported type chan int")
+}
+
+func TestValidateSchema(t *testing.T) {
+	var schema any
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "string"},
+			"count": {"type": "integer"},
+			"status": {"enum": ["open", "closed"]},
+			"items": {"type": "array", "items": {"type": "number"}}
+		},
+		"additionalProperties": false
+	}`), &schema)
+	qt.Assert(t, err, qt.IsNil)
+
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{data: `{"id":"1","count":2,"status":"open","items":[1,2.5]}`},
+		{data: `[]`, wantErr: "message: expected object, got array"},
+		{data: `{"count":2}`, wantErr: `message: missing required property "id"`},
+		{data: `{"id":1}`, wantErr: "message.id: expected string, got integer"},
+		{data: `{"id":"1","count":1.5}`, wantErr: "message.count: expected integer, got number"},
+		{data: `{"id":"1","status":"pending"}`, wantErr: "message.status: value is not one of the values allowed by the schema"},
+		{data: `{"id":"1","items":[1,"2"]}`, wantErr: `message.items\[1\]: expected number, got string`},
+		{data: `{"id":"1","extra":true}`, wantErr: "message.extra: not allowed by the schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var v any
+			qt.Assert(t, json.Unmarshal([]byte(tt.data), &v), qt.IsNil)
+			err := validateSchema(schema, v, "")
+			if tt.wantErr == "" {
+				qt.Assert(t, err, qt.IsNil)
+			} else {
+				qt.Assert(t, err, qt.ErrorMatches, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTopicSchema_Conformance(t *testing.T) {
+	c := qt.New(t)
+	type order struct {
+		ID    string `json:"id"`
+		Total int    `json:"total"`
+	}
+	newSchema := func(jsonSchema string) func() {
+		return func() { newTopicSchema[*order](&TopicSchema{JSONSchema: jsonSchema}) }
+	}
+
+	c.Assert(newSchema(`{"type":"object","required":["id"],"properties":{"total":{"type":"number"}}}`), qt.Not(qt.PanicMatches), ".*")
+	c.Assert(newSchema(`{"type":"array"}`), qt.PanicMatches,
+		`message type \*pubsub.order does not conform to Schema.JSONSchema: message: has type object or null, but the schema expects array`)
+	c.Assert(newSchema(`{"required":["id","customer"]}`), qt.PanicMatches,
+		`.*: message: lacks required property "customer"`)
+	c.Assert(newSchema(`{"properties":{"id":{}},"additionalProperties":false}`), qt.PanicMatches,
+		`.*: message: has property "total", which the schema does not allow`)
+	c.Assert(newSchema(`{"properties":{"id":{"type":"integer"}}}`), qt.PanicMatches,
+		`.*: message.id: has type string, but the schema expects integer`)
+	c.Assert(newSchema(`{`), qt.PanicMatches, "invalid Schema.JSONSchema: .*")
+
+	c.Assert(func() { newTopicSchema[*Blob](&TopicSchema{}) }, qt.PanicMatches, "Schema cannot be used for topics of Blob messages")
+	c.Assert(func() { newTopicSchema[chan int](&TopicSchema{}) }, qt.PanicMatches, "cannot derive the schema of message type chan int: unsupported type chan int")
+}
+
+type fakeSchemaRegistry struct {
+	mu      sync.Mutex
+	fail    bool
+	schemas map[string][]byte
+}
+
+func (r *fakeSchemaRegistry) RegisterSchema(ctx context.Context, topic string, schema []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("registry unavailable")
+	}
+	r.schemas[topic] = schema
+	return nil
+}
+
+func (r *fakeSchemaRegistry) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = fail
+}
+
+func (r *fakeSchemaRegistry) schema(topic string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schemas[topic]
+}
+
+func TestTopic_Schema(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	ctx := context.Background()
+
+	type order struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	registry := &fakeSchemaRegistry{fail: true, schemas: make(map[string][]byte)}
+	topic := newTopic[*order](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Schema: &TopicSchema{
+			JSONSchema: `{"type":"object","required":["id"],"properties":{"status":{"enum":["open","closed"]}}}`,
+			Registry:   registry,
+		},
+	})
+	ft := fake.topics["topic"]
+
+	// Messages which break the contract aren't published
+	_, err := topic.Publish(ctx, &order{ID: "1", Status: "pending"})
+	c.Assert(errs.Code(err), qt.Equals, errs.InvalidArgument)
+	c.Assert(errors.Is(err, errInvalidMessage), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, ".*message does not conform to the schema of topic topic: .*message.status: value is not one of the values allowed by the schema")
+	c.Assert(ft.published, qt.Equals, 0)
+
+	// Nor are messages before the schema is registered
+	_, err = topic.Publish(ctx, &order{ID: "1", Status: "open"})
+	c.Assert(errs.Code(err), qt.Equals, errs.Unavailable)
+	c.Assert(ft.published, qt.Equals, 0)
+
+	// Registration is retried in the background
+	registry.setFail(false)
+	deadline := time.Now().Add(5 * schemaRegisterBackoff)
+	for !topic.schema.isRegistered() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = topic.Publish(ctx, &order{ID: "1", Status: "open"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.published, qt.Equals, 1)
+	c.Assert(string(registry.schema("topic")), qt.Equals, topic.staticCfg.Schema.JSONSchema)
+	c.Assert(topic.Meta().Schema, qt.DeepEquals, registry.schema("topic"))
+
+	// Without an explicit schema, it is derived from the message type
+	topic = newTopic[*order](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Schema: &TopicSchema{}})
+	c.Assert(string(topic.Meta().Schema), qt.JSONEquals, map[string]any{
+		"$schema":              jsonSchemaDialect,
+		"type":                 []any{"object", "null"},
+		"additionalProperties": false,
+		"required":             []any{"id", "status"},
+		"properties": map[string]any{
+			"id":     map[string]any{"type": "string"},
+			"status": map[string]any{"type": "string"},
+		},
+	})
+	_, err = topic.Publish(ctx, &order{ID: "2"})
+	c.Assert(err, qt.IsNil)
+
+	// Custom validators replace the built-in one
+	topic = newTopic[*order](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, Schema: &TopicSchema{
+		Validator: MessageValidatorFunc(func(data []byte) error { return errors.New("rejected") }),
+	}})
+	_, err = topic.Publish(ctx, &order{ID: "3"})
+	c.Assert(err, qt.ErrorMatches, ".*: rejected")
+
+	// Topics without a schema don't report one
+	topic = newTopic[*order](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	c.Assert(topic.Meta().Schema, qt.IsNil)
+}
@@ -0,0 +1,240 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/config"
+	"encore.dev/appruntime/exported/model"
+	"encore.dev/beta/errs"
+)
+
+func TestManager_PublishCount(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ctx := context.Background()
+
+	_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+
+	// The count is not exposed outside of tests
+	c.Assert(mgr.PublishCount(), qt.Equals, uint64(0))
+	mgr.static.Testing = true
+	c.Assert(mgr.PublishCount(), qt.Equals, uint64(1))
+
+	// Failed publishes are not counted
+	fake.topics["topic"].publishErr = errors.New("unavailable")
+	_, err = topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(mgr.PublishCount(), qt.Equals, uint64(1))
+
+	mgr.ResetPublishCount()
+	c.Assert(mgr.PublishCount(), qt.Equals, uint64(0))
+}
+
+func TestTopic_TagProducerVersion(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	mgr.static.AppCommit = config.CommitInfo{Revision: "abc123", Uncommitted: true}
+	ctx := context.Background()
+
+	var meta *MessageMeta
+	untagged := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	NewSubscription(untagged, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			meta, _ = CurrentMessage(ctx)
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+
+	// Messages are not tagged unless the topic opts in
+	_, err := untagged.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[producerVersionAttribute], qt.Equals, "")
+
+	tagged := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce, TagProducerVersion: true})
+	_, err = tagged.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	published := fake.topics["topic"]
+	c.Assert(published.lastAttrs[producerVersionAttribute], qt.Equals, "abc123-modified")
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, published.lastAttrs, published.lastData), qt.IsNil)
+	c.Assert(meta.ProducerVersion, qt.Equals, "abc123-modified")
+}
+
+func TestTopic_Blob(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*Blob](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+	ctx := context.Background()
+
+	var received *Blob
+	NewSubscription(topic, "sub", SubscriptionConfig[*Blob]{
+		Handler: func(ctx context.Context, msg *Blob) error {
+			received = msg
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+
+	// Blobs are published as their raw bytes, without JSON encoding
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	_, err := topic.Publish(ctx, &Blob{Data: data, ContentType: "image/png"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastData, qt.DeepEquals, data)
+	c.Assert(ft.lastAttrs[contentTypeAttribute], qt.Equals, "image/png")
+
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, &Blob{Data: data, ContentType: "image/png"})
+
+	// The content type defaults to application/octet-stream
+	_, err = topic.Publish(ctx, &Blob{Data: data})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[contentTypeAttribute], qt.Equals, "application/octet-stream")
+
+	// Messages without the attribute are still delivered as-is
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte("not json")), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, &Blob{Data: []byte("not json"), ContentType: "application/octet-stream"})
+
+	_, err = topic.Publish(ctx, nil)
+	c.Assert(err, qt.ErrorMatches, ".*cannot publish a nil blob.*")
+}
+
+func TestTopic_PublishLimit(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	ctx := context.Background()
+
+	// Publishes over the limit are rejected when configured to
+	rejecting := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		PublishLimit:      &PublishLimit{MaxPerSecond: 1, Burst: 2, Reject: true},
+	})
+	ft := fake.topics["topic"]
+	for i := 0; i < 2; i++ {
+		_, err := rejecting.Publish(ctx, &testEvent{Value: "hello"})
+		c.Assert(err, qt.IsNil)
+	}
+	_, err := rejecting.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(errs.Code(err), qt.Equals, errs.ResourceExhausted)
+	c.Assert(ft.published, qt.Equals, 2)
+	c.Assert(rejecting.Stats().Throttled, qt.Equals, uint64(1))
+
+	// Otherwise they wait until they are within the limit
+	waiting := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		PublishLimit:      &PublishLimit{MaxPerSecond: 20},
+	})
+	ft = fake.topics["topic"]
+	start := time.Now()
+	for i := 0; i < 22; i++ {
+		_, err := waiting.Publish(ctx, &testEvent{Value: "hello"})
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(time.Since(start) >= 50*time.Millisecond, qt.IsTrue)
+	c.Assert(ft.published, qt.Equals, 22)
+	c.Assert(waiting.Stats().Throttled, qt.Equals, uint64(2))
+
+	// Waiting publishes give up when their context is done
+	ctx2, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = waiting.Publish(ctx2, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(ft.published, qt.Equals, 22)
+}
+
+func TestTopic_InMemoryPubsub(t *testing.T) {
+	c := qt.New(t)
+	mgr, _ := newTestManager(t, "topic", "sub")
+	mgr.static.Testing = true
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var mu sync.Mutex
+	var received []string
+	NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, msg.Value)
+			return nil
+		},
+	})
+
+	mgr.rt.BeginOperation()
+	defer mgr.rt.FinishOperation()
+	mgr.ts.StartTest(t, nil)
+	defer mgr.ts.EndTest(t)
+	ctx := context.Background()
+
+	// By default messages are only recorded
+	_, err := topic.Publish(ctx, &testEvent{Value: "recorded"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(received, qt.HasLen, 0)
+
+	// Once enabled, they are processed by the subscription before Publish returns
+	mgr.ts.SetInMemoryPubsub(true)
+	_, err = topic.Publish(ctx, &testEvent{Value: "delivered"})
+	c.Assert(err, qt.IsNil)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(received, qt.DeepEquals, []string{"delivered"})
+}
+
+func TestTopic_EnforceOwnership(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	ctx := context.Background()
+
+	publishFrom := func(topic *Topic[*testEvent], svc string) error {
+		mgr.rt.BeginOperation()
+		defer mgr.rt.FinishOperation()
+		mgr.rt.BeginRequest(&model.Request{Type: model.RPCCall, RPCData: &model.RPCData{Desc: &model.RPCDesc{Service: svc}}})
+		_, err := topic.Publish(ctx, &testEvent{Value: "hello"})
+		return err
+	}
+
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Owner:             "orders",
+		AllowedPublishers: []string{"billing"},
+		EnforceOwnership:  OwnershipDeny,
+	})
+	ft := fake.topics["topic"]
+
+	// The owner and allowed publishers can publish
+	c.Assert(publishFrom(topic, "orders"), qt.IsNil)
+	c.Assert(publishFrom(topic, "billing"), qt.IsNil)
+
+	// Other services can't
+	err := publishFrom(topic, "shipping")
+	c.Assert(errs.Code(err), qt.Equals, errs.PermissionDenied)
+	c.Assert(err, qt.ErrorMatches, ".*service shipping is not allowed to publish to topic topic, which is owned by service orders")
+	c.Assert(ft.published, qt.Equals, 2)
+
+	// Publishes outside of a request aren't checked
+	_, err = topic.Publish(ctx, &testEvent{Value: "hello"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.published, qt.Equals, 3)
+
+	// When only warning, other services can still publish
+	topic = newTopic[*testEvent](mgr, "topic", TopicConfig{
+		DeliveryGuarantee: AtLeastOnce,
+		Owner:             "orders",
+		EnforceOwnership:  OwnershipWarn,
+	})
+	c.Assert(publishFrom(topic, "shipping"), qt.IsNil)
+	_, warned := topic.publishers.warned.Load("shipping")
+	c.Assert(warned, qt.IsTrue)
+
+	// Enforcing ownership requires an owner
+	c.Assert(func() {
+		newTopic[*testEvent](mgr, "topic", TopicConfig{EnforceOwnership: OwnershipDeny})
+	}, qt.PanicMatches, "Owner is required to enforce ownership")
+}
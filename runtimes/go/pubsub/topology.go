@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// TopologyDrift is a difference between the topics and subscriptions
+// declared by the application and what exists at the cloud provider.
+type TopologyDrift = types.TopologyDrift
+
+// TopologyDriftKind describes how a topic or subscription
+// differs from what the application declares.
+type TopologyDriftKind = types.TopologyDriftKind
+
+const (
+	TopicMissing = types.TopicMissing
+
+	SubscriptionMissing = types.SubscriptionMissing
+
+	SubscriptionExtra = types.SubscriptionExtra
+
+	ConfigMismatch = types.ConfigMismatch
+)
+
+// CheckTopology compares the topics and subscriptions configured on this
+// instance of the service against what exists at the cloud provider,
+// and reports any differences. It never changes anything at the provider.
+//
+// The Retention of topics is compared when declared, while the configuration
+// of subscriptions is only compared for those this instance subscribes to.
+// Topics whose provider cannot report its topology are skipped;
+// currently only GCP Pub/Sub is supported.
+func (mgr *Manager) CheckTopology(ctx context.Context) ([]TopologyDrift, error) {
+	mgr.subsMu.Lock()
+	names := make([]string, 0, len(mgr.topics))
+	topics := make(map[string]types.TopicImplementation, len(mgr.topics))
+	for name, impl := range mgr.topics {
+		names = append(names, name)
+		topics[name] = impl
+	}
+	mgr.subsMu.Unlock()
+	sort.Strings(names)
+
+	var drift []TopologyDrift
+	for _, name := range names {
+		checker, ok := topics[name].(types.TopologyChecker)
+		if !ok {
+			continue
+		}
+		d, err := checker.CheckTopology(ctx)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, d...)
+	}
+	return drift, nil
+}
+
+// LogTopologyDrift runs CheckTopology and logs any differences found,
+// if a provider is configured to check its topology. It is run in the
+// background when the application starts, and stops once ctx is cancelled.
+func (mgr *Manager) LogTopologyDrift(ctx context.Context) {
+	if !mgr.checkTopologyEnabled() {
+		return
+	}
+
+	drift, err := mgr.CheckTopology(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			mgr.rootLogger.Warn().Err(err).Msg("pubsub: unable to check topology")
+		}
+		return
+	}
+
+	for _, d := range drift {
+		ev := mgr.rootLogger.Warn().Str("drift", string(d.Kind)).Str("topic", d.Topic)
+		if d.Subscription != "" {
+			ev = ev.Str("subscription", d.Subscription)
+		}
+		if d.Kind == ConfigMismatch {
+			ev = ev.Str("field", d.Field).Str("declared", d.Declared).Str("actual", d.Actual)
+		}
+		ev.Msg("pubsub: topology differs from what the application declares")
+	}
+}
+
+// checkTopologyEnabled reports whether any provider is
+// configured to check its topology when the application starts.
+func (mgr *Manager) checkTopologyEnabled() bool {
+	for _, p := range mgr.runtime.PubsubProviders {
+		if p.GCP != nil && p.GCP.CheckTopology {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/config"
+)
+
+// checkedTopic is a fakeTopic whose provider reports its topology.
+type checkedTopic struct {
+	*fakeTopic
+	checks int
+	drift  []TopologyDrift
+}
+
+func (t *checkedTopic) CheckTopology(ctx context.Context) ([]TopologyDrift, error) {
+	t.checks++
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.drift, nil
+}
+
+func TestManager_LogTopologyDrift(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	var buf bytes.Buffer
+	mgr.rootLogger = zerolog.New(&buf)
+	topic := &checkedTopic{
+		fakeTopic: fake.topics["topic"],
+		drift:     []TopologyDrift{{Kind: SubscriptionMissing, Topic: "topic", Subscription: "sub"}},
+	}
+	mgr.registerTopic("topic", topic)
+	ctx := context.Background()
+
+	// The topology isn't checked unless a provider enables it
+	mgr.LogTopologyDrift(ctx)
+	c.Assert(topic.checks, qt.Equals, 0)
+
+	mgr.runtime.PubsubProviders = []*config.PubsubProvider{{GCP: &config.GCPPubsubProvider{CheckTopology: true}}}
+	mgr.LogTopologyDrift(ctx)
+	c.Assert(topic.checks, qt.Equals, 1)
+	c.Assert(buf.String(), qt.Contains, `"drift":"subscription_missing","topic":"topic","subscription":"sub","message":"pubsub: topology differs from what the application declares"`)
+
+	// Checks stopped by shutting down aren't reported as failures
+	buf.Reset()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	mgr.LogTopologyDrift(cancelled)
+	c.Assert(topic.checks, qt.Equals, 2)
+	c.Assert(buf.String(), qt.Equals, "")
+}
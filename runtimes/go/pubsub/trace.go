@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"encore.dev/appruntime/exported/trace2"
+	"encore.dev/beta/errs"
+)
+
+// traceError returns err in the form it is recorded in a message's trace span.
+//
+// Traces only record an error's message and stack, so the code and details
+// of the first *errs.Error in err's chain are included in the message.
+// This keeps the code when an *errs.Error has been wrapped with fmt.Errorf.
+func traceError(err error) error {
+	var e *errs.Error
+	if err == nil || !errors.As(err, &e) {
+		return err
+	}
+
+	var msg string
+	if err == error(e) {
+		msg = e.ErrorMessage()
+	} else {
+		msg = err.Error()
+	}
+	if e.Details != nil {
+		if details, jsonErr := json.Marshal(e.Details); jsonErr == nil {
+			msg += " (details: " + string(details) + ")"
+		}
+	}
+
+	te := errs.B().Code(e.Code).Msg(msg).Stack(errs.Stack(e)).Err().(*errs.Error)
+	te.Meta = e.Meta
+	return te
+}
+
+// traceAttributeFields returns the message attributes listed in keys
+// as trace log fields. Attributes missing from the message are skipped.
+func traceAttributeFields(attrs map[string]string, keys []string) []trace2.LogField {
+	var fields []trace2.LogField
+	for _, k := range keys {
+		if v, ok := attrs[k]; ok {
+			fields = append(fields, trace2.LogField{Key: k, Value: v})
+		}
+	}
+	return fields
+}
+
+// safeTrace calls f to record a trace event for the message with the given ID,
+// recovering from any panic so a failure in the tracing subsystem is logged
+// rather than preventing the message from being processed.
+func safeTrace(log zerolog.Logger, msgID, event string, f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("msg_id", msgID).Str("trace_event", event).Interface("panic", r).
+				Msg("failed to record trace event, continuing without it")
+		}
+	}()
+	f()
+}
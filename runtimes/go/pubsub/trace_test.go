@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"encore.dev/appruntime/exported/trace2"
+	"encore.dev/beta/errs"
+)
+
+type testErrDetails struct {
+	OrderID string `json:"order_id"`
+}
+
+func (testErrDetails) ErrDetails() {}
+
+func TestTraceError(t *testing.T) {
+	c := qt.New(t)
+	notFound := errs.B().Code(errs.NotFound).Msg("order not found").Err()
+	withDetails := errs.B().Code(errs.FailedPrecondition).Msg("order closed").Details(testErrDetails{OrderID: "123"}).Err()
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode errs.ErrCode
+		wantMsg  string
+	}{
+		{"nil", nil, errs.OK, ""},
+		{"plain", fmt.Errorf("boom"), errs.Unknown, "boom"},
+		{"errs", notFound, errs.NotFound, "not_found: order not found"},
+		{"wrapped", fmt.Errorf("process: %w", notFound), errs.NotFound, "not_found: process: not_found: order not found"},
+		{"details", withDetails, errs.FailedPrecondition, `failed_precondition: order closed (details: {"order_id":"123"})`},
+	}
+
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			got := traceError(tt.err)
+			c.Assert(errs.Code(got), qt.Equals, tt.wantCode)
+			if tt.err == nil {
+				c.Assert(got, qt.IsNil)
+				return
+			}
+			c.Assert(got.Error(), qt.Equals, tt.wantMsg)
+		})
+	}
+}
+
+func TestTraceAttributeFields(t *testing.T) {
+	c := qt.New(t)
+	attrs := map[string]string{"order_id": "123", "region": "eu", "other": "x"}
+
+	c.Assert(traceAttributeFields(attrs, nil), qt.IsNil)
+	c.Assert(traceAttributeFields(attrs, []string{"region", "missing", "order_id"}), qt.DeepEquals, []trace2.LogField{
+		{Key: "region", Value: "eu"},
+		{Key: "order_id", Value: "123"},
+	})
+}
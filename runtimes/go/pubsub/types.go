@@ -13,6 +13,10 @@ const parentTraceIDAttribute = "encore_parent_trace_id"
 // extCorrelationIDAttribute is the attribute name we use to track externally provided correlation IDs
 const extCorrelationIDAttribute = "encore_ext_correlation_id"
 
+// producerVersionAttribute is the attribute name we use to record the version
+// of the application which published a message; see TopicConfig.TagProducerVersion
+const producerVersionAttribute = "encore_producer_version"
+
 // SubscriptionConfig is used when creating a subscription
 //
 // The values given here may be clamped to the supported values by
@@ -38,7 +42,11 @@ type SubscriptionConfig[T any] struct {
 	// attempt to be made (unless the retry policy's MaxRetries has been reached).
 	//
 	// The ctx passed to the handler will be cancelled when
-	// the AckDeadline passes.
+	// the AckDeadline passes. Use [LeaseDeadline] to find out
+	// when that will happen.
+	//
+	// To pull messages from the subscription using [Subscription.Receive]
+	// instead, use [PullHandler].
 	//
 	// This field is required.
 	//
@@ -67,10 +75,30 @@ type SubscriptionConfig[T any] struct {
 	// [GCP Push Delivery Rate]: https://cloud.google.com/pubsub/docs/push#push_delivery_rate
 	MaxConcurrency int
 
-	// Filter is a boolean expression using =, !=, IN, &&
-	// It is used to filter which messages are forwarded from the
-	// topic to a subscription
-	// Filter string - Filters are not currently supported
+	// FilterExpr, if set, is a boolean expression over message attributes
+	// which selects the messages the subscription processes, using the syntax
+	// of GCP Pub/Sub subscription filters:
+	//
+	//	attributes:key                        the message has the attribute
+	//	attributes.key = "value"              the attribute equals the value
+	//	attributes.key != "value"             the attribute is set to another value
+	//	hasPrefix(attributes.key, "prefix")   the attribute starts with the prefix
+	//
+	// Conditions can be combined with AND, OR, NOT and parentheses,
+	// and keys which aren't identifiers can be written as quoted strings.
+	// An invalid expression causes NewSubscription to panic.
+	//
+	// The filter is evaluated by the subscription on every provider: messages it
+	// excludes are acknowledged without calling the Handler, and the subscription's
+	// Stats report how many messages were filtered out. Messages it can't be
+	// evaluated for, such as those with attribute values which aren't valid UTF-8,
+	// are quarantined like messages which fail to decode.
+	//
+	// On GCP the filter is also part of the subscription's declared topology,
+	// so the messaging service doesn't deliver the messages it excludes once the
+	// subscription is provisioned with it, and CheckTopology reports subscriptions
+	// provisioned with a different filter. Other providers receive every message.
+	FilterExpr string
 
 	// AckDeadline is the time a consumer has to process a message
 	// before it's returned to the subscription
@@ -86,7 +114,314 @@ type SubscriptionConfig[T any] struct {
 
 	// RetryPolicy defines how a message should be retried when
 	// the subscriber returns an error
+	//
+	// It must not be set for subscriptions to AtMostOnce topics,
+	// whose messages are never retried.
 	RetryPolicy *RetryPolicy
+
+	// PanicToError, if set, converts the value a panicking Handler recovered
+	// from into the error the message fails with, for example to classify
+	// panics with a different error code or include details of the message.
+	// The message is retried according to the RetryPolicy as usual.
+	//
+	// If it is nil, returns nil or panics itself, the message fails with an
+	// errs.Internal error describing the panic. Either way the panic is
+	// reported to the function set with OnHandlerPanic.
+	PanicToError func(recovered any, meta *MessageMeta) error
+
+	// OnPanic determines whether a message whose Handler panicked after
+	// calling MarkCompleted is acknowledged or retried. See PanicPolicy for details.
+	//
+	// Defaults to PanicRetry.
+	OnPanic PanicPolicy
+
+	// MaxRetryDuration, if set, bounds how long a message is retried for,
+	// measured from when it was published, regardless of how many attempts
+	// have been made. Once it has passed, a message whose Handler fails is
+	// quarantined instead of being retried; see OnQuarantine. The quarantined
+	// message's Reason wraps the error returned by the Handler.
+	//
+	// It is combined with the RetryPolicy's MaxRetries, and whichever is
+	// reached first stops the message from being retried. As the duration is
+	// only checked when the Handler fails, a message can be retried for up to
+	// the RetryPolicy's MaxBackoff past it. Messages whose publish time is not
+	// reported by the provider are retried according to the RetryPolicy alone.
+	//
+	// The subscription's Stats report how many messages exceeded it.
+	MaxRetryDuration time.Duration
+
+	// DedupByMessageID, if set, causes the subscription to skip calling the
+	// Handler for messages whose message ID it has already processed
+	// successfully within the dedup window. Such redeliveries are acknowledged
+	// without being processed again.
+	//
+	// This protects against redeliveries made by the messaging service, but
+	// not against the same event being published more than once, as each publish
+	// is assigned a new message ID.
+	DedupByMessageID bool
+
+	// Dedup configures the deduplication window and store used
+	// when DedupByMessageID is set. If nil, defaults are used.
+	Dedup *DedupConfig
+
+	// Idempotency, if set, records the result of processing each message,
+	// including the messages the Handler publishes, and answers redeliveries
+	// with the recorded result instead of calling the Handler again.
+	// See IdempotencyConfig for details.
+	Idempotency *IdempotencyConfig
+
+	// CircuitBreaker, if set, stops calling the Handler after repeated
+	// failures, giving a failing downstream dependency time to recover.
+	// See CircuitBreakerConfig for details. If nil, no circuit breaker is used.
+	CircuitBreaker *CircuitBreakerConfig
+
+	// OnDecodeError determines what happens to messages which cannot be
+	// decoded into the message type T. See DecodeErrorPolicy for details.
+	//
+	// Defaults to DecodeErrorRetry.
+	OnDecodeError DecodeErrorPolicy
+
+	// OnQuarantine is called with messages quarantined by the subscription,
+	// for example to store them for later inspection. If it returns an error
+	// the message is redelivered so quarantining it can be retried.
+	//
+	// If nil, quarantined messages are logged and acknowledged.
+	OnQuarantine func(ctx context.Context, msg *QuarantinedMessage) error
+
+	// TraceAttributes lists message attributes to record in the trace
+	// of each message processed by the subscription, to make it easier
+	// to correlate traces with the events they were processing.
+	TraceAttributes []string
+
+	// InitialPosition determines whether a newly created subscription
+	// receives messages published before it was created, where the
+	// provider supports it. It has no effect on existing subscriptions.
+	//
+	// If the provider does not support the requested position, a warning
+	// is logged and the provider's native behaviour is used.
+	// Use the subscription's Stats method to see the effective position.
+	//
+	// Defaults to InitialPositionDefault, the provider's native behaviour.
+	InitialPosition InitialPosition
+
+	// AuditSink, if set, is given a record of every message delivered
+	// to the subscription and its outcome. See AuditSink for details.
+	//
+	// If nil, no audit records are written.
+	AuditSink AuditSink
+
+	// Upgrade migrates the raw JSON data of a message published with an older
+	// schema version (see TopicConfig.SchemaVersion) to the shape of the current
+	// version, before it is decoded. Messages published before the topic had a
+	// schema version are passed with version 0.
+	//
+	// If Upgrade returns an error, the message is handled according to the
+	// subscription's OnDecodeError policy. Messages published with a newer schema
+	// version than the subscription's topic are always quarantined.
+	//
+	// If nil, messages are decoded as published regardless of their version.
+	Upgrade func(version int, raw []byte) ([]byte, error)
+
+	// Commit, if set, defers acknowledging messages until the work of their
+	// Handlers has been committed downstream in batches. See CommitConfig for
+	// details, including ordering guarantees.
+	//
+	// If nil, messages are acknowledged as soon as the Handler returns.
+	Commit *CommitConfig
+
+	// ClockSkewTolerance is how far in the future a message's publish time
+	// may be, compared to when it is received, before the subscription
+	// considers the clocks of the publisher, messaging service and subscriber
+	// to be skewed. Skewed messages are still processed, but a warning is logged
+	// and they are counted in the subscription's Stats.
+	//
+	// Message ages computed by the subscription, such as MessageMeta.Age,
+	// are clamped to zero rather than being negative regardless of this setting.
+	//
+	// Defaults to 5 seconds.
+	ClockSkewTolerance time.Duration
+
+	// LIFO, if set, processes the most recently published messages first
+	// when the subscription has a backlog, for workloads where fresh messages
+	// are more valuable than old ones.
+	//
+	// Reordering only happens within the messages this instance has already
+	// received: the subscription prefetches up to MaxConcurrency messages beyond
+	// those being processed, and when a Handler finishes the newest waiting
+	// message is processed next. Messages still held by the messaging service are
+	// delivered in its usual order. LIFO has no effect unless MaxConcurrency is positive.
+	//
+	// Under a sustained backlog, LIFO can starve older messages until their
+	// AckDeadline passes and they are redelivered, counting towards the
+	// RetryPolicy's MaxRetries. Use the subscription's Stats to monitor
+	// how old messages are when they are processed.
+	LIFO bool
+
+	// MaxInFlight is a safety net for Handlers which block indefinitely,
+	// for example on work which ignores context cancellation. It is the longest
+	// time a message may be in flight, measured from when this instance received
+	// it, before it is negatively acknowledged so it can be redelivered.
+	//
+	// When MaxInFlight passes, the Handler's context is cancelled and the
+	// message is nacked without waiting for the Handler to return, so a wedged
+	// Handler can't hold up the subscription's backlog or a graceful shutdown.
+	// The Handler keeps running in the background until it returns, and its
+	// result is discarded. An error is logged and the message is counted in
+	// the subscription's Stats.
+	//
+	// This differs from AckDeadline, which is enforced by the messaging service:
+	// when it passes the message is redelivered and the Handler's context is
+	// cancelled, but the subscription still waits for the Handler to return,
+	// which counts towards MaxConcurrency and delays shutdown.
+	//
+	// If zero, Handlers are always waited for.
+	MaxInFlight time.Duration
+
+	// MaxProcessingTime caps the processing time publishers can request
+	// for individual messages using WithMaxProcessingTime. Requests for longer
+	// are clamped to it. If zero, requested processing times are not capped.
+	//
+	// A message's processing time is measured from when this instance received
+	// it, and bounds the context passed to the Handler. If the Handler returns
+	// an error once it passes, such as the context's error, the message is
+	// negatively acknowledged and retried according to the RetryPolicy.
+	//
+	// The Handler's context is cancelled when the first of the message's
+	// processing time and the AckDeadline passes. MaxInFlight is enforced
+	// independently, so it should be longer than the processing times of
+	// messages for Handlers to have a chance to return on their own.
+	MaxProcessingTime time.Duration
+
+	// PropagateAuth, if set, processes messages published with auth information
+	// on behalf of the user who published them, so auth.UserID and auth.Data
+	// report that user within the Handler. Publishers record the user by
+	// setting TopicConfig.PropagateAuth.
+	//
+	// Anyone able to publish to the topic can set the auth information of
+	// messages, so only enable it for topics which are published to solely by
+	// trusted services. Messages whose auth data does not match the type
+	// returned by the application's auth handler are counted as decode errors
+	// and quarantined, as retrying them won't help; see OnQuarantine.
+	//
+	// If not set, handlers run without an authenticated user.
+	PropagateAuth bool
+
+	// Dispatch, if set, routes messages with the same key to the same worker
+	// so that they are processed one at a time, in the order they were received.
+	// See DispatchConfig for the ordering guarantees.
+	//
+	// MaxConcurrency still limits the number of messages received at once,
+	// so it should be larger than the number of workers to keep them busy.
+	Dispatch *DispatchConfig[T]
+
+	// Validator, if set, validates each message against a contract, such as
+	// a JSON Schema, after it is decoded and before it is passed to the Handler.
+	// It is given the JSON-encoded message, migrated to the current SchemaVersion.
+	//
+	// Messages which fail validation are counted as decode errors and
+	// quarantined, with the validation error as the QuarantinedMessage's Reason,
+	// as retrying them won't help; see OnQuarantine.
+	//
+	// Validation runs for every message, so for high-volume topics prefer
+	// validators which don't need to decode the message a second time.
+	Validator MessageValidator
+
+	// RedeliveryStorm, if set, protects the subscription against the messaging
+	// service redelivering the same message in a tight loop.
+	// See RedeliveryStormConfig for details. If nil, storms are not detected.
+	RedeliveryStorm *RedeliveryStormConfig
+
+	// IdleTimeout, if set, makes the subscription release most of the
+	// resources it holds open with the messaging service, such as connections
+	// and goroutines, once it hasn't received any messages for this long.
+	// It is useful for subscriptions to sparse topics, particularly when
+	// many of them run in the same instance.
+	//
+	// While idle, the subscription waits for the next message using as few
+	// resources as possible, and re-establishes its resources once it arrives.
+	// No messages are lost in the transition, but messages published just as
+	// the subscription goes idle or wakes up may not be delivered until their
+	// AckDeadline passes, and the first messages after an idle period may be
+	// delivered with slightly higher latency. The subscription's Stats report
+	// whether it is idle.
+	//
+	// It is currently only supported for GCP Pub/Sub pull subscriptions;
+	// for other providers a warning is logged and it has no effect.
+	//
+	// If zero, the subscription keeps its resources while idle.
+	IdleTimeout time.Duration
+
+	// SkipBacklog, if set, acknowledges messages published before the
+	// subscription started without processing them, to skip a stale backlog.
+	// Skipped messages are lost, so it must be explicitly enabled with
+	// AcceptDataLoss. See SkipBacklogConfig for details.
+	//
+	// If nil, all messages are processed.
+	SkipBacklog *SkipBacklogConfig
+
+	// AsyncAck, if set, acknowledges messages in the background once their
+	// Handler has succeeded, rather than waiting for the messaging service to
+	// confirm the acknowledgement before the message is done. This takes the
+	// acknowledgement's latency off the processing of each message, freeing
+	// up MaxConcurrency for the next message sooner, which reduces tail latency
+	// for latency-sensitive subscriptions.
+	//
+	// Messages are still only acknowledged once their Handler succeeds, so
+	// delivery remains at-least-once. Failed acknowledgements are retried
+	// a few times, after which the message is redelivered once its
+	// AckDeadline passes, so Handlers must be idempotent. Acknowledgements
+	// still in progress when the service shuts down may also fail, leading to
+	// redelivery. The subscription's Stats report acknowledgements in progress,
+	// failures, and percentiles of the latency taken off processing.
+	//
+	// It is supported for GCP Pub/Sub pull subscriptions, AWS SQS and Azure
+	// Service Bus. For other providers a warning is logged and messages are
+	// acknowledged as usual; NSQ never waits for acknowledgements anyway.
+	//
+	// If not set, messages are acknowledged synchronously once their Handler
+	// has succeeded, and the message is only done once the messaging service
+	// has confirmed the acknowledgement.
+	AsyncAck bool
+
+	// Coalesce, if set, coalesces messages with the same key arriving within
+	// a short window into a single call to the Handler. See CoalesceConfig for
+	// the delivery and ordering caveats. It cannot be combined with Dispatch.
+	//
+	// If nil, the Handler is called for every message.
+	Coalesce *CoalesceConfig[T]
+
+	// Fairness, if set, schedules the processing of messages fairly between
+	// the tenants they belong to, so one tenant can't monopolize the Handlers.
+	// See FairnessConfig for how messages are scheduled. It cannot be combined
+	// with LIFO, and has no effect unless MaxConcurrency is positive.
+	//
+	// If nil, messages are processed in the order they are received.
+	Fairness *FairnessConfig[T]
+
+	// RecoveryRamp, if set, limits the rate the Handler is called at when the
+	// subscription starts or resumes after being paused, increasing it gradually
+	// so a just-recovered downstream isn't overwhelmed by the backlog.
+	// See RecoveryRampConfig for when the ramp starts.
+	//
+	// If nil, messages are processed as fast as MaxConcurrency allows.
+	RecoveryRamp *RecoveryRampConfig
+
+	// DecodeCache, if set, caches decoded messages so that messages retried
+	// on the same instance aren't decoded again on every attempt. Handlers must
+	// not modify messages when it is set. See DecodeCacheConfig for details.
+	//
+	// If nil, messages are decoded on every delivery.
+	DecodeCache *DecodeCacheConfig
+
+	// GCP configures options specific to GCP Pub/Sub, which are applied when
+	// the subscription's topic is backed by GCP Pub/Sub and ignored otherwise.
+	// A warning is logged if they are set for a topic backed by another provider.
+	GCP *GCPSubscriptionOptions
+
+	// NSQ configures options specific to NSQ, which are applied when
+	// the subscription's topic is backed by NSQ and ignored otherwise.
+	// A warning is logged if they are set for a topic backed by another provider.
+	NSQ *NSQSubscriptionOptions
 }
 
 type RetryPolicy = types.RetryPolicy
@@ -103,6 +438,58 @@ const (
 	AtLeastOnce = types.AtLeastOnce
 
 	ExactlyOnce = types.ExactlyOnce
+
+	AtMostOnce = types.AtMostOnce
 )
 
 type TopicConfig = types.TopicConfig
+
+// OwnershipEnforcement configures how a topic handles publishes
+// from services which aren't allowed to publish to it.
+type OwnershipEnforcement = types.OwnershipEnforcement
+
+const (
+	// OwnershipNotEnforced allows any service to publish to the topic.
+	OwnershipNotEnforced = types.OwnershipNotEnforced
+
+	// OwnershipWarn logs a warning when a service which isn't
+	// allowed to publish to the topic does so.
+	OwnershipWarn = types.OwnershipWarn
+
+	// OwnershipDeny rejects publishes from services which aren't
+	// allowed to publish to the topic.
+	OwnershipDeny = types.OwnershipDeny
+)
+
+// PublishLimit limits the rate messages are published to a topic.
+type PublishLimit = types.PublishLimit
+
+// CloudEventsCodec wraps messages in CloudEvents envelopes.
+type CloudEventsCodec = types.CloudEventsCodec
+
+// TopicSchema declares the JSON Schema of a topic's messages.
+type TopicSchema = types.TopicSchema
+
+// SchemaRegistry registers the schemas of topics with a schema registry.
+type SchemaRegistry = types.SchemaRegistry
+
+// Encryption configures end-to-end encryption of a topic's messages.
+type Encryption = types.Encryption
+
+// KeyProvider provides data keys for envelope encryption.
+type KeyProvider = types.KeyProvider
+
+// DataKey is a data key generated by a KeyProvider.
+type DataKey = types.DataKey
+
+// GCPTopicOptions configures options specific to GCP Pub/Sub for a topic.
+type GCPTopicOptions = types.GCPTopicOptions
+
+// NSQTopicOptions configures options specific to NSQ for a topic.
+type NSQTopicOptions = types.NSQTopicOptions
+
+// GCPSubscriptionOptions configures options specific to GCP Pub/Sub for a subscription.
+type GCPSubscriptionOptions = types.GCPSubscriptionOptions
+
+// NSQSubscriptionOptions configures options specific to NSQ for a subscription.
+type NSQSubscriptionOptions = types.NSQSubscriptionOptions
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+
+	"encore.dev/pubsub/internal/types"
+)
+
+// MessageValidator validates messages against a contract, such as a JSON Schema,
+// so that messages which break the contract are caught before reaching the Handler.
+//
+// Encore does not bundle a JSON Schema implementation; wrap the library of your
+// choice. For example, using github.com/santhosh-tekuri/jsonschema:
+//
+//	schema := jsonschema.MustCompileString("order.json", orderSchema)
+//
+//	var _ = pubsub.NewSubscription(Orders, "fulfil", pubsub.SubscriptionConfig[*Order]{
+//		Handler: Fulfil,
+//		Validator: pubsub.MessageValidatorFunc(func(data []byte) error {
+//			var v any
+//			if err := json.Unmarshal(data, &v); err != nil {
+//				return err
+//			}
+//			return schema.Validate(v)
+//		}),
+//	})
+type MessageValidator = types.MessageValidator
+
+// MessageValidatorFunc adapts a function into a MessageValidator.
+type MessageValidatorFunc func(data []byte) error
+
+// ValidateMessage implements MessageValidator.
+func (f MessageValidatorFunc) ValidateMessage(data []byte) error {
+	return f(data)
+}
+
+// errInvalidMessage is reported when a message is rejected by the subscription's Validator.
+var errInvalidMessage = errors.New("message failed validation")
+
+// validateMessage validates data using validator, if set.
+func validateMessage(validator MessageValidator, data []byte) error {
+	if validator == nil {
+		return nil
+	}
+	if err := validator.ValidateMessage(data); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidMessage, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSubscription_Validator(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var (
+		received    []string
+		quarantined []*QuarantinedMessage
+	)
+	sub := NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+		Handler: func(ctx context.Context, msg *testEvent) error {
+			received = append(received, msg.Value)
+			return nil
+		},
+		Validator: MessageValidatorFunc(func(data []byte) error {
+			if !bytes.Contains(data, []byte(`"Value"`)) {
+				return errors.New("missing property: Value")
+			}
+			return nil
+		}),
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = append(quarantined, msg)
+			return nil
+		},
+	})
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Valid messages reach the handler
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, nil, []byte(`{"Value":"hello"}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, []string{"hello"})
+
+	// Invalid ones are quarantined with the validation error
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, nil, []byte(`{"Other":"hello"}`)), qt.IsNil)
+	c.Assert(received, qt.HasLen, 1)
+	c.Assert(quarantined, qt.HasLen, 1)
+	c.Assert(quarantined[0].ID, qt.Equals, "2")
+	c.Assert(quarantined[0].Reason, qt.ErrorMatches, ".*message failed validation: missing property: Value")
+	c.Assert(sub.Stats().DecodeErrors, qt.Equals, uint64(1))
+}
+
+func BenchmarkSubscription_Validator(b *testing.B) {
+	run := func(b *testing.B, validator MessageValidator) {
+		mgr, fake := newTestManager(b, "topic", "sub")
+		topic := newTopic[*testEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+		NewSubscription(topic, "sub", SubscriptionConfig[*testEvent]{
+			Handler:   func(ctx context.Context, msg *testEvent) error { return nil },
+			Validator: validator,
+		})
+		ft := fake.topics["topic"]
+		ctx := context.Background()
+		data := []byte(`{"Value":"hello"}`)
+
+		// Use distinct message IDs so deliveries aren't mistaken for a redelivery storm
+		ids := make([]string, b.N)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := ft.deliver(ctx, "sub", ids[i], 1, nil, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("none", func(b *testing.B) { run(b, nil) })
+	b.Run("validator", func(b *testing.B) {
+		run(b, MessageValidatorFunc(func(data []byte) error {
+			var v map[string]any
+			return json.Unmarshal(data, &v)
+		}))
+	})
+}
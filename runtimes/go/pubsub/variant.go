@@ -0,0 +1,151 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"encore.dev/beta/errs"
+	"encore.dev/pubsub/internal/utils"
+)
+
+// VariantAttribute is the message attribute which identifies the concrete
+// type of messages published to topics whose message type has variants
+// registered using RegisterVariant.
+const VariantAttribute = "type"
+
+// errUnknownVariant is reported when a message's VariantAttribute does not
+// match any registered variant.
+var errUnknownVariant = errors.New("unknown message variant")
+
+// variantSet is the set of variants registered for a message type.
+type variantSet struct {
+	factories map[string]func() any   // type value -> factory
+	typeNames map[reflect.Type]string // concrete type -> type value
+}
+
+var (
+	variantsMu sync.RWMutex
+	variants   = make(map[reflect.Type]*variantSet) // message type -> variants
+)
+
+// RegisterVariant registers a concrete type for the message type T, for topics
+// carrying several shapes of message distinguished by an attribute.
+//
+// T is typically an interface implemented by each variant, and factory returns a
+// pointer to a new, empty value of the variant's concrete type. Subscriptions to
+// topics of type T decode each message into the variant whose typeValue matches the
+// message's VariantAttribute, and pass it to the handler as a T. Messages whose
+// VariantAttribute does not match a registered variant are quarantined regardless of
+// the subscription's OnDecodeError policy (see DecodeErrorQuarantine).
+//
+// When a message is published to a topic of type T, its VariantAttribute is set
+// based on the concrete type of the message.
+//
+// Topics with no variants registered for their message type are unaffected.
+// RegisterVariant is intended to be called from init functions, before any
+// messages are published or received.
+//
+// For example:
+//
+//	type OrderEvent interface{ OrderID() string }
+//
+//	func init() {
+//		pubsub.RegisterVariant[OrderEvent]("placed", func() OrderEvent { return &OrderPlaced{} })
+//		pubsub.RegisterVariant[OrderEvent]("cancelled", func() OrderEvent { return &OrderCancelled{} })
+//	}
+func RegisterVariant[T any](typeValue string, factory func() T) {
+	if typeValue == "" {
+		panic("pubsub.RegisterVariant: typeValue cannot be empty")
+	}
+	if factory == nil {
+		panic("pubsub.RegisterVariant: factory cannot be nil")
+	}
+
+	concrete := reflect.TypeOf(factory())
+	if concrete == nil || concrete.Kind() != reflect.Pointer || concrete.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("pubsub.RegisterVariant: factory for variant %q must return a pointer to a struct, got %v", typeValue, concrete))
+	}
+
+	msgType := reflect.TypeOf((*T)(nil)).Elem()
+
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	set, ok := variants[msgType]
+	if !ok {
+		set = &variantSet{factories: make(map[string]func() any), typeNames: make(map[reflect.Type]string)}
+		variants[msgType] = set
+	}
+	if _, exists := set.factories[typeValue]; exists {
+		panic(fmt.Sprintf("pubsub.RegisterVariant: variant %q already registered for %v", typeValue, msgType))
+	}
+	if existing, exists := set.typeNames[concrete]; exists {
+		panic(fmt.Sprintf("pubsub.RegisterVariant: %v already registered as variant %q of %v", concrete, existing, msgType))
+	}
+
+	set.factories[typeValue] = func() any { return factory() }
+	set.typeNames[concrete] = typeValue
+}
+
+// variantsOf returns the variants registered for the message type T, or nil if there are none.
+func variantsOf[T any]() *variantSet {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+	return variants[reflect.TypeOf((*T)(nil)).Elem()]
+}
+
+// variantName returns the type value of the variant msg is an instance of.
+func (s *variantSet) variantName(msg any) (string, bool) {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+	name, ok := s.typeNames[reflect.TypeOf(msg)]
+	return name, ok
+}
+
+// variantType returns the concrete type of the variant with the given type value, or nil if there is none.
+func (s *variantSet) variantType(typeValue string) reflect.Type {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+	for typ, name := range s.typeNames {
+		if name == typeValue {
+			return typ
+		}
+	}
+	return nil
+}
+
+// unmarshalMessage decodes a message into a T, picking the concrete
+// type to decode into based on its VariantAttribute if T has variants.
+// Blob messages are not decoded, and carry the data as-is.
+func unmarshalMessage[T any](attrs map[string]string, data []byte) (msg T, err error) {
+	if unmarshalBlob(attrs, data, &msg) {
+		return msg, nil
+	}
+
+	set := variantsOf[T]()
+	if set == nil {
+		return utils.UnmarshalMessage[T](attrs, data)
+	}
+
+	typeValue := attrs[VariantAttribute]
+	variantsMu.RLock()
+	factory, ok := set.factories[typeValue]
+	variantsMu.RUnlock()
+	if !ok {
+		err = errs.B().Cause(errUnknownVariant).Code(errs.InvalidArgument).Msgf("unknown message variant %q", typeValue).Err()
+		return
+	}
+
+	val := factory()
+	if err = json.Unmarshal(data, val); err != nil {
+		err = errs.B().Cause(err).Code(errs.InvalidArgument).Msg("failed to unmarshal message").Err()
+		return
+	}
+	if err = utils.UnmarshalFields(attrs, val, utils.AttrTag); err != nil {
+		err = errs.B().Cause(err).Code(errs.InvalidArgument).Msg("failed to unmarshal attributes").Err()
+		return
+	}
+	return val.(T), nil
+}
@@ -0,0 +1,95 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type shapeEvent interface{ Area() int }
+
+type squareEvent struct {
+	Side int
+}
+
+func (s *squareEvent) Area() int { return s.Side * s.Side }
+
+type rectEvent struct {
+	Width, Height int
+	Color         string `pubsub-attr:"color"`
+}
+
+func (r *rectEvent) Area() int { return r.Width * r.Height }
+
+func init() {
+	RegisterVariant[shapeEvent]("square", func() shapeEvent { return &squareEvent{} })
+	RegisterVariant[shapeEvent]("rect", func() shapeEvent { return &rectEvent{} })
+}
+
+func TestRegisterVariant(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[shapeEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	var received shapeEvent
+	var quarantined *QuarantinedMessage
+	NewSubscription(topic, "sub", SubscriptionConfig[shapeEvent]{
+		Handler: func(ctx context.Context, msg shapeEvent) error {
+			received = msg
+			return nil
+		},
+		OnDecodeError: DecodeErrorRetry,
+		OnQuarantine: func(ctx context.Context, msg *QuarantinedMessage) error {
+			quarantined = msg
+			return nil
+		},
+	})
+
+	ft := fake.topics["topic"]
+	ctx := context.Background()
+
+	// Publishing sets the variant attribute based on the concrete type
+	_, err := topic.Publish(ctx, &rectEvent{Width: 2, Height: 3, Color: "red"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ft.lastAttrs[VariantAttribute], qt.Equals, "rect")
+
+	// Which is used to decode the message into the right type
+	c.Assert(ft.deliver(ctx, "sub", "1", 1, ft.lastAttrs, ft.lastData), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, shapeEvent(&rectEvent{Width: 2, Height: 3, Color: "red"}))
+
+	c.Assert(ft.deliver(ctx, "sub", "2", 1, map[string]string{VariantAttribute: "square"}, []byte(`{"Side":4}`)), qt.IsNil)
+	c.Assert(received, qt.DeepEquals, shapeEvent(&squareEvent{Side: 4}))
+	c.Assert(received.Area(), qt.Equals, 16)
+
+	// Unknown variants are quarantined, even though the policy is to retry
+	received = nil
+	c.Assert(ft.deliver(ctx, "sub", "3", 1, map[string]string{VariantAttribute: "circle"}, []byte(`{}`)), qt.IsNil)
+	c.Assert(received, qt.IsNil)
+	c.Assert(quarantined, qt.IsNotNil)
+	c.Assert(quarantined.ID, qt.Equals, "3")
+
+	// Other decode errors still follow the policy
+	c.Assert(ft.deliver(ctx, "sub", "4", 1, map[string]string{VariantAttribute: "square"}, []byte(`{"Side":"x"}`)), qt.IsNotNil)
+}
+
+type unregisteredShape struct{}
+
+func (unregisteredShape) Area() int { return 0 }
+
+func TestRegisterVariant_PublishUnregistered(t *testing.T) {
+	c := qt.New(t)
+	mgr, fake := newTestManager(t, "topic", "sub")
+	topic := newTopic[shapeEvent](mgr, "topic", TopicConfig{DeliveryGuarantee: AtLeastOnce})
+
+	_, err := topic.Publish(context.Background(), unregisteredShape{})
+	c.Assert(err, qt.ErrorMatches, ".*not a registered variant.*")
+	c.Assert(fake.topics["topic"].published, qt.Equals, 0)
+}
+
+func TestRegisterVariant_Invalid(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(func() { RegisterVariant[shapeEvent]("", func() shapeEvent { return &squareEvent{} }) }, qt.PanicMatches, ".*typeValue cannot be empty")
+	c.Assert(func() { RegisterVariant[shapeEvent]("square", func() shapeEvent { return &squareEvent{} }) }, qt.PanicMatches, `.*variant "square" already registered.*`)
+	c.Assert(func() { RegisterVariant[shapeEvent]("value", func() shapeEvent { return unregisteredShape{} }) }, qt.PanicMatches, ".*must return a pointer to a struct.*")
+}
@@ -4,11 +4,13 @@ package pubsub
 
 import (
 	"encore.dev/appruntime/shared/appconf"
+	"encore.dev/appruntime/shared/health"
 	"encore.dev/appruntime/shared/jsonapi"
 	"encore.dev/appruntime/shared/logging"
 	"encore.dev/appruntime/shared/reqtrack"
 	"encore.dev/appruntime/shared/shutdown"
 	"encore.dev/appruntime/shared/testsupport"
+	"encore.dev/metrics"
 )
 
 // Initialize the singleton instance.
@@ -22,7 +24,8 @@ var Singleton *Manager
 func init() {
 	Singleton = NewManager(
 		appconf.Static, appconf.Runtime, reqtrack.Singleton, testsupport.Singleton,
-		logging.RootLogger, jsonapi.Default,
+		logging.RootLogger, jsonapi.Default, metrics.Singleton,
 	)
 	shutdown.Singleton.RegisterShutdownHandler(Singleton.Shutdown)
+	health.Singleton.Register(Singleton)
 }
@@ -73,6 +73,22 @@ func (mgr *Manager) GetDB(dbName string) *Database {
 // getPool returns a database connection pool for the given database name.
 // Each time it's called it returns a new pool.
 func (mgr *Manager) getPool(encoreName, dbNameOverride string) (pool *pgxpool.Pool, found bool) {
+	cfg, found := mgr.poolConfig(encoreName, dbNameOverride)
+	if !found {
+		return nil, false
+	}
+
+	cfg.ConnConfig.Tracer = &pgxTracer{mgr: mgr}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		panic("sqldb: setup db: " + err.Error())
+	}
+
+	return pool, true
+}
+
+// poolConfig returns the connection pool config for the given database name.
+func (mgr *Manager) poolConfig(encoreName, dbNameOverride string) (cfg *pgxpool.Config, found bool) {
 	var db *config.SQLDatabase
 	for _, d := range mgr.runtime.SQLDatabases {
 		if d.EncoreName == encoreName {
@@ -89,14 +105,7 @@ func (mgr *Manager) getPool(encoreName, dbNameOverride string) (pool *pgxpool.Po
 	if err != nil {
 		panic("sqldb: " + err.Error())
 	}
-
-	cfg.ConnConfig.Tracer = &pgxTracer{mgr: mgr}
-	pool, err = pgxpool.NewWithConfig(context.Background(), cfg)
-	if err != nil {
-		panic("sqldb: setup db: " + err.Error())
-	}
-
-	return pool, true
+	return cfg, true
 }
 
 func (mgr *Manager) Shutdown(p *shutdown.Process) error {
@@ -3,12 +3,23 @@ package sqldb
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rs/xid"
+
+	"encore.dev/appruntime/shared/encoreenv"
 )
 
+// keepTestDBEnvVar is the environment variable which, when set to "1",
+// keeps the test databases of failed tests instead of dropping them.
+const keepTestDBEnvVar = "ENCORE_KEEP_TEST_DB"
+
 //publicapigen:drop
 func (mgr *Manager) NewTestDatabase(ctx context.Context, name string) (*Database, error) {
 	db := mgr.GetDB(name)
@@ -34,8 +45,34 @@ func (mgr *Manager) NewTestDatabase(ctx context.Context, name string) (*Database
 	mgr.ts.AddEndCallback(func(t *testing.T) {
 		// Shut down the connection pools and attempt to drop the database.
 		clone.shutdown()
+
+		// Keep the database of a failed test for inspection, if requested.
+		if t.Failed() && encoreenv.Get(keepTestDBEnvVar) == "1" {
+			if cfg, found := mgr.poolConfig(db.origName, dbName); found {
+				t.Logf("sqldb: keeping test database %s for inspection: %s", dbName, connURL(cfg.ConnConfig.Config))
+			}
+			return
+		}
+
 		_, _ = db.Exec(ctx, fmt.Sprintf("DROP DATABASE %s WITH (FORCE)",
 			pgx.Identifier{dbName}.Sanitize()))
 	})
 	return clone, nil
 }
+
+// connURL returns a connection URL for the database described by cfg,
+// for use with tools like psql.
+func connURL(cfg pgconn.Config) string {
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(cfg.User, cfg.Password),
+		Path:   "/" + cfg.Database,
+	}
+	if strings.HasPrefix(cfg.Host, "/") {
+		// Unix sockets are passed as a query parameter
+		u.RawQuery = url.Values{"host": {cfg.Host}}.Encode()
+	} else {
+		u.Host = net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port)))
+	}
+	return u.String()
+}
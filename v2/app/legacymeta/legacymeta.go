@@ -312,7 +312,9 @@ func (b *builder) Build() *meta.Data {
 			switch r.DeliveryGuarantee {
 			case pubsub.ExactlyOnce:
 				topic.DeliveryGuarantee = meta.PubSubTopic_EXACTLY_ONCE
-			case pubsub.AtLeastOnce:
+			case pubsub.AtLeastOnce, pubsub.AtMostOnce:
+				// At-most-once topics are provisioned as at-least-once,
+				// with the runtime acknowledging messages before processing them.
 				topic.DeliveryGuarantee = meta.PubSubTopic_AT_LEAST_ONCE
 			default:
 				panic(fmt.Sprintf("unknown delivery guarantee %v", r.DeliveryGuarantee))
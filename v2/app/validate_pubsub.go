@@ -81,6 +81,12 @@ func (d *Desc) validatePubSub(pc *parsectx.Context, result *parser.Result) {
 			topic.subs[sub.Name] = sub
 		}
 
+		if topic.resource.DeliveryGuarantee == pubsub.AtMostOnce && sub.RetryPolicy != nil {
+			pc.Errs.Add(pubsub.ErrAtMostOnceRetryPolicy.
+				AtGoNode(sub.RetryPolicy, errors.AsError("retry policy set here")),
+			)
+		}
+
 		subService, ok := d.ServiceForPath(sub.File.FSPath)
 		if !ok {
 			pc.Errs.Add(pubsub.ErrUnableToIdentifyServicesInvolved.AtGoNode(sub, errors.AsError("unable to identify service for subscription")))
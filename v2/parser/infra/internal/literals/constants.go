@@ -14,6 +14,22 @@ var constants = map[paths.Pkg]map[string]any{
 		"InfiniteRetries": -1,
 		"AtLeastOnce":     1,
 		"ExactlyOnce":     2,
+		"AtMostOnce":      3,
+
+		"DecodeErrorRetry":      0,
+		"DecodeErrorQuarantine": 1,
+		"DecodeErrorDrop":       2,
+
+		"PanicRetry":        0,
+		"PanicAckCompleted": 1,
+
+		"InitialPositionDefault":  0,
+		"InitialPositionEarliest": 1,
+		"InitialPositionLatest":   2,
+
+		"OwnershipNotEnforced": 0,
+		"OwnershipWarn":        1,
+		"OwnershipDeny":        2,
 	},
 	"encore.dev/cron": {
 		"Minute": 60,
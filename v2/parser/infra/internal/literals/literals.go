@@ -74,7 +74,12 @@ elemLoop:
 				}
 
 			case *ast.CompositeLit:
-				subStruct = value
+				// Slice, array and map literals are values, not sub structures
+				switch value.Type.(type) {
+				case *ast.ArrayType, *ast.MapType:
+				default:
+					subStruct = value
+				}
 			}
 
 			if subStruct != nil {
@@ -112,6 +117,10 @@ func ParseConstant(errs *perr.List, file *pkginfo.File, value ast.Expr) (rtn con
 		// Functions are not literal constant values
 		return constant.MakeUnknown()
 
+	case *ast.CompositeLit:
+		// Nor are slice, array or map literals
+		return constant.MakeUnknown()
+
 	case *ast.Ident:
 		switch value.Name {
 		case "true":
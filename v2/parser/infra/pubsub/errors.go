@@ -39,7 +39,17 @@ var (
 
 	errInvalidDeliveryGuarantee = errRange.New(
 		"Invalid PubSub topic config",
-		"The configuration field named \"DeliveryGuarantee\" must be set to pubsub.AtLeastOnce or pubsub.ExactlyOnce.",
+		"The configuration field named \"DeliveryGuarantee\" must be set to pubsub.AtLeastOnce, pubsub.ExactlyOnce or pubsub.AtMostOnce.",
+	)
+
+	errInvalidSchemaVersion = errRange.New(
+		"Invalid PubSub topic config",
+		"The configuration field named \"SchemaVersion\" cannot be negative.",
+	)
+
+	errInvalidRetention = errRange.New(
+		"Invalid PubSub topic config",
+		"The configuration field named \"Retention\" cannot be negative.",
 	)
 
 	errOrderingKeyNotExported = errRange.New(
@@ -83,6 +93,11 @@ var (
 		"Subscription names on topics must be unique.",
 	)
 
+	ErrAtMostOnceRetryPolicy = errRange.New(
+		"Invalid PubSub subscription config",
+		"A RetryPolicy cannot be set for subscriptions to topics with the AtMostOnce delivery guarantee, as their messages are never retried.",
+	)
+
 	ErrUnableToIdentifyServicesInvolved = errRange.New(
 		"Unable to identify services involved",
 		"Unable to identify services involved in the PubSub subscription.",
@@ -127,7 +142,7 @@ var (
 
 	errTopicRefInvalidPerms = errRange.New(
 		"Unrecognized permissions in call to pubsub.TopicRef",
-		"The only supported permission is currently pubsub.Publisher[MyMessage].",
+		"The only supported permissions are currently pubsub.Publisher[MyMessage] and pubsub.PublisherWithOptions[MyMessage].",
 	)
 
 	ErrTopicRefOutsideService = errRange.New(
@@ -32,6 +32,10 @@ type Subscription struct {
 
 	// MethodHandler specifies whether the handler is a method on a service struct.
 	MethodHandler option.Option[MethodHandler]
+
+	// RetryPolicy is the AST expression of the subscription's RetryPolicy,
+	// or nil if the default is used.
+	RetryPolicy ast.Expr
 }
 
 // MethodHandler is used to describe a handler that references a method on a service struct.
@@ -118,6 +122,71 @@ func parsePubSubSubscription(d parseutil.ReferenceInfo) {
 		MaxRetryBackoff time.Duration `literal:"MaxBackoff,optional,default"`
 		MaxRetries      int           `literal:"MaxRetries,optional,default"`
 	}
+	type dedupConfig struct {
+		Window  time.Duration `literal:",optional"`
+		MaxSize int           `literal:",optional"`
+		Store   ast.Expr      `literal:",optional,dynamic"`
+	}
+	type idempotencyConfig struct {
+		TTL          time.Duration `literal:",optional"`
+		KeyAttribute string        `literal:",optional"`
+		MaxSize      int           `literal:",optional"`
+		Store        ast.Expr      `literal:",optional,dynamic"`
+		OnReplay     ast.Expr      `literal:",optional,dynamic"`
+	}
+	type circuitBreakerConfig struct {
+		FailureThreshold int           `literal:",optional"`
+		OpenDuration     time.Duration `literal:",optional"`
+		HalfOpenProbes   int           `literal:",optional"`
+	}
+	type commitConfig struct {
+		Hook        ast.Expr      `literal:",dynamic,required"`
+		MaxMessages int           `literal:",optional"`
+		MaxDelay    time.Duration `literal:",optional"`
+	}
+	type dispatchConfig struct {
+		KeyFunc ast.Expr `literal:",dynamic,optional"` // required unless Ordered, checked at runtime
+		Workers int      `literal:",optional"`
+		Ordered bool     `literal:",optional"`
+	}
+	type redeliveryStormConfig struct {
+		MaxDeliveries int           `literal:",optional"`
+		Window        time.Duration `literal:",optional"`
+		Quarantine    bool          `literal:",optional"`
+	}
+	type coalesceConfig struct {
+		KeyFunc ast.Expr      `literal:",dynamic,required"`
+		Window  time.Duration `literal:",optional"`
+		Merge   ast.Expr      `literal:",optional,dynamic"`
+	}
+	type fairnessConfig struct {
+		TenantFunc   ast.Expr `literal:",dynamic,required"`
+		MaxPerTenant int      `literal:",optional"`
+	}
+	type recoveryRampConfig struct {
+		InitialPerSecond int           `literal:",required"`
+		TargetPerSecond  int           `literal:",required"`
+		Duration         time.Duration `literal:",optional"`
+	}
+	type decodeCacheConfig struct {
+		MaxMessages int           `literal:",optional"`
+		TTL         time.Duration `literal:",optional"`
+	}
+	type gcpSubscriptionOptions struct {
+		MaxExtension       time.Duration `literal:",optional"`
+		MaxExtensionPeriod time.Duration `literal:",optional"`
+		MinExtensionPeriod time.Duration `literal:",optional"`
+		NumGoroutines      int           `literal:",optional"`
+	}
+	type nsqSubscriptionOptions struct {
+		HeartbeatInterval   time.Duration `literal:",optional"`
+		ReadTimeout         time.Duration `literal:",optional"`
+		OutputBufferTimeout time.Duration `literal:",optional"`
+	}
+	type skipBacklogConfig struct {
+		OlderThan      time.Duration `literal:",optional"`
+		AcceptDataLoss bool          `literal:",required"`
+	}
 	type decodedConfig struct {
 		Handler ast.Expr `literal:",dynamic,required"`
 
@@ -126,6 +195,42 @@ func parsePubSubSubscription(d parseutil.ReferenceInfo) {
 		AckDeadline      time.Duration `literal:",optional,default"`
 		MessageRetention time.Duration `literal:",optional,default"`
 		RetryPolicy      retryConfig   `literal:",optional,default"`
+
+		// Runtime-only configuration, which doesn't affect the infrastructure
+		DedupByMessageID   bool                  `literal:",optional"`
+		Dedup              dedupConfig           `literal:",optional"`
+		Idempotency        idempotencyConfig     `literal:",optional"`
+		CircuitBreaker     circuitBreakerConfig  `literal:",optional"`
+		OnDecodeError      int                   `literal:",optional"`
+		OnPanic            int                   `literal:",optional"`
+		OnQuarantine       ast.Expr              `literal:",optional,dynamic"`
+		TraceAttributes    ast.Expr              `literal:",optional,dynamic"`
+		InitialPosition    int                   `literal:",optional"`
+		AuditSink          ast.Expr              `literal:",optional,dynamic"`
+		Upgrade            ast.Expr              `literal:",optional,dynamic"`
+		Commit             commitConfig          `literal:",optional"`
+		ClockSkewTolerance time.Duration         `literal:",optional"`
+		LIFO               bool                  `literal:",optional"`
+		MaxInFlight        time.Duration         `literal:",optional"`
+		MaxRetryDuration   time.Duration         `literal:",optional"`
+		FilterExpr         string                `literal:",optional"`
+		PanicToError       ast.Expr              `literal:",optional,dynamic"`
+		MaxProcessingTime  time.Duration         `literal:",optional"`
+		PropagateAuth      bool                  `literal:",optional"`
+		Dispatch           dispatchConfig        `literal:",optional"`
+		Validator          ast.Expr              `literal:",optional,dynamic"`
+		RedeliveryStorm    redeliveryStormConfig `literal:",optional"`
+		IdleTimeout        time.Duration         `literal:",optional"`
+		SkipBacklog        skipBacklogConfig     `literal:",optional"`
+		AsyncAck           bool                  `literal:",optional"`
+		Coalesce           coalesceConfig        `literal:",optional"`
+		Fairness           fairnessConfig        `literal:",optional"`
+		RecoveryRamp       recoveryRampConfig    `literal:",optional"`
+		DecodeCache        decodeCacheConfig     `literal:",optional"`
+
+		// Backend-specific options, applied only by the matching provider
+		GCP gcpSubscriptionOptions `literal:",optional"`
+		NSQ nsqSubscriptionOptions `literal:",optional"`
 	}
 	defaults := decodedConfig{
 		MaxConcurrency:   100,
@@ -174,6 +279,11 @@ func parsePubSubSubscription(d parseutil.ReferenceInfo) {
 		return
 	}
 
+	var retryPolicy ast.Expr
+	if st, ok := cfgLit.ChildStruct("RetryPolicy"); ok {
+		retryPolicy = st.Lit()
+	}
+
 	methodHandler := parseMethodHandler(d, cfg.Handler)
 	sub := &Subscription{
 		AST:           d.Call,
@@ -184,6 +294,7 @@ func parsePubSubSubscription(d parseutil.ReferenceInfo) {
 		Cfg:           subCfg,
 		Handler:       cfg.Handler,
 		MethodHandler: methodHandler,
+		RetryPolicy:   retryPolicy,
 	}
 	d.Pass.RegisterResource(sub)
 	d.Pass.AddBind(d.File, d.Ident, sub)
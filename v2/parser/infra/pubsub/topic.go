@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"go/token"
 	"strings"
+	"time"
 
 	"encr.dev/pkg/errors"
 	"encr.dev/pkg/paths"
@@ -22,6 +23,7 @@ type DeliveryGuarantee int
 const (
 	AtLeastOnce DeliveryGuarantee = iota
 	ExactlyOnce
+	AtMostOnce
 )
 
 type Topic struct {
@@ -92,9 +94,54 @@ func parsePubSubTopic(d parseutil.ReferenceInfo) {
 	}
 
 	// Decode the config
+	type publishLimit struct {
+		MaxPerSecond int  `literal:",required"`
+		Burst        int  `literal:",optional"`
+		Reject       bool `literal:",optional"`
+	}
+	type cloudEventsCodec struct {
+		Source string `literal:",required"`
+		Type   string `literal:",required"`
+	}
+	type topicSchema struct {
+		JSONSchema ast.Expr `literal:",optional,dynamic"`
+		Validator  ast.Expr `literal:",optional,dynamic"`
+		Registry   ast.Expr `literal:",optional,dynamic"`
+	}
+	type encryption struct {
+		Keys            ast.Expr      `literal:",required,dynamic"`
+		DataKeyLifetime time.Duration `literal:",optional"`
+	}
+	type gcpTopicOptions struct {
+		PublishDelayThreshold time.Duration `literal:",optional"`
+		PublishCountThreshold int           `literal:",optional"`
+		PublishByteThreshold  int           `literal:",optional"`
+		PublishTimeout        time.Duration `literal:",optional"`
+		MaxAttributeBytes     int           `literal:",optional"`
+	}
+	type nsqTopicOptions struct {
+		DialTimeout       time.Duration `literal:",optional"`
+		WriteTimeout      time.Duration `literal:",optional"`
+		MaxAttributeBytes int           `literal:",optional"`
+	}
 	type decodedConfig struct {
-		DeliveryGuarantee int    `literal:",optional"` // optional rather than required because we check for a zero value below
-		OrderingAttribute string `literal:",optional"`
+		DeliveryGuarantee  int              `literal:",optional"` // optional rather than required because we check for a zero value below
+		OrderingAttribute  string           `literal:",optional"`
+		SchemaVersion      int              `literal:",optional"`
+		TagProducerVersion bool             `literal:",optional"`
+		PublishLimit       publishLimit     `literal:",optional"`
+		PropagateAuth      bool             `literal:",optional"`
+		CloudEvents        cloudEventsCodec `literal:",optional"`
+		CanonicalJSON      bool             `literal:",optional"`
+		Schema             topicSchema      `literal:",optional"`
+		Encryption         encryption       `literal:",optional"`
+		MergeAttribute     ast.Expr         `literal:",optional,dynamic"`
+		Owner              string           `literal:",optional"`
+		AllowedPublishers  ast.Expr         `literal:",optional,dynamic"`
+		EnforceOwnership   int              `literal:",optional"`
+		Retention          time.Duration    `literal:",optional"`
+		GCP                gcpTopicOptions  `literal:",optional"`
+		NSQ                nsqTopicOptions  `literal:",optional"`
 	}
 	config := literals.Decode[decodedConfig](d.Pass.Errs, cfgLit, nil)
 
@@ -119,8 +166,16 @@ func parsePubSubTopic(d parseutil.ReferenceInfo) {
 		}
 	}
 
+	if config.SchemaVersion < 0 {
+		errs.Add(errInvalidSchemaVersion.AtGoNode(cfgLit.Expr("SchemaVersion")))
+	}
+
+	if config.Retention < 0 {
+		errs.Add(errInvalidRetention.AtGoNode(cfgLit.Expr("Retention")))
+	}
+
 	deliveryGuarantee := DeliveryGuarantee(config.DeliveryGuarantee) - 1 // The runtime variables are 1 indexed so we can detect a zero value
-	if deliveryGuarantee != AtLeastOnce && deliveryGuarantee != ExactlyOnce {
+	if deliveryGuarantee != AtLeastOnce && deliveryGuarantee != ExactlyOnce && deliveryGuarantee != AtMostOnce {
 		pos := cfgLit.Pos("DeliveryGuarantee")
 		errs.Add(errInvalidDeliveryGuarantee.AtGoPos(pos, pos))
 	}
@@ -36,7 +36,7 @@ const (
 func ResolveTopicUsage(data usage.ResolveData, topic *Topic) usage.Usage {
 	switch expr := data.Expr.(type) {
 	case *usage.MethodCall:
-		if expr.Method == "Publish" {
+		if expr.Method == "Publish" || expr.Method == "PublishWithOptions" {
 			return &PublishUsage{
 				Base: usage.Base{
 					File: expr.File,
@@ -58,6 +58,14 @@ func ResolveTopicUsage(data usage.ResolveData, topic *Topic) usage.Usage {
 			return nil
 		case option.Contains(expr.PkgFunc, pkginfo.Q("encore.dev/pubsub", "TopicRef")):
 			return parseTopicRef(data.Errs, expr)
+		case option.Contains(expr.PkgFunc, pkginfo.Q("encore.dev/pubsub", "PublishAll")):
+			return &PublishUsage{
+				Base: usage.Base{
+					File: expr.File,
+					Bind: expr.Bind,
+					Expr: expr,
+				},
+			}
 		}
 	}
 
@@ -72,7 +80,8 @@ func parseTopicRef(errs *perr.List, expr *usage.FuncArg) usage.Usage {
 	}
 
 	checkUsage := func(typ schema.Type) (usage.Usage, bool) {
-		if schemautil.IsNamed(typ, "encore.dev/pubsub", "Publisher") {
+		if schemautil.IsNamed(typ, "encore.dev/pubsub", "Publisher") ||
+			schemautil.IsNamed(typ, "encore.dev/pubsub", "PublisherWithOptions") {
 			return &RefUsage{
 				Base: usage.Base{
 					File: expr.File,
@@ -92,7 +101,8 @@ func parseTopicRef(errs *perr.List, expr *usage.FuncArg) usage.Usage {
 
 	// Determine if we have a custom ref type,
 	// either in the form "type Foo = pubsub.Publisher[Msg]"
-	// or in the form "type Foo interface { pubsub.Publisher[Msg] }"
+	// or in the form "type Foo interface { pubsub.Publisher[Msg] }",
+	// and likewise for pubsub.PublisherWithOptions.
 	if named, ok := expr.TypeArgs[0].(schema.NamedType); ok {
 		underlying := named.Decl().Type
 		if u, ok := checkUsage(underlying); ok {
@@ -29,6 +29,30 @@ var topic = pubsub.NewTopic[Msg]("topic", pubsub.TopicConfig{DeliveryGuarantee:
 
 func Foo() { topic.Publish(context.Background(), Msg{}) }
 
+`,
+			Want: []usage.Usage{&pubsub.PublishUsage{}},
+		},
+		{
+			Name: "publish_with_options",
+			Code: `
+type Msg struct{}
+
+var topic = pubsub.NewTopic[Msg]("topic", pubsub.TopicConfig{DeliveryGuarantee: pubsub.AtLeastOnce})
+
+func Foo() { topic.PublishWithOptions(context.Background(), Msg{}, pubsub.WithDurableConfirm()) }
+
+`,
+			Want: []usage.Usage{&pubsub.PublishUsage{}},
+		},
+		{
+			Name: "publish_all",
+			Code: `
+type Msg struct{}
+
+var topic = pubsub.NewTopic[Msg]("topic", pubsub.TopicConfig{DeliveryGuarantee: pubsub.AtLeastOnce})
+
+func Foo() { pubsub.PublishAll(context.Background(), Msg{}, topic) }
+
 `,
 			Want: []usage.Usage{&pubsub.PublishUsage{}},
 		},
@@ -40,6 +64,19 @@ type Msg struct{}
 var topic = pubsub.NewTopic[Msg]("topic", pubsub.TopicConfig{DeliveryGuarantee: pubsub.AtLeastOnce})
 
 var ref = pubsub.TopicRef[pubsub.Publisher[Msg]](topic)
+`,
+			Want: []usage.Usage{&pubsub.RefUsage{
+				Perms: []pubsub.Perm{pubsub.PublishPerm},
+			}},
+		},
+		{
+			Name: "ref_with_options",
+			Code: `
+type Msg struct{}
+
+var topic = pubsub.NewTopic[Msg]("topic", pubsub.TopicConfig{DeliveryGuarantee: pubsub.AtLeastOnce})
+
+var ref = pubsub.TopicRef[pubsub.PublisherWithOptions[Msg]](topic)
 `,
 			Want: []usage.Usage{&pubsub.RefUsage{
 				Perms: []pubsub.Perm{pubsub.PublishPerm},